@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -54,7 +55,10 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	storeFactory := quickfix.NewMemoryStoreFactory()
+	if err := exchange.App.ConfigureDropCopy(appSettings); err != nil {
+		panic(err)
+	}
+	storeFactory := exchange.NewStoreFactory(appSettings)
 	//logFactory, _ := quickfix.NewFileLogFactory(appSettings)
 	useLogging, err := appSettings.GlobalSettings().BoolSetting("Logging")
 	var logFactory quickfix.LogFactory
@@ -70,7 +74,8 @@ func main() {
 
 	var ex = &exchange.TheExchange
 
-	ex.Start()
+	ex.Start(p)
+	defer ex.Stop()
 
 	err = acceptor.Start()
 	if err!=nil {
@@ -120,7 +125,7 @@ func main() {
 			goto again
 		}
 		if "help" == parts[0] {
-			fmt.Println("The available commands are: quit, sessions, book SYMBOL, watch SYMBOL, unwatch SYMBOL, list")
+			fmt.Println("The available commands are: quit, sessions, book SYMBOL, watch SYMBOL, unwatch SYMBOL, list, auction SYMBOL|all, uncross SYMBOL|all, halt SYMBOL|all, resume SYMBOL|all, state SYMBOL|all [STATE], kill all|session ID|account ACCOUNT|symbol SYMBOL [block], enable all|session ID|account ACCOUNT|symbol SYMBOL, blocked, snapshot, instrument create SYMBOL [ATTRIBUTES]|disable SYMBOL|enable SYMBOL|delete SYMBOL")
 		} else if "quit" == parts[0] {
 			break
 		} else if "sessions" == parts[0] {
@@ -157,6 +162,92 @@ func main() {
 				instrument := common.IMap.GetBySymbol(symbol)
 				fmt.Println(instrument)
 			}
+		} else if ("auction" == parts[0] || "uncross" == parts[0] || "halt" == parts[0] || "resume" == parts[0] || "state" == parts[0]) && len(parts) >= 2 {
+			symbols := []string{parts[1]}
+			if "all" == parts[1] {
+				symbols = common.IMap.AllSymbols()
+			}
+			for _, symbol := range symbols {
+				instrument := common.IMap.GetBySymbol(symbol)
+				if instrument == nil {
+					fmt.Println("unknown symbol", symbol)
+					continue
+				}
+				var err error
+				switch parts[0] {
+				case "auction":
+					err = ex.StartAuction(instrument)
+				case "uncross":
+					err = ex.Uncross(instrument)
+				case "halt":
+					err = ex.Halt(instrument)
+				case "resume":
+					err = ex.Resume(instrument)
+				case "state":
+					if len(parts) == 2 {
+						fmt.Println(symbol, ex.GetTradingState(instrument))
+						continue
+					}
+					var state common.TradingState
+					state, err = common.ParseTradingState(parts[2])
+					if err == nil {
+						err = ex.SetTradingState(instrument, state)
+					}
+				}
+				if err != nil {
+					fmt.Println(err)
+				}
+			}
+		} else if ("kill" == parts[0] || "enable" == parts[0]) && len(parts) >= 2 {
+			value := ""
+			if len(parts) >= 3 {
+				value = parts[2]
+			}
+			k, err := exchange.ParseKillSwitch(parts[1], value)
+			if err != nil {
+				fmt.Println(err)
+			} else if "kill" == parts[0] {
+				block := parts[len(parts)-1] == "block"
+				fmt.Println("cancelled", ex.MassCancel(k, block), "orders and quotes for", k)
+			} else if !ex.EnableTrading(k) {
+				fmt.Println("no block for", k)
+			}
+		} else if "snapshot" == parts[0] {
+			file, err := ex.TakeSnapshot()
+			if err != nil {
+				fmt.Println(err)
+			} else {
+				fmt.Println("snapshot written to", file)
+			}
+		} else if "instrument" == parts[0] && len(parts) >= 3 {
+			var err error
+			switch parts[1] {
+			case "create":
+				var spec common.InstrumentSpec
+				spec, err = common.ParseInstrumentSpec(parts[3:])
+				if err == nil {
+					var instrument common.Instrument
+					instrument, err = ex.CreateInstrument(parts[2], spec)
+					if err == nil {
+						fmt.Println(instrument)
+					}
+				}
+			case "disable":
+				err = ex.DisableInstrument(parts[2])
+			case "enable":
+				err = ex.EnableInstrument(parts[2])
+			case "delete":
+				err = ex.DeleteInstrument(parts[2])
+			default:
+				err = errors.New("unknown instrument command " + parts[1])
+			}
+			if err != nil {
+				fmt.Println(err)
+			}
+		} else if "blocked" == parts[0] {
+			for _, k := range ex.Blocked() {
+				fmt.Println(k)
+			}
 		} else {
 			fmt.Println("Unknown command, '", s, "' use 'help'")
 		}
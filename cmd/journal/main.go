@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/robaho/go-trader/internal/exchange"
+	"github.com/robaho/go-trader/pkg/common"
+)
+
+// journal dumps the exchange event journal, optionally filtered by entry type, session, symbol, sequence or time
+func main() {
+	props := flag.String("props", "configs/got_settings", "set the exchange properties file")
+	file := flag.String("file", "", "the journal file, defaults to journal_file in the exchange properties")
+	types := flag.String("type", "", "only show these entry types, comma separated, e.g. order,cancel,trade")
+	session := flag.String("session", "", "only show entries for the session")
+	symbol := flag.String("symbol", "", "only show entries for the symbol")
+	from := flag.Uint64("from", 0, "only show entries with a sequence number >= from")
+	to := flag.Uint64("to", 0, "only show entries with a sequence number <= to, 0 for all")
+	since := flag.String("since", "", "only show entries at or after the time, RFC3339 format")
+	asJSON := flag.Bool("json", false, "output the entries as JSON")
+
+	flag.Parse()
+
+	path := *file
+	if path == "" {
+		p, err := common.NewProperties(*props)
+		if err != nil {
+			fmt.Println("unable to load exchange properties", err)
+			os.Exit(1)
+		}
+		path = p.GetString("journal_file", "")
+		if path == "" {
+			fmt.Println("journal_file is not configured, use -file")
+			os.Exit(1)
+		}
+	}
+
+	var sinceTime time.Time
+	if *since != "" {
+		var err error
+		sinceTime, err = time.Parse(time.RFC3339, *since)
+		if err != nil {
+			fmt.Println("invalid since time", err)
+			os.Exit(1)
+		}
+	}
+
+	typeFilter := make(map[exchange.JournalType]bool)
+	for _, t := range strings.Split(*types, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			typeFilter[exchange.JournalType(t)] = true
+		}
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	count := 0
+
+	err := exchange.ReadJournal(path, func(entry *exchange.JournalEntry) error {
+		if len(typeFilter) > 0 && !typeFilter[entry.Type] {
+			return nil
+		}
+		if *session != "" && entry.Session != *session && !tradeSession(entry, *session) {
+			return nil
+		}
+		if *symbol != "" && entry.Symbol != *symbol {
+			return nil
+		}
+		if entry.Seq < *from || (*to != 0 && entry.Seq > *to) {
+			return nil
+		}
+		if entry.Time.Before(sinceTime) {
+			return nil
+		}
+		count++
+		if *asJSON {
+			return enc.Encode(entry)
+		}
+		fmt.Println(entry)
+		return nil
+	})
+	if err != nil {
+		fmt.Println("unable to read journal", err)
+		os.Exit(1)
+	}
+	if !*asJSON {
+		fmt.Println(count, "entries")
+	}
+}
+
+// tradeSession returns true if the session was the buyer or seller of a trade entry
+func tradeSession(entry *exchange.JournalEntry, session string) bool {
+	return entry.Trade != nil && (entry.Trade.Buyer == session || entry.Trade.Seller == session)
+}
@@ -0,0 +1,283 @@
+package exchange
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/robaho/fixed"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+// call auctions, during an auction orders are booked without matching, and the indicative uncross price and
+// volume are published with the book. When the auction ends the book is uncrossed at the single price that
+// maximizes the executed volume, and continuous matching resumes.
+
+const immediateInAuction = "immediate orders are not accepted during an auction"
+
+func isMarketPrice(price Fixed) bool {
+	return price.Cmp(buyMarketPrice) == 0 || price.Cmp(sellMarketPrice) == 0
+}
+
+// bookAuction books the order without matching
+func (ob *orderBook) bookAuction(so sessionOrder) {
+	if so.isImmediate() {
+		so.order.OrderState = Cancelled
+		so.order.RejectReason = immediateInAuction
+		return
+	}
+	if so.order.Side == Buy {
+		ob.bids = insertSort(ob.bids, so, 1)
+	} else {
+		ob.asks = insertSort(ob.asks, so, -1)
+	}
+}
+
+func levelQuantity(level priceLevel) Fixed {
+	quantity := ZERO
+	for node := level.head; node != nil; node = node.next {
+		quantity = quantity.Add(node.order.order.Remaining)
+	}
+	return quantity
+}
+
+type uncrossCandidate struct {
+	price   Fixed
+	volume  Fixed
+	surplus Fixed // buy quantity less sell quantity at the price
+}
+
+// uncrossPrice returns the price that maximizes the executed volume. If several prices execute the same
+// volume, the price with the smallest surplus is used, then the highest price if all surpluses are on the buy
+// side, the lowest if all are on the sell side, otherwise the price closest to the last trade price.
+// ok is false if the book does not cross.
+func (ob *orderBook) uncrossPrice() (price Fixed, volume Fixed, ok bool) {
+	var candidates []uncrossCandidate
+
+	addCandidates := func(levels []priceLevel) {
+		for _, level := range levels {
+			if isMarketPrice(level.price) {
+				continue
+			}
+			buy, sell := ZERO, ZERO
+			for _, bid := range ob.bids {
+				if bid.price.GreaterThanOrEqual(level.price) {
+					buy = buy.Add(levelQuantity(bid))
+				}
+			}
+			for _, ask := range ob.asks {
+				if ask.price.LessThanOrEqual(level.price) {
+					sell = sell.Add(levelQuantity(ask))
+				}
+			}
+			volume := MinDecimal(buy, sell)
+			if volume.GreaterThan(ZERO) {
+				candidates = append(candidates, uncrossCandidate{level.price, volume, buy.Sub(sell)})
+			}
+		}
+	}
+	addCandidates(ob.bids)
+	addCandidates(ob.asks)
+
+	if len(candidates) == 0 {
+		return ZERO, ZERO, false
+	}
+
+	candidates = filterCandidates(candidates, func(c uncrossCandidate, best uncrossCandidate) int {
+		return c.volume.Cmp(best.volume)
+	})
+	candidates = filterCandidates(candidates, func(c uncrossCandidate, best uncrossCandidate) int {
+		return best.surplus.Abs().Cmp(c.surplus.Abs())
+	})
+
+	allBuy, allSell := true, true
+	for _, c := range candidates {
+		allBuy = allBuy && c.surplus.GreaterThan(ZERO)
+		allSell = allSell && c.surplus.LessThan(ZERO)
+	}
+
+	switch {
+	case allBuy:
+		candidates = filterCandidates(candidates, func(c uncrossCandidate, best uncrossCandidate) int {
+			return c.price.Cmp(best.price)
+		})
+	case allSell:
+		candidates = filterCandidates(candidates, func(c uncrossCandidate, best uncrossCandidate) int {
+			return best.price.Cmp(c.price)
+		})
+	case ob.hasLastPrice:
+		candidates = filterCandidates(candidates, func(c uncrossCandidate, best uncrossCandidate) int {
+			return best.price.Sub(ob.lastPrice).Abs().Cmp(c.price.Sub(ob.lastPrice).Abs())
+		})
+	}
+
+	// any remaining tie uses the lowest price
+	best := candidates[0]
+	for _, c := range candidates {
+		if c.price.LessThan(best.price) {
+			best = c
+		}
+	}
+	return best.price, best.volume, true
+}
+
+// filterCandidates returns the candidates that are the best according to cmp, which returns > 0 if c is
+// better than best
+func filterCandidates(candidates []uncrossCandidate, cmp func(c uncrossCandidate, best uncrossCandidate) int) []uncrossCandidate {
+	var filtered []uncrossCandidate
+	for _, c := range candidates {
+		if len(filtered) == 0 {
+			filtered = append(filtered, c)
+			continue
+		}
+		switch cmp(c, filtered[0]) {
+		case 1:
+			filtered = append(filtered[:0], c)
+		case 0:
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// uncross ends the auction, executing the crossing orders in price/time priority at the uncross price
+func (ob *orderBook) uncross(when time.Time) []trade {
+	price, _, ok := ob.uncrossPrice()
+
+	var trades []trade
+
+	if ok {
+		// the auction price is the reference for the static price band
+		ob.referencePrice = price
+		ob.hasReference = true
+
+		tradeID := atomic.AddInt64(&nextTradeID, 1)
+
+		for len(ob.bids) > 0 && len(ob.asks) > 0 {
+			bidNode := ob.bids[0].head
+			askNode := ob.asks[0].head
+
+			if bidNode.order.getPrice().LessThan(price) || askNode.order.getPrice().GreaterThan(price) {
+				break
+			}
+			if ob.stp.isSelfTrade(bidNode.order, askNode.order) {
+				ob.preventSelfTrade(bidNode, askNode)
+				continue
+			}
+			qty := MinDecimal(bidNode.order.order.Remaining, askNode.order.order.Remaining)
+			trades = append(trades, ob.execute(bidNode, askNode, price, qty, tradeID, when, ""))
+		}
+	}
+
+	// market orders do not rest during continuous trading
+	ob.cancelMarketOrders(&ob.bids)
+	ob.cancelMarketOrders(&ob.asks)
+
+	trades = append(trades, matchTrades(ob, when)...)
+
+	return ob.triggerStops(trades)
+}
+
+func (ob *orderBook) cancelMarketOrders(levels *[]priceLevel) {
+	for len(*levels) > 0 && isMarketPrice((*levels)[0].price) {
+		so := (*levels)[0].head.order
+		ob.remove(so)
+		ob.changed = append(ob.changed, so)
+	}
+}
+
+// auctionSchedule holds the daily auction periods, as offsets from midnight
+type auctionSchedule []struct {
+	start, end time.Duration
+}
+
+// parseAuctionSchedule parses a list of periods in the form HH:MM-HH:MM,HH:MM-HH:MM
+func parseAuctionSchedule(s string) (auctionSchedule, error) {
+	var schedule auctionSchedule
+	for _, period := range strings.Split(s, ",") {
+		period = strings.TrimSpace(period)
+		if period == "" {
+			continue
+		}
+		parts := strings.Split(period, "-")
+		if len(parts) != 2 {
+			return nil, errors.New("invalid auction period " + period)
+		}
+		start, err := parseTimeOfDay(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseTimeOfDay(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		schedule = append(schedule, struct{ start, end time.Duration }{start, end})
+	}
+	return schedule, nil
+}
+
+// parseTimeOfDay parses HH:MM and returns the offset from midnight
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	year, month, day := t.Date()
+	return t.Sub(time.Date(year, month, day, 0, 0, 0, 0, t.Location()))
+}
+
+// events returns whether an auction period started or ended in (last,now]
+func (schedule auctionSchedule) events(last time.Time, now time.Time) (started bool, ended bool) {
+	if last.IsZero() || now.Sub(last) >= 24*time.Hour {
+		return false, false
+	}
+	from, to := sinceMidnight(last), sinceMidnight(now)
+	crossed := func(t time.Duration) bool {
+		if from <= to {
+			return from < t && t <= to
+		}
+		// passed midnight
+		return from < t || t <= to
+	}
+	for _, period := range schedule {
+		started = started || crossed(period.start)
+		ended = ended || crossed(period.end)
+	}
+	return
+}
+
+// StartAuction stops continuous matching for the instrument, orders accumulate until Uncross is called
+func (e *exchange) StartAuction(instrument Instrument) error {
+	if state := e.GetTradingState(instrument); state != Open {
+		return errors.New(instrument.Symbol() + " is " + string(state) + ", an auction can only be started when open")
+	}
+	return e.SetTradingState(instrument, Auction)
+}
+
+// Uncross ends the auction for the instrument, and resumes continuous matching
+func (e *exchange) Uncross(instrument Instrument) error {
+	if state := e.GetTradingState(instrument); state != Auction && state != PreOpen {
+		return errors.New(instrument.Symbol() + " is not in an auction")
+	}
+	return e.SetTradingState(instrument, Open)
+}
+
+// runAuctionSchedule starts and uncrosses the auctions for all instruments, according to the schedule
+func (e *exchange) runAuctionSchedule(last time.Time, now time.Time) {
+	started, ended := e.auctions.events(last, now)
+	for _, symbol := range IMap.AllSymbols() {
+		instrument := IMap.GetBySymbol(symbol)
+		if ended {
+			e.Uncross(instrument)
+		}
+		if started {
+			e.StartAuction(instrument)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/robaho/fixed"
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+func TestAuctionUncross(t *testing.T) {
+	var ob = orderBook{state: Auction}
+	var ex = testExchangeClient{}
+
+	var i = Equity{}
+
+	b1 := LimitOrder(i, Buy, NewDecimal("102"), NewDecimal("10"))
+	b2 := LimitOrder(i, Buy, NewDecimal("101"), NewDecimal("20"))
+	b3 := MarketOrder(i, Buy, NewDecimal("5"))
+	s1 := LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("15"))
+	s2 := LimitOrder(i, Sell, NewDecimal("101"), NewDecimal("10"))
+	s3 := LimitOrder(i, Sell, NewDecimal("103"), NewDecimal("10"))
+
+	for _, o := range []*Order{b1, b2, b3, s1, s2, s3} {
+		trades, _ := ob.add(sessionOrder{ex, o, time.Now()})
+		if len(trades) != 0 {
+			t.Fatal("should not match during an auction", trades)
+		}
+	}
+
+	book := ob.buildBook()
+	if !book.InAuction {
+		t.Error("book should be in auction")
+	}
+	if len(book.Bids) != 2 {
+		t.Error("market orders should not be published", book.Bids)
+	}
+	if !book.IndicativePrice.Equal(NewDecimal("101")) || !book.IndicativeVolume.Equal(NewDecimal("25")) {
+		t.Error("wrong indicative price", book.IndicativeVolume, book.IndicativePrice)
+	}
+
+	ioc := LimitOrder(i, Buy, NewDecimal("103"), NewDecimal("1"))
+	ioc.TimeInForce = ImmediateOrCancel
+	ob.add(sessionOrder{ex, ioc, time.Now()})
+	if ioc.OrderState != Cancelled {
+		t.Error("immediate orders should be cancelled during an auction", ioc)
+	}
+
+	trades := ob.setState(Open, time.Now())
+	if !ob.isMatching() {
+		t.Error("auction should have ended")
+	}
+
+	volume := ZERO
+	for _, trade := range trades {
+		if !trade.price.Equal(NewDecimal("101")) {
+			t.Error("all trades should be at the uncross price", trade.price)
+		}
+		volume = volume.Add(trade.quantity)
+	}
+	if !volume.Equal(NewDecimal("25")) {
+		t.Error("wrong uncross volume", volume)
+	}
+	if b3.OrderState != Filled || b1.OrderState != Filled || s1.OrderState != Filled || s2.OrderState != Filled {
+		t.Error("orders should be filled", b3, b1, s1, s2)
+	}
+	if b2.OrderState != PartialFill || !b2.Remaining.Equal(NewDecimal("10")) {
+		t.Error("wrong remaining", b2)
+	}
+
+	book = ob.buildBook()
+	if book.InAuction {
+		t.Error("book should not be in auction")
+	}
+	if len(book.Bids) != 1 || len(book.Asks) != 1 {
+		t.Error("wrong book after uncross", book)
+	}
+}
+
+func TestAuctionUncrossPrice(t *testing.T) {
+	var ob = orderBook{state: Auction}
+	var ex = testExchangeClient{}
+
+	var i = Equity{}
+
+	// equal volume and surplus at 100 and 101, use the price closest to the last trade
+	ob.lastPrice = NewDecimal("101")
+	ob.hasLastPrice = true
+
+	ob.add(sessionOrder{ex, LimitOrder(i, Buy, NewDecimal("101"), NewDecimal("10")), time.Now()})
+	ob.add(sessionOrder{ex, LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("10")), time.Now()})
+
+	price, volume, ok := ob.uncrossPrice()
+	if !ok || !price.Equal(NewDecimal("101")) || !volume.Equal(NewDecimal("10")) {
+		t.Error("wrong uncross", price, volume, ok)
+	}
+
+	// buy surplus moves the price up
+	ob.add(sessionOrder{ex, LimitOrder(i, Buy, NewDecimal("101"), NewDecimal("5")), time.Now()})
+	ob.lastPrice = NewDecimal("100")
+
+	price, volume, ok = ob.uncrossPrice()
+	if !ok || !price.Equal(NewDecimal("101")) || !volume.Equal(NewDecimal("10")) {
+		t.Error("wrong uncross", price, volume, ok)
+	}
+
+	var empty = orderBook{state: Auction}
+	empty.add(sessionOrder{ex, LimitOrder(i, Buy, NewDecimal("99"), NewDecimal("10")), time.Now()})
+	empty.add(sessionOrder{ex, LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("10")), time.Now()})
+	if _, _, ok := empty.uncrossPrice(); ok {
+		t.Error("book does not cross")
+	}
+}
+
+func TestAuctionSchedule(t *testing.T) {
+	schedule, err := parseAuctionSchedule("09:25-09:30, 15:55-16:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2024, 3, 4, 0, 0, 0, 0, time.Local)
+
+	started, ended := schedule.events(day.Add(9*time.Hour+25*time.Minute-time.Second), day.Add(9*time.Hour+25*time.Minute))
+	if !started || ended {
+		t.Error("auction should start", started, ended)
+	}
+	started, ended = schedule.events(day.Add(16*time.Hour-time.Second), day.Add(16*time.Hour))
+	if started || !ended {
+		t.Error("auction should end", started, ended)
+	}
+	started, ended = schedule.events(day.Add(12*time.Hour), day.Add(12*time.Hour+time.Second))
+	if started || ended {
+		t.Error("no auction event", started, ended)
+	}
+
+	if _, err := parseAuctionSchedule("09:25"); err == nil {
+		t.Error("should not parse")
+	}
+}
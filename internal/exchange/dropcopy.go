@@ -0,0 +1,167 @@
+package exchange
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/quickfixgo/fix44/executionreport"
+	"github.com/quickfixgo/quickfix"
+	"github.com/quickfixgo/quickfix/config"
+	"github.com/quickfixgo/quickfix/store/file"
+	. "github.com/robaho/fixed"
+)
+
+// A drop copy session is a static FIX session configured with DropCopy=Y, which receives a copy of the execution
+// reports sent to the trading sessions of every protocol. It does not trade. The copied sessions can be limited with
+// DropCopySessions, by TargetCompID for FIX sessions and the session id for the others, e.g. grpc:NAME, and the
+// accounts with DropCopyAccounts, both comma separated and empty for all. Each copy has CopyMsgIndicator set, the
+// originating session in DeliverToCompID/DeliverToSubID, and the order's Account.
+//
+// The drop copy sessions should have a FileStorePath and PersistMessages=Y, so that the copies sent while the session
+// is disconnected are resent when the drop copy client requests them after the next logon.
+
+const (
+	dropCopySetting         = "DropCopy"
+	dropCopySessionsSetting = "DropCopySessions"
+	dropCopyAccountsSetting = "DropCopyAccounts"
+)
+
+type dropCopy struct {
+	sessionID quickfix.SessionID
+	// the TargetCompIDs and accounts to copy, nil for all
+	sessions map[string]bool
+	accounts map[string]bool
+}
+
+// ConfigureDropCopy reads the drop copy sessions from the FIX settings, it must be called before the acceptor is started
+func (app *myApplication) ConfigureDropCopy(settings *quickfix.Settings) error {
+	app.dropCopies = nil
+	for sessionID, ss := range settings.SessionSettings() {
+		if !ss.HasSetting(dropCopySetting) {
+			continue
+		}
+		enabled, err := ss.BoolSetting(dropCopySetting)
+		if err != nil {
+			return fmt.Errorf("invalid %s for %v: %v", dropCopySetting, sessionID, err)
+		}
+		if !enabled {
+			continue
+		}
+		dc := &dropCopy{sessionID: sessionID}
+		dc.sessions = parseDropCopyFilter(ss, dropCopySessionsSetting)
+		dc.accounts = parseDropCopyFilter(ss, dropCopyAccountsSetting)
+		app.dropCopies = append(app.dropCopies, dc)
+	}
+	return nil
+}
+
+func parseDropCopyFilter(ss *quickfix.SessionSettings, setting string) map[string]bool {
+	if !ss.HasSetting(setting) {
+		return nil
+	}
+	value, _ := ss.Setting(setting)
+	var filter map[string]bool
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			if filter == nil {
+				filter = make(map[string]bool)
+			}
+			filter[s] = true
+		}
+	}
+	return filter
+}
+
+// isDropCopy returns true if the session is a drop copy session
+func (app *myApplication) isDropCopy(sessionID quickfix.SessionID) bool {
+	for _, dc := range app.dropCopies {
+		if dc.sessionID == sessionID {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns true if the execution reports of the session and account are copied
+func (dc *dropCopy) matches(compID string, account string) bool {
+	if dc.sessions != nil && !dc.sessions[compID] {
+		return false
+	}
+	if dc.accounts != nil && !dc.accounts[account] {
+		return false
+	}
+	return true
+}
+
+// dropCopySource returns the comp and sub id a copy is delivered to, the TargetCompID and TargetSubID of a FIX
+// session, e.g. FIX.4.4:GOX->CLIENT1/DESK, or the session id of the other sessions
+func dropCopySource(sessionID string) (compID string, subID string) {
+	i := strings.Index(sessionID, "->")
+	if !strings.HasPrefix(sessionID, "FIX") || i < 0 {
+		return sessionID, ""
+	}
+	target := strings.Split(strings.SplitN(sessionID[i+2:], ":", 2)[0], "/")
+	if len(target) > 1 {
+		subID = target[1]
+	}
+	return target[0], subID
+}
+
+// copyOrderStatus sends a copy of the order status to the matching drop copy sessions. The copies are sent even if
+// the drop copy session is not logged on, so they are stored for resend.
+func (app *myApplication) copyOrderStatus(so sessionOrder, triggered bool) {
+	app.sendCopies(so, func() executionreport.ExecutionReport {
+		return newExecutionReport(statusExecType(so.order, triggered), so.order)
+	})
+}
+
+// copyFill sends a copy of the fill to the matching drop copy sessions
+func (app *myApplication) copyFill(so sessionOrder, price Fixed, quantity Fixed, remaining Fixed) {
+	app.sendCopies(so, func() executionreport.ExecutionReport {
+		return newTradeExecutionReport(so.order, price, quantity, remaining)
+	})
+}
+
+func (app *myApplication) sendCopies(so sessionOrder, report func() executionreport.ExecutionReport) {
+	if len(app.dropCopies) == 0 {
+		return
+	}
+	compID, subID := dropCopySource(so.client.SessionID())
+	for _, dc := range app.dropCopies {
+		if dc.matches(compID, so.order.Account) {
+			quickfix.SendToTarget(newDropCopy(report(), compID, subID), dc.sessionID)
+		}
+	}
+}
+
+// newDropCopy returns a copy of the execution report tagged with the originating session
+func newDropCopy(msg executionreport.ExecutionReport, compID string, subID string) *quickfix.Message {
+	m := quickfix.NewMessage()
+	msg.ToMessage().CopyInto(m)
+	er := executionreport.FromMessage(m)
+	er.SetCopyMsgIndicator(true)
+	er.Header.SetDeliverToCompID(compID)
+	if subID != "" {
+		er.Header.SetDeliverToSubID(subID)
+	}
+	return m
+}
+
+type storeFactory struct {
+	settings *quickfix.Settings
+	file     quickfix.MessageStoreFactory
+	memory   quickfix.MessageStoreFactory
+}
+
+// NewStoreFactory returns a message store factory that uses a file store for the sessions configured with a
+// FileStorePath, e.g. the drop copy sessions, and a memory store for all other sessions
+func NewStoreFactory(settings *quickfix.Settings) quickfix.MessageStoreFactory {
+	return &storeFactory{settings: settings, file: file.NewStoreFactory(settings), memory: quickfix.NewMemoryStoreFactory()}
+}
+
+func (f *storeFactory) Create(sessionID quickfix.SessionID) (quickfix.MessageStore, error) {
+	if ss, ok := f.settings.SessionSettings()[sessionID]; ok && ss.HasSetting(config.FileStorePath) {
+		return f.file.Create(sessionID)
+	}
+	return f.memory.Create(sessionID)
+}
@@ -0,0 +1,93 @@
+package exchange
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/field"
+	"github.com/quickfixgo/fix44/executionreport"
+	"github.com/quickfixgo/quickfix"
+	"github.com/shopspring/decimal"
+)
+
+func TestDropCopy(t *testing.T) {
+	settings, err := quickfix.ParseSettings(strings.NewReader(`
+[DEFAULT]
+SenderCompID=GOX
+BeginString=FIX.4.4
+
+[SESSION]
+TargetCompID=*
+
+[SESSION]
+TargetCompID=DROPALL
+DropCopy=Y
+
+[SESSION]
+TargetCompID=DROPSOME
+DropCopy=Y
+DropCopySessions=CLIENT1, CLIENT2
+DropCopyAccounts=ACC1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var app myApplication
+	if err := app.ConfigureDropCopy(settings); err != nil {
+		t.Fatal(err)
+	}
+	if len(app.dropCopies) != 2 {
+		t.Fatal("wrong number of drop copy sessions", app.dropCopies)
+	}
+
+	client1 := quickfix.SessionID{BeginString: "FIX.4.4", SenderCompID: "GOX", TargetCompID: "CLIENT1", TargetSubID: "DESK"}
+	client3 := quickfix.SessionID{BeginString: "FIX.4.4", SenderCompID: "GOX", TargetCompID: "CLIENT3"}
+	if compID, subID := dropCopySource(client1.String()); compID != "CLIENT1" || subID != "DESK" {
+		t.Error("wrong FIX source", compID, subID)
+	}
+	if compID, subID := dropCopySource(fixClient{sessionID: client3}.SessionID()); compID != "CLIENT3" || subID != "" {
+		t.Error("wrong FIX source", compID, subID)
+	}
+	if compID, subID := dropCopySource("grpc:CLIENT2"); compID != "grpc:CLIENT2" || subID != "" {
+		t.Error("other sessions should use the session id", compID, subID)
+	}
+	for _, dc := range app.dropCopies {
+		if !app.isDropCopy(dc.sessionID) {
+			t.Error("should be a drop copy session", dc.sessionID)
+		}
+		switch dc.sessionID.TargetCompID {
+		case "DROPALL":
+			if !dc.matches("CLIENT3", "") || !dc.matches("grpc:CLIENT2", "") {
+				t.Error("all sessions should be copied")
+			}
+		case "DROPSOME":
+			if !dc.matches("CLIENT1", "ACC1") || dc.matches("CLIENT1", "ACC2") || dc.matches("CLIENT3", "ACC1") {
+				t.Error("only the configured sessions and accounts should be copied")
+			}
+		}
+	}
+	if app.isDropCopy(client1) {
+		t.Error("trading session is not a drop copy session")
+	}
+
+	msg := executionreport.New(field.NewOrderID("1"), field.NewExecID("1"), field.NewExecType(enum.ExecType_NEW), field.NewOrdStatus(enum.OrdStatus_NEW), field.NewSide(enum.Side_BUY), field.NewLeavesQty(decimal.NewFromInt(1), 4), field.NewCumQty(decimal.NewFromInt(1), 4), field.NewAvgPx(decimal.NewFromInt(1), 4))
+	msg.SetAccount("ACC1")
+	er := executionreport.FromMessage(newDropCopy(msg, "CLIENT1", "DESK"))
+	if indicator, _ := er.GetCopyMsgIndicator(); !indicator {
+		t.Error("copy should have CopyMsgIndicator")
+	}
+	if compID, _ := er.Header.GetDeliverToCompID(); compID != "CLIENT1" {
+		t.Error("copy should have the originating session", compID)
+	}
+	if subID, _ := er.Header.GetDeliverToSubID(); subID != "DESK" {
+		t.Error("copy should have the originating sub id", subID)
+	}
+	if account, _ := er.GetAccount(); account != "ACC1" {
+		t.Error("copy should have the account", account)
+	}
+	if msg.HasCopyMsgIndicator() {
+		t.Error("original should not be modified")
+	}
+}
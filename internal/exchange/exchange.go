@@ -1,6 +1,7 @@
 package exchange
 
 import (
+	"errors"
 	"fmt"
 	. "github.com/robaho/fixed"
 	"strconv"
@@ -29,10 +30,17 @@ func (so sessionOrder) String() string {
 
 type exchangeClient interface {
 	SendOrderStatus(so sessionOrder)
-	SendTrades(trades []trade)
+	// SendFill reports one side of a trade, so is the client's order
+	SendFill(so sessionOrder, price Fixed, quantity Fixed, remaining Fixed)
 	SessionID() string
 }
 
+// triggerReporter is implemented by the clients that report a stop order being triggered differently from its other
+// status changes
+type triggerReporter interface {
+	SendTriggered(so sessionOrder)
+}
+
 type session struct {
 	sync.Mutex
 	id     string
@@ -44,9 +52,19 @@ type session struct {
 var buyMarketPrice = NewDecimal("9999999999999")
 var sellMarketPrice = ZERO
 
+// a triggered stop order is executed as a market order
+func (so *sessionOrder) isMarket() bool {
+	return so.order.OrderType == Market || so.order.OrderType == Stop
+}
+
+// immediate orders are never booked, any quantity remaining after matching is cancelled
+func (so *sessionOrder) isImmediate() bool {
+	return so.order.TimeInForce == ImmediateOrCancel || so.order.TimeInForce == FillOrKill
+}
+
 // return the "effective price" of an order - so market orders can always be at the top
 func (so *sessionOrder) getPrice() Fixed {
-	if so.order.OrderType == Market {
+	if so.isMarket() {
 		if so.order.Side == Buy {
 			return buyMarketPrice
 		} else {
@@ -57,6 +75,10 @@ func (so *sessionOrder) getPrice() Fixed {
 }
 
 func (e *exchange) newSession(client exchangeClient) *session {
+	if s := e.reconnect(client); s != nil {
+		return s
+	}
+
 	s := session{}
 	s.id = client.SessionID()
 	s.orders = make(map[OrderID]*Order)
@@ -84,7 +106,7 @@ func (e *exchange) lockSession(client exchangeClient) *session {
 func (e *exchange) lockOrderBook(instrument Instrument) *orderBook {
 	ob, ok := e.orderBooks.Load(instrument)
 	if !ok {
-		ob = &orderBook{Instrument: instrument}
+		ob = &orderBook{Instrument: instrument, stp: e.stp, state: Open, bands: instrument.Spec().PriceBands, onBreach: e.bands.onBreach}
 		ob, _ = e.orderBooks.LoadOrStore(instrument, ob)
 	}
 	_ob := ob.(*orderBook)
@@ -97,42 +119,119 @@ type exchange struct {
 	callbacks  []ConnectorCallback
 	orderBooks sync.Map // map of Instrument to *orderBook
 	sessions   sync.Map // map of string to session
+	// map of session id to the *session restored from the journal, until the session logs on again
+	recovered sync.Map
 	nextOrder  int32
+	// the time of day, as an offset from midnight, that day orders expire
+	sessionClose time.Duration
+	stp          selfTradePrevention
+	auctions     auctionSchedule
+	bands        priceBandConfig
+	risk         *riskManager
+	journal      *journal
+	snapshots    snapshotConfig
+	// commands hold a read lock, so a snapshot can hold the write lock to see a consistent state
+	quiesce sync.RWMutex
+	// see killswitch.go
+	blockLock sync.Mutex
+	blocked   []KillSwitch
+	// see instrumentstore.go
+	instrumentLock sync.Mutex
+	instruments    *instrumentStore
+	// see tradestore.go, the trades are not added while replaying since they are loaded from the journal
+	trades    tradeStore
+	replaying bool
+}
+
+// newInstrument creates an instrument with the configured price bands added to the trading parameters
+func (e *exchange) newInstrument(id int64, symbol string, spec InstrumentSpec) (Instrument, error) {
+	var err error
+	spec.PriceBands, err = e.bands.priceBands(NewInstrument(id, symbol))
+	if err != nil {
+		return nil, err
+	}
+	return NewInstrumentWithSpec(id, symbol, spec), nil
 }
 
 func (e *exchange) CreateOrder(client exchangeClient, order *Order) (OrderID, error) {
+	order.ExchangeId = strconv.Itoa(int(atomic.AddInt32(&e.nextOrder, 1)))
+	return e.createOrder(client, order, time.Now())
+}
+
+// createOrder processes an order that has been assigned an exchange id, received at 'now'
+func (e *exchange) createOrder(client exchangeClient, order *Order, now time.Time) (OrderID, error) {
+	e.quiesce.RLock()
+	defer e.quiesce.RUnlock()
+
 	ob := e.lockOrderBook(order.Instrument)
 	defer ob.Unlock()
 
-	nextOrder := atomic.AddInt32(&e.nextOrder, 1)
-
 	s := e.lockSession(client)
 	defer s.Unlock()
 
 	var orderID = order.Id
 
-	order.ExchangeId = strconv.Itoa(int(nextOrder))
-
 	s.orders[orderID] = order
 
-	so := sessionOrder{client, order, time.Now()}
+	so := sessionOrder{client, order, now}
+
+	if order.TimeInForce == Day {
+		order.ExpireTime = e.nextSessionClose(so.time)
+	}
+
+	reason := ob.checkState()
+	if reason == "" {
+		reason = e.checkBlocked(so)
+	}
+	if reason == "" {
+		reason = checkOrder(order, so.time)
+	}
+	if reason == "" {
+		reason = checkSpec(order.Instrument.Spec(), order.Quantity, order.Price, order.StopPrice)
+	}
+	if reason == "" && (order.OrderType == Limit || order.OrderType == StopLimit) {
+		reason = ob.checkPriceBand(order.Price)
+	}
+	// the risk limits and message rates are not checked while replaying, the command is journaled with the reason
+	// it was rejected, see recovery.go
+	if reason == "" && !e.replaying {
+		reason = e.risk.checkOrder(so, ob.notionalPrice(so), order.Quantity, nil)
+	}
+
+	e.journal.recordOrder(so, reason)
+
+	if reason != "" {
+		order.OrderState = Rejected
+		order.RejectReason = reason
+		e.sendOrderStatus(so)
+		return -1, OrderRejected
+	}
 
 	trades, err := ob.add(so)
 	if err != nil {
 		return -1, err
 	}
 
-	book := ob.buildBook()
-	sendMarketData(MarketEvent{book, trades})
-	client.SendTrades(trades)
+	// the trades are journaled and committed before they are published
+	e.sendTrades(trades)
+	sendMarketData(ob.marketEvent(trades))
 	if len(trades) == 0 || order.OrderState == Cancelled {
-		client.SendOrderStatus(so)
+		e.sendOrderStatus(so)
 	}
+	e.sendStatusChanges(ob, order)
+	e.handleInterruption(ob)
 
 	return orderID, nil
 }
 
 func (e *exchange) ModifyOrder(client exchangeClient, orderId OrderID, price Fixed, quantity Fixed) error {
+	return e.modifyOrder(client, orderId, price, quantity, time.Now())
+}
+
+func (e *exchange) modifyOrder(client exchangeClient, orderId OrderID, price Fixed, quantity Fixed, now time.Time) error {
+	e.quiesce.RLock()
+	defer e.quiesce.RUnlock()
+
 	s := e.lockSession(client)
 	defer s.Unlock()
 
@@ -144,10 +243,36 @@ func (e *exchange) ModifyOrder(client exchangeClient, orderId OrderID, price Fix
 	ob := e.lockOrderBook(order.Instrument)
 	defer ob.Unlock()
 
-	so := sessionOrder{client, order, time.Now()}
+	so := sessionOrder{client, order, now}
+
+	reason := ob.checkState()
+	if reason == "" {
+		reason = checkSpec(order.Instrument.Spec(), quantity, price)
+	}
+	if reason == "" && (order.OrderType == Limit || order.OrderType == StopLimit) {
+		reason = ob.checkPriceBand(price)
+	}
+	if reason == "" && !e.replaying {
+		notional := price
+		if so.isMarket() {
+			notional = ob.notionalPrice(so)
+		}
+		reason = e.risk.checkOrder(so, notional, quantity, order)
+	}
+
+	e.journal.recordModify(so, price, quantity, reason)
+
+	if reason != "" {
+		// the order is unchanged, the reason is only reported on the status
+		order.RejectReason = reason
+		e.sendOrderStatus(so)
+		order.RejectReason = ""
+		return errors.New(reason)
+	}
+
 	err := ob.remove(so)
 	if err != nil {
-		client.SendOrderStatus(so)
+		e.sendOrderStatus(so)
 		return nil
 	}
 
@@ -159,17 +284,26 @@ func (e *exchange) ModifyOrder(client exchangeClient, orderId OrderID, price Fix
 	if err != nil {
 		return nil
 	}
-	book := ob.buildBook()
-	sendMarketData(MarketEvent{book, trades})
-	client.SendTrades(trades)
-	if len(trades) == 0 {
-		client.SendOrderStatus(so)
+	e.sendTrades(trades)
+	sendMarketData(ob.marketEvent(trades))
+	if len(trades) == 0 || order.OrderState == Cancelled {
+		e.sendOrderStatus(so)
 	}
+	e.sendStatusChanges(ob, order)
+	e.handleInterruption(ob)
 
 	return nil
 }
 
 func (e *exchange) CancelOrder(client exchangeClient, orderId OrderID) error {
+	return e.cancelOrder(client, orderId, "")
+}
+
+// cancelOrder cancels the order, reason is reported if it was cancelled by the exchange
+func (e *exchange) cancelOrder(client exchangeClient, orderId OrderID, reason string) error {
+	e.quiesce.RLock()
+	defer e.quiesce.RUnlock()
+
 	s := e.lockSession(client)
 	defer s.Unlock()
 
@@ -185,64 +319,232 @@ func (e *exchange) CancelOrder(client exchangeClient, orderId OrderID) error {
 	if err != nil {
 		return err
 	}
-	book := ob.buildBook()
-	sendMarketData(MarketEvent{book: book})
-	client.SendOrderStatus(so)
+	if reason != "" {
+		order.RejectReason = reason
+	}
+	e.journal.recordCancel(so, reason)
+	e.sendOrderStatus(so)
+	sendMarketData(ob.marketEvent(nil))
 
 	return nil
 }
 
 func (e *exchange) Quote(client exchangeClient, instrument Instrument, bidPrice Fixed, bidQuantity Fixed, askPrice Fixed, askQuantity Fixed) error {
+	return e.quote(client, instrument, bidPrice, bidQuantity, askPrice, askQuantity, time.Now())
+}
+
+func (e *exchange) quote(client exchangeClient, instrument Instrument, bidPrice Fixed, bidQuantity Fixed, askPrice Fixed, askQuantity Fixed, now time.Time) error {
+	e.quiesce.RLock()
+	defer e.quiesce.RUnlock()
+
+	defer e.journal.commit()
+
 	ob := e.lockOrderBook(instrument)
 	defer ob.Unlock()
 
+	reason := ob.checkState()
+	if reason == "" && !bidPrice.IsZero() {
+		reason = checkSpec(instrument.Spec(), bidQuantity, bidPrice)
+	}
+	if reason == "" && !askPrice.IsZero() {
+		reason = checkSpec(instrument.Spec(), askQuantity, askPrice)
+	}
+	if reason == "" {
+		reason = ob.checkPriceBand(bidPrice)
+	}
+	if reason == "" {
+		reason = ob.checkPriceBand(askPrice)
+	}
+
 	s := e.lockSession(client)
 	defer s.Unlock()
 
+	var bid, ask *Order
+	if !bidPrice.IsZero() {
+		bid = LimitOrder(instrument, Buy, bidPrice, bidQuantity)
+		bid.ExchangeId = "quote.bid." + strconv.FormatInt(instrument.ID(), 10)
+	}
+	if !askPrice.IsZero() {
+		ask = LimitOrder(instrument, Sell, askPrice, askQuantity)
+		ask.ExchangeId = "quote.ask." + strconv.FormatInt(instrument.ID(), 10)
+	}
+
+	for _, order := range []*Order{bid, ask} {
+		if order == nil || reason != "" {
+			continue
+		}
+		reason = e.checkBlocked(sessionOrder{client, order, now})
+	}
+
 	qp, ok := s.quotes[instrument]
+	if reason == "" && !e.replaying {
+		reason = e.risk.checkQuote(client, now, bid, ask, qp)
+	}
+
+	e.journal.recordQuote(client, instrument, now, bidPrice, bidQuantity, askPrice, askQuantity, reason)
+
+	if reason != "" {
+		return errors.New(reason)
+	}
+
 	if ok {
 		if qp.bid.order != nil {
 			ob.remove(qp.bid)
+			e.risk.track(qp.bid)
 			qp.bid.order = nil
 		}
 		if qp.ask.order != nil {
 			ob.remove(qp.ask)
+			e.risk.track(qp.ask)
 			qp.ask.order = nil
 		}
 	} else {
 		qp = quotePair{}
 	}
 	var trades []trade
-	if !bidPrice.IsZero() {
-		order := LimitOrder(instrument, Buy, bidPrice, bidQuantity)
-		order.ExchangeId = "quote.bid." + strconv.FormatInt(instrument.ID(), 10)
-		so := sessionOrder{client, order, time.Now()}
+	if bid != nil {
+		so := sessionOrder{client, bid, now}
 		qp.bid = so
 		bidTrades, _ := ob.add(so)
+		e.risk.track(so)
 		if bidTrades != nil {
 			trades = append(trades, bidTrades...)
 		}
 	}
-	if !askPrice.IsZero() {
-		order := LimitOrder(instrument, Sell, askPrice, askQuantity)
-		order.ExchangeId = "quote.ask." + strconv.FormatInt(instrument.ID(), 10)
-		so := sessionOrder{client, order, time.Now()}
+	if ask != nil {
+		so := sessionOrder{client, ask, now}
 		qp.ask = so
 		askTrades, _ := ob.add(so)
+		e.risk.track(so)
 		if askTrades != nil {
 			trades = append(trades, askTrades...)
 		}
 	}
 	s.quotes[instrument] = qp
 
-	book := ob.buildBook()
-	sendMarketData(MarketEvent{book, trades})
-
-	client.SendTrades(trades)
+	e.sendTrades(trades)
+	sendMarketData(ob.marketEvent(trades))
+	e.sendStatusChanges(ob, nil)
+	e.handleInterruption(ob)
 
 	return nil
 }
 
+// checkOrder returns the reason the order should be rejected, or "" if it is valid
+func checkOrder(order *Order, now time.Time) string {
+	switch order.TimeInForce {
+	case Day:
+		// the expire time is the session close
+	case GoodTillCancel, ImmediateOrCancel, FillOrKill:
+		if !order.ExpireTime.IsZero() {
+			return "expire time is only supported for good till date orders"
+		}
+	case GoodTillDate:
+		if !order.ExpireTime.After(now) {
+			return "expire time is required and must be in the future"
+		}
+	default:
+		return "unsupported time in force " + string(order.TimeInForce)
+	}
+	if !order.DisplayQuantity.IsZero() {
+		if order.OrderType != Limit && order.OrderType != StopLimit {
+			return "display quantity is only supported for limit orders"
+		}
+		if order.DisplayQuantity.LessThan(ZERO) || order.DisplayQuantity.GreaterThan(order.Quantity) {
+			return "display quantity must be between zero and the order quantity"
+		}
+	}
+	switch order.OrderType {
+	case Stop:
+		if order.StopPrice.IsZero() {
+			return "stop price is required"
+		}
+	case StopLimit:
+		if order.StopPrice.IsZero() {
+			return "stop price is required"
+		}
+		if order.Price.IsZero() {
+			return "limit price is required"
+		}
+	}
+	return ""
+}
+
+// checkSpec returns the reason the quantity or prices are not valid for the instrument, or "" if they are valid.
+// Zero prices are not checked, e.g. the price of a market order.
+func checkSpec(spec InstrumentSpec, quantity Fixed, prices ...Fixed) string {
+	for _, price := range prices {
+		if !price.IsZero() && !IsMultiple(price, spec.TickSizes.TickSize(price)) {
+			return "price " + price.String() + " is not a multiple of the tick size " + spec.TickSizes.TickSize(price).String()
+		}
+	}
+	if !IsMultiple(quantity, spec.LotSize) {
+		return "quantity " + quantity.String() + " is not a multiple of the lot size " + spec.LotSize.String()
+	}
+	if !spec.MinQuantity.IsZero() && quantity.LessThan(spec.MinQuantity) {
+		return "quantity " + quantity.String() + " is less than the minimum " + spec.MinQuantity.String()
+	}
+	if !spec.MaxQuantity.IsZero() && quantity.GreaterThan(spec.MaxQuantity) {
+		return "quantity " + quantity.String() + " is greater than the maximum " + spec.MaxQuantity.String()
+	}
+	return ""
+}
+
+// sendOrderStatus reports the order status to the client and the drop copy sessions, and updates the account risk.
+// The status is journaled before it is sent.
+func (e *exchange) sendOrderStatus(so sessionOrder) {
+	e.sendStatus(so, false)
+}
+
+// sendStatus reports the order status, triggered is true if the status change is a stop order being triggered, which
+// is only reported differently to the clients that implement triggerReporter
+func (e *exchange) sendStatus(so sessionOrder, triggered bool) {
+	e.risk.track(so)
+	e.journal.recordStatus(so)
+	e.journal.commit()
+	if tr, ok := so.client.(triggerReporter); ok && triggered {
+		tr.SendTriggered(so)
+	} else {
+		so.client.SendOrderStatus(so)
+	}
+	if !e.replaying {
+		App.copyOrderStatus(so, triggered)
+	}
+}
+
+// sendTrades reports the trades to the buyer and seller and the drop copy sessions, and updates the account
+// positions. The trades are journaled before they are sent.
+func (e *exchange) sendTrades(trades []trade) {
+	e.risk.onTrades(trades)
+	e.journal.recordTrades(trades)
+	e.journal.commit()
+	if !e.replaying {
+		e.trades.addTrades(trades)
+	}
+	for _, t := range trades {
+		t.buyer.client.SendFill(t.buyer, t.price, t.quantity, t.buyRemaining)
+		t.seller.client.SendFill(t.seller, t.price, t.quantity, t.sellRemaining)
+		if !e.replaying {
+			App.copyFill(t.buyer, t.price, t.quantity, t.buyRemaining)
+			App.copyFill(t.seller, t.price, t.quantity, t.sellRemaining)
+		}
+	}
+}
+
+// report the orders whose status was changed by the last book change, after the trades have been sent so
+// the status reflects any fills. The order being processed is skipped, as it is reported by the caller.
+func (e *exchange) sendStatusChanges(ob *orderBook, order *Order) {
+	sent := make(map[*Order]bool)
+	changed, triggered := ob.takeChanged()
+	for _, so := range changed {
+		if so.order == order || sent[so.order] {
+			continue
+		}
+		sent[so.order] = true
+		e.sendStatus(so, triggered[so.order])
+	}
+}
+
 var TheExchange exchange
 
 func (e *exchange) ListSessions() string {
@@ -255,31 +557,207 @@ func (e *exchange) ListSessions() string {
 	return strings.Join(s, ",")
 }
 func (e *exchange) SessionDisconnect(client exchangeClient) {
+	e.quiesce.RLock()
+	defer e.quiesce.RUnlock()
+
 	orderCount := 0
 	quoteCount := 0
 
 	s := e.lockSession(client)
 	defer s.Unlock()
 
+	e.journal.recordDisconnect(client)
+
 	for _, v := range s.orders {
 		ob := e.lockOrderBook(v.Instrument)
 		so := sessionOrder{client: client, order: v}
-		ob.remove(so)
-		client.SendOrderStatus(so)
-		sendMarketData(MarketEvent{book: ob.buildBook()})
+		if ob.remove(so) == nil {
+			e.journal.recordCancel(so, "")
+		}
+		e.sendOrderStatus(so)
+		sendMarketData(ob.marketEvent(nil))
 		ob.Unlock()
 		orderCount++
 	}
 	for k, v := range s.quotes {
 		ob := e.lockOrderBook(k)
-		ob.remove(v.bid)
-		ob.remove(v.ask)
-		sendMarketData(MarketEvent{book: ob.buildBook()})
+		for _, so := range []sessionOrder{v.bid, v.ask} {
+			if so.order != nil && ob.remove(so) == nil {
+				e.journal.recordCancelQuote(so, "")
+				e.risk.track(so)
+			}
+		}
+		e.journal.commit()
+		sendMarketData(ob.marketEvent(nil))
 		ob.Unlock()
 		quoteCount++
 	}
+	e.journal.commit()
 	fmt.Println("session", client.SessionID(), "disconnected, cancelled", orderCount, "orders", quoteCount, "quotes")
 }
-func (e *exchange) Start() {
+
+// nextSessionClose returns the time that a day order entered at 'now' expires
+func (e *exchange) nextSessionClose(now time.Time) time.Time {
+	year, month, day := now.Date()
+	expires := time.Date(year, month, day, 0, 0, 0, 0, now.Location()).Add(e.sessionClose)
+	if !expires.After(now) {
+		expires = time.Date(year, month, day+1, 0, 0, 0, 0, now.Location()).Add(e.sessionClose)
+	}
+	return expires
+}
+
+// orderExpired is the reason a day or good till date order was cancelled at its expire time
+const orderExpired = "order expired"
+
+// isExpired returns true if the order was cancelled at its expire time
+func isExpired(order *Order) bool {
+	return order.OrderState == Cancelled && order.RejectReason == orderExpired
+}
+
+// expireOrders cancels any day or good till date orders that have reached their expire time
+func (e *exchange) expireOrders(now time.Time) {
+	e.quiesce.RLock()
+	defer e.quiesce.RUnlock()
+
+	e.sessions.Range(func(key, value interface{}) bool {
+		s := value.(*session)
+		s.Lock()
+		defer s.Unlock()
+
+		for _, order := range s.orders {
+			if !order.IsActive() || (order.TimeInForce != Day && order.TimeInForce != GoodTillDate) || order.ExpireTime.IsZero() || now.Before(order.ExpireTime) {
+				continue
+			}
+			ob := e.lockOrderBook(order.Instrument)
+			so := sessionOrder{client: s.client, order: order}
+			if ob.remove(so) == nil {
+				order.RejectReason = orderExpired
+				e.journal.recordCancel(so, order.RejectReason)
+				e.sendOrderStatus(so)
+				sendMarketData(ob.marketEvent(nil))
+			}
+			ob.Unlock()
+		}
+		return true
+	})
+}
+
+// Stop writes any buffered journal entries and closes the journal
+func (e *exchange) Stop() {
+	if err := e.journal.close(); err != nil {
+		fmt.Println("unable to close journal", err)
+	}
+}
+
+func (e *exchange) Start(props Properties) {
+	var err error
+
+	// day orders expire at session_close (HH:MM local time), or midnight if not configured
+	e.sessionClose, err = parseTimeOfDay(props.GetString("session_close", "00:00"))
+	if err != nil {
+		panic("unable to parse session_close " + err.Error())
+	}
+
+	e.auctions, err = parseAuctionSchedule(props.GetString("auctions", ""))
+	if err != nil {
+		panic("unable to parse auctions " + err.Error())
+	}
+
+	e.stp, err = newSelfTradePrevention(props)
+	if err != nil {
+		panic(err)
+	}
+
+	e.risk, err = newRiskManager(props)
+	if err != nil {
+		panic(err)
+	}
+
+	e.bands, err = newPriceBandConfig(props)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, symbol := range IMap.AllSymbols() {
+		loaded := IMap.GetBySymbol(symbol)
+		instrument, err := e.newInstrument(loaded.ID(), symbol, loaded.Spec())
+		if err != nil {
+			panic(err)
+		}
+		IMap.Put(instrument)
+	}
+
+	e.instruments, err = newInstrumentStore(props)
+	if err != nil {
+		panic(err)
+	}
+	if err := e.loadInstruments(); err != nil {
+		panic("unable to load stored instruments " + err.Error())
+	}
+
 	startMarketData()
+
+	e.snapshots, err = newSnapshotConfig(props)
+	if err != nil {
+		panic(err)
+	}
+
+	var snap *snapshot
+	if e.snapshots.dir != "" {
+		if snap = latestSnapshot(e.snapshots.dir); snap != nil {
+			e.restoreSnapshot(snap)
+		}
+	}
+
+	if path := props.GetString("journal_file", ""); path != "" {
+		rule, timeout, err := recoveryRules(props)
+		if err != nil {
+			panic(err)
+		}
+		var from uint64
+		if snap != nil {
+			from = snap.Seq
+		}
+		count, err := e.replayJournal(path, from)
+		if err != nil {
+			panic("unable to recover from journal " + err.Error())
+		}
+		fmt.Println("recovered", count, "journal entries")
+		e.scheduleRecoveryCancel(rule, timeout)
+	} else if snap != nil {
+		rule, timeout, err := recoveryRules(props)
+		if err != nil {
+			panic(err)
+		}
+		e.scheduleRecoveryCancel(rule, timeout)
+	}
+
+	e.journal, err = newJournal(props)
+	if err != nil {
+		panic("unable to open journal " + err.Error())
+	}
+	if snap != nil {
+		e.journal.skipTo(snap.Seq)
+	}
+	e.closeDisabledInstruments()
+	e.scheduleRecoveredAuctions()
+
+	if e.snapshots.dir != "" && e.snapshots.interval > 0 {
+		go func() {
+			for range time.Tick(e.snapshots.interval) {
+				if _, err := e.TakeSnapshot(); err != nil {
+					fmt.Println("unable to take snapshot", err)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		last := time.Now()
+		for now := range time.Tick(time.Second) {
+			e.runAuctionSchedule(last, now)
+			e.expireOrders(now)
+			last = now
+		}
+	}()
 }
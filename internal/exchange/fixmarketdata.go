@@ -0,0 +1,373 @@
+package exchange
+
+import (
+	"time"
+
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/field"
+	"github.com/quickfixgo/fix44/marketdataincrementalrefresh"
+	"github.com/quickfixgo/fix44/marketdatarequest"
+	"github.com/quickfixgo/fix44/marketdatarequestreject"
+	"github.com/quickfixgo/fix44/marketdatasnapshotfullrefresh"
+	"github.com/quickfixgo/quickfix"
+	. "github.com/robaho/fixed"
+	. "github.com/robaho/go-trader/pkg/common"
+	"github.com/robaho/go-trader/pkg/protocol"
+)
+
+// FIX sessions subscribe to the market data with a MarketDataRequest. The latest book of each symbol is sent as a
+// MarketDataSnapshotFullRefresh when the request is received, followed by MarketDataIncrementalRefresh messages with
+// the changed price levels, up to the requested depth, and the trades. The books are aggregated, and the updates are
+// fed from the market data publishers.
+
+// sendToTarget sends the market data messages, it is replaced by the tests
+var sendToTarget = quickfix.SendToTarget
+
+type fixSubscription struct {
+	reqID       string
+	sessionID   quickfix.SessionID
+	instruments []Instrument
+	// the number of price levels, 0 for the complete book
+	depth int
+	types map[enum.MDEntryType]bool
+	sub   *subscriber
+	done  chan struct{}
+}
+
+// mdEntry is a price level or trade in a FIX market data message
+type mdEntry struct {
+	action    enum.MDUpdateAction
+	entryType enum.MDEntryType
+	price     Fixed
+	size      Fixed
+	// the trade id and time, for a trade
+	id   string
+	time time.Time
+}
+
+func (app *myApplication) onMarketDataRequest(msg marketdatarequest.MarketDataRequest, sessionID quickfix.SessionID) quickfix.MessageRejectError {
+	reqID, err := msg.GetMDReqID()
+	if err != nil {
+		return err
+	}
+	requestType, err := msg.GetSubscriptionRequestType()
+	if err != nil {
+		return err
+	}
+
+	if requestType == enum.SubscriptionRequestType_DISABLE_PREVIOUS_SNAPSHOT_PLUS_UPDATE_REQUEST {
+		if !app.removeSubscription(sessionID, reqID) {
+			app.sendMarketDataReject(reqID, enum.MDReqRejReason_UNSUPPORTED_SUBSCRIPTIONREQUESTTYPE, "no subscription "+reqID, sessionID)
+		}
+		return nil
+	}
+	if requestType != enum.SubscriptionRequestType_SNAPSHOT && requestType != enum.SubscriptionRequestType_SNAPSHOT_PLUS_UPDATES {
+		app.sendMarketDataReject(reqID, enum.MDReqRejReason_UNSUPPORTED_SUBSCRIPTIONREQUESTTYPE, "unsupported subscription request type", sessionID)
+		return nil
+	}
+
+	s := &fixSubscription{reqID: reqID, sessionID: sessionID, types: make(map[enum.MDEntryType]bool)}
+
+	s.depth, err = msg.GetMarketDepth()
+	if err != nil {
+		return err
+	}
+	if s.depth < 0 {
+		app.sendMarketDataReject(reqID, enum.MDReqRejReason_UNSUPPORTED_MARKETDEPTH, "invalid market depth", sessionID)
+		return nil
+	}
+	if msg.HasMDUpdateType() {
+		updateType, err := msg.GetMDUpdateType()
+		if err != nil {
+			return err
+		}
+		if updateType != enum.MDUpdateType_INCREMENTAL_REFRESH {
+			app.sendMarketDataReject(reqID, enum.MDReqRejReason_UNSUPPORTED_MDUPDATETYPE, "only incremental refresh is supported", sessionID)
+			return nil
+		}
+	}
+	if msg.HasAggregatedBook() {
+		aggregated, err := msg.GetAggregatedBook()
+		if err != nil {
+			return err
+		}
+		if !aggregated {
+			app.sendMarketDataReject(reqID, enum.MDReqRejReason_UNSUPPORTED_AGGREGATEDBOOK, "only aggregated books are supported", sessionID)
+			return nil
+		}
+	}
+
+	types, err := msg.GetNoMDEntryTypes()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < types.Len(); i++ {
+		entryType, err := types.Get(i).GetMDEntryType()
+		if err != nil {
+			return err
+		}
+		switch entryType {
+		case enum.MDEntryType_BID, enum.MDEntryType_OFFER, enum.MDEntryType_TRADE:
+			s.types[entryType] = true
+		default:
+			app.sendMarketDataReject(reqID, enum.MDReqRejReason_UNSUPPORTED_MDENTRYTYPE, "unsupported entry type "+string(entryType), sessionID)
+			return nil
+		}
+	}
+
+	symbols, err := msg.GetNoRelatedSym()
+	if err != nil {
+		return err
+	}
+	var names []string
+	for i := 0; i < symbols.Len(); i++ {
+		symbol, err := symbols.Get(i).GetSymbol()
+		if err != nil {
+			return err
+		}
+		instrument := IMap.GetBySymbol(symbol)
+		if instrument == nil {
+			app.sendMarketDataReject(reqID, enum.MDReqRejReason_UNKNOWN_SYMBOL, "unknown symbol "+symbol, sessionID)
+			return nil
+		}
+		s.instruments = append(s.instruments, instrument)
+		names = append(names, symbol)
+	}
+	if len(s.instruments) == 0 {
+		app.sendMarketDataReject(reqID, enum.MDReqRejReason_UNKNOWN_SYMBOL, "no symbols requested", sessionID)
+		return nil
+	}
+
+	if requestType == enum.SubscriptionRequestType_SNAPSHOT {
+		app.sendMarketDataSnapshots(s)
+		return nil
+	}
+
+	s.sub = newSubscriber(names, false)
+	s.done = make(chan struct{})
+	if !app.addSubscription(s) {
+		app.sendMarketDataReject(reqID, enum.MDReqRejReason_DUPLICATE_MDREQID, "duplicate request id "+reqID, sessionID)
+		return nil
+	}
+	// the snapshots are sent after subscribing so no update is missed, the older books queued are dropped
+	last := app.sendMarketDataSnapshots(s)
+	go app.publishMarketData(s, last)
+	return nil
+}
+
+// sendMarketDataSnapshots sends the latest book of each instrument, or an empty book if it has none, and returns the
+// books sent
+func (app *myApplication) sendMarketDataSnapshots(s *fixSubscription) map[Instrument]*Book {
+	sent := make(map[Instrument]*Book)
+	for _, instrument := range s.instruments {
+		book := GetLatestBook(instrument)
+		if book == nil {
+			book = &Book{Instrument: instrument}
+		}
+		app.sendMarketDataSnapshot(s, book)
+		sent[instrument] = book
+	}
+	return sent
+}
+
+// addSubscription starts receiving the market data, returning false if the session already has a subscription with
+// the request id
+func (app *myApplication) addSubscription(s *fixSubscription) bool {
+	app.mdLock.Lock()
+	defer app.mdLock.Unlock()
+
+	if app.mdSubscriptions == nil {
+		app.mdSubscriptions = make(map[quickfix.SessionID]map[string]*fixSubscription)
+	}
+	subs, ok := app.mdSubscriptions[s.sessionID]
+	if !ok {
+		subs = make(map[string]*fixSubscription)
+		app.mdSubscriptions[s.sessionID] = subs
+	}
+	if _, ok := subs[s.reqID]; ok {
+		return false
+	}
+	subs[s.reqID] = s
+	subscribe(s.sub)
+	return true
+}
+
+// removeSubscription stops the subscription, returning false if it does not exist
+func (app *myApplication) removeSubscription(sessionID quickfix.SessionID, reqID string) bool {
+	app.mdLock.Lock()
+	defer app.mdLock.Unlock()
+
+	s, ok := app.mdSubscriptions[sessionID][reqID]
+	if !ok {
+		return false
+	}
+	delete(app.mdSubscriptions[sessionID], reqID)
+	close(s.done)
+	unsubscribe(s.sub)
+	return true
+}
+
+// removeSubscriptions stops the session's subscriptions when it logs out
+func (app *myApplication) removeSubscriptions(sessionID quickfix.SessionID) {
+	app.mdLock.Lock()
+	defer app.mdLock.Unlock()
+
+	for _, s := range app.mdSubscriptions[sessionID] {
+		close(s.done)
+		unsubscribe(s.sub)
+	}
+	delete(app.mdSubscriptions, sessionID)
+}
+
+// publishMarketData sends the incremental updates from the snapshots in last, until the subscription is removed
+func (app *myApplication) publishMarketData(s *fixSubscription, last map[Instrument]*Book) {
+	for {
+		select {
+		case <-s.sub.ready:
+		case <-s.done:
+			return
+		}
+		updates, err := s.sub.take()
+		if err != nil {
+			app.sendMarketDataReject(s.reqID, enum.MDReqRejReason_INSUFFICIENT_BANDWIDTH, err.Error(), s.sessionID)
+			app.removeSubscription(s.sessionID, s.reqID)
+			return
+		}
+		for _, update := range updates {
+			switch {
+			case update.book != nil:
+				book := update.book
+				prev, ok := last[book.Instrument]
+				if !ok {
+					app.sendMarketDataSnapshot(s, book)
+				} else if book.Sequence > prev.Sequence {
+					app.sendMarketDataIncremental(s, book.Instrument, s.filter(bookEntries(prev, book, s.depth)))
+				} else {
+					continue
+				}
+				last[book.Instrument] = book
+			case len(update.trades) > 0:
+				app.sendMarketDataIncremental(s, update.trades[0].Instrument, s.filter(tradeEntries(update.trades)))
+			}
+		}
+	}
+}
+
+// filter returns the entries of the subscribed types, all types are sent if none were requested
+func (s *fixSubscription) filter(entries []mdEntry) []mdEntry {
+	if len(s.types) == 0 {
+		return entries
+	}
+	var filtered []mdEntry
+	for _, entry := range entries {
+		if s.types[entry.entryType] {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// bookEntries returns the changes to the price levels from prev to book, up to the depth
+func bookEntries(prev *Book, book *Book, depth int) []mdEntry {
+	levels, _ := protocol.DiffBook(topOfBook(prev, depth), topOfBook(book, depth))
+	var entries []mdEntry
+	for _, level := range levels {
+		entry := mdEntry{price: level.Price, size: level.Quantity, entryType: enum.MDEntryType_BID}
+		if level.Side == Sell {
+			entry.entryType = enum.MDEntryType_OFFER
+		}
+		switch level.Action {
+		case protocol.LevelAdd:
+			entry.action = enum.MDUpdateAction_NEW
+		case protocol.LevelChange:
+			entry.action = enum.MDUpdateAction_CHANGE
+		case protocol.LevelDelete:
+			entry.action = enum.MDUpdateAction_DELETE
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func tradeEntries(trades []Trade) []mdEntry {
+	var entries []mdEntry
+	for _, trade := range trades {
+		entries = append(entries, mdEntry{action: enum.MDUpdateAction_NEW, entryType: enum.MDEntryType_TRADE, price: trade.Price, size: trade.Quantity, id: trade.ExchangeID, time: trade.TradeTime})
+	}
+	return entries
+}
+
+// topOfBook returns the book limited to the depth
+func topOfBook(book *Book, depth int) *Book {
+	if depth == 0 {
+		return book
+	}
+	top := *book
+	if len(top.Bids) > depth {
+		top.Bids = top.Bids[:depth]
+	}
+	if len(top.Asks) > depth {
+		top.Asks = top.Asks[:depth]
+	}
+	return &top
+}
+
+func (app *myApplication) sendMarketDataSnapshot(s *fixSubscription, book *Book) {
+	book = topOfBook(book, s.depth)
+
+	msg := marketdatasnapshotfullrefresh.New()
+	msg.SetMDReqID(s.reqID)
+	msg.SetSymbol(book.Instrument.Symbol())
+	group := marketdatasnapshotfullrefresh.NewNoMDEntriesRepeatingGroup()
+	add := func(entryType enum.MDEntryType, levels []BookLevel) {
+		if len(s.types) > 0 && !s.types[entryType] {
+			return
+		}
+		for i, level := range levels {
+			entry := group.Add()
+			entry.SetMDEntryType(entryType)
+			entry.SetMDEntryPx(ToDecimal(level.Price), 4)
+			entry.SetMDEntrySize(ToDecimal(level.Quantity), 4)
+			entry.SetMDEntryPositionNo(i + 1)
+		}
+	}
+	add(enum.MDEntryType_BID, book.Bids)
+	add(enum.MDEntryType_OFFER, book.Asks)
+	msg.SetNoMDEntries(group)
+
+	sendToTarget(msg, s.sessionID)
+}
+
+func (app *myApplication) sendMarketDataIncremental(s *fixSubscription, instrument Instrument, entries []mdEntry) {
+	if len(entries) == 0 {
+		return
+	}
+	msg := marketdataincrementalrefresh.New()
+	msg.SetMDReqID(s.reqID)
+	group := marketdataincrementalrefresh.NewNoMDEntriesRepeatingGroup()
+	for _, e := range entries {
+		entry := group.Add()
+		entry.SetMDUpdateAction(e.action)
+		entry.SetMDEntryType(e.entryType)
+		entry.SetSymbol(instrument.Symbol())
+		entry.SetMDEntryPx(ToDecimal(e.price), 4)
+		entry.SetMDEntrySize(ToDecimal(e.size), 4)
+		if e.id != "" {
+			entry.SetMDEntryID(e.id)
+		}
+		if !e.time.IsZero() {
+			entry.SetMDEntryDate(e.time.UTC().Format("20060102"))
+			entry.SetMDEntryTime(e.time.UTC().Format("15:04:05.000"))
+		}
+	}
+	msg.SetNoMDEntries(group)
+
+	sendToTarget(msg, s.sessionID)
+}
+
+func (app *myApplication) sendMarketDataReject(reqID string, reason enum.MDReqRejReason, text string, sessionID quickfix.SessionID) {
+	msg := marketdatarequestreject.New(field.NewMDReqID(reqID))
+	msg.SetMDReqRejReason(reason)
+	msg.SetText(text)
+	sendToTarget(msg, sessionID)
+}
@@ -0,0 +1,132 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/field"
+	"github.com/quickfixgo/fix44/marketdatarequest"
+	"github.com/quickfixgo/quickfix"
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+func TestFixMarketDataEntries(t *testing.T) {
+	i := NewInstrument(3101, "FIXMD")
+
+	prev := &Book{Instrument: i}
+	prev.Bids = []BookLevel{{Price: NewDecimal("100"), Quantity: NewDecimal("10")}, {Price: NewDecimal("99"), Quantity: NewDecimal("5")}}
+	prev.Asks = []BookLevel{{Price: NewDecimal("101"), Quantity: NewDecimal("7")}}
+
+	book := &Book{Instrument: i}
+	book.Bids = []BookLevel{{Price: NewDecimal("100.5"), Quantity: NewDecimal("1")}, {Price: NewDecimal("100"), Quantity: NewDecimal("10")}, {Price: NewDecimal("99"), Quantity: NewDecimal("5")}}
+	book.Asks = []BookLevel{{Price: NewDecimal("101"), Quantity: NewDecimal("3")}}
+
+	entries := bookEntries(prev, book, 0)
+	if len(entries) != 2 {
+		t.Fatal("wrong number of entries", entries)
+	}
+	if entries[0].action != enum.MDUpdateAction_NEW || entries[0].entryType != enum.MDEntryType_BID || !entries[0].price.Equal(NewDecimal("100.5")) {
+		t.Error("wrong new bid entry", entries[0])
+	}
+	if entries[1].action != enum.MDUpdateAction_CHANGE || entries[1].entryType != enum.MDEntryType_OFFER || !entries[1].size.Equal(NewDecimal("3")) {
+		t.Error("wrong changed offer entry", entries[1])
+	}
+
+	// the level pushed out of the top 2 is deleted
+	entries = bookEntries(prev, book, 2)
+	var deleted int
+	for _, entry := range entries {
+		if entry.action == enum.MDUpdateAction_DELETE {
+			deleted++
+			if !entry.price.Equal(NewDecimal("99")) {
+				t.Error("wrong level deleted", entry)
+			}
+		}
+	}
+	if deleted != 1 {
+		t.Error("level outside the depth should be deleted", entries)
+	}
+
+	trades := tradeEntries([]Trade{{Instrument: i, Price: NewDecimal("100"), Quantity: NewDecimal("2"), ExchangeID: "7", TradeTime: time.Now()}})
+	s := &fixSubscription{types: map[enum.MDEntryType]bool{enum.MDEntryType_TRADE: true}}
+	if filtered := s.filter(append(bookEntries(prev, book, 0), trades...)); len(filtered) != 1 || filtered[0].id != "7" {
+		t.Error("only trades should be sent", filtered)
+	}
+}
+
+func TestFixMarketDataSubscription(t *testing.T) {
+	sent := make(chan *quickfix.Message, 16)
+	sendToTarget = func(m quickfix.Messagable, sessionID quickfix.SessionID) error {
+		sent <- m.ToMessage()
+		return nil
+	}
+	defer func() { sendToTarget = quickfix.SendToTarget }()
+
+	booked := NewInstrument(3102, "FIXMDBOOK")
+	empty := NewInstrument(3103, "FIXMDEMPTY")
+	IMap.Put(booked)
+	IMap.Put(empty)
+
+	cached := &Book{Instrument: booked, Bids: []BookLevel{{Price: NewDecimal("100"), Quantity: NewDecimal("10")}}}
+	cacheBook(cached)
+
+	msg := marketdatarequest.New(field.NewMDReqID("1"), field.NewSubscriptionRequestType(enum.SubscriptionRequestType_SNAPSHOT_PLUS_UPDATES), field.NewMarketDepth(0))
+	types := marketdatarequest.NewNoMDEntryTypesRepeatingGroup()
+	types.Add().SetMDEntryType(enum.MDEntryType_BID)
+	types.Add().SetMDEntryType(enum.MDEntryType_OFFER)
+	types.Add().SetMDEntryType(enum.MDEntryType_TRADE)
+	msg.SetNoMDEntryTypes(types)
+	symbols := marketdatarequest.NewNoRelatedSymRepeatingGroup()
+	symbols.Add().SetSymbol(booked.Symbol())
+	symbols.Add().SetSymbol(empty.Symbol())
+	msg.SetNoRelatedSym(symbols)
+
+	sessionID := quickfix.SessionID{BeginString: "FIX.4.4", SenderCompID: "GOX", TargetCompID: "CLIENT"}
+	var app myApplication
+	if err := app.onMarketDataRequest(msg, sessionID); err != nil {
+		t.Fatal(err)
+	}
+	defer app.removeSubscription(sessionID, "1")
+
+	next := func() (string, string) {
+		select {
+		case m := <-sent:
+			msgType, _ := m.Header.GetString(quickfix.Tag(35))
+			if msgType == "W" {
+				symbol, _ := m.Body.GetString(quickfix.Tag(55))
+				return msgType, symbol
+			}
+			return msgType, ""
+		case <-time.After(time.Second):
+			t.Fatal("no market data sent")
+		}
+		return "", ""
+	}
+
+	// the snapshots are sent before the request returns, including the symbol without a book
+	if len(sent) != 2 {
+		t.Fatal("snapshots should be sent when subscribing", len(sent))
+	}
+	if msgType, symbol := next(); msgType != "W" || symbol != booked.Symbol() {
+		t.Error("wrong snapshot", msgType, symbol)
+	}
+	if msgType, symbol := next(); msgType != "W" || symbol != empty.Symbol() {
+		t.Error("wrong empty snapshot", msgType, symbol)
+	}
+
+	// the first trade of the symbol without a book follows its snapshot as an update
+	book := &Book{Instrument: empty, Asks: []BookLevel{{Price: NewDecimal("50"), Quantity: NewDecimal("1")}}}
+	cacheBook(book)
+	publishToSubscribers(book, []Trade{{Instrument: empty, Price: NewDecimal("50"), Quantity: NewDecimal("2"), ExchangeID: "1", TradeTime: time.Now()}}, nil)
+	for _, want := range []string{"book", "trade"} {
+		if msgType, _ := next(); msgType != "X" {
+			t.Error("should send an incremental", want, msgType)
+		}
+	}
+	select {
+	case m := <-sent:
+		t.Error("the cached book should not be sent again", m)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
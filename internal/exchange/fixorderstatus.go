@@ -0,0 +1,128 @@
+package exchange
+
+import (
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/field"
+	"github.com/quickfixgo/fix44/executionreport"
+	"github.com/quickfixgo/fix44/ordermassstatusrequest"
+	"github.com/quickfixgo/fix44/orderstatusrequest"
+	"github.com/quickfixgo/quickfix"
+	. "github.com/robaho/go-trader/pkg/common"
+	"github.com/shopspring/decimal"
+)
+
+// An OrderStatusRequest (35=H) is answered with an ExecutionReport with ExecType=I (order status) for the session's
+// order with the ClOrdID, or OrdStatus=Rejected and OrdRejReason=UnknownOrder if there is no such order.
+//
+// An OrderMassStatusRequest (35=AF) is answered with an order status ExecutionReport for each of the session's
+// open orders, for all instruments or the Symbol, with the MassStatusReqID, TotNumReports and LastRptRequested set
+// on the last report. If there are no open orders a single report with TotNumReports=0 and OrdStatus=Rejected is sent.
+// The status reports are not sent to the drop copy sessions.
+
+func (app *myApplication) onOrderStatusRequest(msg orderstatusrequest.OrderStatusRequest, sessionID quickfix.SessionID) quickfix.MessageRejectError {
+	clOrdId, err := msg.GetClOrdID()
+	if err != nil {
+		return err
+	}
+	side, err := msg.GetSide()
+	if err != nil {
+		return err
+	}
+
+	c := fixClient{sessionID: sessionID}
+	var rpt executionreport.ExecutionReport
+	if order := app.e.orderStatus(c, NewOrderID(clOrdId)); order != nil {
+		rpt = newExecutionReport(enum.ExecType_ORDER_STATUS, order)
+	} else {
+		rpt = newUnknownOrderReport(clOrdId, side, "unknown order")
+		if msg.HasSymbol() {
+			symbol, _ := msg.GetSymbol()
+			rpt.SetSymbol(symbol)
+		}
+	}
+	if msg.HasOrdStatusReqID() {
+		reqID, err := msg.GetOrdStatusReqID()
+		if err != nil {
+			return err
+		}
+		rpt.SetOrdStatusReqID(reqID)
+	}
+	quickfix.SendToTarget(rpt, sessionID)
+	return nil
+}
+
+func (app *myApplication) onOrderMassStatusRequest(msg ordermassstatusrequest.OrderMassStatusRequest, sessionID quickfix.SessionID) quickfix.MessageRejectError {
+	reqID, err := msg.GetMassStatusReqID()
+	if err != nil {
+		return err
+	}
+	requestType, err := msg.GetMassStatusReqType()
+	if err != nil {
+		return err
+	}
+
+	var instrument Instrument
+	switch requestType {
+	case enum.MassStatusReqType_STATUS_FOR_ALL_ORDERS:
+	case enum.MassStatusReqType_STATUS_FOR_ORDERS_FOR_A_SECURITY:
+		symbol, err := msg.GetSymbol()
+		if err != nil {
+			return err
+		}
+		instrument = IMap.GetBySymbol(symbol)
+		if instrument == nil {
+			return quickfix.NewBusinessMessageRejectError("unknown symbol "+symbol, 0, nil)
+		}
+	default:
+		return quickfix.NewBusinessMessageRejectError("unsupported mass status request type "+string(requestType), 0, nil)
+	}
+
+	c := fixClient{sessionID: sessionID}
+	orders := app.e.openOrders(c, instrument)
+	if msg.HasSide() {
+		side, err := msg.GetSide()
+		if err != nil {
+			return err
+		}
+		var filtered []*Order
+		for _, order := range orders {
+			if order.Side == MapFromFixSide(side) {
+				filtered = append(filtered, order)
+			}
+		}
+		orders = filtered
+	}
+
+	if len(orders) == 0 {
+		rpt := newUnknownOrderReport("NONE", enum.Side_BUY, "no open orders")
+		rpt.SetMassStatusReqID(reqID)
+		rpt.SetTotNumReports(0)
+		rpt.SetLastRptRequested(true)
+		quickfix.SendToTarget(rpt, sessionID)
+		return nil
+	}
+	for i, order := range orders {
+		rpt := newExecutionReport(enum.ExecType_ORDER_STATUS, order)
+		rpt.SetMassStatusReqID(reqID)
+		rpt.SetTotNumReports(len(orders))
+		rpt.SetLastRptRequested(i == len(orders)-1)
+		quickfix.SendToTarget(rpt, sessionID)
+	}
+	return nil
+}
+
+// newUnknownOrderReport returns the order status report for an order the exchange does not have
+func newUnknownOrderReport(clOrdId string, side enum.Side, text string) executionreport.ExecutionReport {
+	rpt := executionreport.New(field.NewOrderID("NONE"),
+		field.NewExecID("NONE"),
+		field.NewExecType(enum.ExecType_ORDER_STATUS),
+		field.NewOrdStatus(enum.OrdStatus_REJECTED),
+		field.NewSide(side),
+		field.NewLeavesQty(decimal.Zero, 4),
+		field.NewCumQty(decimal.Zero, 4),
+		field.NewAvgPx(decimal.Zero, 4))
+	rpt.SetClOrdID(clOrdId)
+	rpt.SetOrdRejReason(enum.OrdRejReason_UNKNOWN_ORDER)
+	rpt.SetText(text)
+	return rpt
+}
@@ -0,0 +1,165 @@
+package exchange
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/field"
+	"github.com/quickfixgo/fix44/tradecapturereport"
+	"github.com/quickfixgo/fix44/tradecapturereportrequest"
+	"github.com/quickfixgo/fix44/tradecapturereportrequestack"
+	"github.com/quickfixgo/quickfix"
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+// A TradeCaptureReportRequest (35=AD) is answered from the trade store with a TradeCaptureReportRequestAck (35=AQ)
+// giving the number of trades, followed by a TradeCaptureReport (35=AE) for each trade. Only snapshots are supported.
+// The trades can be limited by Symbol, and by the NoDates group, the first entry is the start and the optional second
+// entry the end of the range, using the TransactTime or the whole TradeDate.
+
+func (app *myApplication) onTradeCaptureReportRequest(msg tradecapturereportrequest.TradeCaptureReportRequest, sessionID quickfix.SessionID) quickfix.MessageRejectError {
+	reqID, err := msg.GetTradeRequestID()
+	if err != nil {
+		return err
+	}
+	requestType, err := msg.GetTradeRequestType()
+	if err != nil {
+		return err
+	}
+
+	switch requestType {
+	case enum.TradeRequestType_ALL_TRADES, enum.TradeRequestType_MATCHED_TRADES_MATCHING_CRITERIA_PROVIDED_ON_REQUEST:
+	default:
+		app.sendTradeCaptureReject(reqID, requestType, enum.TradeRequestResult_TRADEREQUESTTYPE_NOT_SUPPORTED, "unsupported trade request type", sessionID)
+		return nil
+	}
+	if msg.HasSubscriptionRequestType() {
+		subscriptionType, err := msg.GetSubscriptionRequestType()
+		if err != nil {
+			return err
+		}
+		if subscriptionType != enum.SubscriptionRequestType_SNAPSHOT {
+			app.sendTradeCaptureReject(reqID, requestType, enum.TradeRequestResult_OTHER, "only snapshots are supported", sessionID)
+			return nil
+		}
+	}
+
+	var symbol string
+	if msg.HasSymbol() {
+		symbol, err = msg.GetSymbol()
+		if err != nil {
+			return err
+		}
+		if IMap.GetBySymbol(symbol) == nil {
+			app.sendTradeCaptureReject(reqID, requestType, enum.TradeRequestResult_INVALID_OR_UNKNOWN_INSTRUMENT, "unknown symbol "+symbol, sessionID)
+			return nil
+		}
+	}
+
+	var from, to time.Time
+	if msg.HasNoDates() {
+		dates, err := msg.GetNoDates()
+		if err != nil {
+			return err
+		}
+		var times []time.Time
+		for i := 0; i < dates.Len() && i < 2; i++ {
+			t, err := tradeCaptureTime(dates.Get(i), i == 1)
+			if err != nil {
+				app.sendTradeCaptureReject(reqID, requestType, enum.TradeRequestResult_OTHER, err.Error(), sessionID)
+				return nil
+			}
+			times = append(times, t)
+		}
+		if len(times) > 0 {
+			from = times[0]
+		}
+		if len(times) > 1 {
+			to = times[1]
+		}
+	}
+
+	trades := app.e.trades.query(symbol, from, to)
+
+	status := enum.TradeRequestStatus_ACCEPTED
+	if len(trades) == 0 {
+		status = enum.TradeRequestStatus_COMPLETED
+	}
+	ack := tradecapturereportrequestack.New(field.NewTradeRequestID(reqID), field.NewTradeRequestType(requestType),
+		field.NewTradeRequestResult(enum.TradeRequestResult_SUCCESSFUL), field.NewTradeRequestStatus(status))
+	ack.SetTotNumTradeReports(len(trades))
+	if symbol != "" {
+		ack.SetSymbol(symbol)
+	}
+	quickfix.SendToTarget(ack, sessionID)
+
+	for i, t := range trades {
+		app.sendTradeCaptureReport(reqID, t, len(trades), i == len(trades)-1, sessionID)
+	}
+	return nil
+}
+
+// tradeCaptureTime returns the time of a NoDates entry, the TransactTime if present, otherwise the start of the
+// TradeDate, or the end of the TradeDate if it is the end of the range
+func tradeCaptureTime(date tradecapturereportrequest.NoDates, end bool) (time.Time, error) {
+	if date.HasTransactTime() {
+		return date.GetTransactTime()
+	}
+	tradeDate, err := date.GetTradeDate()
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err0 := time.ParseInLocation("20060102", tradeDate, time.Local)
+	if err0 != nil {
+		return time.Time{}, err0
+	}
+	if end {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}
+
+func (app *myApplication) sendTradeCaptureReport(reqID string, t storedTrade, total int, last bool, sessionID quickfix.SessionID) {
+	msg := tradecapturereport.New(field.NewTradeReportID(strconv.FormatInt(t.ReportID, 10)), field.NewPreviouslyReported(true),
+		field.NewLastQty(ToDecimal(t.Quantity), 4), field.NewLastPx(ToDecimal(t.Price), 4),
+		field.NewTradeDate(t.Time.Format("20060102")), field.NewTransactTime(t.Time))
+	msg.SetTradeRequestID(reqID)
+	msg.SetTotNumTradeReports(total)
+	msg.SetLastRptRequested(last)
+	msg.SetTradeReportTransType(enum.TradeReportTransType_NEW)
+	msg.SetExecID(strconv.FormatInt(t.TradeID, 10))
+	msg.SetSymbol(t.Symbol)
+	if instrument := IMap.GetBySymbol(t.Symbol); instrument != nil {
+		msg.SetSecurityID(strconv.FormatInt(instrument.ID(), 10))
+	}
+
+	sides := tradecapturereport.NewNoSidesRepeatingGroup()
+	addSide := func(side Side, session string, orderID string) {
+		s := sides.Add()
+		s.SetSide(MapToFixSide(side))
+		s.SetOrderID(orderID)
+		parties := tradecapturereport.NewNoPartyIDsRepeatingGroup()
+		party := parties.Add()
+		party.SetPartyID(session)
+		party.SetPartyIDSource(enum.PartyIDSource_PROPRIETARY)
+		party.SetPartyRole(enum.PartyRole_EXECUTING_FIRM)
+		s.SetNoPartyIDs(parties)
+		if t.Aggressor != "" {
+			// AggressorIndicator is not in FIX 4.4, it is added as in later versions
+			s.Set(field.NewAggressorIndicator(t.Aggressor == side))
+		}
+	}
+	addSide(Buy, t.Buyer, t.BuyOrder)
+	addSide(Sell, t.Seller, t.SellOrder)
+	msg.SetNoSides(sides)
+
+	quickfix.SendToTarget(msg, sessionID)
+}
+
+func (app *myApplication) sendTradeCaptureReject(reqID string, requestType enum.TradeRequestType, result enum.TradeRequestResult, text string, sessionID quickfix.SessionID) {
+	msg := tradecapturereportrequestack.New(field.NewTradeRequestID(reqID), field.NewTradeRequestType(requestType),
+		field.NewTradeRequestResult(result), field.NewTradeRequestStatus(enum.TradeRequestStatus_REJECTED))
+	msg.SetText(text)
+	quickfix.SendToTarget(msg, sessionID)
+}
@@ -1,9 +1,12 @@
 package exchange
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strconv"
+	"sync"
+	"time"
 
 	. "github.com/robaho/fixed"
 
@@ -14,20 +17,32 @@ import (
 
 type grpcServer struct {
 	e *exchange
+	// holds session id->*grpcClient of the logged in sessions
+	sessions sync.Map
 }
 
 type grpcClient struct {
 	conn     protocol.Exchange_ConnectionServer
 	loggedIn bool
 	user     string
+	// the session id, from the login session name so the session can be recovered, or unique to the connection
+	id string
+	// the protocol version negotiated at login
+	version protocol.Version
 }
 
 func (c *grpcClient) SendOrderStatus(so sessionOrder) {
+	reply := &protocol.OutMessage_Execrpt{Execrpt: newStatusReport(so.order, c.version)}
+	c.conn.Send(&protocol.OutMessage{Reply: reply})
+}
+
+// newStatusReport returns a status execution report with the current state of the order
+func newStatusReport(order *Order, v protocol.Version) *protocol.ExecutionReport {
 	rpt := &protocol.ExecutionReport{}
-	rpt.Symbol = so.order.Symbol()
-	rpt.ExOrdId = so.order.ExchangeId
+	rpt.Symbol = order.Symbol()
+	rpt.ExOrdId = order.ExchangeId
 	rpt.ReportType = protocol.ExecutionReport_Status
-	switch so.order.OrderState {
+	switch order.OrderState {
 	case New, Booked:
 		rpt.OrderState = protocol.ExecutionReport_Booked
 	case PartialFill:
@@ -39,45 +54,52 @@ func (c *grpcClient) SendOrderStatus(so sessionOrder) {
 	case Rejected:
 		rpt.OrderState = protocol.ExecutionReport_Rejected
 	}
-	rpt.RejectReason = so.order.RejectReason
-	rpt.ClOrdId = int32(so.order.Id)
-	rpt.Quantity = ToFloat(so.order.Quantity)
-	rpt.Price = ToFloat(so.order.Price)
-	rpt.Remaining = ToFloat(so.order.Remaining)
-	if so.order.Side == Buy {
+	rpt.RejectReason = order.RejectReason
+	rpt.ClOrdId = int32(order.Id)
+	v.Encode(order.Quantity, &rpt.Quantity, &rpt.ScaledQuantity)
+	v.Encode(order.Price, &rpt.Price, &rpt.ScaledPrice)
+	v.Encode(order.Remaining, &rpt.Remaining, &rpt.ScaledRemaining)
+	v.Encode(order.StopPrice, &rpt.StopPrice, &rpt.ScaledStopPrice)
+	rpt.Triggered = order.Triggered
+	if !order.ExpireTime.IsZero() {
+		rpt.ExpireTime = order.ExpireTime.UnixNano()
+	}
+	v.Encode(order.DisplayQuantity, &rpt.DisplayQuantity, &rpt.ScaledDisplayQuantity)
+	rpt.Account = order.Account
+	if order.Side == Buy {
 		rpt.Side = protocol.CreateOrderRequest_Buy
 	} else {
 		rpt.Side = protocol.CreateOrderRequest_Sell
 	}
-	reply := &protocol.OutMessage_Execrpt{Execrpt: rpt}
-	so.client.(*grpcClient).conn.Send(&protocol.OutMessage{Reply: reply})
-}
-
-func (c *grpcClient) SendTrades(trades []trade) {
-	for _, k := range trades {
-		c.sendTradeExecutionReport(k.buyer, k.price, k.quantity, k.buyRemaining)
-		c.sendTradeExecutionReport(k.seller, k.price, k.quantity, k.sellRemaining)
-	}
+	return rpt
 }
 
 func (c *grpcClient) SessionID() string {
-	return fmt.Sprint(c.conn)
+	return c.id
 }
 
 func (c *grpcClient) String() string {
 	return c.SessionID()
 }
 
-func (c *grpcClient) sendTradeExecutionReport(so sessionOrder, price Fixed, quantity Fixed, remaining Fixed) {
+func (c *grpcClient) SendFill(so sessionOrder, price Fixed, quantity Fixed, remaining Fixed) {
 	rpt := &protocol.ExecutionReport{}
 	rpt.Symbol = so.order.Symbol()
 	rpt.ExOrdId = so.order.ExchangeId
 	rpt.ReportType = protocol.ExecutionReport_Fill
 	rpt.ClOrdId = int32(so.order.Id)
-	rpt.Quantity = ToFloat(so.order.Quantity)
-	rpt.Price = ToFloat(so.order.Price)
-	rpt.LastPrice = ToFloat(price)
-	rpt.LastQuantity = ToFloat(quantity)
+	v := c.version
+	v.Encode(so.order.Quantity, &rpt.Quantity, &rpt.ScaledQuantity)
+	v.Encode(so.order.Price, &rpt.Price, &rpt.ScaledPrice)
+	v.Encode(price, &rpt.LastPrice, &rpt.ScaledLastPrice)
+	v.Encode(quantity, &rpt.LastQuantity, &rpt.ScaledLastQuantity)
+	v.Encode(so.order.StopPrice, &rpt.StopPrice, &rpt.ScaledStopPrice)
+	rpt.Triggered = so.order.Triggered
+	if !so.order.ExpireTime.IsZero() {
+		rpt.ExpireTime = so.order.ExpireTime.UnixNano()
+	}
+	v.Encode(so.order.DisplayQuantity, &rpt.DisplayQuantity, &rpt.ScaledDisplayQuantity)
+	rpt.Account = so.order.Account
 	if so.order.Side == Buy {
 		rpt.Side = protocol.CreateOrderRequest_Buy
 	} else {
@@ -100,21 +122,23 @@ func (c *grpcClient) sendTradeExecutionReport(so sessionOrder, price Fixed, quan
 		rpt.OrderState = protocol.ExecutionReport_Partial
 	}
 
-	rpt.Remaining = ToFloat(remaining)
+	v.Encode(remaining, &rpt.Remaining, &rpt.ScaledRemaining)
 	reply := &protocol.OutMessage_Execrpt{Execrpt: rpt}
-	so.client.(*grpcClient).conn.Send(&protocol.OutMessage{Reply: reply})
+	c.conn.Send(&protocol.OutMessage{Reply: reply})
 }
 
 func (s *grpcServer) Connection(conn protocol.Exchange_ConnectionServer) error {
 
 	client := &grpcClient{conn: conn}
 
-	s.e.newSession(client)
-
-	log.Println("grpc session connect", client)
+	log.Println("grpc session connect", conn)
 	defer func() {
+		if !client.loggedIn {
+			return
+		}
 		log.Println("grpc session disconnect", client)
 		s.e.SessionDisconnect(client)
+		s.sessions.Delete(client.id)
 	}()
 
 	for {
@@ -151,6 +175,10 @@ func (s *grpcServer) Connection(conn protocol.Exchange_ConnectionServer) error {
 			err = s.cancel(conn, client, msg.GetRequest().(*protocol.InMessage_Cancel).Cancel)
 		case *protocol.InMessage_Secdefreq:
 			err = s.createInstrument(conn, client, msg.GetRequest().(*protocol.InMessage_Secdefreq).Secdefreq)
+		case *protocol.InMessage_Orderstatus:
+			err = s.orderStatus(conn, client, msg.GetRequest().(*protocol.InMessage_Orderstatus).Orderstatus)
+		case *protocol.InMessage_Openorders:
+			err = s.openOrders(conn, client, msg.GetRequest().(*protocol.InMessage_Openorders).Openorders)
 		}
 
 		if err != nil {
@@ -162,17 +190,32 @@ func (s *grpcServer) Connection(conn protocol.Exchange_ConnectionServer) error {
 func (s *grpcServer) login(conn protocol.Exchange_ConnectionServer, client *grpcClient, request *protocol.LoginRequest) error {
 	log.Println("login received", request)
 	var err error = nil
-	reply := &protocol.OutMessage_Login{Login: &protocol.LoginReply{Error: toErrS(err)}}
-	err = conn.Send(&protocol.OutMessage{Reply: reply})
-	client.loggedIn = true
-	client.user = request.Username
-	return err
+	if client.loggedIn {
+		err = errors.New("session already logged in")
+	} else {
+		// the session is only created at login, since its id is the session name
+		id := fmt.Sprint(conn)
+		if request.SessionName != "" {
+			id = "grpc:" + request.SessionName
+		}
+		if _, loaded := s.sessions.LoadOrStore(id, client); loaded {
+			err = errors.New("session " + request.SessionName + " already logged in")
+		} else {
+			client.id = id
+			client.version = protocol.NegotiateVersion(request.ProtocolVersion)
+			client.user = request.Username
+			s.e.newSession(client)
+			client.loggedIn = true
+		}
+	}
+	reply := &protocol.OutMessage_Login{Login: &protocol.LoginReply{Error: toErrS(err), ProtocolVersion: int32(client.version)}}
+	return conn.Send(&protocol.OutMessage{Reply: reply})
 }
 func (s *grpcServer) download(conn protocol.Exchange_ConnectionServer, client *grpcClient) {
 	log.Println("downloading...")
 	for _, symbol := range IMap.AllSymbols() {
 		instrument := IMap.GetBySymbol(symbol)
-		sec := &protocol.OutMessage_Secdef{Secdef: &protocol.SecurityDefinition{Symbol: symbol, InstrumentID: instrument.ID()}}
+		sec := &protocol.OutMessage_Secdef{Secdef: newSecurityDefinition(instrument, client.version)}
 		err := conn.Send(&protocol.OutMessage{Reply: sec})
 		if err != nil {
 			return
@@ -187,7 +230,14 @@ func (s *grpcServer) massquote(server protocol.Exchange_ConnectionServer, client
 	if instrument == nil {
 		return errors.New("unknown symbol " + q.Symbol)
 	}
-	return s.e.Quote(client, instrument, NewDecimalF(q.BidPrice), NewDecimalF(q.BidQuantity), NewDecimalF(q.AskPrice), NewDecimalF(q.AskQuantity))
+	v := client.version
+	err := s.e.Quote(client, instrument, v.Decode(q.BidPrice, q.ScaledBidPrice), v.Decode(q.BidQuantity, q.ScaledBidQuantity),
+		v.Decode(q.AskPrice, q.ScaledAskPrice), v.Decode(q.AskQuantity, q.ScaledAskQuantity))
+	if err != nil {
+		reply := &protocol.OutMessage_Reject{Reject: &protocol.SessionReject{Error: err.Error()}}
+		return server.Send(&protocol.OutMessage{Reply: reply})
+	}
+	return nil
 }
 func (s *grpcServer) create(conn protocol.Exchange_ConnectionServer, client *grpcClient, request *protocol.CreateOrderRequest) error {
 
@@ -200,24 +250,49 @@ func (s *grpcServer) create(conn protocol.Exchange_ConnectionServer, client *grp
 	var order *Order
 	var side Side
 
+	v := client.version
+	price := v.Decode(request.Price, request.ScaledPrice)
+	quantity := v.Decode(request.Quantity, request.ScaledQuantity)
+	stopPrice := v.Decode(request.StopPrice, request.ScaledStopPrice)
+
 	if request.OrderSide == protocol.CreateOrderRequest_Buy {
 		side = Buy
 	} else {
 		side = Sell
 	}
 
-	if request.OrderType == protocol.CreateOrderRequest_Limit {
-		order = LimitOrder(instrument, side, NewDecimalF(request.Price), NewDecimalF(request.Quantity))
-	} else {
-		order = MarketOrder(instrument, side, NewDecimalF(request.Quantity))
+	switch request.OrderType {
+	case protocol.CreateOrderRequest_Limit:
+		order = LimitOrder(instrument, side, price, quantity)
+	case protocol.CreateOrderRequest_Stop:
+		order = StopOrder(instrument, side, stopPrice, quantity)
+	case protocol.CreateOrderRequest_StopLimit:
+		order = StopLimitOrder(instrument, side, price, stopPrice, quantity)
+	default:
+		order = MarketOrder(instrument, side, quantity)
 	}
 	order.Id = NewOrderID(strconv.Itoa(int(request.ClOrdId)))
+	switch request.TimeInForce {
+	case protocol.CreateOrderRequest_Day:
+		order.TimeInForce = Day
+	case protocol.CreateOrderRequest_IOC:
+		order.TimeInForce = ImmediateOrCancel
+	case protocol.CreateOrderRequest_FOK:
+		order.TimeInForce = FillOrKill
+	case protocol.CreateOrderRequest_GTD:
+		order.TimeInForce = GoodTillDate
+	}
+	if request.ExpireTime != 0 {
+		order.ExpireTime = time.Unix(0, request.ExpireTime)
+	}
+	order.DisplayQuantity = v.Decode(request.DisplayQuantity, request.ScaledDisplayQuantity)
+	order.Account = request.Account
 	s.e.CreateOrder(client, order)
 	return nil
 }
 func (s *grpcServer) modify(server protocol.Exchange_ConnectionServer, client *grpcClient, request *protocol.ModifyOrderRequest) error {
-	price := NewDecimalF(request.Price)
-	qty := NewDecimalF(request.Quantity)
+	price := client.version.Decode(request.Price, request.ScaledPrice)
+	qty := client.version.Decode(request.Quantity, request.ScaledQuantity)
 	s.e.ModifyOrder(client, NewOrderID(strconv.Itoa(int(request.ClOrdId))), price, qty)
 	return nil
 }
@@ -226,17 +301,182 @@ func (s *grpcServer) cancel(server protocol.Exchange_ConnectionServer, client *g
 	return nil
 }
 func (s *grpcServer) createInstrument(conn protocol.Exchange_ConnectionServer, client *grpcClient, request *protocol.SecurityDefinitionRequest) error {
-	symbol := request.Symbol
-	instrument := IMap.GetBySymbol(symbol)
-	if instrument != nil {
-		sec := &protocol.OutMessage_Secdef{Secdef: &protocol.SecurityDefinition{Symbol: symbol, InstrumentID: instrument.ID()}}
-		return conn.Send(&protocol.OutMessage{Reply: sec})
+	instrument, err := s.e.CreateInstrument(request.Symbol, InstrumentSpec{})
+	if err != nil {
+		reply := &protocol.OutMessage_Reject{Reject: &protocol.SessionReject{Error: err.Error()}}
+		return conn.Send(&protocol.OutMessage{Reply: reply})
+	}
+	sec := &protocol.OutMessage_Secdef{Secdef: newSecurityDefinition(instrument, client.version)}
+	return conn.Send(&protocol.OutMessage{Reply: sec})
+}
+
+func (s *grpcServer) orderStatus(conn protocol.Exchange_ConnectionServer, client *grpcClient, request *protocol.OrderStatusRequest) error {
+	var rpt *protocol.ExecutionReport
+	if order := s.e.orderStatus(client, OrderID(request.ClOrdId)); order != nil {
+		rpt = newStatusReport(order, client.version)
 	} else {
-		instrument = NewInstrument(IMap.NextID(), symbol)
-		IMap.Put(instrument)
-		sec := &protocol.OutMessage_Secdef{Secdef: &protocol.SecurityDefinition{Symbol: symbol, InstrumentID: instrument.ID()}}
-		return conn.Send(&protocol.OutMessage{Reply: sec})
+		rpt = &protocol.ExecutionReport{ClOrdId: request.ClOrdId, ReportType: protocol.ExecutionReport_Status, OrderState: protocol.ExecutionReport_Rejected, RejectReason: "unknown order"}
+	}
+	return conn.Send(&protocol.OutMessage{Reply: &protocol.OutMessage_Execrpt{Execrpt: rpt}})
+}
+
+func (s *grpcServer) openOrders(conn protocol.Exchange_ConnectionServer, client *grpcClient, request *protocol.OpenOrdersRequest) error {
+	reply := &protocol.OpenOrdersReply{}
+	var instrument Instrument
+	if request.Symbol != "" {
+		instrument = IMap.GetBySymbol(request.Symbol)
+		if instrument == nil {
+			reply.Error = "unknown symbol " + request.Symbol
+		}
+	}
+	if reply.Error == "" {
+		for _, order := range s.e.openOrders(client, instrument) {
+			reply.Orders = append(reply.Orders, newStatusReport(order, client.version))
+		}
+	}
+	return conn.Send(&protocol.OutMessage{Reply: &protocol.OutMessage_Openorders{Openorders: reply}})
+}
+
+// MarketData streams the market data of the requested symbols until the client cancels the stream
+func (s *grpcServer) MarketData(request *protocol.MarketDataRequest, stream protocol.Exchange_MarketDataServer) error {
+	log.Println("grpc market data subscription", request.Symbols)
+
+	v := protocol.NegotiateVersion(request.ProtocolVersion)
+
+	interval := time.Duration(request.ConflateInterval) * time.Millisecond
+	sub := newSubscriber(request.Symbols, interval > 0)
+	subscribe(sub)
+	defer unsubscribe(sub)
+
+	ctx := stream.Context()
+	// the sequence of the last book sent for each instrument
+	sent := make(map[Instrument]uint64)
+	for {
+		select {
+		case <-sub.ready:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		updates, err := sub.take()
+		if err != nil {
+			return err
+		}
+		for _, update := range updates {
+			var msgs []*protocol.MarketDataMessage
+			switch {
+			case update.book != nil:
+				if update.book.Sequence <= sent[update.book.Instrument] {
+					continue
+				}
+				sent[update.book.Instrument] = update.book.Sequence
+				book := &protocol.MarketDataMessage_Book{Book: newMarketDataBook(update.book, int(request.Depth), v)}
+				msgs = append(msgs, &protocol.MarketDataMessage{Update: book, ProtocolVersion: int32(v)})
+			case update.stats != nil:
+				stats := &protocol.MarketDataMessage_Statistics{Statistics: newMarketDataStatistics(update.stats, v)}
+				msgs = append(msgs, &protocol.MarketDataMessage{Update: stats, ProtocolVersion: int32(v)})
+			default:
+				for _, trade := range update.trades {
+					mt := &protocol.MarketDataMessage_Trade{Trade: newMarketDataTrade(trade, v)}
+					msgs = append(msgs, &protocol.MarketDataMessage{Update: mt, ProtocolVersion: int32(v)})
+				}
+			}
+			for _, msg := range msgs {
+				if err := stream.Send(msg); err != nil {
+					return err
+				}
+			}
+		}
+		if interval > 0 {
+			select {
+			case <-time.After(interval):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// Trades returns the trades of the request's symbol and time range from the trade store
+func (s *grpcServer) Trades(ctx context.Context, request *protocol.TradesRequest) (*protocol.TradesReply, error) {
+	if request.Symbol != "" && IMap.GetBySymbol(request.Symbol) == nil {
+		return nil, errors.New("unknown symbol " + request.Symbol)
+	}
+	var from, to time.Time
+	if request.FromTime != 0 {
+		from = time.Unix(0, request.FromTime)
+	}
+	if request.ToTime != 0 {
+		to = time.Unix(0, request.ToTime)
+	}
+	v := protocol.NegotiateVersion(request.ProtocolVersion)
+	reply := &protocol.TradesReply{ProtocolVersion: int32(v)}
+	for _, t := range s.e.trades.query(request.Symbol, from, to) {
+		reply.Trades = append(reply.Trades, newTradeCapture(t, v))
+	}
+	return reply, nil
+}
+
+func newTradeCapture(t storedTrade, v protocol.Version) *protocol.TradeCapture {
+	tc := &protocol.TradeCapture{ReportID: t.ReportID, TradeID: t.TradeID, Symbol: t.Symbol,
+		Buyer: t.Buyer, BuyOrder: t.BuyOrder, Seller: t.Seller, SellOrder: t.SellOrder, Aggressor: string(t.Aggressor), TradeTime: t.Time.UnixNano()}
+	v.Encode(t.Price, &tc.Price, &tc.ScaledPrice)
+	v.Encode(t.Quantity, &tc.Quantity, &tc.ScaledQuantity)
+	return tc
+}
+
+func newMarketDataBook(book *Book, depth int, v protocol.Version) *protocol.MarketDataBook {
+	levels := func(levels []BookLevel) []*protocol.MarketDataLevel {
+		if depth > 0 && len(levels) > depth {
+			levels = levels[:depth]
+		}
+		var result []*protocol.MarketDataLevel
+		for _, level := range levels {
+			ml := &protocol.MarketDataLevel{}
+			v.Encode(level.Price, &ml.Price, &ml.ScaledPrice)
+			v.Encode(level.Quantity, &ml.Quantity, &ml.ScaledQuantity)
+			result = append(result, ml)
+		}
+		return result
+	}
+	mb := &protocol.MarketDataBook{Symbol: book.Instrument.Symbol(), Sequence: book.Sequence, State: string(book.State)}
+	mb.Bids = levels(book.Bids)
+	mb.Asks = levels(book.Asks)
+	mb.InAuction = book.InAuction
+	v.Encode(book.IndicativePrice, &mb.IndicativePrice, &mb.ScaledIndicativePrice)
+	v.Encode(book.IndicativeVolume, &mb.IndicativeVolume, &mb.ScaledIndicativeVolume)
+	return mb
+}
+
+func newMarketDataTrade(trade Trade, v protocol.Version) *protocol.MarketDataTrade {
+	mt := &protocol.MarketDataTrade{Symbol: trade.Instrument.Symbol(), ExchangeID: trade.ExchangeID, TradeTime: trade.TradeTime.UnixNano()}
+	v.Encode(trade.Price, &mt.Price, &mt.ScaledPrice)
+	v.Encode(trade.Quantity, &mt.Quantity, &mt.ScaledQuantity)
+	return mt
+}
+
+func newMarketDataStatistics(stats *Statistics, v protocol.Version) *protocol.MarketDataStatistics {
+	ms := &protocol.MarketDataStatistics{Symbol: stats.Symbol, HasHighLow: stats.HasHighLow}
+	v.Encode(stats.Volume, &ms.Volume, &ms.ScaledVolume)
+	v.Encode(stats.High, &ms.High, &ms.ScaledHigh)
+	v.Encode(stats.Low, &ms.Low, &ms.ScaledLow)
+	return ms
+}
+
+func newSecurityDefinition(instrument Instrument, v protocol.Version) *protocol.SecurityDefinition {
+	spec := instrument.Spec()
+	sec := &protocol.SecurityDefinition{Symbol: instrument.Symbol(), InstrumentID: instrument.ID()}
+	v.Encode(spec.PriceBands.Static, &sec.StaticPriceBand, &sec.ScaledStaticPriceBand)
+	v.Encode(spec.PriceBands.Dynamic, &sec.DynamicPriceBand, &sec.ScaledDynamicPriceBand)
+	for _, level := range spec.TickSizes {
+		tl := &protocol.TickSizeLevel{}
+		v.Encode(level.Price, &tl.Price, &tl.ScaledPrice)
+		v.Encode(level.TickSize, &tl.TickSize, &tl.ScaledTickSize)
+		sec.TickSizes = append(sec.TickSizes, tl)
 	}
+	v.Encode(spec.LotSize, &sec.LotSize, &sec.ScaledLotSize)
+	v.Encode(spec.MinQuantity, &sec.MinQuantity, &sec.ScaledMinQuantity)
+	v.Encode(spec.MaxQuantity, &sec.MaxQuantity, &sec.ScaledMaxQuantity)
+	return sec
 }
 
 func toErrS(err error) string {
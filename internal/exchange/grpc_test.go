@@ -0,0 +1,115 @@
+package exchange
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/robaho/go-trader/pkg/common"
+	"github.com/robaho/go-trader/pkg/protocol"
+	"google.golang.org/grpc"
+)
+
+// testStream is a grpc connection stream fed from a channel, the stream ends when the channel is closed
+type testStream struct {
+	grpc.ServerStream
+	in  chan *protocol.InMessage
+	out chan *protocol.OutMessage
+}
+
+func newTestStream() *testStream {
+	return &testStream{in: make(chan *protocol.InMessage, 16), out: make(chan *protocol.OutMessage, 16)}
+}
+
+func (s *testStream) Send(msg *protocol.OutMessage) error {
+	s.out <- msg
+	return nil
+}
+
+func (s *testStream) Recv() (*protocol.InMessage, error) {
+	msg, ok := <-s.in
+	if !ok {
+		return nil, io.EOF
+	}
+	return msg, nil
+}
+
+func (s *testStream) login(t *testing.T, name string) *protocol.LoginReply {
+	login := &protocol.LoginRequest{Username: "guest", ProtocolVersion: int32(protocol.LatestVersion), SessionName: name}
+	s.in <- &protocol.InMessage{Request: &protocol.InMessage_Login{Login: login}}
+	select {
+	case msg := <-s.out:
+		return msg.GetLogin()
+	case <-time.After(time.Second):
+		t.Fatal("no login reply")
+	}
+	return nil
+}
+
+func TestGrpcReconnect(t *testing.T) {
+	discardMarketData()
+
+	path := filepath.Join(t.TempDir(), "test.journal")
+	j, err := openJournal(path, fsyncNever)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var i = NewInstrument(1031, "GRPCRECOVER")
+	IMap.Put(i)
+
+	e1 := &exchange{journal: j}
+	s1 := &grpcServer{e: e1}
+	conn1 := newTestStream()
+	c1 := &grpcClient{conn: conn1}
+	if err := s1.login(conn1, c1, &protocol.LoginRequest{SessionName: "A", ProtocolVersion: int32(protocol.LatestVersion)}); err != nil {
+		t.Fatal(err)
+	}
+	<-conn1.out
+	create := &protocol.CreateOrderRequest{Symbol: i.Symbol(), ClOrdId: 1, OrderType: protocol.CreateOrderRequest_Limit,
+		OrderSide: protocol.CreateOrderRequest_Buy, ScaledPrice: 1000000000, ScaledQuantity: 100000000}
+	if err := s1.create(conn1, c1, create); err != nil {
+		t.Fatal(err)
+	}
+	j.close()
+
+	// the exchange restarts, the session logs in on a new connection with the same session name
+	e2 := &exchange{}
+	if _, err := e2.replayJournal(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	s2 := &grpcServer{e: e2}
+	conn2 := newTestStream()
+	go s2.Connection(conn2)
+	if reply := conn2.login(t, "A"); reply == nil || reply.Error != "" {
+		t.Fatal("login failed", reply)
+	}
+
+	conn3 := newTestStream()
+	go s2.Connection(conn3)
+	if reply := conn3.login(t, "A"); reply == nil || reply.Error == "" {
+		t.Error("second connection with the same session name should be rejected", reply)
+	}
+	close(conn3.in)
+
+	c2, ok := s2.sessions.Load("grpc:A")
+	if !ok {
+		t.Fatal("session not logged in")
+	}
+	e2.cancelRecovered(recoveryCancelAll)
+	open := e2.openOrders(c2.(*grpcClient), nil)
+	if len(open) != 1 || open[0].Id != 1 || open[0].OrderState != Booked {
+		t.Fatal("order not recovered for the session", open)
+	}
+
+	close(conn2.in)
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if _, ok := s2.sessions.Load("grpc:A"); !ok {
+			break
+		}
+	}
+	if open := e2.openOrders(c2.(*grpcClient), nil); len(open) != 0 {
+		t.Error("orders should be cancelled when the session disconnects", open)
+	}
+}
@@ -0,0 +1,352 @@
+package exchange
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+// Instruments created through FIX or gRPC, or by the admin api, are written to the instrument store configured by
+// instrument_store, and loaded with the same ids when the exchange is restarted. A deleted instrument is kept as a
+// tombstone so its id is never reused, since clients may have cached it from the market data.
+
+type storedInstrument struct {
+	ID     int64
+	Symbol string
+	// the price bands are not stored, they are added from the configuration when the instrument is loaded
+	Spec     InstrumentSpec
+	Disabled bool `json:",omitempty"`
+	Deleted  bool `json:",omitempty"`
+}
+
+type instrumentStore struct {
+	sync.Mutex
+	path        string
+	instruments map[string]*storedInstrument
+	// the highest id assigned, including deleted instruments
+	maxID int64
+}
+
+// newInstrumentStore opens the configured instrument store, or returns nil if instruments are not persisted
+func newInstrumentStore(props Properties) (*instrumentStore, error) {
+	path := props.GetString("instrument_store", "")
+	if path == "" {
+		return nil, nil
+	}
+	return openInstrumentStore(path)
+}
+
+func openInstrumentStore(path string) (*instrumentStore, error) {
+	st := &instrumentStore{path: path, instruments: make(map[string]*storedInstrument)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stored []*storedInstrument
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("unable to read instrument store %s: %w", path, err)
+	}
+	for _, si := range stored {
+		st.instruments[si.Symbol] = si
+		if si.ID > st.maxID {
+			st.maxID = si.ID
+		}
+	}
+	return st, nil
+}
+
+// lookup returns the stored instrument with the symbol, or nil. The store must be locked.
+func (st *instrumentStore) lookup(symbol string) *storedInstrument {
+	if st == nil {
+		return nil
+	}
+	if si, ok := st.instruments[symbol]; ok && !si.Deleted {
+		return si
+	}
+	return nil
+}
+
+// save writes the store atomically. The store must be locked.
+func (st *instrumentStore) save() error {
+	var stored []*storedInstrument
+	for _, si := range st.instruments {
+		stored = append(stored, si)
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].ID < stored[j].ID })
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(st.path), 0755); err != nil {
+		return err
+	}
+	tmp := st.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if err0 := file.Close(); err == nil {
+		err = err0
+	}
+	if err == nil {
+		err = os.Rename(tmp, st.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// update applies the change to the stored instrument and saves the store, the change is undone if the store
+// cannot be written
+func (st *instrumentStore) update(si *storedInstrument, change func(si *storedInstrument)) error {
+	previous := *si
+	change(si)
+	if err := st.save(); err != nil {
+		*si = previous
+		return err
+	}
+	return nil
+}
+
+// loadInstruments adds the stored instruments to the instrument map. Instruments from the instrument file take
+// precedence over a stored instrument with the same symbol.
+func (e *exchange) loadInstruments() error {
+	st := e.instruments
+	if st == nil {
+		return nil
+	}
+	st.Lock()
+	defer st.Unlock()
+
+	for _, si := range st.instruments {
+		if si.Deleted {
+			continue
+		}
+		if existing := IMap.GetBySymbol(si.Symbol); existing != nil {
+			fmt.Println("stored instrument", si.Symbol, "ignored, it is in the instrument file")
+			continue
+		}
+		if existing := IMap.GetByID(si.ID); existing != nil {
+			return fmt.Errorf("stored instrument %s has the id %d of %s", si.Symbol, si.ID, existing.Symbol())
+		}
+		instrument, err := e.newInstrument(si.ID, si.Symbol, si.Spec)
+		if err != nil {
+			return err
+		}
+		IMap.Put(instrument)
+	}
+	IMap.Reserve(st.maxID)
+	return nil
+}
+
+// closeDisabledInstruments closes trading in the disabled instruments, after the books have been recovered
+func (e *exchange) closeDisabledInstruments() {
+	for _, symbol := range e.disabledInstruments() {
+		instrument := IMap.GetBySymbol(symbol)
+		if instrument != nil && e.GetTradingState(instrument) != Closed {
+			e.SetTradingState(instrument, Closed)
+		}
+	}
+}
+
+func (e *exchange) disabledInstruments() []string {
+	st := e.instruments
+	if st == nil {
+		return nil
+	}
+	st.Lock()
+	defer st.Unlock()
+
+	var symbols []string
+	for symbol, si := range st.instruments {
+		if si.Disabled && !si.Deleted {
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols
+}
+
+// CreateInstrument returns the instrument with the symbol, creating it with the next instrument id if it does not
+// exist. The new instrument is saved to the instrument store before it is available.
+func (e *exchange) CreateInstrument(symbol string, spec InstrumentSpec) (Instrument, error) {
+	e.instrumentLock.Lock()
+	defer e.instrumentLock.Unlock()
+
+	if instrument := IMap.GetBySymbol(symbol); instrument != nil {
+		return instrument, nil
+	}
+	if symbol == "" {
+		return nil, errors.New("missing symbol")
+	}
+
+	instrument, err := e.newInstrument(IMap.NextID(), symbol, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if st := e.instruments; st != nil {
+		st.Lock()
+		defer st.Unlock()
+
+		spec.PriceBands = PriceBands{}
+		si := &storedInstrument{ID: instrument.ID(), Symbol: symbol, Spec: spec}
+		previous := st.instruments[symbol]
+		previousMax := st.maxID
+		st.instruments[symbol] = si
+		if si.ID > st.maxID {
+			st.maxID = si.ID
+		}
+		if err := st.save(); err != nil {
+			if previous != nil {
+				st.instruments[symbol] = previous
+			} else {
+				delete(st.instruments, symbol)
+			}
+			st.maxID = previousMax
+			return nil, fmt.Errorf("unable to save instrument %s: %w", symbol, err)
+		}
+	}
+
+	IMap.Put(instrument)
+	return instrument, nil
+}
+
+var errNoInstrumentStore = errors.New("the instrument store is not configured")
+
+// storedInstrument returns the instrument and its stored entry, only instruments created at runtime can be
+// managed. The store must be locked.
+func (e *exchange) storedInstrument(symbol string) (Instrument, *storedInstrument, error) {
+	instrument := IMap.GetBySymbol(symbol)
+	if instrument == nil {
+		return nil, nil, errors.New("the symbol " + symbol + " is unknown")
+	}
+	si := e.instruments.lookup(symbol)
+	if si == nil || si.ID != instrument.ID() {
+		return nil, nil, errors.New(symbol + " is not a created instrument")
+	}
+	return instrument, si, nil
+}
+
+// DisableInstrument closes trading in a created instrument, it remains closed after a restart until it is enabled
+func (e *exchange) DisableInstrument(symbol string) error {
+	e.instrumentLock.Lock()
+	defer e.instrumentLock.Unlock()
+
+	if e.instruments == nil {
+		return errNoInstrumentStore
+	}
+
+	e.instruments.Lock()
+	instrument, si, err := e.storedInstrument(symbol)
+	if err == nil {
+		err = e.instruments.update(si, func(si *storedInstrument) { si.Disabled = true })
+	}
+	e.instruments.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if e.GetTradingState(instrument) != Closed {
+		return e.SetTradingState(instrument, Closed)
+	}
+	return nil
+}
+
+// EnableInstrument opens trading in a disabled instrument
+func (e *exchange) EnableInstrument(symbol string) error {
+	e.instrumentLock.Lock()
+	defer e.instrumentLock.Unlock()
+
+	if e.instruments == nil {
+		return errNoInstrumentStore
+	}
+
+	e.instruments.Lock()
+	instrument, si, err := e.storedInstrument(symbol)
+	if err == nil && !si.Disabled {
+		err = errors.New(symbol + " is not disabled")
+	}
+	if err == nil {
+		err = e.instruments.update(si, func(si *storedInstrument) { si.Disabled = false })
+	}
+	e.instruments.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if e.GetTradingState(instrument) != Open {
+		return e.SetTradingState(instrument, Open)
+	}
+	return nil
+}
+
+// DeleteInstrument removes a created instrument that has no resting orders or quotes. Its id is not reused.
+func (e *exchange) DeleteInstrument(symbol string) error {
+	e.instrumentLock.Lock()
+	defer e.instrumentLock.Unlock()
+
+	if e.instruments == nil {
+		return errNoInstrumentStore
+	}
+
+	e.quiesce.RLock()
+	defer e.quiesce.RUnlock()
+
+	e.instruments.Lock()
+	defer e.instruments.Unlock()
+
+	instrument, si, err := e.storedInstrument(symbol)
+	if err != nil {
+		return err
+	}
+
+	ob := e.lockOrderBook(instrument)
+	defer ob.Unlock()
+
+	if len(ob.bids) > 0 || len(ob.asks) > 0 || len(ob.stops) > 0 {
+		return errors.New(symbol + " has resting orders")
+	}
+	if err := e.instruments.update(si, func(si *storedInstrument) { si.Deleted = true }); err != nil {
+		return err
+	}
+
+	IMap.Remove(instrument)
+	e.orderBooks.Delete(instrument)
+	bookCache.Delete(instrument)
+	statsCache.Delete(instrument)
+	return nil
+}
+
+// CreatedInstruments returns the symbols of the instruments created at runtime, and if they are disabled
+func (e *exchange) CreatedInstruments() map[string]bool {
+	m := make(map[string]bool)
+	st := e.instruments
+	if st == nil {
+		return m
+	}
+	st.Lock()
+	defer st.Unlock()
+
+	for symbol, si := range st.instruments {
+		if !si.Deleted {
+			m[symbol] = si.Disabled
+		}
+	}
+	return m
+}
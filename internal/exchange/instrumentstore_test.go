@@ -0,0 +1,78 @@
+package exchange
+
+import (
+	"path/filepath"
+	"testing"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+func TestInstrumentStore(t *testing.T) {
+	discardMarketData()
+
+	path := filepath.Join(t.TempDir(), "instruments.json")
+	st, err := openInstrumentStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e1 := &exchange{instruments: st}
+	defer func(e *exchange) { App.e = e }(App.e)
+	App.e = e1
+
+	spec, _ := ParseInstrumentSpec([]string{"lot=10"})
+	i1, err := e1.CreateInstrument("STORE1", spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i, _ := e1.CreateInstrument("STORE1", InstrumentSpec{}); i != i1 {
+		t.Error("existing instrument should be returned")
+	}
+	i2, _ := e1.CreateInstrument("STORE2", InstrumentSpec{})
+
+	if err := e1.DisableInstrument("STORE1"); err != nil {
+		t.Fatal(err)
+	}
+	if e1.GetTradingState(i1) != Closed {
+		t.Error("disabled instrument should be closed")
+	}
+	if err := e1.DeleteInstrument("STORE2"); err != nil {
+		t.Fatal(err)
+	}
+	if IMap.GetBySymbol("STORE2") != nil || IMap.GetByID(i2.ID()) != nil {
+		t.Error("deleted instrument should be removed")
+	}
+
+	i3 := NewInstrument(2000, "CONFIGURED")
+	IMap.Put(i3)
+	if err := e1.DeleteInstrument("CONFIGURED"); err == nil {
+		t.Error("configured instruments should not be managed")
+	}
+	IMap.Remove(i3)
+
+	// restart
+	IMap.Remove(i1)
+
+	st, err = openInstrumentStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e2 := &exchange{instruments: st}
+	if err := e2.loadInstruments(); err != nil {
+		t.Fatal(err)
+	}
+	loaded := IMap.GetBySymbol("STORE1")
+	if loaded == nil || loaded.ID() != i1.ID() || !loaded.Spec().LotSize.Equal(NewDecimal("10")) {
+		t.Fatal("stored instrument not loaded", loaded)
+	}
+	if IMap.GetBySymbol("STORE2") != nil {
+		t.Error("deleted instrument should not be loaded")
+	}
+	if id := IMap.NextID(); id <= i2.ID() {
+		t.Error("id of deleted instrument should not be reused", id)
+	}
+	if disabled := e2.CreatedInstruments(); len(disabled) != 1 || !disabled["STORE1"] {
+		t.Error("disabled instrument not restored", disabled)
+	}
+	IMap.Remove(loaded)
+}
@@ -0,0 +1,460 @@
+package exchange
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/robaho/fixed"
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+// The journal is an append-only file of JSON entries, one per line. Every command that changes a book or a
+// session is recorded while the book is locked, so the journal order is the order the commands were applied.
+// Commands that affect many orders, e.g. a session disconnect or mass cancel, are recorded as the individual
+// cancels. The outcomes, order status and trades, are recorded and committed before they are sent to the client,
+// and the journal is committed before the book changes are published as market data.
+
+// JournalType is the type of a journal entry
+type JournalType string
+
+const (
+	JournalCreateOrder  JournalType = "order"
+	JournalModifyOrder  JournalType = "modify"
+	JournalCancelOrder  JournalType = "cancel"
+	JournalQuote        JournalType = "quote"
+	JournalCancelQuote  JournalType = "cancelquote"
+	JournalTradingState JournalType = "state"
+	JournalDisconnect   JournalType = "disconnect"
+	JournalMassCancel   JournalType = "masscancel"
+	JournalEnable       JournalType = "enable"
+	// outcomes
+	JournalOrderStatus JournalType = "status"
+	JournalTrade       JournalType = "trade"
+)
+
+// JournalEntry is a single journal record, only the fields relevant to the type are set
+type JournalEntry struct {
+	Seq     uint64
+	Time    time.Time
+	Type    JournalType
+	Session string `json:",omitempty"`
+	Symbol  string `json:",omitempty"`
+	Account string `json:",omitempty"`
+	Side    Side   `json:",omitempty"`
+	// the reason an order was cancelled by the exchange, or a command was rejected
+	Reason string          `json:",omitempty"`
+	Order  *JournaledOrder `json:",omitempty"`
+	Quote  *JournaledQuote `json:",omitempty"`
+	Trade  *JournaledTrade `json:",omitempty"`
+	State  TradingState    `json:",omitempty"`
+	// true if a mass cancel blocks new orders
+	Block bool `json:",omitempty"`
+}
+
+// JournaledOrder is an order as received, or its status for outcomes. Modify and cancel only set the Id, and the
+// new price and quantity.
+type JournaledOrder struct {
+	Id              OrderID
+	ExchangeId      string      `json:",omitempty"`
+	Side            Side        `json:",omitempty"`
+	OrderType       OrderType   `json:",omitempty"`
+	TimeInForce     TimeInForce `json:",omitempty"`
+	Price           Fixed
+	Quantity        Fixed
+	Remaining       Fixed
+	StopPrice       Fixed
+	DisplayQuantity Fixed
+	Triggered       bool       `json:",omitempty"`
+	ExpireTime      *time.Time `json:",omitempty"`
+	Account         string     `json:",omitempty"`
+	OrderState      OrderState `json:",omitempty"`
+	RejectReason    string     `json:",omitempty"`
+}
+
+type JournaledQuote struct {
+	BidPrice    Fixed
+	BidQuantity Fixed
+	AskPrice    Fixed
+	AskQuantity Fixed
+}
+
+type JournaledTrade struct {
+	TradeID   int64
+	Price     Fixed
+	Quantity  Fixed
+	Buyer     string
+	BuyOrder  string
+	Seller    string
+	SellOrder string
+	Aggressor Side `json:",omitempty"`
+}
+
+func (entry *JournalEntry) String() string {
+	s := fmt.Sprint(entry.Seq, " ", entry.Time.Format("2006-01-02 15:04:05.000000"), " ", entry.Type)
+	for _, field := range []string{entry.Session, entry.Symbol, entry.Account, string(entry.Side), string(entry.State)} {
+		if field != "" {
+			s += " " + field
+		}
+	}
+	if o := entry.Order; o != nil {
+		s += " oid " + o.Id.String()
+		if o.ExchangeId != "" {
+			s += " eoid " + o.ExchangeId
+		}
+		s += " " + o.Quantity.String() + "@" + o.Price.String()
+		if o.OrderState != "" {
+			s += " remaining " + o.Remaining.String() + " " + string(o.OrderState)
+		}
+		if o.RejectReason != "" {
+			s += " (" + o.RejectReason + ")"
+		}
+	}
+	if q := entry.Quote; q != nil {
+		s += " " + q.BidQuantity.String() + "@" + q.BidPrice.String() + " " + q.AskQuantity.String() + "@" + q.AskPrice.String()
+	}
+	if t := entry.Trade; t != nil {
+		s += fmt.Sprint(" id ", t.TradeID, " ", t.Quantity, "@", t.Price, " buyer ", t.Buyer, "/", t.BuyOrder, " seller ", t.Seller, "/", t.SellOrder)
+		if t.Aggressor != "" {
+			s += " aggressor " + string(t.Aggressor)
+		}
+	}
+	if entry.Block {
+		s += " block"
+	}
+	if entry.Reason != "" {
+		s += " (" + entry.Reason + ")"
+	}
+	return s
+}
+
+type fsyncPolicy string
+
+const (
+	// sync before every acknowledgement
+	fsyncAlways fsyncPolicy = "always"
+	// written before every acknowledgement, synced periodically
+	fsyncInterval fsyncPolicy = "interval"
+	// written before every acknowledgement, synced by the operating system
+	fsyncNever fsyncPolicy = "never"
+)
+
+type journal struct {
+	sync.Mutex
+	file   *os.File
+	w      *bufio.Writer
+	enc    *json.Encoder
+	seq    uint64
+	policy fsyncPolicy
+	err    error
+}
+
+// newJournal opens the journal configured by journal_file, or returns nil if the journal is disabled
+func newJournal(props Properties) (*journal, error) {
+	path := props.GetString("journal_file", "")
+	if path == "" {
+		return nil, nil
+	}
+	policy := fsyncPolicy(props.GetString("journal_fsync", string(fsyncAlways)))
+	switch policy {
+	case fsyncAlways, fsyncInterval, fsyncNever:
+	default:
+		return nil, errors.New("invalid journal_fsync " + string(policy))
+	}
+	interval, err := time.ParseDuration(props.GetString("journal_fsync_interval", "100ms"))
+	if err != nil || (policy == fsyncInterval && interval <= 0) {
+		return nil, errors.New("invalid journal_fsync_interval")
+	}
+
+	j, err := openJournal(path, policy)
+	if err != nil {
+		return nil, err
+	}
+	if policy == fsyncInterval {
+		go func() {
+			for range time.Tick(interval) {
+				j.sync()
+			}
+		}()
+	}
+	return j, nil
+}
+
+// openJournal opens the journal for appending. An incomplete last entry, written when the exchange stopped, is
+// removed.
+func openJournal(path string, policy fsyncPolicy) (*journal, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	var seq uint64
+	var valid int64
+	r := bufio.NewReader(file)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		var entry JournalEntry
+		if json.Unmarshal(line, &entry) != nil {
+			break
+		}
+		seq = entry.Seq
+		valid += int64(len(line))
+	}
+	if err := file.Truncate(valid); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(valid, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	j := &journal{file: file, seq: seq, policy: policy}
+	j.w = bufio.NewWriterSize(file, 64*1024)
+	j.enc = json.NewEncoder(j.w)
+	return j, nil
+}
+
+// ReadJournal calls fn for each entry in the journal, in order. An incomplete last entry is ignored.
+func ReadJournal(path string, fn func(entry *JournalEntry) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return fmt.Errorf("invalid journal entry '%s': %w", strings.TrimSpace(string(line)), err)
+		}
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+}
+
+// record appends the entry to the journal, it is not written to the file until commit
+func (j *journal) record(entry *JournalEntry) {
+	if j == nil {
+		return
+	}
+	j.Lock()
+	defer j.Unlock()
+
+	j.seq++
+	entry.Seq = j.seq
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	j.check(j.enc.Encode(entry))
+}
+
+// commit writes the recorded entries to the file, and syncs it if the policy is always. It must be called before
+// a client is sent the outcome of a command.
+func (j *journal) commit() {
+	if j == nil {
+		return
+	}
+	j.Lock()
+	defer j.Unlock()
+
+	if j.w.Buffered() == 0 {
+		return
+	}
+	j.check(j.w.Flush())
+	if j.policy == fsyncAlways {
+		j.check(j.file.Sync())
+	}
+}
+
+// lastSeq returns the sequence number of the last recorded entry
+func (j *journal) lastSeq() uint64 {
+	if j == nil {
+		return 0
+	}
+	j.Lock()
+	defer j.Unlock()
+	return j.seq
+}
+
+// skipTo continues the sequence numbers after seq, if the journal is behind a snapshot
+func (j *journal) skipTo(seq uint64) {
+	if j == nil {
+		return
+	}
+	j.Lock()
+	defer j.Unlock()
+	if j.seq < seq {
+		j.seq = seq
+	}
+}
+
+func (j *journal) sync() {
+	j.Lock()
+	defer j.Unlock()
+
+	j.check(j.w.Flush())
+	j.check(j.file.Sync())
+}
+
+func (j *journal) close() error {
+	if j == nil {
+		return nil
+	}
+	j.sync()
+	return j.file.Close()
+}
+
+// check reports the first write error, the exchange keeps running without a complete journal
+func (j *journal) check(err error) {
+	if err != nil && j.err == nil {
+		j.err = err
+		fmt.Println("unable to write journal", err)
+	}
+}
+
+func journalOrder(order *Order) *JournaledOrder {
+	o := &JournaledOrder{
+		Id:              order.Id,
+		ExchangeId:      order.ExchangeId,
+		Side:            order.Side,
+		OrderType:       order.OrderType,
+		TimeInForce:     order.TimeInForce,
+		Price:           order.Price,
+		Quantity:        order.Quantity,
+		Remaining:       order.Remaining,
+		StopPrice:       order.StopPrice,
+		DisplayQuantity: order.DisplayQuantity,
+		Triggered:       order.Triggered,
+		Account:         order.Account,
+		OrderState:      order.OrderState,
+		RejectReason:    order.RejectReason,
+	}
+	if !order.ExpireTime.IsZero() {
+		expires := order.ExpireTime
+		o.ExpireTime = &expires
+	}
+	return o
+}
+
+// recordOrder records a new order after it is checked, reason is set if it was rejected
+func (j *journal) recordOrder(so sessionOrder, reason string) {
+	if j == nil {
+		return
+	}
+	j.record(&JournalEntry{Time: so.time, Type: JournalCreateOrder, Session: so.client.SessionID(), Symbol: so.order.Symbol(), Order: journalOrder(so.order), Reason: reason})
+}
+
+// recordModify records a modify after it is checked, reason is set if it was rejected
+func (j *journal) recordModify(so sessionOrder, price Fixed, quantity Fixed, reason string) {
+	if j == nil {
+		return
+	}
+	order := &JournaledOrder{Id: so.order.Id, Price: price, Quantity: quantity}
+	j.record(&JournalEntry{Time: so.time, Type: JournalModifyOrder, Session: so.client.SessionID(), Symbol: so.order.Symbol(), Order: order, Reason: reason})
+}
+
+// recordCancel records the cancel of an order, reason is set if it was cancelled by the exchange
+func (j *journal) recordCancel(so sessionOrder, reason string) {
+	if j == nil {
+		return
+	}
+	j.record(&JournalEntry{Type: JournalCancelOrder, Session: so.client.SessionID(), Symbol: so.order.Symbol(), Order: &JournaledOrder{Id: so.order.Id}, Reason: reason})
+}
+
+// recordQuote records a quote after it is checked, reason is set if it was rejected
+func (j *journal) recordQuote(client exchangeClient, instrument Instrument, now time.Time, bidPrice Fixed, bidQuantity Fixed, askPrice Fixed, askQuantity Fixed, reason string) {
+	if j == nil {
+		return
+	}
+	quote := &JournaledQuote{BidPrice: bidPrice, BidQuantity: bidQuantity, AskPrice: askPrice, AskQuantity: askQuantity}
+	j.record(&JournalEntry{Time: now, Type: JournalQuote, Session: client.SessionID(), Symbol: instrument.Symbol(), Quote: quote, Reason: reason})
+}
+
+// recordCancelQuote records the cancel of one side of a quote by the exchange
+func (j *journal) recordCancelQuote(so sessionOrder, reason string) {
+	if j == nil {
+		return
+	}
+	j.record(&JournalEntry{Type: JournalCancelQuote, Session: so.client.SessionID(), Symbol: so.order.Symbol(), Side: so.order.Side, Reason: reason})
+}
+
+func (j *journal) recordState(instrument Instrument, state TradingState, now time.Time) {
+	if j == nil {
+		return
+	}
+	j.record(&JournalEntry{Time: now, Type: JournalTradingState, Symbol: instrument.Symbol(), State: state})
+}
+
+func (j *journal) recordDisconnect(client exchangeClient) {
+	if j == nil {
+		return
+	}
+	j.record(&JournalEntry{Type: JournalDisconnect, Session: client.SessionID()})
+}
+
+// recordKillSwitch records a mass cancel or enable, the cancelled orders are recorded separately
+func (j *journal) recordKillSwitch(entryType JournalType, k KillSwitch, block bool) {
+	if j == nil {
+		return
+	}
+	entry := &JournalEntry{Type: entryType, Session: k.SessionID, Account: k.Account, Side: k.Side, Block: block}
+	if k.Instrument != nil {
+		entry.Symbol = k.Instrument.Symbol()
+	}
+	j.record(entry)
+}
+
+func (j *journal) recordStatus(so sessionOrder) {
+	if j == nil {
+		return
+	}
+	j.record(&JournalEntry{Type: JournalOrderStatus, Session: so.client.SessionID(), Symbol: so.order.Symbol(), Order: journalOrder(so.order)})
+}
+
+func (j *journal) recordTrades(trades []trade) {
+	if j == nil {
+		return
+	}
+	for _, t := range trades {
+		jt := &JournaledTrade{
+			TradeID:   t.tradeid,
+			Price:     t.price,
+			Quantity:  t.quantity,
+			Buyer:     t.buyer.client.SessionID(),
+			BuyOrder:  t.buyer.order.ExchangeId,
+			Seller:    t.seller.client.SessionID(),
+			SellOrder: t.seller.order.ExchangeId,
+			Aggressor: t.aggressor,
+		}
+		j.record(&JournalEntry{Time: t.when, Type: JournalTrade, Symbol: t.buyer.order.Symbol(), Trade: jt})
+	}
+}
@@ -0,0 +1,124 @@
+package exchange
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/robaho/fixed"
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.journal")
+
+	j, err := openJournal(path, fsyncAlways)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ex = testExchangeClient{}
+	var i = NewInstrument(1, "TEST")
+
+	order := LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("10"))
+	order.ExchangeId = "1"
+	so := sessionOrder{ex, order, time.Now()}
+	j.recordOrder(so, "")
+	j.recordModify(so, NewDecimal("101"), NewDecimal("20"), "rejected")
+	j.commit()
+	j.recordCancel(so, "")
+	if err := j.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// simulate a partial write
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"Seq":4,"Type":"ord`)
+	f.Close()
+
+	j, err = openJournal(path, fsyncAlways)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j.recordState(i, Halted, time.Now())
+	j.close()
+
+	var entries []*JournalEntry
+	err = ReadJournal(path, func(entry *JournalEntry) error {
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatal("wrong number of entries", len(entries))
+	}
+	for index, entry := range entries {
+		if entry.Seq != uint64(index+1) {
+			t.Error("wrong sequence", entry.Seq)
+		}
+	}
+	if entries[0].Type != JournalCreateOrder || entries[0].Session != "X" || entries[0].Symbol != "TEST" || entries[0].Order.ExchangeId != "1" {
+		t.Error("wrong order entry", entries[0])
+	}
+	if !entries[0].Order.Price.Equal(NewDecimal("100")) || !entries[0].Time.Equal(so.time) {
+		t.Error("wrong order price or time", entries[0])
+	}
+	if entries[1].Type != JournalModifyOrder || !entries[1].Order.Quantity.Equal(NewDecimal("20")) || entries[1].Reason != "rejected" {
+		t.Error("wrong modify entry", entries[1])
+	}
+	if entries[2].Type != JournalCancelOrder || entries[3].Type != JournalTradingState || entries[3].State != Halted {
+		t.Error("wrong entries", entries[2], entries[3])
+	}
+}
+
+// publishCheckClient records, when a fill is reported, the journaled trades and the latest published book
+type publishCheckClient struct {
+	namedExchangeClient
+	path   string
+	trades int
+	book   *Book
+}
+
+func (c *publishCheckClient) SendFill(so sessionOrder, price Fixed, quantity Fixed, remaining Fixed) {
+	c.trades = 0
+	ReadJournal(c.path, func(entry *JournalEntry) error {
+		if entry.Type == JournalTrade {
+			c.trades++
+		}
+		return nil
+	})
+	c.book = GetLatestBook(so.order.Instrument)
+}
+
+func TestJournalBeforeMarketData(t *testing.T) {
+	discardMarketData()
+
+	path := filepath.Join(t.TempDir(), "test.journal")
+	j, err := openJournal(path, fsyncNever)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.close()
+
+	var i = NewInstrument(1038, "JOURNALFIRST")
+	IMap.Put(i)
+
+	e := &exchange{journal: j}
+	client := &publishCheckClient{namedExchangeClient: "A", path: path}
+	e.CreateOrder(client, LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("10")))
+	published := GetLatestBook(i)
+	e.CreateOrder(namedExchangeClient("B"), LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("10")))
+
+	if client.trades != 1 {
+		t.Error("the trade should be committed to the journal before it is reported", client.trades)
+	}
+	if client.book != published {
+		t.Error("the trade should be journaled before the book is published", client.book)
+	}
+	if GetLatestBook(i) == published {
+		t.Error("the book should be published after the trade")
+	}
+}
@@ -0,0 +1,178 @@
+package exchange
+
+import (
+	"errors"
+	"strings"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+const killSwitchReason = "cancelled by kill switch"
+
+// KillSwitch selects the orders and quotes to cancel, and optionally block. Empty fields match everything.
+type KillSwitch struct {
+	SessionID string
+	// the account as used for the risk limits, quotes use the session id
+	Account    string
+	Instrument Instrument
+	Side       Side
+}
+
+func (k KillSwitch) String() string {
+	var s []string
+	if k.SessionID != "" {
+		s = append(s, "session "+k.SessionID)
+	}
+	if k.Account != "" {
+		s = append(s, "account "+k.Account)
+	}
+	if k.Instrument != nil {
+		s = append(s, "symbol "+k.Instrument.Symbol())
+	}
+	if k.Side != "" {
+		s = append(s, "side "+string(k.Side))
+	}
+	if len(s) == 0 {
+		return "all"
+	}
+	return strings.Join(s, ", ")
+}
+
+// ParseKillSwitch creates a kill switch for the scope, one of all, session, account, or symbol, and its value
+func ParseKillSwitch(scope string, value string) (KillSwitch, error) {
+	var k KillSwitch
+	if scope != "all" && value == "" {
+		return k, errors.New("missing " + scope)
+	}
+	switch scope {
+	case "all":
+	case "session":
+		k.SessionID = value
+	case "account":
+		k.Account = value
+	case "symbol":
+		k.Instrument = IMap.GetBySymbol(value)
+		if k.Instrument == nil {
+			return k, errors.New("unknown symbol " + value)
+		}
+	default:
+		return k, errors.New("unknown kill switch scope " + scope)
+	}
+	return k, nil
+}
+
+func (k KillSwitch) matches(so sessionOrder) bool {
+	if k.SessionID != "" && so.client.SessionID() != k.SessionID {
+		return false
+	}
+	if k.Account != "" && accountName(so) != k.Account {
+		return false
+	}
+	if k.Instrument != nil && so.order.Instrument != k.Instrument {
+		return false
+	}
+	if k.Side != "" && so.order.Side != k.Side {
+		return false
+	}
+	return true
+}
+
+// MassCancel cancels every order and quote selected by the kill switch, and returns the number cancelled. If block
+// is true new orders and quotes that match are rejected until EnableTrading is called.
+func (e *exchange) MassCancel(k KillSwitch, block bool) int {
+	e.quiesce.RLock()
+	defer e.quiesce.RUnlock()
+
+	defer e.journal.commit()
+
+	e.journal.recordKillSwitch(JournalMassCancel, k, block)
+
+	if block {
+		e.blockLock.Lock()
+		e.blocked = append(e.blocked, k)
+		e.blockLock.Unlock()
+	}
+
+	count := 0
+
+	e.sessions.Range(func(key, value interface{}) bool {
+		s := value.(*session)
+		s.Lock()
+		defer s.Unlock()
+
+		for _, order := range s.orders {
+			so := sessionOrder{client: s.client, order: order}
+			if !order.IsActive() || !k.matches(so) {
+				continue
+			}
+			ob := e.lockOrderBook(order.Instrument)
+			if ob.remove(so) == nil {
+				order.RejectReason = killSwitchReason
+				e.journal.recordCancel(so, killSwitchReason)
+				e.sendOrderStatus(so)
+				sendMarketData(ob.marketEvent(nil))
+				count++
+			}
+			ob.Unlock()
+		}
+		for instrument, qp := range s.quotes {
+			ob := e.lockOrderBook(instrument)
+			for _, so := range []sessionOrder{qp.bid, qp.ask} {
+				if so.order == nil || !so.order.IsActive() || !k.matches(so) {
+					continue
+				}
+				if ob.remove(so) == nil {
+					e.journal.recordCancelQuote(so, killSwitchReason)
+					e.journal.commit()
+					e.risk.track(so)
+					sendMarketData(ob.marketEvent(nil))
+					count++
+				}
+			}
+			ob.Unlock()
+		}
+		return true
+	})
+
+	return count
+}
+
+// EnableTrading removes a block added by MassCancel, it returns false if there was no matching block
+func (e *exchange) EnableTrading(k KillSwitch) bool {
+	e.quiesce.RLock()
+	defer e.quiesce.RUnlock()
+
+	e.blockLock.Lock()
+	defer e.blockLock.Unlock()
+
+	for i, blocked := range e.blocked {
+		if blocked == k {
+			e.journal.recordKillSwitch(JournalEnable, k, false)
+			e.journal.commit()
+			e.blocked = append(e.blocked[:i], e.blocked[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Blocked returns the kill switches that are blocking new orders
+func (e *exchange) Blocked() []KillSwitch {
+	e.blockLock.Lock()
+	defer e.blockLock.Unlock()
+
+	return append([]KillSwitch(nil), e.blocked...)
+}
+
+// checkBlocked returns the reason the order or quote should be rejected, or "" if it is not blocked
+func (e *exchange) checkBlocked(so sessionOrder) string {
+	e.blockLock.Lock()
+	defer e.blockLock.Unlock()
+
+	for _, k := range e.blocked {
+		if k.matches(so) {
+			return "orders are blocked by the kill switch for " + k.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+package exchange
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+func TestKillSwitchMatches(t *testing.T) {
+	var a = namedExchangeClient("A")
+	var b = namedExchangeClient("B")
+	var i1 = NewInstrument(1, "TEST1")
+	var i2 = NewInstrument(2, "TEST2")
+
+	order := LimitOrder(i1, Buy, NewDecimal("100"), NewDecimal("10"))
+	order.Account = "ACC"
+	so := sessionOrder{a, order, time.Now()}
+
+	if !(KillSwitch{}).matches(so) {
+		t.Error("empty kill switch should match all orders")
+	}
+	if !(KillSwitch{SessionID: "A", Account: "ACC", Instrument: i1, Side: Buy}).matches(so) {
+		t.Error("kill switch should match")
+	}
+	if (KillSwitch{SessionID: "B"}).matches(so) {
+		t.Error("kill switch should not match session")
+	}
+	if (KillSwitch{Account: "OTHER"}).matches(so) {
+		t.Error("kill switch should not match account")
+	}
+	if (KillSwitch{Instrument: i2}).matches(so) {
+		t.Error("kill switch should not match instrument")
+	}
+	if (KillSwitch{Side: Sell}).matches(so) {
+		t.Error("kill switch should not match side")
+	}
+
+	quote := LimitOrder(i1, Sell, NewDecimal("101"), NewDecimal("10"))
+	quote.ExchangeId = "quote.1"
+	if !(KillSwitch{Account: "B"}).matches(sessionOrder{b, quote, time.Now()}) {
+		t.Error("quotes should match using the session as the account")
+	}
+}
+
+func TestKillSwitchBlock(t *testing.T) {
+	var e = exchange{}
+	var a = namedExchangeClient("A")
+	var i = NewInstrument(1, "TEST")
+
+	so := sessionOrder{a, LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("10")), time.Now()}
+
+	if e.MassCancel(KillSwitch{SessionID: "A"}, true) != 0 {
+		t.Error("there should be no orders to cancel")
+	}
+	if e.checkBlocked(so) == "" {
+		t.Error("order should be blocked")
+	}
+	if e.checkBlocked(sessionOrder{namedExchangeClient("B"), so.order, time.Now()}) != "" {
+		t.Error("order from other session should not be blocked")
+	}
+	if e.EnableTrading(KillSwitch{SessionID: "B"}) {
+		t.Error("there should be no block for session B")
+	}
+	if !e.EnableTrading(KillSwitch{SessionID: "A"}) {
+		t.Error("block should be removed")
+	}
+	if e.checkBlocked(so) != "" || len(e.Blocked()) != 0 {
+		t.Error("order should not be blocked")
+	}
+}
+
+func TestKillSwitchRecovery(t *testing.T) {
+	discardMarketData()
+
+	path := filepath.Join(t.TempDir(), "test.journal")
+	j, err := openJournal(path, fsyncNever)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var a = namedExchangeClient("A")
+	var b = namedExchangeClient("B")
+	var i = NewInstrument(1035, "KILLRECOVER")
+	IMap.Put(i)
+
+	e1 := &exchange{journal: j}
+	e1.CreateOrder(a, LimitOrder(i, Buy, NewDecimal("99"), NewDecimal("10")))
+	e1.Quote(a, i, NewDecimal("98"), NewDecimal("1"), NewDecimal("102"), NewDecimal("1"))
+	e1.CreateOrder(b, LimitOrder(i, Sell, NewDecimal("101"), NewDecimal("10")))
+	if count := e1.MassCancel(KillSwitch{SessionID: "A"}, true); count != 3 {
+		t.Fatal("wrong number cancelled", count)
+	}
+	j.close()
+
+	// the orders and quotes cancelled by the kill switch are journaled individually
+	cancels := 0
+	ReadJournal(path, func(entry *JournalEntry) error {
+		if (entry.Type == JournalCancelOrder || entry.Type == JournalCancelQuote) && entry.Reason == killSwitchReason {
+			cancels++
+		}
+		return nil
+	})
+	if cancels != 3 {
+		t.Error("wrong number of journaled cancels", cancels)
+	}
+
+	e2 := &exchange{}
+	if _, err := e2.replayJournal(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	books := func(e *exchange) string {
+		ob := e.lockOrderBook(i)
+		defer ob.Unlock()
+		return ob.String()
+	}
+	if books(e1) != books(e2) {
+		t.Error("recovered book does not match", books(e1), books(e2))
+	}
+	order := e2.newSession(a).orders[0]
+	if order == nil || order.OrderState != Cancelled || order.RejectReason != killSwitchReason {
+		t.Error("cancelled order not recovered", order)
+	}
+	if len(e2.Blocked()) != 1 {
+		t.Error("block not recovered", e2.Blocked())
+	}
+}
@@ -0,0 +1,78 @@
+package exchange
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/robaho/go-trader/pkg/common"
+	"github.com/robaho/go-trader/pkg/connector/marketdata"
+)
+
+func TestMarketByOrder(t *testing.T) {
+	marketByOrder = true
+	defer func() { marketByOrder = false }()
+
+	var ex = testExchangeClient{}
+	var i = NewInstrument(1002, "MBO")
+	var ob = orderBook{Instrument: i}
+	var f = &feed{}
+	var received *marketdata.OrderBook
+
+	publish := func(trades []trade) {
+		event := ob.marketEvent(trades)
+		for _, update := range f.updates(event.book, event.orders, event.trades, coalesceTrades(trades), nil) {
+			if update.Snapshot != nil {
+				received = marketdata.NewOrderBook(i)
+			}
+			if err := received.Apply(update.Orders); err != nil {
+				t.Error(err)
+			}
+		}
+		book := received.Book()
+		if !reflect.DeepEqual(book.Bids, event.book.Bids) || !reflect.DeepEqual(book.Asks, event.book.Asks) {
+			t.Error("order book does not match", book, event.book)
+		}
+	}
+
+	o1 := sessionOrder{ex, LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("10")), time.Now()}
+	o2 := sessionOrder{ex, LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("5")), time.Now()}
+	iceberg := LimitOrder(i, Sell, NewDecimal("102"), NewDecimal("10"))
+	iceberg.DisplayQuantity = NewDecimal("4")
+	o3 := sessionOrder{ex, iceberg, time.Now()}
+	ob.add(o1)
+	ob.add(o2)
+	ob.add(o3)
+	publish(nil)
+
+	bids := received.Orders(Buy)
+	if len(bids) != 2 {
+		t.Fatal("wrong number of orders", bids)
+	}
+	id2 := bids[1].OrderID
+	if ahead, _ := received.QuantityAhead(id2); !ahead.Equal(NewDecimal("10")) {
+		t.Error("wrong quantity ahead", ahead)
+	}
+	iid := received.Orders(Sell)[0].OrderID
+
+	trades, _ := ob.add(sessionOrder{ex, LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("12")), time.Now()})
+	publish(trades)
+	if ahead, _ := received.QuantityAhead(id2); !ahead.IsZero() || !received.Order(id2).Quantity.Equal(NewDecimal("3")) {
+		t.Error("executed order not updated", received.Order(id2))
+	}
+
+	// the visible slice of the iceberg is filled and replenished
+	trades, _ = ob.add(sessionOrder{ex, LimitOrder(i, Buy, NewDecimal("102"), NewDecimal("4")), time.Now()})
+	publish(trades)
+	if order := received.Order(iid); order == nil || !order.Quantity.Equal(NewDecimal("4")) {
+		t.Error("iceberg order not replenished", order)
+	}
+
+	ob.remove(o2)
+	o2.order.Price = NewDecimal("101")
+	ob.add(o2)
+	publish(nil)
+	if order := received.Order(id2); order == nil || !order.Price.Equal(NewDecimal("101")) {
+		t.Error("modified order not updated", order)
+	}
+}
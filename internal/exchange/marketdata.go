@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"sort"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -23,18 +24,26 @@ import (
 var bookCache sync.Map
 var statsCache sync.Map
 
-var eventChannel chan MarketEvent
 var lastSentBook map[string]uint64 // to avoid publishing exact same book multiple times due to coalescing
 var sequence uint64
-var udpCon *net.UDPConn
-var pUdpCon *ipv4.PacketConn
 var subMutex sync.Mutex
-var subscriptions []chan *Book
-var buffers = &SPSC{}
+// the market data subscribers, []*subscriber
+var subscriptions atomic.Value
+// the market data channels, the last is the default channel
+var channels []*channel
+var channelConfigs []protocol.ChannelConfig
+// the number of incremental updates between snapshots of an instrument's book
+var snapshotInterval = 100
+// if true the orders in the book are published, in addition to the price levels
+var marketByOrder bool
 
 type MarketEvent struct {
-	book   *Book
+	book *Book
+	// the orders in the book, if the market by order feed is enabled
+	orders []bookOrder
 	trades []trade
+	// the statistics including the book and trades
+	stats Statistics
 }
 
 type Statistics struct {
@@ -49,29 +58,34 @@ type Statistics struct {
 	HasHighLow bool
 }
 
-func subscribe(sub chan *Book) {
-	subMutex.Lock()
-	defer subMutex.Unlock()
-
-	subscriptions = append(subscriptions, sub)
+// channel publishes the market data of a set of instruments, with its own packet numbers and replay history
+type channel struct {
+	name    string
+	events  chan MarketEvent
+	udpCon  *net.UDPConn
+	pUdpCon *ipv4.PacketConn
+	buffers *SPSC
+	// snapshot requests are processed by the publisher, so the snapshot is consistent with the published updates
+	snapshotRequests chan snapshotRequest
+	packetNumber     uint64
+	history          PacketHistory
 }
 
-func unsubscribe(sub chan *Book) {
-	subMutex.Lock()
-	defer subMutex.Unlock()
+func newChannel(name string) *channel {
+	return &channel{name: name, events: make(chan MarketEvent, 1024*1024), buffers: &SPSC{}, snapshotRequests: make(chan snapshotRequest)}
+}
 
-	copy := subscriptions[:0]
-	for _, v := range subscriptions {
-		if v != sub {
-			copy = append(copy, v)
-		}
+// channelFor returns the channel that publishes the instrument
+func channelFor(instrument Instrument) *channel {
+	if len(channels) == 1 {
+		return channels[0]
 	}
-	subscriptions = copy
+	return channels[protocol.ChannelFor(channelConfigs, instrument)]
 }
 
 func sendMarketData(event MarketEvent) {
 	cacheBook(event.book)
-	eventChannel <- event
+	channelFor(event.book.Instrument).events <- event
 }
 
 func cacheBook(book *Book) {
@@ -95,10 +109,10 @@ func GetBook(symbol string) *Book {
 	return GetLatestBook(i)
 }
 
-func newBuffer() *bytes.Buffer {
+func (ch *channel) newBuffer() *bytes.Buffer {
 	placeholder := make([]byte, 8)
 
-	p := buffers.get()
+	p := ch.buffers.get()
 
 	if p != nil {
 		p.Write(placeholder) // leave room for packet number
@@ -111,79 +125,252 @@ func newBuffer() *bytes.Buffer {
 	return buf
 }
 
-func publish() {
+func (ch *channel) publish() {
 	stats := make(map[Instrument]*Statistics)
+	feeds := make(map[Instrument]*feed)
 
-	buf := newBuffer()
+	buf := ch.newBuffer()
+	msg := new(bytes.Buffer)
 
 	for {
-		event := <-eventChannel
+		var event MarketEvent
+		select {
+		case request := <-ch.snapshotRequests:
+			request.reply <- snapshotUpdates(feeds, stats, request.instrumentID)
+			continue
+		case event = <-ch.events:
+		}
 
 		book := getLatestBook(event.book)
 		trades := coalesceTrades(event.trades)
 
-		s, ok := stats[book.Instrument]
+		// the statistics are updated by the book, a copy is kept since the previous may still be read
+		s := &Statistics{}
+		*s = event.stats
+		stats[book.Instrument] = s
+		statsCache.Store(book.Instrument, s)
+
+		f, ok := feeds[book.Instrument]
 		if !ok {
-			s = &Statistics{}
-			s.Symbol = book.Instrument.Symbol()
-			stats[book.Instrument] = s
+			f = &feed{}
+			feeds[book.Instrument] = f
 		}
 
-		if book.HasBids() {
-			s.BidPrice = book.Bids[0].Price
-			s.BidQty = book.Bids[0].Quantity
+		for _, update := range f.updates(book, event.orders, event.trades, trades, s) {
+			msg.Reset()
+			protocol.EncodeBookUpdate(msg, update)
+
+			if buf.Len() > 8 && buf.Len()+msg.Len() > protocol.MaxMsgSize {
+				ch.sendPacket(buf.Bytes())
+				buf = ch.newBuffer()
+			}
+			buf.Write(msg.Bytes())
 		}
-		if book.HasAsks() {
-			s.AskPrice = book.Asks[0].Price
-			s.AskQty = book.Asks[0].Quantity
+
+		// if there is another update delay sending, so it can be added to the packet
+		if len(ch.events) == 0 && buf.Len() > 8 {
+			ch.sendPacket(buf.Bytes())
+			buf = ch.newBuffer()
 		}
 
-		for _, t := range trades {
-			s.Volume = s.Volume.Add(t.Quantity)
-			if !s.HasHighLow {
-				s.High = t.Price
-				s.Low = s.High
-				s.HasHighLow = true
-			} else {
-				if t.Price.GreaterThan(s.High) {
-					s.High = t.Price
-				}
-				if t.Price.LessThan(s.Low) {
-					s.Low = t.Price
-				}
+		// publish to internal subscribers
+		publishToSubscribers(book, trades, s)
+	}
+}
+
+// feed is the state of the incremental market data for an instrument
+type feed struct {
+	sequence uint64
+	// the last book published
+	book *Book
+	// the number of incremental updates since the last snapshot
+	incremental int
+	// the orders last published by the market by order feed
+	orders     map[*Order]*publishedOrder
+	bookOrders []bookOrder
+	// an order keeps its id while it is in the book, e.g. when the visible slice of an iceberg order is replenished
+	ids         map[*Order]uint64
+	nextOrderID uint64
+}
+
+type publishedOrder struct {
+	id       uint64
+	side     Side
+	price    Fixed
+	quantity Fixed
+	priority uint64
+}
+
+// updates returns the numbered updates to publish the book and trades, a snapshot is sent for the first book and
+// then every snapshotInterval updates, so a receiver that missed an update can recover
+func (f *feed) updates(book *Book, orders []bookOrder, executed []trade, trades []Trade, stats *Statistics) []*protocol.BookUpdate {
+	update := &protocol.BookUpdate{Instrument: book.Instrument, Trades: trades}
+	if marketByOrder {
+		update.Orders = f.orderUpdates(orders, executed)
+		f.bookOrders = orders
+	}
+	if f.book == nil || f.incremental >= snapshotInterval {
+		snapshot := *book
+		update.Snapshot = &snapshot
+		if marketByOrder {
+			update.Orders = f.orderSnapshot(orders)
+		}
+		update.Statistics = toProtocolStatistics(stats)
+		f.incremental = 0
+	} else {
+		update.Levels, update.Status = protocol.DiffBook(f.book, book)
+		if len(update.Levels) == 0 && update.Status == nil && len(update.Orders) == 0 && len(trades) == 0 {
+			return nil
+		}
+		f.incremental++
+	}
+	f.book = book
+
+	updates := update.Split(protocol.MaxMsgSize - 8)
+	for _, update := range updates {
+		f.sequence++
+		update.Sequence = f.sequence
+		if update.Snapshot != nil {
+			update.Snapshot.Sequence = f.sequence
+		}
+	}
+	return updates
+}
+
+// orderUpdates returns the changes to the orders since they were last published. The executions are sent first,
+// then the deletes, then the added and re-queued orders in priority order.
+func (f *feed) orderUpdates(orders []bookOrder, executed []trade) []protocol.OrderUpdate {
+	if f.orders == nil {
+		f.orders = make(map[*Order]*publishedOrder)
+		f.ids = make(map[*Order]uint64)
+	}
+	var updates []protocol.OrderUpdate
+
+	for _, t := range executed {
+		for _, so := range []sessionOrder{t.buyer, t.seller} {
+			po, ok := f.orders[so.order]
+			if !ok {
+				// the aggressor was not in the book
+				continue
+			}
+			quantity := MinDecimal(t.quantity, po.quantity)
+			updates = append(updates, protocol.OrderUpdate{Action: protocol.OrderExecute, OrderID: po.id, Side: po.side, Price: po.price, Quantity: quantity})
+			po.quantity = po.quantity.Sub(quantity)
+			if po.quantity.IsZero() {
+				delete(f.orders, so.order)
 			}
 		}
+	}
 
-		statsCache.Store(book.Instrument, s)
+	current := make(map[*Order]bool, len(orders))
+	for _, o := range orders {
+		current[o.order] = true
+	}
+	var deleted []*publishedOrder
+	for order, po := range f.orders {
+		if !current[order] {
+			deleted = append(deleted, po)
+			delete(f.orders, order)
+		}
+	}
+	for order := range f.ids {
+		if !current[order] {
+			delete(f.ids, order)
+		}
+	}
+	sort.Slice(deleted, func(i, j int) bool { return deleted[i].id < deleted[j].id })
+	for _, po := range deleted {
+		updates = append(updates, protocol.OrderUpdate{Action: protocol.OrderDelete, OrderID: po.id, Side: po.side, Price: po.price, Quantity: ZERO})
+	}
 
-		buf2 := newBuffer()
-
-		protocol.EncodeMarketEvent(buf2, book, trades)
-
-		if len(eventChannel) == 0 || buf2.Len()+buf.Len() > protocol.MaxMsgSize {
-			if buf.Len() == 8 { // the group packet is empty, so just use this one
-				sendPacket(buf2.Bytes())
-			} else {
-				if buf2.Len()+buf.Len() > protocol.MaxMsgSize {
-					sendPacket(buf.Bytes())
-					sendPacket(buf2.Bytes())
-				} else {
-					// copy current into the group packet, skipping packet number
-					buf.Write(buf2.Bytes()[8:])
-					sendPacket(buf.Bytes())
-				}
-				buf = newBuffer()
+	// within a price level the re-queued orders are behind the unchanged orders, so they can be sent in book order
+	for _, o := range orders {
+		po, ok := f.orders[o.order]
+		if !ok {
+			id, ok := f.ids[o.order]
+			if !ok {
+				f.nextOrderID++
+				id = f.nextOrderID
+				f.ids[o.order] = id
 			}
-		} else {
-			// there is another update so delay sending, and this fits, so copy to group packet
-			buf.Write(buf2.Bytes()[8:])
+			po = &publishedOrder{id: id, side: o.side, price: o.price, quantity: o.quantity, priority: o.priority}
+			f.orders[o.order] = po
+			updates = append(updates, protocol.OrderUpdate{Action: protocol.OrderAdd, OrderID: po.id, Side: o.side, Price: o.price, Quantity: o.quantity})
+		} else if po.priority != o.priority || !po.price.Equal(o.price) || !po.quantity.Equal(o.quantity) {
+			po.price, po.quantity, po.priority = o.price, o.quantity, o.priority
+			updates = append(updates, protocol.OrderUpdate{Action: protocol.OrderModify, OrderID: po.id, Side: o.side, Price: o.price, Quantity: o.quantity})
 		}
+	}
+	return updates
+}
 
-		// publish to internal subscribers
-		for _, sub := range subscriptions {
-			sub <- book
+// orderSnapshot returns the orders in the book as adds in priority order, orderUpdates must be called first to
+// assign the order ids
+func (f *feed) orderSnapshot(orders []bookOrder) []protocol.OrderUpdate {
+	var updates []protocol.OrderUpdate
+	for _, o := range orders {
+		po := f.orders[o.order]
+		updates = append(updates, protocol.OrderUpdate{Action: protocol.OrderAdd, OrderID: po.id, Side: o.side, Price: o.price, Quantity: o.quantity})
+	}
+	return updates
+}
+
+type snapshotRequest struct {
+	// 0 for all instruments
+	instrumentID int64
+	reply        chan []*protocol.BookUpdate
+}
+
+// snapshotUpdates returns the snapshots of the books, each tagged with the sequence number of the last update
+// published for the instrument
+func snapshotUpdates(feeds map[Instrument]*feed, stats map[Instrument]*Statistics, instrumentID int64) []*protocol.BookUpdate {
+	var updates []*protocol.BookUpdate
+	for instrument, f := range feeds {
+		if f.book == nil || (instrumentID != 0 && instrument.ID() != instrumentID) {
+			continue
+		}
+		snapshot := *f.book
+		snapshot.Sequence = f.sequence
+		update := &protocol.BookUpdate{Instrument: instrument, Sequence: f.sequence, Snapshot: &snapshot}
+		if marketByOrder {
+			update.Orders = f.orderSnapshot(f.bookOrders)
+		}
+		update.Statistics = toProtocolStatistics(stats[instrument])
+		for _, part := range update.Split(protocol.MaxMsgSize) {
+			part.Sequence = f.sequence
+			updates = append(updates, part)
+		}
+	}
+	return updates
+}
+
+func toProtocolStatistics(s *Statistics) *protocol.Statistics {
+	if s == nil {
+		return nil
+	}
+	return &protocol.Statistics{Volume: s.Volume, High: s.High, Low: s.Low, HasHighLow: s.HasHighLow}
+}
+
+// sendSnapshot writes the snapshots requested on the connection
+func (ch *channel) sendSnapshot(conn net.Conn, request protocol.SnapshotRequest) error {
+	reply := make(chan []*protocol.BookUpdate)
+	ch.snapshotRequests <- snapshotRequest{request.InstrumentID, reply}
+	updates := <-reply
+
+	buf := new(bytes.Buffer)
+	for _, update := range updates {
+		buf.Reset()
+		protocol.EncodeBookUpdate(buf, update)
+		var len = uint16(buf.Len())
+		if err := binary.Write(conn, binary.LittleEndian, &len); err != nil {
+			return err
+		}
+		if _, err := conn.Write(buf.Bytes()); err != nil {
+			return err
 		}
 	}
+	var end uint16
+	return binary.Write(conn, binary.LittleEndian, &end)
 }
 
 func getLatestBook(book *Book) *Book {
@@ -222,36 +409,29 @@ func coalesceTrades(trades []trade) []Trade {
 	return Trades
 }
 
-var packetNumber uint64
-
-func sendPacket(data []byte) {
+func (ch *channel) sendPacket(data []byte) {
 
-	packetNumber++
+	ch.packetNumber++
 
-	binary.LittleEndian.PutUint64(data, packetNumber)
+	binary.LittleEndian.PutUint64(data, ch.packetNumber)
 
-	_, err := udpCon.Write(data)
+	_, err := ch.udpCon.Write(data)
 	if err != nil {
 		fmt.Println("error sending packet", err)
 	}
 
-	rememberPacket(packetNumber, data)
+	ch.rememberPacket(ch.packetNumber, data)
 }
 
 func startMarketData() {
-	eventChannel = make(chan MarketEvent, 1024*1024)
 	lastSentBook = make(map[string]uint64)
 
-	// read settings and create socket
+	// read settings and create sockets
 
 	props, err := NewProperties("configs/got_settings")
 	if err != nil {
 		panic(err)
 	}
-	saddr := props.GetString("multicast_addr", "")
-	if saddr == "" {
-		panic("unable to read multicast addr")
-	}
 	intf := props.GetString("multicast_intf", "lo0")
 	if intf == "" {
 		panic("unable to read multicast addr")
@@ -261,23 +441,48 @@ func startMarketData() {
 		panic("unable to read multicast interface")
 	}
 
-	fmt.Println("publishing marketdata at", saddr)
+	snapshotInterval, err = strconv.Atoi(props.GetString("marketdata_snapshot_interval", "100"))
+	if err != nil {
+		panic("invalid marketdata_snapshot_interval " + err.Error())
+	}
+	marketByOrder = props.GetString("marketdata_by_order", "false") == "true"
 
-	addr, err := net.ResolveUDPAddr("udp", saddr)
+	configs, err := protocol.ParseChannels(props)
 	if err != nil {
 		panic(err)
 	}
+	bufferSize := props.GetBytes("marketdata_buffer",1024 * 1024)
 
-	rport := props.GetString("replay_port", "")
-	if rport == "" {
-		panic("unable to read replay port")
+	var started []*channel
+	for _, config := range configs {
+		if config.MulticastAddr == "" {
+			panic("unable to read multicast addr")
+		}
+		if config.ReplayPort == "" {
+			panic("unable to read replay port")
+		}
+		ch := newChannel(config.Name)
+		if err := ch.start(config, _intf, bufferSize); err != nil {
+			panic(err)
+		}
+		started = append(started, ch)
 	}
+	channelConfigs = configs
+	channels = started
+}
+
+// start creates the channel's socket, and starts the publisher and the replay and snapshot services
+func (ch *channel) start(config protocol.ChannelConfig, intf *net.Interface, bufferSize int) error {
+	fmt.Println("publishing marketdata", ch.name, "at", config.MulticastAddr)
 
+	addr, err := net.ResolveUDPAddr("udp", config.MulticastAddr)
+	if err != nil {
+		return err
+	}
 	c, err := net.DialUDP("udp", nil, addr)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	bufferSize := props.GetBytes("marketdata_buffer",1024 * 1024)
 	err = c.SetWriteBuffer(bufferSize)
 	if err!=nil {
 		fmt.Println("unable to set market data write buffer size to",bufferSize,err)
@@ -285,41 +490,63 @@ func startMarketData() {
 		fmt.Println("set market data buffer size",bufferSize)
 	}
 
-	udpCon = c
-	pUdpCon = ipv4.NewPacketConn(udpCon)
-	pUdpCon.SetMulticastInterface(_intf)
+	ch.udpCon = c
+	ch.pUdpCon = ipv4.NewPacketConn(ch.udpCon)
+	ch.pUdpCon.SetMulticastInterface(intf)
 
 	go func() {
-		publish()
+		ch.publish()
 	}()
 
-	go func() {
-		ln, err := net.Listen("tcp", "0.0.0.0:"+rport)
-		if err != nil {
-			log.Fatal("unable to listen on replay port", err)
-		} else {
-			log.Println("listening for replay requests on", ln.Addr())
-		}
-		for {
-			conn, _ := ln.Accept()
-
-			go func(conn net.Conn) {
-				var request protocol.ReplayRequest
-				for {
-					err := binary.Read(conn, binary.LittleEndian, &request)
-					if err != nil {
-						log.Println("failure to read replay request", err)
-						return
-					}
-					err = resendPackets(conn, request)
-					if err != nil {
-						log.Println("failure to resend replay packets", err)
-						return
-					}
+	if config.SnapshotPort != "" {
+		go ch.serve("snapshot", config.SnapshotPort, func(conn net.Conn) {
+			defer conn.Close()
+			var request protocol.SnapshotRequest
+			for {
+				err := binary.Read(conn, binary.LittleEndian, &request)
+				if err != nil {
+					return
 				}
-			}(conn)
+				err = ch.sendSnapshot(conn, request)
+				if err != nil {
+					log.Println("failure to send snapshot", err)
+					return
+				}
+			}
+		})
+	}
+
+	go ch.serve("replay", config.ReplayPort, func(conn net.Conn) {
+		var request protocol.ReplayRequest
+		for {
+			err := binary.Read(conn, binary.LittleEndian, &request)
+			if err != nil {
+				log.Println("failure to read replay request", err)
+				return
+			}
+			err = ch.resendPackets(conn, request)
+			if err != nil {
+				log.Println("failure to resend replay packets", err)
+				return
+			}
 		}
-	}()
+	})
+	return nil
+}
+
+// serve accepts connections on the port, handling each with its own routine
+func (ch *channel) serve(service string, port string, handler func(conn net.Conn)) {
+	ln, err := net.Listen("tcp", "0.0.0.0:"+port)
+	if err != nil {
+		log.Fatal("unable to listen on "+service+" port", err)
+	} else {
+		log.Println("listening for", ch.name, service, "requests on", ln.Addr())
+	}
+	for {
+		conn, _ := ln.Accept()
+
+		go handler(conn)
+	}
 }
 
 type Packet struct {
@@ -331,15 +558,14 @@ type PacketHistory struct {
 	packets list.List
 }
 
-var history PacketHistory
-
-func rememberPacket(packetNumber uint64, data []byte) {
+func (ch *channel) rememberPacket(packetNumber uint64, data []byte) {
+	history := &ch.history
 	history.Lock()
 	defer history.Unlock()
 
 	if history.packets.Len() > 10000 {
 		p := history.packets.Remove(history.packets.Front()).(*Packet)
-		buffers.put(bytes.NewBuffer(p.data[:0]))
+		ch.buffers.put(bytes.NewBuffer(p.data[:0]))
 	}
 
 	packet := Packet{packetNumber, data}
@@ -347,7 +573,8 @@ func rememberPacket(packetNumber uint64, data []byte) {
 	history.packets.PushBack(&packet)
 }
 
-func resendPackets(conn net.Conn, request protocol.ReplayRequest) error {
+func (ch *channel) resendPackets(conn net.Conn, request protocol.ReplayRequest) error {
+	history := &ch.history
 	history.RLock()
 	defer history.RUnlock()
 
@@ -0,0 +1,48 @@
+package exchange
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	. "github.com/robaho/go-trader/pkg/common"
+	"github.com/robaho/go-trader/pkg/protocol"
+)
+
+func TestSnapshotUpdates(t *testing.T) {
+	var ex = testExchangeClient{}
+	var i = NewInstrument(1003, "SNAPSVC")
+	var ob = orderBook{Instrument: i}
+	var f = &feed{}
+	feeds := map[Instrument]*feed{i: f}
+	stats := map[Instrument]*Statistics{i: {Volume: NewDecimal("5")}}
+
+	for n := 0; n < 200; n++ {
+		price := NewDecimalF(100 - float64(n)*0.01)
+		ob.add(sessionOrder{ex, LimitOrder(i, Buy, price, NewDecimal("10")), time.Now()})
+		event := ob.marketEvent(nil)
+		f.updates(event.book, event.orders, nil, nil, stats[i])
+	}
+	book := ob.buildBook()
+
+	updates := snapshotUpdates(feeds, stats, 0)
+	if len(updates) < 2 || updates[0].Snapshot == nil {
+		t.Fatal("snapshot should be split", len(updates))
+	}
+	if updates[0].Statistics == nil || !updates[0].Statistics.Volume.Equal(NewDecimal("5")) {
+		t.Error("statistics not included", updates[0].Statistics)
+	}
+	var received *Book
+	for _, update := range updates {
+		if update.Sequence != f.sequence {
+			t.Error("snapshot should have the last sequence", update.Sequence, f.sequence)
+		}
+		received = protocol.ApplyBookUpdate(received, update)
+	}
+	if !reflect.DeepEqual(received.Bids, book.Bids) {
+		t.Error("snapshot does not match the book")
+	}
+	if len(snapshotUpdates(feeds, stats, 1)) != 0 {
+		t.Error("only the requested instrument should be sent")
+	}
+}
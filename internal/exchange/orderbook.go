@@ -26,6 +26,29 @@ type orderBook struct {
 	Instrument
 	bids []priceLevel
 	asks []priceLevel
+	// stop orders are held off-book, in time priority, until triggered by a trade
+	stops []sessionOrder
+	// orders whose status was changed by the book other than by a trade, e.g. a triggered stop order,
+	// since the last call to takeChanged
+	changed []sessionOrder
+	// the changed stop orders that were triggered, so the trigger is reported once
+	triggered    map[*Order]bool
+	lastPrice    Fixed
+	hasLastPrice bool
+	stp          selfTradePrevention
+	// orders are only matched while the instrument is open, see tradingstate.go
+	state TradingState
+	// see priceband.go
+	bands          PriceBands
+	onBreach       TradingState
+	referencePrice Fixed
+	hasReference   bool
+	// true if the last match breached a price band and interrupted trading
+	interrupted bool
+	// true while in an auction started by a price band breach, which ends after the volatility auction
+	volatilityAuction bool
+	// updated with every published book and trades, so it is consistent with the book
+	stats Statistics
 }
 
 type trade struct {
@@ -35,6 +58,8 @@ type trade struct {
 	quantity Fixed
 	tradeid  int64
 	when     time.Time
+	// the side of the incoming order, empty for auction trades
+	aggressor Side
 
 	buyRemaining  Fixed
 	sellRemaining Fixed
@@ -47,22 +72,162 @@ func (ob *orderBook) String() string {
 func (ob *orderBook) add(so sessionOrder) ([]trade, error) {
 	so.order.OrderState = Booked
 
+	if so.order.IsStop() && !so.order.Triggered {
+		if !ob.hasLastPrice || !isTriggeredBy(so, ob.lastPrice) {
+			ob.stops = append(ob.stops, so)
+			return nil, nil
+		}
+		// the market has already traded through the stop price
+		so.order.Triggered = true
+	}
+
+	if !ob.isMatching() {
+		ob.bookAuction(so)
+		return nil, nil
+	}
+
+	trades := ob.book(so)
+
+	return ob.triggerStops(trades), nil
+}
+
+// book the order and match it
+func (ob *orderBook) book(so sessionOrder) []trade {
+	// fill or kill orders are cancelled before any fill if they cannot be completely filled
+	if so.order.TimeInForce == FillOrKill && !ob.canFill(so) {
+		so.order.OrderState = Cancelled
+		return nil
+	}
+
 	if so.order.Side == Buy {
 		ob.bids = insertSort(ob.bids, so, 1)
 	} else {
 		ob.asks = insertSort(ob.asks, so, -1)
 	}
 
-	// match and build trades
-	var trades = matchTrades(ob)
+	// match and build trades, at the time the order was received
+	var trades = matchTrades(ob, so.time)
 
-	// cancel any remaining market order
-	if so.order.OrderType == Market && so.order.IsActive() {
+	// cancel any remaining market or immediate order, market orders remain if trading was interrupted
+	if (so.isImmediate() || (so.isMarket() && ob.isMatching())) && so.order.IsActive() {
 		so.order.OrderState = Cancelled
 		ob.remove(so)
 	}
 
-	return trades, nil
+	return trades
+}
+
+// canFill returns true if there is enough quantity on the opposite side of the book to fill the order completely.
+// Matching stops at the first price outside the price bands, and the resting orders of the same session or account
+// are handled as self trade prevention will: skipped if they are cancelled, reducing the quantity needed if both are
+// decremented, and ending the match if the incoming order is cancelled.
+func (ob *orderBook) canFill(so sessionOrder) bool {
+	var levels []priceLevel
+	if so.order.Side == Buy {
+		levels = ob.asks
+	} else {
+		levels = ob.bids
+	}
+	available := ZERO
+	needed := so.order.Remaining
+	for _, level := range levels {
+		if so.order.Side == Buy && level.price.GreaterThan(so.getPrice()) {
+			break
+		}
+		if so.order.Side == Sell && level.price.LessThan(so.getPrice()) {
+			break
+		}
+		if !ob.withinBands(level.price) {
+			break
+		}
+		for node := level.head; node != nil; node = node.next {
+			if ob.isSelfTrade(so, node.order) {
+				switch ob.stp.mode {
+				case stpCancelOldest:
+					continue
+				case stpDecrement:
+					needed = needed.Sub(MinDecimal(needed, node.order.order.Remaining))
+					continue
+				default:
+					return available.GreaterThanOrEqual(needed)
+				}
+			}
+			available = available.Add(node.order.order.Remaining)
+		}
+		if available.GreaterThanOrEqual(needed) {
+			return true
+		}
+	}
+	return available.GreaterThanOrEqual(needed)
+}
+
+// isSelfTrade returns true if the incoming order would self trade with the resting order
+func (ob *orderBook) isSelfTrade(so sessionOrder, resting sessionOrder) bool {
+	if so.order.Side == Buy {
+		return ob.stp.isSelfTrade(so, resting)
+	}
+	return ob.stp.isSelfTrade(resting, so)
+}
+
+func isTriggeredBy(so sessionOrder, price Fixed) bool {
+	if so.order.Side == Buy {
+		return price.GreaterThanOrEqual(so.order.StopPrice)
+	}
+	return price.LessThanOrEqual(so.order.StopPrice)
+}
+
+// triggerStops books any held stop orders that the trades printed through. The trades generated by the
+// triggered orders are appended, and are themselves checked, so that stops can cascade.
+func (ob *orderBook) triggerStops(trades []trade) []trade {
+	for i := 0; i < len(trades); i++ {
+		price := trades[i].price
+		ob.lastPrice = price
+		ob.hasLastPrice = true
+
+		for j := 0; j < len(ob.stops); {
+			so := ob.stops[j]
+			if !isTriggeredBy(so, price) {
+				j++
+				continue
+			}
+			ob.stops = append(ob.stops[:j], ob.stops[j+1:]...)
+			so.order.Triggered = true
+			// the triggered order has time priority from when it was triggered, not when it was entered, which is
+			// just after the trade that triggered it
+			so.time = trades[i].when.Add(time.Nanosecond)
+			ob.changed = append(ob.changed, so)
+			if ob.triggered == nil {
+				ob.triggered = make(map[*Order]bool)
+			}
+			ob.triggered[so.order] = true
+			if ob.isMatching() {
+				trades = append(trades, ob.book(so)...)
+			} else {
+				ob.bookAuction(so)
+			}
+		}
+	}
+	return trades
+}
+
+// takeChanged returns the orders whose status was changed since the last call, and which of them were triggered
+func (ob *orderBook) takeChanged() ([]sessionOrder, map[*Order]bool) {
+	changed, triggered := ob.changed, ob.triggered
+	ob.changed, ob.triggered = nil, nil
+	return changed, triggered
+}
+
+func (ob *orderBook) removeStop(so sessionOrder) error {
+	for i, v := range ob.stops {
+		if v.order == so.order {
+			ob.stops = append(ob.stops[:i], ob.stops[i+1:]...)
+			if so.order.IsActive() {
+				so.order.OrderState = Cancelled
+			}
+			return nil
+		}
+	}
+	return OrderNotFound
 }
 
 func insertSort(levels []priceLevel, so sessionOrder, direction int) []priceLevel {
@@ -86,59 +251,97 @@ func insertSort(levels []priceLevel, so sessionOrder, direction int) []priceLeve
 
 var nextTradeID int64 = 0
 
-func matchTrades(book *orderBook) []trade {
+// matchTrades matches the crossing orders at the top of the book, the trades are made at 'when'
+func matchTrades(book *orderBook, when time.Time) []trade {
 	var trades []trade
 	var tradeID int64 = 0
-	var when = time.Now()
 
 	for len(book.bids) > 0 && len(book.asks) > 0 {
-		bid := book.bids[0].orderList.top()
-		ask := book.asks[0].orderList.top()
+		bidNode := book.bids[0].head
+		askNode := book.asks[0].head
+		bid := bidNode.order
+		ask := askNode.order
 
 		if !bid.getPrice().GreaterThanOrEqual(ask.getPrice()) {
 			break
 		}
 
+		if book.stp.isSelfTrade(bid, ask) {
+			book.preventSelfTrade(bidNode, askNode)
+			continue
+		}
+
 		var price Fixed
+		var aggressor Side
+		// only the visible slice of a resting iceberg order is available, the aggressor can trade its entire quantity
+		var bidQty, askQty Fixed
 		// need to use price of resting order
 		if bid.time.Before(ask.time) {
 			price = bid.order.Price
+			aggressor = Sell
+			bidQty, askQty = bidNode.quantity(), ask.order.Remaining
 		} else {
 			price = ask.order.Price
+			aggressor = Buy
+			bidQty, askQty = bid.order.Remaining, askNode.quantity()
 		}
 
-		var qty = MinDecimal(bid.order.Remaining, ask.order.Remaining)
+		if !book.withinBands(price) {
+			book.interrupt()
+			break
+		}
 
-		var trade = trade{}
+		var qty = MinDecimal(bidQty, askQty)
 
 		if tradeID == 0 {
 			// use same tradeID for all trades
 			tradeID = atomic.AddInt64(&nextTradeID, 1)
 		}
 
-		trade.price = price
-		trade.quantity = qty
-		trade.buyer = bid
-		trade.seller = ask
-		trade.tradeid = tradeID
-		trade.when = when
+		trades = append(trades, book.execute(bidNode, askNode, price, qty, tradeID, when, aggressor))
+	}
+	return trades
+}
 
-		fill(bid.order, qty, price)
-		fill(ask.order, qty, price)
+// execute fills the orders at the top of the book, removing them if they are completely filled
+func (book *orderBook) execute(bidNode *listNode, askNode *listNode, price Fixed, qty Fixed, tradeID int64, when time.Time, aggressor Side) trade {
+	bid := bidNode.order
+	ask := askNode.order
 
-		trade.buyRemaining = bid.order.Remaining
-		trade.sellRemaining = ask.order.Remaining
+	var trade = trade{}
 
-		trades = append(trades, trade)
+	trade.price = price
+	trade.quantity = qty
+	trade.buyer = bid
+	trade.seller = ask
+	trade.tradeid = tradeID
+	trade.when = when
+	trade.aggressor = aggressor
 
-		if bid.order.Remaining.Equal(ZERO) {
-			book.remove(bid)
-		}
-		if ask.order.Remaining.Equal(ZERO) {
-			book.remove(ask)
-		}
+	if !book.hasReference {
+		book.referencePrice = price
+		book.hasReference = true
 	}
-	return trades
+
+	fill(bid.order, qty, price)
+	fill(ask.order, qty, price)
+	bidNode.visible = bidNode.visible.Sub(qty)
+	askNode.visible = askNode.visible.Sub(qty)
+
+	trade.buyRemaining = bid.order.Remaining
+	trade.sellRemaining = ask.order.Remaining
+
+	if bid.order.Remaining.Equal(ZERO) {
+		book.remove(bid)
+	} else if bidNode.isExhausted() {
+		book.bids[0].orderList.refresh(bid)
+	}
+	if ask.order.Remaining.Equal(ZERO) {
+		book.remove(ask)
+	} else if askNode.isExhausted() {
+		book.asks[0].orderList.refresh(ask)
+	}
+	return trade
 }
 
 func fill(order *Order, qty Fixed, price Fixed) {
@@ -151,6 +354,9 @@ func fill(order *Order, qty Fixed, price Fixed) {
 }
 
 func (ob *orderBook) remove(so sessionOrder) error {
+	if so.order.IsStop() && !so.order.Triggered {
+		return ob.removeStop(so)
+	}
 
 	var levels []priceLevel
 	var direction int
@@ -203,9 +409,81 @@ func (ob *orderBook) buildBook() *Book {
 	book.Bids = createBookLevels(ob.bids)
 	book.Asks = createBookLevels(ob.asks)
 
+	book.State = ob.state
+	if ob.state == Auction {
+		book.InAuction = true
+		book.IndicativePrice, book.IndicativeVolume, _ = ob.uncrossPrice()
+	}
+
 	return book
 }
 
+// bookOrder is an order as shown in the market by order feed
+type bookOrder struct {
+	order    *Order
+	side     Side
+	price    Fixed
+	quantity Fixed
+	priority uint64
+}
+
+// buildOrders returns the orders in the book in price and time priority, or nil if the market by order feed is
+// not enabled
+func (ob *orderBook) buildOrders() []bookOrder {
+	if !marketByOrder {
+		return nil
+	}
+	var orders []bookOrder
+	for _, levels := range [][]priceLevel{ob.bids, ob.asks} {
+		for _, level := range levels {
+			if isMarketPrice(level.price) {
+				continue
+			}
+			for node := level.head; node != nil; node = node.next {
+				order := node.order.order
+				orders = append(orders, bookOrder{order, order.Side, level.price, node.quantity(), node.priority})
+			}
+		}
+	}
+	return orders
+}
+
+// marketEvent returns the event to publish the current book and the trades
+func (ob *orderBook) marketEvent(trades []trade) MarketEvent {
+	book := ob.buildBook()
+	ob.updateStatistics(book, trades)
+	return MarketEvent{book: book, orders: ob.buildOrders(), trades: trades, stats: ob.stats}
+}
+
+// updateStatistics updates the top of the book, and the volume and range of the trades
+func (ob *orderBook) updateStatistics(book *Book, trades []trade) {
+	s := &ob.stats
+	s.Symbol = ob.Symbol()
+	if book.HasBids() {
+		s.BidPrice = book.Bids[0].Price
+		s.BidQty = book.Bids[0].Quantity
+	}
+	if book.HasAsks() {
+		s.AskPrice = book.Asks[0].Price
+		s.AskQty = book.Asks[0].Quantity
+	}
+	for _, t := range trades {
+		s.Volume = s.Volume.Add(t.quantity)
+		if !s.HasHighLow {
+			s.High = t.price
+			s.Low = s.High
+			s.HasHighLow = true
+		} else {
+			if t.price.GreaterThan(s.High) {
+				s.High = t.price
+			}
+			if t.price.LessThan(s.Low) {
+				s.Low = t.price
+			}
+		}
+	}
+}
+
 func createBookLevels(_levels []priceLevel) []BookLevel {
 	var levels []BookLevel
 
@@ -213,9 +491,13 @@ func createBookLevels(_levels []priceLevel) []BookLevel {
 		return levels
 	}
 	for _, level := range _levels {
+		// market orders resting during an auction are not shown
+		if isMarketPrice(level.price) {
+			continue
+		}
 		quantity := ZERO
 		for node := level.head; node!=nil; node = node.next {
-			quantity = quantity.Add(node.order.order.Remaining)
+			quantity = quantity.Add(node.quantity())
 		}
 		bl := BookLevel{Price: level.price, Quantity: quantity}
 		levels = append(levels, bl)
@@ -5,6 +5,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/quickfixgo/enum"
+
 	. "github.com/robaho/fixed"
 	. "github.com/robaho/go-trader/pkg/common"
 )
@@ -15,7 +17,7 @@ func TestWaitForKey(t *testing.T) {
 
 type testExchangeClient struct{}
 func (c testExchangeClient) SendOrderStatus(so sessionOrder){}
-func (c testExchangeClient) SendTrades(trades []trade){}
+func (c testExchangeClient) SendFill(so sessionOrder, price Fixed, quantity Fixed, remaining Fixed){}
 func (c testExchangeClient) SessionID() string {
 	return "X"
 }
@@ -194,3 +196,336 @@ func TestOrderMatchSweep(t *testing.T) {
 	}
 
 }
+
+func TestStopOrder(t *testing.T) {
+	var ob = orderBook{}
+	var ex = testExchangeClient{}
+
+	var i = Equity{}
+
+	var stop = StopOrder(i, Buy, NewDecimal("105"), NewDecimal("10"))
+	var s1 = sessionOrder{ex, stop, time.Now()}
+
+	trades, _ := ob.add(s1)
+	if len(trades) != 0 || len(ob.bids) != 0 {
+		t.Error("stop order should not be booked", &ob)
+	}
+
+	ob.add(sessionOrder{ex, LimitOrder(i, Sell, NewDecimal("104"), NewDecimal("1")), time.Now()})
+	ob.add(sessionOrder{ex, LimitOrder(i, Sell, NewDecimal("106"), NewDecimal("20")), time.Now()})
+
+	// trade below the stop price does not trigger
+	trades, _ = ob.add(sessionOrder{ex, MarketOrder(i, Buy, NewDecimal("1")), time.Now()})
+	if len(trades) != 1 || stop.Triggered {
+		t.Error("stop should not be triggered", trades)
+	}
+
+	// trade at 106 triggers the stop, which buys 10 at 106
+	trades, _ = ob.add(sessionOrder{ex, LimitOrder(i, Buy, NewDecimal("106"), NewDecimal("1")), time.Now()})
+	if len(trades) != 2 {
+		t.Error("wrong trades", trades)
+	}
+	if !stop.Triggered || stop.OrderState != Filled {
+		t.Error("stop should be triggered and filled", stop)
+	}
+	if !trades[1].price.Equal(NewDecimal("106")) || !trades[1].quantity.Equal(NewDecimal("10")) {
+		t.Error("wrong stop trade", trades)
+	}
+	changed, triggered := ob.takeChanged()
+	if len(changed) != 1 || changed[0].order != stop || !triggered[stop] {
+		t.Error("wrong triggered", changed, triggered)
+	}
+}
+
+// execTypeClient records the ExecType of the FIX status reports
+type execTypeClient struct {
+	namedExchangeClient
+	execTypes *[]enum.ExecType
+}
+
+func (c execTypeClient) SendOrderStatus(so sessionOrder) {
+	*c.execTypes = append(*c.execTypes, statusExecType(so.order, false))
+}
+
+func (c execTypeClient) SendTriggered(so sessionOrder) {
+	*c.execTypes = append(*c.execTypes, statusExecType(so.order, true))
+}
+
+func TestStopTriggeredReport(t *testing.T) {
+	discardMarketData()
+
+	var execTypes []enum.ExecType
+	var a = execTypeClient{namedExchangeClient("A"), &execTypes}
+	var b = namedExchangeClient("B")
+	var i = NewInstrument(1034, "TRIGGERED")
+	IMap.Put(i)
+
+	e := &exchange{}
+	stop := StopLimitOrder(i, Sell, NewDecimal("95"), NewDecimal("96"), NewDecimal("10"))
+	stop.Id = 1
+	e.CreateOrder(a, stop)
+	e.CreateOrder(b, LimitOrder(i, Buy, NewDecimal("96"), NewDecimal("1")))
+	e.CreateOrder(b, MarketOrder(i, Sell, NewDecimal("1")))
+	if !stop.Triggered || stop.OrderState != Booked {
+		t.Fatal("stop should be triggered and booked", stop)
+	}
+	e.ModifyOrder(a, 1, NewDecimal("94"), NewDecimal("10"))
+	e.CancelOrder(a, 1)
+
+	expected := []enum.ExecType{enum.ExecType_ORDER_STATUS, enum.ExecType_TRIGGERED_OR_ACTIVATED_BY_SYSTEM, enum.ExecType_ORDER_STATUS, enum.ExecType_CANCELED}
+	if len(execTypes) != len(expected) {
+		t.Fatal("wrong reports", execTypes)
+	}
+	for j := range expected {
+		if execTypes[j] != expected[j] {
+			t.Error("wrong ExecType", j, execTypes[j])
+		}
+	}
+}
+
+func TestExpiredReport(t *testing.T) {
+	discardMarketData()
+
+	var execTypes []enum.ExecType
+	var a = execTypeClient{namedExchangeClient("A"), &execTypes}
+	var i = NewInstrument(1039, "EXPIREDREPORT")
+	IMap.Put(i)
+
+	e := &exchange{}
+	day := LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("10"))
+	day.Id = 1
+	day.TimeInForce = Day
+	e.CreateOrder(a, day)
+	cancelled := LimitOrder(i, Buy, NewDecimal("99"), NewDecimal("10"))
+	cancelled.Id = 2
+	e.CreateOrder(a, cancelled)
+	e.CancelOrder(a, 2)
+	e.expireOrders(day.ExpireTime.Add(time.Second))
+
+	expected := []enum.ExecType{enum.ExecType_ORDER_STATUS, enum.ExecType_ORDER_STATUS, enum.ExecType_CANCELED, enum.ExecType_EXPIRED}
+	if len(execTypes) != len(expected) {
+		t.Fatal("wrong reports", execTypes)
+	}
+	for j := range expected {
+		if execTypes[j] != expected[j] {
+			t.Error("wrong ExecType", j, execTypes[j])
+		}
+	}
+	if status := fixOrdStatus(day); status != enum.OrdStatus_EXPIRED {
+		t.Error("expired order should have OrdStatus expired", status)
+	}
+	if status := fixOrdStatus(cancelled); status != enum.OrdStatus_CANCELED {
+		t.Error("cancelled order should have OrdStatus cancelled", status)
+	}
+}
+
+func TestStopLimitOrderCancel(t *testing.T) {
+	var ob = orderBook{}
+	var ex = testExchangeClient{}
+
+	var i = Equity{}
+
+	var stop = StopLimitOrder(i, Sell, NewDecimal("95"), NewDecimal("96"), NewDecimal("10"))
+	ob.add(sessionOrder{ex, stop, time.Now()})
+
+	// cancel using a different sessionOrder, as the exchange does
+	err := ob.remove(sessionOrder{ex, stop, time.Now()})
+	if err != nil {
+		t.Error("unexpected ", err)
+	}
+	if stop.OrderState != Cancelled || len(ob.stops) != 0 {
+		t.Error("stop should be cancelled", stop)
+	}
+
+	var stop2 = StopLimitOrder(i, Sell, NewDecimal("95"), NewDecimal("96"), NewDecimal("10"))
+	ob.add(sessionOrder{ex, stop2, time.Now()})
+	ob.add(sessionOrder{ex, LimitOrder(i, Buy, NewDecimal("96"), NewDecimal("1")), time.Now()})
+	trades, _ := ob.add(sessionOrder{ex, MarketOrder(i, Sell, NewDecimal("1")), time.Now()})
+
+	// the triggered stop limit has nothing to match, so it rests at its limit
+	if len(trades) != 1 || !stop2.Triggered {
+		t.Error("stop limit should be triggered", trades, stop2)
+	}
+	b := ob.buildBook()
+	if len(b.Asks) != 1 || !b.Asks[0].Price.Equal(NewDecimal("95")) {
+		t.Error("incorrect asks", b.Asks)
+	}
+
+	err = ob.remove(sessionOrder{ex, stop2, time.Now()})
+	if err != nil || len(ob.asks) != 0 {
+		t.Error("unable to cancel triggered stop", err, &ob)
+	}
+}
+
+func TestImmediateOrCancel(t *testing.T) {
+	var ob = orderBook{}
+	var ex = testExchangeClient{}
+
+	var i = Equity{}
+
+	ob.add(sessionOrder{ex, LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("5")), time.Now()})
+
+	var ioc = LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("10"))
+	ioc.TimeInForce = ImmediateOrCancel
+
+	trades, _ := ob.add(sessionOrder{ex, ioc, time.Now()})
+	if len(trades) != 1 || !trades[0].quantity.Equal(NewDecimal("5")) {
+		t.Error("wrong trades", trades)
+	}
+	if ioc.OrderState != Cancelled || !ioc.Remaining.Equal(NewDecimal("5")) {
+		t.Error("ioc remaining should be cancelled", ioc)
+	}
+	if len(ob.bids) != 0 {
+		t.Error("ioc should not be booked", &ob)
+	}
+}
+
+func TestFillOrKill(t *testing.T) {
+	var ob = orderBook{}
+	var ex = testExchangeClient{}
+
+	var i = Equity{}
+
+	ob.add(sessionOrder{ex, LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("5")), time.Now()})
+	ob.add(sessionOrder{ex, LimitOrder(i, Sell, NewDecimal("101"), NewDecimal("5")), time.Now()})
+	ob.add(sessionOrder{ex, LimitOrder(i, Sell, NewDecimal("102"), NewDecimal("5")), time.Now()})
+
+	var fok = LimitOrder(i, Buy, NewDecimal("101"), NewDecimal("11"))
+	fok.TimeInForce = FillOrKill
+
+	trades, _ := ob.add(sessionOrder{ex, fok, time.Now()})
+	if len(trades) != 0 || fok.OrderState != Cancelled || !fok.Remaining.Equal(NewDecimal("11")) {
+		t.Error("fok should be killed without any fills", trades, fok)
+	}
+
+	fok = LimitOrder(i, Buy, NewDecimal("101"), NewDecimal("10"))
+	fok.TimeInForce = FillOrKill
+
+	trades, _ = ob.add(sessionOrder{ex, fok, time.Now()})
+	if len(trades) != 2 || fok.OrderState != Filled {
+		t.Error("fok should be filled", trades, fok)
+	}
+}
+
+func TestNextSessionClose(t *testing.T) {
+	e := exchange{sessionClose: 16 * time.Hour}
+
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.Local)
+	if expires := e.nextSessionClose(now); !expires.Equal(time.Date(2024, 3, 1, 16, 0, 0, 0, time.Local)) {
+		t.Error("wrong session close", expires)
+	}
+	now = time.Date(2024, 3, 1, 17, 0, 0, 0, time.Local)
+	if expires := e.nextSessionClose(now); !expires.Equal(time.Date(2024, 3, 2, 16, 0, 0, 0, time.Local)) {
+		t.Error("wrong session close", expires)
+	}
+}
+
+func TestExpireTime(t *testing.T) {
+	discardMarketData()
+
+	var a = namedExchangeClient("A")
+	var i = NewInstrument(1021, "EXPIRE")
+	IMap.Put(i)
+
+	e := &exchange{sessionClose: 16 * time.Hour}
+
+	gtc := LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("10"))
+	gtc.Id = 1
+	gtc.ExpireTime = time.Now().Add(-time.Hour)
+	e.CreateOrder(a, gtc)
+	if gtc.OrderState != Rejected {
+		t.Error("expire time should be rejected for gtc orders", gtc)
+	}
+
+	day := LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("10"))
+	day.Id = 2
+	day.TimeInForce = Day
+	day.ExpireTime = time.Now().Add(-time.Hour)
+	e.CreateOrder(a, day)
+	if day.OrderState != Booked || !day.ExpireTime.Equal(e.nextSessionClose(time.Now())) {
+		t.Error("day order should expire at the session close", day)
+	}
+
+	stop := StopOrder(i, Sell, NewDecimal("90"), NewDecimal("10"))
+	stop.Id = 3
+	e.CreateOrder(a, stop)
+	// an expire time on an order that is not day or gtd is ignored
+	stop.ExpireTime = time.Now()
+
+	e.expireOrders(day.ExpireTime.Add(time.Second))
+	if day.OrderState != Cancelled || day.RejectReason != "order expired" {
+		t.Error("day order should expire", day)
+	}
+	if !stop.IsActive() {
+		t.Error("gtc order should not expire", stop)
+	}
+}
+
+func TestIcebergOrder(t *testing.T) {
+	var ob = orderBook{}
+	var ex = testExchangeClient{}
+
+	var i = Equity{}
+
+	var iceberg = LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("30"))
+	iceberg.DisplayQuantity = NewDecimal("10")
+	ob.add(sessionOrder{ex, iceberg, time.Now()})
+
+	var o2 = LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("5"))
+	ob.add(sessionOrder{ex, o2, time.Now()})
+
+	b := ob.buildBook()
+	if len(b.Asks) != 1 || !b.Asks[0].Quantity.Equal(NewDecimal("15")) {
+		t.Error("only the visible slice should be displayed", b.Asks)
+	}
+
+	// fill the visible slice, the refreshed slice goes behind o2
+	trades, _ := ob.add(sessionOrder{ex, MarketOrder(i, Buy, NewDecimal("12")), time.Now()})
+	if len(trades) != 2 {
+		t.Error("wrong trades", trades)
+	}
+	if trades[0].seller.order != iceberg || !trades[0].quantity.Equal(NewDecimal("10")) {
+		t.Error("wrong first trade", trades)
+	}
+	if trades[1].seller.order != o2 || !trades[1].quantity.Equal(NewDecimal("2")) {
+		t.Error("refreshed slice should lose priority", trades)
+	}
+	if !iceberg.Remaining.Equal(NewDecimal("20")) {
+		t.Error("wrong iceberg remaining", iceberg)
+	}
+
+	b = ob.buildBook()
+	if !b.Asks[0].Quantity.Equal(NewDecimal("13")) {
+		t.Error("wrong visible quantity", b.Asks)
+	}
+
+	// sweep the rest, including the hidden quantity
+	trades, _ = ob.add(sessionOrder{ex, MarketOrder(i, Buy, NewDecimal("30")), time.Now()})
+	if len(trades) != 3 || iceberg.OrderState != Filled || o2.OrderState != Filled {
+		t.Error("wrong sweep", trades, iceberg, o2)
+	}
+	if len(ob.asks) != 0 {
+		t.Error("book should be empty", &ob)
+	}
+}
+
+func TestCheckSpec(t *testing.T) {
+	spec := InstrumentSpec{LotSize: NewDecimal("10"), MinQuantity: NewDecimal("10"), MaxQuantity: NewDecimal("1000")}
+	spec.TickSizes, _ = ParseTickTable("0:0.01,10:0.05")
+
+	if reason := checkSpec(spec, NewDecimal("100"), NewDecimal("10.05"), ZERO); reason != "" {
+		t.Error("should be valid", reason)
+	}
+	if reason := checkSpec(spec, NewDecimal("100"), NewDecimal("10.01")); reason == "" {
+		t.Error("price is not on a tick")
+	}
+	if reason := checkSpec(spec, NewDecimal("15"), NewDecimal("1")); reason == "" {
+		t.Error("quantity is not a multiple of the lot size")
+	}
+	if reason := checkSpec(spec, NewDecimal("2000"), NewDecimal("1")); reason == "" {
+		t.Error("quantity is greater than the maximum")
+	}
+	if reason := checkSpec(InstrumentSpec{}, NewDecimal("1.2345"), NewDecimal("1.2345")); reason != "" {
+		t.Error("an empty spec allows any price and quantity", reason)
+	}
+}
@@ -3,7 +3,9 @@ package exchange
 import (
 	"fmt"
 	"strings"
+	"sync/atomic"
 
+	. "github.com/robaho/fixed"
 	"github.com/robaho/go-trader/pkg/common"
 )
 
@@ -11,6 +13,26 @@ type listNode struct {
 	prev *listNode
 	next *listNode;
 	order sessionOrder
+	// the current visible slice of an iceberg order
+	visible Fixed
+	// increases each time an order is queued, an order that is re-queued loses its priority
+	priority uint64
+}
+
+var nextPriority uint64
+
+// quantity returns the quantity shown in the book, which for iceberg orders is only the visible slice
+func (node *listNode) quantity() Fixed {
+	if node.order.order.DisplayQuantity.IsZero() {
+		return node.order.order.Remaining
+	}
+	return node.visible
+}
+
+// isExhausted returns true if the visible slice of an iceberg order has been filled, and more remains
+func (node *listNode) isExhausted() bool {
+	order := node.order.order
+	return !order.DisplayQuantity.IsZero() && !order.Remaining.IsZero() && !node.visible.GreaterThan(ZERO)
 }
 
 // optimized structure to allow efficient removal at start, middle, and end
@@ -18,11 +40,12 @@ type orderList struct {
 	head *listNode
 	tail *listNode
 	size int
-	allOrders map[sessionOrder]*listNode
+	// keyed by order, since the sessionOrder used to remove an order may not have the same time as when it was added
+	allOrders map[*common.Order]*listNode
 }
 
 func OrderList() orderList {
-	return orderList{allOrders: make(map[sessionOrder]*listNode)}
+	return orderList{allOrders: make(map[*common.Order]*listNode)}
 }
 
 func (list *orderList) String() string {
@@ -40,8 +63,18 @@ func (l *orderList) top() sessionOrder {
 	return l.head.order
 }
 
+// refresh replenishes the visible slice of an iceberg order, and moves it to the back of the queue
+func (l *orderList) refresh(so sessionOrder) {
+	if l.remove(so) == nil {
+		l.pushBack(so)
+	}
+}
+
 func (l *orderList) pushBack(so sessionOrder) {
-	node := &listNode{prev: l.tail, next: nil, order: so}
+	node := &listNode{prev: l.tail, next: nil, order: so, priority: atomic.AddUint64(&nextPriority, 1)}
+	if !so.order.DisplayQuantity.IsZero() {
+		node.visible = common.MinDecimal(so.order.DisplayQuantity, so.order.Remaining)
+	}
 	if(l.tail!=nil) {
 		l.tail.next = node
 	}
@@ -50,24 +83,27 @@ func (l *orderList) pushBack(so sessionOrder) {
 	}
 	l.tail = node
 	l.size++
-	l.allOrders[so]=node
+	l.allOrders[so.order]=node
 }
 
 func (l *orderList) pushFront(so sessionOrder) {
-	node := &listNode{prev: nil, next: l.head, order: so}
+	node := &listNode{prev: nil, next: l.head, order: so, priority: atomic.AddUint64(&nextPriority, 1)}
+	if !so.order.DisplayQuantity.IsZero() {
+		node.visible = common.MinDecimal(so.order.DisplayQuantity, so.order.Remaining)
+	}
 	if l.head!=nil {
 		l.head.prev = node
 	}
 	l.head = node
 	l.size++
-	l.allOrders[so]=node
+	l.allOrders[so.order]=node
 }
 func (l *orderList) remove(so sessionOrder) error {
-	node,ok := l.allOrders[so]
+	node,ok := l.allOrders[so.order]
 	if !ok {
 		return common.OrderNotFound
 	}
-	delete(l.allOrders,so)
+	delete(l.allOrders,so.order)
 
 	if node == l.head {
 		if(node.next!=nil) {
@@ -0,0 +1,112 @@
+package exchange
+
+import (
+	"sort"
+	"strconv"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+// The order status queries answer from the orders held in each session, so a client that has lost its state can
+// rebuild it. The orders are returned as copies taken with the order book locked, since fills from other sessions
+// only lock the book.
+
+// copyOrder returns a copy of the order, it must be called with the order's book locked
+func copyOrder(order *Order) *Order {
+	return &Order{Instrument: order.Instrument, Id: order.Id, ExchangeId: order.ExchangeId, Price: order.Price, Side: order.Side,
+		Quantity: order.Quantity, Remaining: order.Remaining, OrderType: order.OrderType, OrderState: order.OrderState,
+		RejectReason: order.RejectReason, StopPrice: order.StopPrice, Triggered: order.Triggered, TimeInForce: order.TimeInForce,
+		ExpireTime: order.ExpireTime, DisplayQuantity: order.DisplayQuantity, Account: order.Account}
+}
+
+// copyOrders returns copies of the orders, ordered by exchange id
+func (e *exchange) copyOrders(orders []*Order) []*Order {
+	var result []*Order
+	for _, order := range orders {
+		ob := e.lockOrderBook(order.Instrument)
+		result = append(result, copyOrder(order))
+		ob.Unlock()
+	}
+	id := func(order *Order) int {
+		id, _ := strconv.Atoi(order.ExchangeId)
+		return id
+	}
+	sort.Slice(result, func(i, j int) bool { return id(result[i]) < id(result[j]) })
+	return result
+}
+
+// sessionOrders returns copies of the orders of the session accepted by the filter. The session is not locked while
+// the orders are copied, so the locking order of the commands is not reversed.
+func (e *exchange) sessionOrders(s *session, filter func(order *Order) bool) []*Order {
+	var orders []*Order
+	s.Lock()
+	for _, order := range s.orders {
+		orders = append(orders, order)
+	}
+	s.Unlock()
+
+	var result []*Order
+	for _, order := range e.copyOrders(orders) {
+		if filter(order) {
+			result = append(result, order)
+		}
+	}
+	return result
+}
+
+// orderStatus returns a copy of the client's order with the client order id, or nil if there is no such order
+func (e *exchange) orderStatus(client exchangeClient, id OrderID) *Order {
+	e.quiesce.RLock()
+	defer e.quiesce.RUnlock()
+
+	v, ok := e.sessions.Load(client)
+	if !ok {
+		return nil
+	}
+	orders := e.sessionOrders(v.(*session), func(order *Order) bool { return order.Id == id })
+	if len(orders) == 0 {
+		return nil
+	}
+	return orders[0]
+}
+
+// openOrders returns copies of the client's active orders, for the instrument or all instruments if nil
+func (e *exchange) openOrders(client exchangeClient, instrument Instrument) []*Order {
+	e.quiesce.RLock()
+	defer e.quiesce.RUnlock()
+
+	v, ok := e.sessions.Load(client)
+	if !ok {
+		return nil
+	}
+	return e.sessionOrders(v.(*session), openFilter(instrument))
+}
+
+// allOpenOrders returns copies of the active orders by session id, for the session or all sessions if empty, and the
+// instrument or all instruments if nil
+func (e *exchange) allOpenOrders(sessionID string, instrument Instrument) map[string][]*Order {
+	e.quiesce.RLock()
+	defer e.quiesce.RUnlock()
+
+	var sessions []*session
+	e.sessions.Range(func(key, value interface{}) bool {
+		if s := value.(*session); sessionID == "" || s.id == sessionID {
+			sessions = append(sessions, s)
+		}
+		return true
+	})
+
+	result := make(map[string][]*Order)
+	for _, s := range sessions {
+		if orders := e.sessionOrders(s, openFilter(instrument)); len(orders) > 0 {
+			result[s.id] = orders
+		}
+	}
+	return result
+}
+
+func openFilter(instrument Instrument) func(order *Order) bool {
+	return func(order *Order) bool {
+		return order.IsActive() && (instrument == nil || order.Instrument == instrument)
+	}
+}
@@ -0,0 +1,64 @@
+package exchange
+
+import (
+	"testing"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+func TestOrderStatus(t *testing.T) {
+	discardMarketData()
+
+	var a = namedExchangeClient("A")
+	var b = namedExchangeClient("B")
+	var i1 = NewInstrument(1011, "OSTAT1")
+	var i2 = NewInstrument(1012, "OSTAT2")
+	IMap.Put(i1)
+	IMap.Put(i2)
+
+	e := &exchange{}
+
+	newOrder := func(id OrderID, order *Order) *Order {
+		order.Id = id
+		return order
+	}
+	o1 := newOrder(1, LimitOrder(i1, Sell, NewDecimal("100"), NewDecimal("10")))
+	e.CreateOrder(a, o1)
+	e.CreateOrder(a, newOrder(2, LimitOrder(i2, Buy, NewDecimal("50"), NewDecimal("5"))))
+	e.CreateOrder(b, newOrder(1, LimitOrder(i1, Buy, NewDecimal("100"), NewDecimal("10"))))
+
+	if e.orderStatus(a, 3) != nil {
+		t.Error("unknown order should not be found")
+	}
+	if e.orderStatus(namedExchangeClient("C"), 1) != nil {
+		t.Error("order of unknown session should not be found")
+	}
+	status := e.orderStatus(a, 1)
+	if status == nil || status.OrderState != Filled || status.ExchangeId != o1.ExchangeId {
+		t.Fatal("wrong order status", status)
+	}
+	if status == o1 {
+		t.Error("order status should be a copy")
+	}
+
+	open := e.openOrders(a, nil)
+	if len(open) != 1 || open[0].Id != 2 || open[0].Instrument != i2 {
+		t.Error("wrong open orders", open)
+	}
+	if open := e.openOrders(a, i1); len(open) != 0 {
+		t.Error("open orders should be limited to the instrument", open)
+	}
+	if open := e.openOrders(b, nil); len(open) != 0 {
+		t.Error("filled orders should not be open", open)
+	}
+
+	e.CreateOrder(b, newOrder(2, LimitOrder(i2, Buy, NewDecimal("49"), NewDecimal("5"))))
+	all := e.allOpenOrders("", nil)
+	if len(all) != 2 || len(all["A"]) != 1 || len(all["B"]) != 1 {
+		t.Error("wrong open orders by session", all)
+	}
+	all = e.allOpenOrders("B", i2)
+	if len(all) != 1 || len(all["B"]) != 1 || all["B"][0].Price.Cmp(NewDecimal("49")) != 0 {
+		t.Error("wrong open orders of session", all)
+	}
+}
@@ -0,0 +1,138 @@
+package exchange
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/robaho/fixed"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+// price bands protect against erroneous orders. Orders and quotes priced outside the static band are rejected,
+// and a match that would trade outside either band interrupts continuous trading, moving the instrument into
+// a volatility auction or halting it.
+
+type priceBandConfig struct {
+	props Properties
+	// the state an instrument moves to when a trade would breach a band, Auction or Halted
+	onBreach TradingState
+	// the length of a volatility auction, zero requires a manual uncross
+	auctionDuration time.Duration
+}
+
+func newPriceBandConfig(props Properties) (priceBandConfig, error) {
+	c := priceBandConfig{props: props}
+
+	switch action := props.GetString("price_band_action", "auction"); action {
+	case "auction":
+		c.onBreach = Auction
+	case "halt":
+		c.onBreach = Halted
+	default:
+		return c, errors.New("invalid price_band_action " + action)
+	}
+
+	var err error
+	c.auctionDuration, err = time.ParseDuration(props.GetString("volatility_auction", "30s"))
+	if err != nil {
+		return c, errors.New("invalid volatility_auction " + err.Error())
+	}
+	return c, nil
+}
+
+// get returns the property for the instrument, a key suffixed by the symbol overrides a key suffixed by the
+// group, which overrides the exchange default
+func (c priceBandConfig) get(key string, instrument Instrument) string {
+	return c.props.GetString(key+"."+instrument.Symbol(), c.props.GetString(key+"."+instrument.Group(), c.props.GetString(key, "0")))
+}
+
+func (c priceBandConfig) priceBands(instrument Instrument) (PriceBands, error) {
+	var bands PriceBands
+	var err error
+	if c.props == nil {
+		return bands, nil
+	}
+	bands.Static, err = NewSErr(c.get("price_band_static", instrument))
+	if err != nil {
+		return bands, errors.New("invalid price_band_static for " + instrument.Symbol())
+	}
+	bands.Dynamic, err = NewSErr(c.get("price_band_dynamic", instrument))
+	if err != nil {
+		return bands, errors.New("invalid price_band_dynamic for " + instrument.Symbol())
+	}
+	return bands, nil
+}
+
+// checkPriceBand returns the reason the price should be rejected, or "" if it is within the static band. There
+// is no static band until the instrument has a reference price.
+func (ob *orderBook) checkPriceBand(price Fixed) string {
+	if !ob.hasReference || price.IsZero() || WithinBand(ob.referencePrice, ob.bands.Static, price) {
+		return ""
+	}
+	return "price " + price.String() + " is outside the price band"
+}
+
+// withinBands returns true if a trade at price is within both the static and dynamic bands
+func (ob *orderBook) withinBands(price Fixed) bool {
+	if ob.hasReference && !WithinBand(ob.referencePrice, ob.bands.Static, price) {
+		return false
+	}
+	if ob.hasLastPrice && !WithinBand(ob.lastPrice, ob.bands.Dynamic, price) {
+		return false
+	}
+	return true
+}
+
+// interrupt stops continuous trading after a band breach
+func (ob *orderBook) interrupt() {
+	if ob.onBreach == Halted {
+		ob.state = Halted
+	} else {
+		ob.state = Auction
+		ob.volatilityAuction = true
+	}
+	ob.interrupted = true
+}
+
+// handleInterruption publishes a trading interruption caused by the last match, and schedules the end of the
+// volatility auction
+func (e *exchange) handleInterruption(ob *orderBook) {
+	if !ob.interrupted {
+		return
+	}
+	ob.interrupted = false
+
+	App.sendSecurityStatus(ob.Instrument, ob.state)
+
+	// while replaying the uncross is journaled as a trading state change, the auctions that had not ended are
+	// scheduled once the recovery is complete
+	if ob.state == Auction && !e.replaying {
+		e.scheduleUncross(ob.Instrument)
+	}
+}
+
+// scheduleUncross ends the volatility auction of the instrument after the configured duration
+func (e *exchange) scheduleUncross(instrument Instrument) {
+	if e.bands.auctionDuration <= 0 {
+		return
+	}
+	time.AfterFunc(e.bands.auctionDuration, func() {
+		e.Uncross(instrument)
+	})
+}
+
+// scheduleRecoveredAuctions schedules the end of the volatility auctions that were in progress when the exchange
+// stopped, they last the full duration from the restart
+func (e *exchange) scheduleRecoveredAuctions() {
+	e.orderBooks.Range(func(key, value interface{}) bool {
+		ob := value.(*orderBook)
+		ob.Lock()
+		defer ob.Unlock()
+
+		if ob.state == Auction && ob.volatilityAuction {
+			e.scheduleUncross(ob.Instrument)
+		}
+		return true
+	})
+}
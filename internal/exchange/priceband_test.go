@@ -0,0 +1,129 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+func TestPriceBands(t *testing.T) {
+	var i = NewInstrument(1, "TEST")
+	var ob = orderBook{Instrument: i, state: Open}
+	ob.bands = PriceBands{Static: NewDecimal("10"), Dynamic: NewDecimal("5")}
+	var ex = testExchangeClient{}
+
+	if reason := ob.checkPriceBand(NewDecimal("1000")); reason != "" {
+		t.Error("there is no band without a reference price", reason)
+	}
+
+	ob.add(sessionOrder{ex, LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("10")), time.Now()})
+	trades, _ := ob.add(sessionOrder{ex, LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("5")), time.Now()})
+	if len(trades) != 1 || !ob.referencePrice.Equal(NewDecimal("100")) {
+		t.Fatal("first trade should set the reference price", trades, ob.referencePrice)
+	}
+
+	if reason := ob.checkPriceBand(NewDecimal("111")); reason == "" {
+		t.Error("price should be outside the static band")
+	}
+	if reason := ob.checkPriceBand(NewDecimal("90")); reason != "" {
+		t.Error("price should be inside the static band", reason)
+	}
+
+	// the resting bid at 100 is within the band, but the market order sweeps to 94 which is not
+	ob.add(sessionOrder{ex, LimitOrder(i, Buy, NewDecimal("94"), NewDecimal("10")), time.Now()})
+	market := MarketOrder(i, Sell, NewDecimal("10"))
+	trades, _ = ob.add(sessionOrder{ex, market, time.Now()})
+
+	if len(trades) != 1 || !trades[0].price.Equal(NewDecimal("100")) {
+		t.Error("should only trade inside the band", trades)
+	}
+	if ob.state != Auction || !ob.interrupted {
+		t.Error("band breach should start a volatility auction", ob.state)
+	}
+	if market.OrderState != PartialFill {
+		t.Error("market order should remain in the auction", market)
+	}
+
+	ob.interrupted = false
+	trades = ob.setState(Open, time.Now())
+	if len(trades) != 1 || !trades[0].price.Equal(NewDecimal("94")) {
+		t.Error("auction should uncross at 94", trades)
+	}
+	if !ob.referencePrice.Equal(NewDecimal("94")) {
+		t.Error("auction should set the reference price", ob.referencePrice)
+	}
+}
+
+func TestFillOrKillPriceBand(t *testing.T) {
+	var i = NewInstrument(1, "TEST")
+	var ob = orderBook{Instrument: i, state: Open}
+	ob.bands = PriceBands{Static: NewDecimal("10"), Dynamic: NewDecimal("5")}
+	var ex = testExchangeClient{}
+
+	ob.add(sessionOrder{ex, LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("5")), time.Now()})
+	ob.add(sessionOrder{ex, LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("5")), time.Now()})
+
+	// the resting bid at 100 is within the band, but the bid at 94 is not
+	ob.add(sessionOrder{ex, LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("5")), time.Now()})
+	ob.add(sessionOrder{ex, LimitOrder(i, Buy, NewDecimal("94"), NewDecimal("10")), time.Now()})
+
+	fok := LimitOrder(i, Sell, NewDecimal("94"), NewDecimal("10"))
+	fok.TimeInForce = FillOrKill
+	trades, _ := ob.add(sessionOrder{ex, fok, time.Now()})
+	if len(trades) != 0 || fok.OrderState != Cancelled || !fok.Remaining.Equal(NewDecimal("10")) {
+		t.Error("fok should be killed without any fills", trades, fok)
+	}
+	if ob.state != Open || ob.interrupted {
+		t.Error("fok should not interrupt trading", ob.state)
+	}
+
+	fok = LimitOrder(i, Sell, NewDecimal("94"), NewDecimal("5"))
+	fok.TimeInForce = FillOrKill
+	trades, _ = ob.add(sessionOrder{ex, fok, time.Now()})
+	if len(trades) != 1 || fok.OrderState != Filled {
+		t.Error("fok should be filled inside the band", trades, fok)
+	}
+}
+
+func TestPriceBandHalt(t *testing.T) {
+	var i = NewInstrument(1, "TEST")
+	var ob = orderBook{Instrument: i, state: Open, onBreach: Halted}
+	ob.bands = PriceBands{Dynamic: NewDecimal("1")}
+	var ex = testExchangeClient{}
+
+	ob.add(sessionOrder{ex, LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("1")), time.Now()})
+	ob.add(sessionOrder{ex, LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("1")), time.Now()})
+	ob.add(sessionOrder{ex, LimitOrder(i, Buy, NewDecimal("90"), NewDecimal("1")), time.Now()})
+	trades, _ := ob.add(sessionOrder{ex, LimitOrder(i, Sell, NewDecimal("90"), NewDecimal("1")), time.Now()})
+
+	if len(trades) != 0 || ob.state != Halted {
+		t.Error("band breach should halt the instrument", trades, ob.state)
+	}
+	if reason := ob.checkState(); reason == "" {
+		t.Error("orders should be rejected when halted")
+	}
+}
+
+func TestVolatilityAuctionReplay(t *testing.T) {
+	discardMarketData()
+
+	var i = NewInstrument(1036, "VOLAUCTION")
+	IMap.Put(i)
+
+	for _, replaying := range []bool{true, false} {
+		e := &exchange{replaying: replaying, bands: priceBandConfig{auctionDuration: 10 * time.Millisecond}}
+		ob := e.lockOrderBook(i)
+		ob.state = Auction
+		ob.interrupted = true
+		e.handleInterruption(ob)
+		ob.Unlock()
+
+		time.Sleep(100 * time.Millisecond)
+		if state := e.GetTradingState(i); replaying && state != Auction {
+			t.Error("the auction should not be uncrossed by a timer while replaying", state)
+		} else if !replaying && state != Open {
+			t.Error("the auction should be uncrossed after the duration", state)
+		}
+	}
+}
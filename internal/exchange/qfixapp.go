@@ -4,20 +4,29 @@ import (
 	"fmt"
 	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/quickfixgo/fix44/securitydefinition"
 	"github.com/quickfixgo/fix44/securitylistrequest"
+	"github.com/quickfixgo/fix44/securitystatus"
 	. "github.com/robaho/fixed"
 
 	"github.com/quickfixgo/enum"
 	"github.com/quickfixgo/field"
 	"github.com/quickfixgo/fix44/executionreport"
+	"github.com/quickfixgo/fix44/marketdatarequest"
 	"github.com/quickfixgo/fix44/massquote"
 	"github.com/quickfixgo/fix44/massquoteacknowledgement"
 	"github.com/quickfixgo/fix44/newordersingle"
 	"github.com/quickfixgo/fix44/ordercancelreplacerequest"
 	"github.com/quickfixgo/fix44/ordercancelrequest"
+	"github.com/quickfixgo/fix44/ordermasscancelreport"
+	"github.com/quickfixgo/fix44/ordermasscancelrequest"
+	"github.com/quickfixgo/fix44/ordermassstatusrequest"
+	"github.com/quickfixgo/fix44/orderstatusrequest"
 	"github.com/quickfixgo/fix44/securitydefinitionrequest"
+	"github.com/quickfixgo/fix44/tradecapturereportrequest"
 	"github.com/quickfixgo/quickfix"
 	. "github.com/robaho/go-trader/pkg/common"
 	"github.com/shopspring/decimal"
@@ -29,8 +38,12 @@ var endOfDownload = NewInstrument(0, "endofdownload")
 type myApplication struct {
 	*quickfix.MessageRouter
 	e            *exchange
-	lock         sync.Mutex
 	instrumentID int64
+	// the market data subscriptions of each session by request id
+	mdLock          sync.Mutex
+	mdSubscriptions map[quickfix.SessionID]map[string]*fixSubscription
+	// the drop copy sessions, configured at startup
+	dropCopies []*dropCopy
 }
 
 type fixClient struct {
@@ -38,10 +51,37 @@ type fixClient struct {
 }
 
 func (c fixClient) SendOrderStatus(so sessionOrder) {
-	App.sendExecutionReport(enum.ExecType_ORDER_STATUS, so)
+	App.sendExecutionReport(statusExecType(so.order, false), so, c.sessionID)
 }
-func (c fixClient) SendTrades(trades []trade) {
-	App.sendExecutionReports(trades)
+
+// SendTriggered reports the stop order being triggered, the later status reports have the usual ExecType
+func (c fixClient) SendTriggered(so sessionOrder) {
+	App.sendExecutionReport(statusExecType(so.order, true), so, c.sessionID)
+}
+
+// statusExecType returns the ExecType of an order status report, triggered is true if it reports the order being
+// triggered. A triggered order that already traded is reported by the fills.
+func statusExecType(order *Order, triggered bool) enum.ExecType {
+	switch {
+	case triggered && order.OrderState == Booked && order.Remaining.Equal(order.Quantity):
+		return enum.ExecType_TRIGGERED_OR_ACTIVATED_BY_SYSTEM
+	case isExpired(order):
+		return enum.ExecType_EXPIRED
+	case order.OrderState == Cancelled:
+		return enum.ExecType_CANCELED
+	}
+	return enum.ExecType_ORDER_STATUS
+}
+
+// fixOrdStatus returns the OrdStatus of the order, an expired order is reported as expired rather than cancelled
+func fixOrdStatus(order *Order) enum.OrdStatus {
+	if isExpired(order) {
+		return enum.OrdStatus_EXPIRED
+	}
+	return MapToFixOrdStatus(order.OrderState)
+}
+func (c fixClient) SendFill(so sessionOrder, price Fixed, quantity Fixed, remaining Fixed) {
+	App.sendTradeExecutionReport(so, price, quantity, remaining, c.sessionID)
 }
 func (c fixClient) SessionID() string {
 	return c.sessionID.String()
@@ -51,13 +91,22 @@ func (app *myApplication) OnCreate(sessionID quickfix.SessionID) {
 }
 
 func (app *myApplication) OnLogon(sessionID quickfix.SessionID) {
+	if app.isDropCopy(sessionID) {
+		fmt.Println("drop copy login", sessionID)
+		return
+	}
 	c := fixClient{sessionID: sessionID}
 	app.e.newSession(c)
 	fmt.Println("login, sessions are ", app.e.ListSessions())
 }
 
 func (app *myApplication) OnLogout(sessionID quickfix.SessionID) {
+	if app.isDropCopy(sessionID) {
+		fmt.Println("drop copy logout", sessionID)
+		return
+	}
 	c := fixClient{sessionID: sessionID}
+	app.removeSubscriptions(sessionID)
 	app.e.SessionDisconnect(c)
 	fmt.Println("logout, sessions are ", app.e.ListSessions())
 }
@@ -76,6 +125,9 @@ func (app *myApplication) FromAdmin(message *quickfix.Message, sessionID quickfi
 }
 
 func (app *myApplication) FromApp(message *quickfix.Message, sessionID quickfix.SessionID) quickfix.MessageRejectError {
+	if app.isDropCopy(sessionID) {
+		return quickfix.NewBusinessMessageRejectError("drop copy sessions cannot send application messages", 0, nil)
+	}
 	app.Route(message, sessionID)
 	return nil
 }
@@ -102,23 +154,68 @@ func (app *myApplication) onNewOrderSingle(msg newordersingle.NewOrderSingle, se
 		return err
 	}
 	var price decimal.Decimal
-	if ordType == enum.OrdType_LIMIT {
+	if ordType == enum.OrdType_LIMIT || ordType == enum.OrdType_STOP_LIMIT {
 		price, err = msg.GetPrice()
 		if err != nil {
 			return err
 		}
 	}
+	var stopPx decimal.Decimal
+	if ordType == enum.OrdType_STOP || ordType == enum.OrdType_STOP_LIMIT {
+		stopPx, err = msg.GetStopPx()
+		if err != nil {
+			return err
+		}
+	}
+	tif := enum.TimeInForce_GOOD_TILL_CANCEL
+	if msg.HasTimeInForce() {
+		tif, err = msg.GetTimeInForce()
+		if err != nil {
+			return err
+		}
+	}
+	switch tif {
+	case enum.TimeInForce_GOOD_TILL_CANCEL, enum.TimeInForce_DAY, enum.TimeInForce_IMMEDIATE_OR_CANCEL, enum.TimeInForce_FILL_OR_KILL, enum.TimeInForce_GOOD_TILL_DATE:
+	default:
+		return quickfix.NewMessageRejectError("unsupported time in force "+string(tif), 0, nil)
+	}
 	instrument := IMap.GetBySymbol(symbol)
 	if instrument == nil {
 		return quickfix.NewMessageRejectError("unknown symbol "+symbol, 0, nil)
 	}
 	var order *Order
-	if ordType == enum.OrdType_LIMIT {
+	switch ordType {
+	case enum.OrdType_LIMIT:
 		order = LimitOrder(instrument, MapFromFixSide(side), ToFixed(price), ToFixed(qty))
-	} else {
+	case enum.OrdType_STOP:
+		order = StopOrder(instrument, MapFromFixSide(side), ToFixed(stopPx), ToFixed(qty))
+	case enum.OrdType_STOP_LIMIT:
+		order = StopLimitOrder(instrument, MapFromFixSide(side), ToFixed(price), ToFixed(stopPx), ToFixed(qty))
+	default:
 		order = MarketOrder(instrument, MapFromFixSide(side), ToFixed(qty))
 	}
 	order.Id = NewOrderID(clOrdId)
+	order.TimeInForce = MapFromFixTimeInForce(tif)
+	if msg.HasAccount() {
+		order.Account, err = msg.GetAccount()
+		if err != nil {
+			return err
+		}
+	}
+	if msg.HasMaxFloor() {
+		maxFloor, err := msg.GetMaxFloor()
+		if err != nil {
+			return err
+		}
+		order.DisplayQuantity = ToFixed(maxFloor)
+	}
+	if msg.HasExpireTime() {
+		expireTime, err := msg.GetExpireTime()
+		if err != nil {
+			return err
+		}
+		order.ExpireTime = expireTime
+	}
 
 	c := fixClient{sessionID: sessionID}
 	app.e.CreateOrder(c, order)
@@ -155,6 +252,78 @@ func (app *myApplication) onOrderCancelReplaceRequest(msg ordercancelreplacerequ
 
 	return nil
 }
+// onOrderMassCancelRequest cancels the session's orders and quotes, for all instruments or a single instrument
+func (app *myApplication) onOrderMassCancelRequest(msg ordermasscancelrequest.OrderMassCancelRequest, sessionID quickfix.SessionID) quickfix.MessageRejectError {
+	clOrdId, err := msg.GetClOrdID()
+	if err != nil {
+		return err
+	}
+	requestType, err := msg.GetMassCancelRequestType()
+	if err != nil {
+		return err
+	}
+
+	c := fixClient{sessionID: sessionID}
+	k := KillSwitch{SessionID: c.SessionID()}
+
+	if msg.HasSide() {
+		side, err := msg.GetSide()
+		if err != nil {
+			return err
+		}
+		k.Side = MapFromFixSide(side)
+	}
+
+	var symbol string
+
+	switch requestType {
+	case enum.MassCancelRequestType_CANCEL_ALL_ORDERS:
+	case enum.MassCancelRequestType_CANCEL_ORDERS_FOR_A_SECURITY:
+		symbol, err = msg.GetSymbol()
+		if err != nil {
+			return err
+		}
+		k.Instrument = IMap.GetBySymbol(symbol)
+		if k.Instrument == nil {
+			app.sendMassCancelReject(clOrdId, requestType, enum.MassCancelRejectReason_INVALID_OR_UNKNOWN_SECURITY, "unknown symbol "+symbol, sessionID)
+			return nil
+		}
+	default:
+		app.sendMassCancelReject(clOrdId, requestType, enum.MassCancelRejectReason_MASS_CANCEL_NOT_SUPPORTED, "unsupported mass cancel request type", sessionID)
+		return nil
+	}
+
+	count := app.e.MassCancel(k, false)
+
+	response := field.NewMassCancelResponse(enum.MassCancelResponse(requestType))
+	msgOut := ordermasscancelreport.New(field.NewOrderID(newMassActionID()), field.NewMassCancelRequestType(requestType), response)
+	msgOut.SetClOrdID(clOrdId)
+	msgOut.SetTotalAffectedOrders(count)
+	if symbol != "" {
+		msgOut.SetSymbol(symbol)
+	}
+	quickfix.SendToTarget(msgOut, sessionID)
+
+	return nil
+}
+
+var nextMassActionID int64
+
+// newMassActionID returns the exchange id of a mass cancel request, reported in the OrderID of the
+// OrderMassCancelReport
+func newMassActionID() string {
+	return "mass." + strconv.FormatInt(atomic.AddInt64(&nextMassActionID, 1), 10)
+}
+
+func (app *myApplication) sendMassCancelReject(clOrdId string, requestType enum.MassCancelRequestType, reason enum.MassCancelRejectReason, text string, sessionID quickfix.SessionID) {
+	response := field.NewMassCancelResponse(enum.MassCancelResponse_CANCEL_REQUEST_REJECTED)
+	msg := ordermasscancelreport.New(field.NewOrderID(newMassActionID()), field.NewMassCancelRequestType(requestType), response)
+	msg.SetClOrdID(clOrdId)
+	msg.SetMassCancelRejectReason(reason)
+	msg.SetText(text)
+	quickfix.SendToTarget(msg, sessionID)
+}
+
 func (app *myApplication) onMassQuote(msg massquote.MassQuote, sessionID quickfix.SessionID) quickfix.MessageRejectError {
 	rgNoQuoteSets, err := msg.GetNoQuoteSets()
 	if err != nil {
@@ -210,10 +379,13 @@ func (app *myApplication) onMassQuote(msg massquote.MassQuote, sessionID quickfi
 	}
 
 	c := fixClient{sessionID: sessionID}
-	app.e.Quote(c, instrument, ToFixed(bidPrice), ToFixed(bidQty), ToFixed(offerPrice), ToFixed(offerQty))
+	if err := app.e.Quote(c, instrument, ToFixed(bidPrice), ToFixed(bidQty), ToFixed(offerPrice), ToFixed(offerQty)); err != nil {
+		app.sendQuoteAck(quoteId, enum.QuoteStatus_REJECTED, err.Error(), sessionID)
+		return nil
+	}
 
 	if ackRequested {
-		app.sendQuoteAcceptedAck(quoteId,sessionID);
+		app.sendQuoteAck(quoteId, enum.QuoteStatus_ACCEPTED, "", sessionID)
 	}
 
 	return nil
@@ -230,17 +402,11 @@ func (app *myApplication) onSecurityDefinitionRequest(msg securitydefinitionrequ
 		return err
 	}
 
-	app.lock.Lock()
-	defer app.lock.Unlock()
-
-	instrument := IMap.GetBySymbol(symbol)
-	if instrument != nil {
-		app.sendInstrument(instrument, reqid, sessionID)
-	} else {
-		instrument = NewInstrument(IMap.NextID(),symbol)
-		IMap.Put(instrument)
-		app.sendInstrument(instrument, reqid, sessionID)
+	instrument, err0 := app.e.CreateInstrument(symbol, InstrumentSpec{})
+	if err0 != nil {
+		return quickfix.NewBusinessMessageRejectError(err0.Error(), 0, nil)
 	}
+	app.sendInstrument(instrument, reqid, sessionID)
 	return nil
 }
 
@@ -267,22 +433,46 @@ func (app *myApplication) sendInstrument(instrument Instrument, reqid string, se
 
 	msg.SetSymbol(instrument.Symbol())
 	msg.SetSecurityID(strconv.FormatInt(instrument.ID(), 10))
+	SetInstrumentSpec(msg.Body, instrument.Spec())
 
 	quickfix.SendToTarget(msg, sessionID)
 }
 
-func (app *myApplication) sendQuoteAcceptedAck(quoteID string, sessionID quickfix.SessionID) {
-	status := field.QuoteStatusField{FIXString: quickfix.FIXString(enum.QuoteStatus_ACCEPTED)}
+func (app *myApplication) sendQuoteAck(quoteID string, quoteStatus enum.QuoteStatus, text string, sessionID quickfix.SessionID) {
+	status := field.QuoteStatusField{FIXString: quickfix.FIXString(quoteStatus)}
 	
 	msg := massquoteacknowledgement.New(status);
 	msg.SetQuoteID(quoteID);
+	if text != "" {
+		msg.SetText(text)
+	}
 	quickfix.SendToTarget(msg, sessionID)
 }
 
+// sendSecurityStatus reports the trading state of the instrument to all FIX sessions
+func (app *myApplication) sendSecurityStatus(instrument Instrument, state TradingState) {
+	app.e.sessions.Range(func(key, value interface{}) bool {
+		c, ok := key.(fixClient)
+		if !ok {
+			return true
+		}
+		msg := securitystatus.New()
+		msg.SetSymbol(instrument.Symbol())
+		msg.SetSecurityID(strconv.FormatInt(instrument.ID(), 10))
+		msg.SetSecurityTradingStatus(MapToFixSecurityTradingStatus(state))
+		msg.SetTransactTime(time.Now())
+		quickfix.SendToTarget(msg, c.sessionID)
+		return true
+	})
+}
+
 
-func (app *myApplication) sendTradeExecutionReport(so sessionOrder, price Fixed, qty Fixed, remaining Fixed) {
+func (app *myApplication) sendTradeExecutionReport(so sessionOrder, price Fixed, qty Fixed, remaining Fixed, sessionID quickfix.SessionID) {
+	quickfix.SendToTarget(newTradeExecutionReport(so.order, price, qty, remaining), sessionID)
+}
 
-	order := so.order
+// newTradeExecutionReport returns the execution report of a fill of the order
+func newTradeExecutionReport(order *Order, price Fixed, qty Fixed, remaining Fixed) executionreport.ExecutionReport {
 
 	var ordStatus enum.OrdStatus
 
@@ -308,20 +498,42 @@ func (app *myApplication) sendTradeExecutionReport(so sessionOrder, price Fixed,
 	msg.SetSymbol(order.Instrument.Symbol())
 	msg.SetLastPx(ToDecimal(price), 4)
 	msg.SetLastQty(ToDecimal(qty), 4)
+	setOrderAttributes(&msg, order)
+	return msg
+}
 
-	quickfix.SendToTarget(msg, so.client.(fixClient).sessionID)
+// set the optional order attributes on the execution report
+func setOrderAttributes(msg *executionreport.ExecutionReport, order *Order) {
+	msg.SetOrdType(MapToFixOrdType(order.OrderType))
+	if order.IsStop() {
+		msg.SetStopPx(ToDecimal(order.StopPrice), 4)
+	}
+	msg.SetTimeInForce(MapToFixTimeInForce(order.TimeInForce))
+	if !order.ExpireTime.IsZero() {
+		msg.SetExpireTime(order.ExpireTime)
+	}
+	if !order.DisplayQuantity.IsZero() {
+		msg.SetMaxFloor(ToDecimal(order.DisplayQuantity), 4)
+	}
+	if order.Account != "" {
+		msg.SetAccount(order.Account)
+	}
 }
 
-func (app *myApplication) sendExecutionReport(execType enum.ExecType, so sessionOrder) {
+func (app *myApplication) sendExecutionReport(execType enum.ExecType, so sessionOrder, sessionID quickfix.SessionID) {
+	msg := newExecutionReport(execType, so.order)
+	quickfix.SendToTarget(msg, sessionID)
+}
 
-	order := so.order
+// newExecutionReport returns an execution report with the current state of the order
+func newExecutionReport(execType enum.ExecType, order *Order) executionreport.ExecutionReport {
 
 	var side = MapToFixSide(order.Side)
 
 	msg := executionreport.New(field.NewOrderID(order.ExchangeId),
 		field.NewExecID(order.ExchangeId),
 		field.NewExecType(execType),
-		field.NewOrdStatus(MapToFixOrdStatus(order.OrderState)),
+		field.NewOrdStatus(fixOrdStatus(order)),
 		field.NewSide(side),
 		field.NewLeavesQty(ToDecimal(order.Remaining), 4),
 		field.NewCumQty(ToDecimal(order.Quantity.Sub(order.Remaining)), 4),
@@ -330,15 +542,11 @@ func (app *myApplication) sendExecutionReport(execType enum.ExecType, so session
 	msg.SetPrice(ToDecimal(order.Price), 4)
 	msg.SetOrderQty(ToDecimal(order.Quantity), 4)
 	msg.SetSymbol(order.Instrument.Symbol())
-
-	quickfix.SendToTarget(msg, so.client.(fixClient).sessionID)
-}
-
-func (app *myApplication) sendExecutionReports(trades []trade) {
-	for _, k := range trades {
-		app.sendTradeExecutionReport(k.buyer, k.price, k.quantity, k.buyRemaining)
-		app.sendTradeExecutionReport(k.seller, k.price, k.quantity, k.sellRemaining)
+	setOrderAttributes(&msg, order)
+	if order.RejectReason != "" {
+		msg.SetText(order.RejectReason)
 	}
+	return msg
 }
 
 func init() {
@@ -349,6 +557,11 @@ func init() {
 	App.AddRoute(ordercancelrequest.Route(App.onOrderCancelRequest))
 	App.AddRoute(ordercancelreplacerequest.Route(App.onOrderCancelReplaceRequest))
 	App.AddRoute(massquote.Route(App.onMassQuote))
+	App.AddRoute(ordermasscancelrequest.Route(App.onOrderMassCancelRequest))
 	App.AddRoute(securitydefinitionrequest.Route(App.onSecurityDefinitionRequest))
 	App.AddRoute(securitylistrequest.Route(App.onSecurityListRequest))
+	App.AddRoute(marketdatarequest.Route(App.onMarketDataRequest))
+	App.AddRoute(tradecapturereportrequest.Route(App.onTradeCaptureReportRequest))
+	App.AddRoute(orderstatusrequest.Route(App.onOrderStatusRequest))
+	App.AddRoute(ordermassstatusrequest.Route(App.onOrderMassStatusRequest))
 }
@@ -0,0 +1,378 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/robaho/fixed"
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+// On startup the books and sessions are rebuilt by replaying the journal. The replayed commands are processed
+// exactly as they were originally, using the journaled times and exchange ids, so the result is the same. The risk
+// limits and message rates are not checked again, the commands that were rejected are journaled with the reason. The
+// sessions are restored with a recoveredClient, which is bound to the client when the session logs on again.
+// Sessions that do not return within recovery_timeout have their orders cancelled according to recovery_cancel.
+
+const recoveryCancelReason = "session did not reconnect"
+
+// recoveryCancel selects what is cancelled for sessions that do not reconnect after recovery
+type recoveryCancel string
+
+const (
+	recoveryCancelAll    recoveryCancel = "all"
+	recoveryCancelQuotes recoveryCancel = "quotes"
+	recoveryCancelNone   recoveryCancel = "none"
+)
+
+// recoveryRules returns the configured recovery_cancel rule and recovery_timeout
+func recoveryRules(props Properties) (recoveryCancel, time.Duration, error) {
+	rule := recoveryCancel(props.GetString("recovery_cancel", string(recoveryCancelAll)))
+	switch rule {
+	case recoveryCancelAll, recoveryCancelQuotes, recoveryCancelNone:
+	default:
+		return rule, 0, errors.New("invalid recovery_cancel " + string(rule))
+	}
+	timeout, err := time.ParseDuration(props.GetString("recovery_timeout", "60s"))
+	if err != nil {
+		return rule, 0, errors.New("invalid recovery_timeout " + err.Error())
+	}
+	return rule, timeout, nil
+}
+
+// recoveredClient is the client of a session restored from the journal. Reports are forwarded to the client once
+// the session logs on again, and dropped until then.
+type recoveredClient struct {
+	sync.Mutex
+	id     string
+	client exchangeClient
+}
+
+func (c *recoveredClient) bound() exchangeClient {
+	c.Lock()
+	defer c.Unlock()
+	return c.client
+}
+
+func (c *recoveredClient) bind(client exchangeClient) {
+	c.Lock()
+	defer c.Unlock()
+	c.client = client
+}
+
+func (c *recoveredClient) SendOrderStatus(so sessionOrder) {
+	if client := c.bound(); client != nil {
+		client.SendOrderStatus(so)
+	}
+}
+
+func (c *recoveredClient) SendTriggered(so sessionOrder) {
+	client := c.bound()
+	if tr, ok := client.(triggerReporter); ok {
+		tr.SendTriggered(so)
+	} else if client != nil {
+		client.SendOrderStatus(so)
+	}
+}
+
+func (c *recoveredClient) SendFill(so sessionOrder, price Fixed, quantity Fixed, remaining Fixed) {
+	if client := c.bound(); client != nil {
+		client.SendFill(so, price, quantity, remaining)
+	}
+}
+
+func (c *recoveredClient) SessionID() string {
+	return c.id
+}
+
+func (c *recoveredClient) String() string {
+	return c.id
+}
+
+// recoveredSession returns the restored session with the id, creating it if needed
+func (e *exchange) recoveredSession(id string) exchangeClient {
+	if s, ok := e.recovered.Load(id); ok {
+		return s.(*session).client
+	}
+	s := e.newSession(&recoveredClient{id: id})
+	e.recovered.Store(id, s)
+	return s.client
+}
+
+// reconnect binds a restored session to the client logging on with the same session id, or returns nil if there
+// is no restored session
+func (e *exchange) reconnect(client exchangeClient) *session {
+	if _, ok := client.(*recoveredClient); ok {
+		return nil
+	}
+	v, ok := e.recovered.LoadAndDelete(client.SessionID())
+	if !ok {
+		return nil
+	}
+	s := v.(*session)
+	s.Lock()
+	defer s.Unlock()
+
+	rc := s.client.(*recoveredClient)
+	rc.bind(client)
+	e.sessions.Delete(rc)
+	e.sessions.Store(client, s)
+
+	active := 0
+	for _, order := range s.orders {
+		if order.IsActive() {
+			active++
+		}
+	}
+	fmt.Println("session", client.SessionID(), "reconnected,", active, "orders restored")
+	return s
+}
+
+// replayJournal rebuilds the books and sessions from the journal entries after 'from', e.g. the sequence number of
+// a snapshot. It must be called before the journal is opened for writing, and returns the number of entries replayed.
+func (e *exchange) replayJournal(path string, from uint64) (int, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+
+	var count int
+	var lastTradeID int64
+
+	e.replaying = true
+	defer func() { e.replaying = false }()
+
+	err := ReadJournal(path, func(entry *JournalEntry) error {
+		// the trade store is loaded with all of the journaled trades, since it is not in the snapshot
+		if entry.Type == JournalTrade && entry.Trade != nil {
+			e.trades.addJournaled(entry)
+		}
+		if entry.Seq <= from {
+			return nil
+		}
+		if entry.Trade != nil && entry.Trade.TradeID > lastTradeID {
+			lastTradeID = entry.Trade.TradeID
+		}
+		if err := e.replay(entry); err != nil {
+			return fmt.Errorf("unable to replay journal entry %d: %w", entry.Seq, err)
+		}
+		count++
+		return nil
+	})
+
+	// trades may be numbered in a different order when replayed, so continue after the highest used
+	if atomic.LoadInt64(&nextTradeID) < lastTradeID {
+		atomic.StoreInt64(&nextTradeID, lastTradeID)
+	}
+	return count, err
+}
+
+func (e *exchange) replay(entry *JournalEntry) error {
+	var instrument Instrument
+	if entry.Symbol != "" {
+		instrument = IMap.GetBySymbol(entry.Symbol)
+	}
+
+	switch entry.Type {
+	case JournalOrderStatus, JournalTrade, JournalDisconnect:
+		// outcomes are reproduced by the replay, and the cancels caused by a disconnect are journaled separately
+		return nil
+	case JournalMassCancel, JournalEnable:
+		// only the block is replayed, the orders and quotes cancelled by a mass cancel are journaled separately
+		k := KillSwitch{SessionID: entry.Session, Account: entry.Account, Instrument: instrument, Side: entry.Side}
+		if entry.Type == JournalEnable {
+			e.EnableTrading(k)
+		} else if entry.Block {
+			e.blockLock.Lock()
+			e.blocked = append(e.blocked, k)
+			e.blockLock.Unlock()
+		}
+		return nil
+	}
+
+	if instrument == nil {
+		fmt.Println("skipping journal entry", entry.Seq, "unknown symbol", entry.Symbol)
+		return nil
+	}
+
+	switch entry.Type {
+	case JournalCreateOrder:
+		if entry.Order == nil {
+			return errors.New("missing order")
+		}
+		order := replayedOrder(instrument, entry.Order)
+		if id, err := strconv.Atoi(order.ExchangeId); err == nil && int32(id) > atomic.LoadInt32(&e.nextOrder) {
+			atomic.StoreInt32(&e.nextOrder, int32(id))
+		}
+		if entry.Reason != "" {
+			e.replayRejected(e.recoveredSession(entry.Session), order, entry.Reason)
+			return nil
+		}
+		e.createOrder(e.recoveredSession(entry.Session), order, entry.Time)
+	case JournalModifyOrder:
+		if entry.Order == nil {
+			return errors.New("missing order")
+		}
+		if entry.Reason != "" {
+			// a rejected modify leaves the order unchanged
+			return nil
+		}
+		e.modifyOrder(e.recoveredSession(entry.Session), entry.Order.Id, entry.Order.Price, entry.Order.Quantity, entry.Time)
+	case JournalCancelOrder:
+		if entry.Order == nil {
+			return errors.New("missing order")
+		}
+		e.cancelOrder(e.recoveredSession(entry.Session), entry.Order.Id, entry.Reason)
+	case JournalQuote:
+		if entry.Quote == nil {
+			return errors.New("missing quote")
+		}
+		if entry.Reason != "" {
+			return nil
+		}
+		q := entry.Quote
+		e.quote(e.recoveredSession(entry.Session), instrument, q.BidPrice, q.BidQuantity, q.AskPrice, q.AskQuantity, entry.Time)
+	case JournalCancelQuote:
+		e.cancelQuote(e.recoveredSession(entry.Session), instrument, entry.Side)
+	case JournalTradingState:
+		e.setTradingState(instrument, entry.State, entry.Time)
+	default:
+		return errors.New("unknown entry type " + string(entry.Type))
+	}
+	return nil
+}
+
+// replayRejected adds an order that was rejected to the session, so its status is known, without checking it again
+func (e *exchange) replayRejected(client exchangeClient, order *Order, reason string) {
+	s := e.lockSession(client)
+	defer s.Unlock()
+
+	order.OrderState = Rejected
+	order.RejectReason = reason
+	s.orders[order.Id] = order
+}
+
+// replayedOrder creates the order as it was received by the exchange
+func replayedOrder(instrument Instrument, o *JournaledOrder) *Order {
+	order := &Order{
+		Instrument:      instrument,
+		Id:              o.Id,
+		ExchangeId:      o.ExchangeId,
+		Price:           o.Price,
+		Side:            o.Side,
+		Quantity:        o.Quantity,
+		Remaining:       o.Quantity,
+		OrderType:       o.OrderType,
+		OrderState:      New,
+		StopPrice:       o.StopPrice,
+		TimeInForce:     o.TimeInForce,
+		DisplayQuantity: o.DisplayQuantity,
+		Account:         o.Account,
+	}
+	if o.ExpireTime != nil {
+		order.ExpireTime = *o.ExpireTime
+	}
+	return order
+}
+
+// cancelQuote cancels one side of the client's quote for the instrument
+func (e *exchange) cancelQuote(client exchangeClient, instrument Instrument, side Side) {
+	e.quiesce.RLock()
+	defer e.quiesce.RUnlock()
+
+	s := e.lockSession(client)
+	defer s.Unlock()
+
+	qp, ok := s.quotes[instrument]
+	if !ok {
+		return
+	}
+	so := qp.bid
+	if side == Sell {
+		so = qp.ask
+	}
+	if so.order == nil {
+		return
+	}
+
+	ob := e.lockOrderBook(instrument)
+	defer ob.Unlock()
+
+	if ob.remove(so) == nil {
+		e.journal.recordCancelQuote(so, "")
+		e.journal.commit()
+		e.risk.track(so)
+		sendMarketData(ob.marketEvent(nil))
+	}
+}
+
+// scheduleRecoveryCancel cancels the orders and quotes of the restored sessions that have not logged on again
+// after the timeout
+func (e *exchange) scheduleRecoveryCancel(rule recoveryCancel, timeout time.Duration) {
+	if rule == recoveryCancelNone {
+		return
+	}
+	time.AfterFunc(timeout, func() {
+		e.cancelRecovered(rule)
+	})
+}
+
+// cancelRecovered cancels the orders and quotes of the restored sessions that have not logged on again. The
+// session is kept, so a later logon can query its orders.
+func (e *exchange) cancelRecovered(rule recoveryCancel) {
+	e.quiesce.RLock()
+	defer e.quiesce.RUnlock()
+
+	defer e.journal.commit()
+
+	e.recovered.Range(func(key, value interface{}) bool {
+		s := value.(*session)
+		s.Lock()
+		defer s.Unlock()
+
+		if s.client.(*recoveredClient).bound() != nil {
+			return true
+		}
+
+		orderCount, quoteCount := 0, 0
+
+		if rule == recoveryCancelAll {
+			for _, order := range s.orders {
+				if !order.IsActive() {
+					continue
+				}
+				ob := e.lockOrderBook(order.Instrument)
+				so := sessionOrder{client: s.client, order: order}
+				if ob.remove(so) == nil {
+					order.RejectReason = recoveryCancelReason
+					e.journal.recordCancel(so, recoveryCancelReason)
+					e.sendOrderStatus(so)
+					sendMarketData(ob.marketEvent(nil))
+					orderCount++
+				}
+				ob.Unlock()
+			}
+		}
+		for instrument, qp := range s.quotes {
+			ob := e.lockOrderBook(instrument)
+			for _, so := range []sessionOrder{qp.bid, qp.ask} {
+				if so.order != nil && so.order.IsActive() && ob.remove(so) == nil {
+					e.journal.recordCancelQuote(so, recoveryCancelReason)
+					e.journal.commit()
+					e.risk.track(so)
+					sendMarketData(ob.marketEvent(nil))
+					quoteCount++
+				}
+			}
+			ob.Unlock()
+		}
+		if orderCount > 0 || quoteCount > 0 {
+			fmt.Println("session", s.id, "did not reconnect, cancelled", orderCount, "orders", quoteCount, "quotes")
+		}
+		return true
+	})
+}
@@ -0,0 +1,202 @@
+package exchange
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+func discardMarketData() {
+	if channels == nil {
+		ch := newChannel("")
+		channels = []*channel{ch}
+		go func() {
+			for range ch.events {
+			}
+		}()
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	discardMarketData()
+
+	path := filepath.Join(t.TempDir(), "test.journal")
+	j, err := openJournal(path, fsyncNever)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var a = namedExchangeClient("A")
+	var b = namedExchangeClient("B")
+	var i = NewInstrument(1000, "RECOVER")
+	IMap.Put(i)
+
+	e1 := &exchange{journal: j}
+	e1.CreateOrder(a, LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("10")))
+	e1.CreateOrder(b, LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("4")))
+	o3 := LimitOrder(i, Buy, NewDecimal("98"), NewDecimal("5"))
+	o3.Id = 3
+	e1.CreateOrder(b, o3)
+	e1.ModifyOrder(b, 3, NewDecimal("99"), NewDecimal("5"))
+	e1.Quote(a, i, NewDecimal("97"), NewDecimal("1"), NewDecimal("102"), NewDecimal("1"))
+	e1.cancelQuote(a, i, Sell)
+	j.close()
+
+	e2 := &exchange{}
+	count, err := e2.replayJournal(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count == 0 {
+		t.Fatal("no entries replayed")
+	}
+
+	books := func(e *exchange) string {
+		ob := e.lockOrderBook(i)
+		defer ob.Unlock()
+		return ob.String()
+	}
+	if books(e1) != books(e2) {
+		t.Error("recovered book does not match", books(e1), books(e2))
+	}
+	if e2.nextOrder != e1.nextOrder {
+		t.Error("wrong next order", e2.nextOrder, e1.nextOrder)
+	}
+
+	s := e2.newSession(b)
+	order, ok := s.orders[3]
+	if !ok || !order.Price.Equal(NewDecimal("99")) || order.OrderState != Booked {
+		t.Fatal("modified order not recovered", order)
+	}
+	if s.client.(*recoveredClient).bound() != b {
+		t.Error("session should be bound to the client")
+	}
+
+	e2.cancelRecovered(recoveryCancelAll)
+	if order.OrderState != Booked {
+		t.Error("order of reconnected session should not be cancelled")
+	}
+	ob := e2.lockOrderBook(i)
+	if len(ob.asks) != 0 || len(ob.bids) != 1 {
+		t.Error("orders of session A should be cancelled", ob)
+	}
+	ob.Unlock()
+}
+
+func TestReplayJournaledOutcome(t *testing.T) {
+	discardMarketData()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.journal")
+	j, err := openJournal(path, fsyncNever)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var a = namedExchangeClient("A")
+	var b = namedExchangeClient("B")
+	var i = NewInstrument(1032, "REPLAYOUTCOME")
+	IMap.Put(i)
+
+	e1 := &exchange{journal: j, risk: newTestRiskManager(t, "risk_max_open_orders=1")}
+	e1.CreateOrder(a, LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("10")))
+	rejected := LimitOrder(i, Sell, NewDecimal("101"), NewDecimal("10"))
+	rejected.Id = 2
+	e1.CreateOrder(a, rejected)
+	if rejected.OrderState != Rejected {
+		t.Fatal("order should be rejected by the risk limits", rejected)
+	}
+	time.Sleep(10 * time.Millisecond)
+	e1.CreateOrder(b, LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("4")))
+	j.close()
+
+	// the exchange restarts without the risk limits, the replayed trades are journaled again to compare them
+	replayed := filepath.Join(dir, "replayed.journal")
+	j2, err := openJournal(replayed, fsyncNever)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e2 := &exchange{journal: j2}
+	if _, err := e2.replayJournal(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	j2.close()
+
+	order := e2.newSession(a).orders[2]
+	if order == nil || order.OrderState != Rejected || order.RejectReason != rejected.RejectReason {
+		t.Fatal("rejected order should be replayed as rejected", order)
+	}
+
+	trades := func(path string) []*JournalEntry {
+		var entries []*JournalEntry
+		ReadJournal(path, func(entry *JournalEntry) error {
+			if entry.Type == JournalTrade {
+				entries = append(entries, entry)
+			}
+			return nil
+		})
+		return entries
+	}
+	original, again := trades(path), trades(replayed)
+	if len(original) != 1 || len(again) != 1 {
+		t.Fatal("wrong number of trades", original, again)
+	}
+	if !again[0].Time.Equal(original[0].Time) {
+		t.Error("replayed trade should have the journaled time", original[0].Time, again[0].Time)
+	}
+}
+
+func TestVolatilityAuctionRecovery(t *testing.T) {
+	discardMarketData()
+
+	path := filepath.Join(t.TempDir(), "test.journal")
+	j, err := openJournal(path, fsyncNever)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var a = namedExchangeClient("A")
+	var b = namedExchangeClient("B")
+	var i = NewInstrumentWithSpec(1037, "VOLRECOVER", InstrumentSpec{PriceBands: PriceBands{Dynamic: NewDecimal("1")}})
+	IMap.Put(i)
+
+	// the exchange stops during the volatility auction, so the uncross is not journaled
+	e1 := &exchange{journal: j}
+	e1.CreateOrder(a, LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("1")))
+	e1.CreateOrder(b, LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("1")))
+	e1.CreateOrder(a, LimitOrder(i, Buy, NewDecimal("90"), NewDecimal("1")))
+	e1.CreateOrder(b, LimitOrder(i, Sell, NewDecimal("90"), NewDecimal("1")))
+	if state := e1.GetTradingState(i); state != Auction {
+		t.Fatal("band breach should start a volatility auction", state)
+	}
+	j.close()
+
+	bands := priceBandConfig{auctionDuration: 10 * time.Millisecond}
+
+	e2 := &exchange{bands: bands}
+	if _, err := e2.replayJournal(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	snap := e2.snapshot()
+
+	e3 := &exchange{bands: bands}
+	e3.restoreSnapshot(snap)
+
+	for _, e := range []*exchange{e2, e3} {
+		if state := e.GetTradingState(i); state != Auction {
+			t.Fatal("recovered instrument should be in the volatility auction", state)
+		}
+		e.scheduleRecoveredAuctions()
+	}
+	time.Sleep(100 * time.Millisecond)
+	for _, e := range []*exchange{e2, e3} {
+		if state := e.GetTradingState(i); state != Open {
+			t.Error("recovered volatility auction should be uncrossed after the duration", state)
+		}
+		if open := e.openOrders(a, nil); len(open) != 0 {
+			t.Error("auction orders should trade when uncrossed", open)
+		}
+	}
+}
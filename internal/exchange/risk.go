@@ -0,0 +1,385 @@
+package exchange
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/robaho/fixed"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+// pre-trade risk limits per account. Orders and quotes are checked before they are booked, and any breach is
+// rejected with a reason. The account is the order account, or the session id if the order has no account, quotes
+// always use the session id. The positions include the open orders, so a limit cannot be exceeded by fills.
+// A zero limit is not checked. A session may only send orders for the accounts configured in risk_accounts, so it
+// cannot move to a fresh account to avoid its limits.
+
+type riskLimits struct {
+	MaxOrderSize     Fixed
+	MaxNotional      Fixed
+	MaxOpenOrders    int
+	MaxGrossPosition Fixed
+	MaxNetPosition   Fixed
+	// messages per second, orders, modifies and quotes are counted, cancels are always accepted
+	MaxMessageRate int
+}
+
+type riskManager struct {
+	props    Properties
+	accounts sync.Map // map of string to *riskAccount
+}
+
+type openOrder struct {
+	instrument Instrument
+	side       Side
+	remaining  Fixed
+	quote      bool
+}
+
+type riskAccount struct {
+	sync.Mutex
+	name       string
+	limits     riskLimits
+	open       map[*Order]openOrder
+	openOrders int // excludes quotes
+	openBuy    map[Instrument]Fixed
+	openSell   map[Instrument]Fixed
+	position   map[Instrument]Fixed
+	// the messages received in the current second
+	messages int
+	second   int64
+}
+
+// RiskUtilisation is the current usage of an account's limits, for display
+type RiskUtilisation struct {
+	Account       string
+	Limits        riskLimits
+	OpenOrders    int
+	GrossPosition Fixed
+	// the largest net position of any instrument
+	NetPosition Fixed
+	MessageRate int
+}
+
+func newRiskManager(props Properties) (*riskManager, error) {
+	rm := &riskManager{props: props}
+	// validate the defaults
+	_, err := rm.limits("")
+	return rm, err
+}
+
+// get returns the property for the account, a key suffixed by the account overrides the exchange default
+func (rm *riskManager) get(key string, account string) string {
+	return rm.props.GetString(key+"."+account, rm.props.GetString(key, "0"))
+}
+
+func (rm *riskManager) limits(account string) (riskLimits, error) {
+	var l riskLimits
+	var err error
+
+	fixedLimit := func(key string) Fixed {
+		v, err0 := NewSErr(rm.get(key, account))
+		if err0 != nil {
+			err = errors.New("invalid " + key + " for " + account)
+		}
+		return v
+	}
+	intLimit := func(key string) int {
+		v, err0 := strconv.Atoi(rm.get(key, account))
+		if err0 != nil {
+			err = errors.New("invalid " + key + " for " + account)
+		}
+		return v
+	}
+
+	l.MaxOrderSize = fixedLimit("risk_max_order_size")
+	l.MaxNotional = fixedLimit("risk_max_notional")
+	l.MaxOpenOrders = intLimit("risk_max_open_orders")
+	l.MaxGrossPosition = fixedLimit("risk_max_gross_position")
+	l.MaxNetPosition = fixedLimit("risk_max_net_position")
+	l.MaxMessageRate = intLimit("risk_max_messages_per_second")
+
+	return l, err
+}
+
+// accountName returns the account of the order, quotes and orders without an account use the session id
+func accountName(so sessionOrder) string {
+	if so.order.Account != "" && !isQuote(so.order) {
+		return so.order.Account
+	}
+	return so.client.SessionID()
+}
+
+// allowedAccount returns true if the session may send orders for the account. The accounts are a comma separated
+// list in risk_accounts, which can be set for a session by adding the session id as a suffix, * allows any account
+func (rm *riskManager) allowedAccount(session string, account string) bool {
+	if account == "" || account == session {
+		return true
+	}
+	accounts := rm.props.GetString("risk_accounts."+session, rm.props.GetString("risk_accounts", ""))
+	for _, allowed := range strings.Split(accounts, ",") {
+		if allowed = strings.TrimSpace(allowed); allowed == "*" || allowed == account {
+			return true
+		}
+	}
+	return false
+}
+
+func isQuote(order *Order) bool {
+	return strings.HasPrefix(order.ExchangeId, "quote.")
+}
+
+func (rm *riskManager) account(name string) *riskAccount {
+	a, ok := rm.accounts.Load(name)
+	if !ok {
+		limits, err := rm.limits(name)
+		if err != nil {
+			// the defaults were validated on start
+			limits, _ = rm.limits("")
+		}
+		a, _ = rm.accounts.LoadOrStore(name, &riskAccount{
+			name:     name,
+			limits:   limits,
+			open:     make(map[*Order]openOrder),
+			openBuy:  make(map[Instrument]Fixed),
+			openSell: make(map[Instrument]Fixed),
+			position: make(map[Instrument]Fixed),
+		})
+	}
+	return a.(*riskAccount)
+}
+
+// checkOrder returns the reason the order should be rejected, or "" if it is within the limits. The exposure of
+// replaces is excluded, e.g. the order being modified. price is used for the notional, zero if unknown.
+func (rm *riskManager) checkOrder(so sessionOrder, price Fixed, quantity Fixed, replaces *Order) string {
+	if rm == nil {
+		return ""
+	}
+	if !isQuote(so.order) && !rm.allowedAccount(so.client.SessionID(), so.order.Account) {
+		return "account " + so.order.Account + " is not allowed for the session"
+	}
+	a := rm.account(accountName(so))
+	a.Lock()
+	defer a.Unlock()
+
+	if reason := a.countMessage(so.time); reason != "" {
+		return reason
+	}
+	return a.check(so.order, price, quantity, replaces)
+}
+
+// checkQuote checks both sides of a quote, replacing the previous quote
+func (rm *riskManager) checkQuote(client exchangeClient, now time.Time, bid *Order, ask *Order, previous quotePair) string {
+	if rm == nil {
+		return ""
+	}
+	a := rm.account(client.SessionID())
+	a.Lock()
+	defer a.Unlock()
+
+	if reason := a.countMessage(now); reason != "" {
+		return reason
+	}
+	if bid != nil {
+		if reason := a.check(bid, bid.Price, bid.Quantity, previous.bid.order); reason != "" {
+			return reason
+		}
+	}
+	if ask != nil {
+		if reason := a.check(ask, ask.Price, ask.Quantity, previous.ask.order); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+func (a *riskAccount) countMessage(now time.Time) string {
+	if second := now.Unix(); second != a.second {
+		a.second = second
+		a.messages = 0
+	}
+	a.messages++
+	if a.limits.MaxMessageRate > 0 && a.messages > a.limits.MaxMessageRate {
+		return "message rate exceeds the limit of " + strconv.Itoa(a.limits.MaxMessageRate) + " per second"
+	}
+	return ""
+}
+
+func (a *riskAccount) check(order *Order, price Fixed, quantity Fixed, replaces *Order) string {
+	l := a.limits
+
+	if !l.MaxOrderSize.IsZero() && quantity.GreaterThan(l.MaxOrderSize) {
+		return "order size " + quantity.String() + " exceeds the limit " + l.MaxOrderSize.String()
+	}
+	if !l.MaxNotional.IsZero() && !price.IsZero() {
+		if notional := price.Mul(quantity); notional.GreaterThan(l.MaxNotional) {
+			return "order notional " + notional.String() + " exceeds the limit " + l.MaxNotional.String()
+		}
+	}
+
+	openOrders := a.openOrders
+	instrument := order.Instrument
+	buy, sell := a.openBuy[instrument], a.openSell[instrument]
+	gross := a.grossPosition()
+
+	if previous, ok := a.open[replaces]; ok && replaces != nil {
+		if !previous.quote {
+			openOrders--
+		}
+		if previous.instrument == instrument {
+			if previous.side == Buy {
+				buy = buy.Sub(previous.remaining)
+			} else {
+				sell = sell.Sub(previous.remaining)
+			}
+		}
+		gross = gross.Sub(previous.remaining)
+	}
+
+	if l.MaxOpenOrders > 0 && !isQuote(order) && openOrders >= l.MaxOpenOrders {
+		return "open orders exceed the limit of " + strconv.Itoa(l.MaxOpenOrders)
+	}
+
+	// the worst case positions if all open orders are filled
+	var net Fixed
+	if order.Side == Buy {
+		net = a.position[instrument].Add(buy).Add(quantity)
+	} else {
+		net = a.position[instrument].Sub(sell).Sub(quantity)
+	}
+	gross = gross.Add(quantity)
+
+	if !l.MaxNetPosition.IsZero() && net.Abs().GreaterThan(l.MaxNetPosition) {
+		return "net position " + net.String() + " would exceed the limit " + l.MaxNetPosition.String()
+	}
+	if !l.MaxGrossPosition.IsZero() && gross.GreaterThan(l.MaxGrossPosition) {
+		return "gross position " + gross.String() + " would exceed the limit " + l.MaxGrossPosition.String()
+	}
+	return ""
+}
+
+// grossPosition is the sum of the absolute positions, and the open orders
+func (a *riskAccount) grossPosition() Fixed {
+	gross := ZERO
+	for _, position := range a.position {
+		gross = gross.Add(position.Abs())
+	}
+	for _, qty := range a.openBuy {
+		gross = gross.Add(qty)
+	}
+	for _, qty := range a.openSell {
+		gross = gross.Add(qty)
+	}
+	return gross
+}
+
+// track updates the open order exposure to the current state of the order. It can be called any number of times.
+func (rm *riskManager) track(so sessionOrder) {
+	if rm == nil || so.order == nil {
+		return
+	}
+	a := rm.account(accountName(so))
+	a.Lock()
+	defer a.Unlock()
+
+	a.track(so.order)
+}
+
+func (a *riskAccount) track(order *Order) {
+	if previous, ok := a.open[order]; ok {
+		a.addOpen(previous, -1)
+		delete(a.open, order)
+	}
+	if order.IsActive() && order.OrderState != New {
+		current := openOrder{order.Instrument, order.Side, order.Remaining, isQuote(order)}
+		a.addOpen(current, 1)
+		a.open[order] = current
+	}
+}
+
+func (a *riskAccount) addOpen(o openOrder, sign int) {
+	qty := o.remaining
+	if sign < 0 {
+		qty = ZERO.Sub(qty)
+	}
+	if o.side == Buy {
+		a.openBuy[o.instrument] = a.openBuy[o.instrument].Add(qty)
+	} else {
+		a.openSell[o.instrument] = a.openSell[o.instrument].Add(qty)
+	}
+	if !o.quote {
+		a.openOrders += sign
+	}
+}
+
+// onTrades updates the positions, and the open order exposure of the orders that traded
+func (rm *riskManager) onTrades(trades []trade) {
+	if rm == nil {
+		return
+	}
+	for _, t := range trades {
+		rm.fill(t.buyer, t.quantity)
+		rm.fill(t.seller, ZERO.Sub(t.quantity))
+	}
+}
+
+func (rm *riskManager) fill(so sessionOrder, qty Fixed) {
+	a := rm.account(accountName(so))
+	a.Lock()
+	defer a.Unlock()
+
+	instrument := so.order.Instrument
+	a.position[instrument] = a.position[instrument].Add(qty)
+	a.track(so.order)
+}
+
+// utilisation returns the current usage of the limits for all accounts, ordered by account
+func (rm *riskManager) utilisation() []RiskUtilisation {
+	var accounts []RiskUtilisation
+	if rm == nil {
+		return accounts
+	}
+	rm.accounts.Range(func(key, value interface{}) bool {
+		a := value.(*riskAccount)
+		a.Lock()
+		defer a.Unlock()
+
+		u := RiskUtilisation{Account: a.name, Limits: a.limits, OpenOrders: a.openOrders}
+		u.GrossPosition = a.grossPosition()
+		for _, position := range a.position {
+			if position.Abs().GreaterThan(u.NetPosition.Abs()) {
+				u.NetPosition = position
+			}
+		}
+		if a.second == time.Now().Unix() {
+			u.MessageRate = a.messages
+		}
+		accounts = append(accounts, u)
+		return true
+	})
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Account < accounts[j].Account
+	})
+	return accounts
+}
+
+// RiskUtilisation returns the current usage of the risk limits for all accounts
+func (e *exchange) RiskUtilisation() []RiskUtilisation {
+	return e.risk.utilisation()
+}
+
+// notionalPrice returns the price used for the order notional, market orders use the last trade price
+func (ob *orderBook) notionalPrice(so sessionOrder) Fixed {
+	if so.isMarket() {
+		if ob.hasLastPrice {
+			return ob.lastPrice
+		}
+		return ZERO
+	}
+	return so.order.Price
+}
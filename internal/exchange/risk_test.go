@@ -0,0 +1,156 @@
+package exchange
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/robaho/fixed"
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+func newTestRiskManager(t *testing.T, settings string) *riskManager {
+	props, _ := NewPropertiesFromReader(strings.NewReader(settings))
+	rm, err := newRiskManager(props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rm
+}
+
+func TestRiskOrderLimits(t *testing.T) {
+	rm := newTestRiskManager(t, "risk_max_order_size=100\nrisk_max_notional=5000\nrisk_max_open_orders=2\nrisk_max_open_orders.BIG=10")
+	var ex = testExchangeClient{}
+	var i = NewInstrument(1, "TEST")
+
+	order := LimitOrder(i, Buy, NewDecimal("10"), NewDecimal("101"))
+	if reason := rm.checkOrder(sessionOrder{ex, order, time.Now()}, order.Price, order.Quantity, nil); reason == "" {
+		t.Error("order size should be rejected")
+	}
+	order = LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("100"))
+	if reason := rm.checkOrder(sessionOrder{ex, order, time.Now()}, order.Price, order.Quantity, nil); reason == "" {
+		t.Error("notional should be rejected")
+	}
+
+	for j := 0; j < 2; j++ {
+		order = LimitOrder(i, Buy, NewDecimal("10"), NewDecimal("10"))
+		order.OrderState = Booked
+		so := sessionOrder{ex, order, time.Now()}
+		if reason := rm.checkOrder(so, order.Price, order.Quantity, nil); reason != "" {
+			t.Error("order should be accepted", reason)
+		}
+		rm.track(so)
+	}
+	next := LimitOrder(i, Buy, NewDecimal("10"), NewDecimal("10"))
+	if reason := rm.checkOrder(sessionOrder{ex, next, time.Now()}, next.Price, next.Quantity, nil); reason == "" {
+		t.Error("open orders should be rejected")
+	}
+
+	// a cancelled order is no longer open
+	order.OrderState = Cancelled
+	rm.track(sessionOrder{ex, order, time.Now()})
+	if reason := rm.checkOrder(sessionOrder{ex, next, time.Now()}, next.Price, next.Quantity, nil); reason != "" {
+		t.Error("order should be accepted", reason)
+	}
+
+	big := LimitOrder(i, Buy, NewDecimal("10"), NewDecimal("10"))
+	big.Account = "BIG"
+	if a := rm.account(accountName(sessionOrder{ex, big, time.Now()})); a.limits.MaxOpenOrders != 10 {
+		t.Error("account limit should override the default", a.limits)
+	}
+}
+
+func TestRiskPositionLimits(t *testing.T) {
+	rm := newTestRiskManager(t, "risk_max_net_position=100\nrisk_max_gross_position=150")
+	var ex = testExchangeClient{}
+	var i = NewInstrument(1, "TEST")
+
+	buy := LimitOrder(i, Buy, NewDecimal("10"), NewDecimal("80"))
+	buy.OrderState = Booked
+	buyer := sessionOrder{ex, buy, time.Now()}
+	rm.track(buyer)
+
+	// the open buy counts towards the net position
+	order := LimitOrder(i, Buy, NewDecimal("10"), NewDecimal("30"))
+	if reason := rm.checkOrder(sessionOrder{ex, order, time.Now()}, order.Price, order.Quantity, nil); reason == "" {
+		t.Error("net position should be rejected")
+	}
+	// unless it is the order being modified
+	if reason := rm.checkOrder(buyer, buy.Price, NewDecimal("90"), buy); reason != "" {
+		t.Error("modify should be accepted", reason)
+	}
+
+	// a fill moves the quantity from open to the position
+	seller := sessionOrder{namedExchangeClient("other"), LimitOrder(i, Sell, NewDecimal("10"), NewDecimal("80")), time.Now()}
+	buy.Remaining = ZERO
+	buy.OrderState = Filled
+	rm.onTrades([]trade{{buyer: buyer, seller: seller, price: NewDecimal("10"), quantity: NewDecimal("80")}})
+
+	a := rm.account(accountName(buyer))
+	if !a.position[i].Equal(NewDecimal("80")) || a.openOrders != 0 || !a.openBuy[i].IsZero() {
+		t.Error("wrong position after fill", a.position[i], a.openOrders, a.openBuy[i])
+	}
+
+	// selling reduces the net position, but increases the gross
+	order = LimitOrder(i, Sell, NewDecimal("10"), NewDecimal("75"))
+	if reason := rm.checkOrder(sessionOrder{ex, order, time.Now()}, order.Price, order.Quantity, nil); reason == "" {
+		t.Error("gross position should be rejected")
+	}
+	order = LimitOrder(i, Sell, NewDecimal("10"), NewDecimal("70"))
+	if reason := rm.checkOrder(sessionOrder{ex, order, time.Now()}, order.Price, order.Quantity, nil); reason != "" {
+		t.Error("order should be accepted", reason)
+	}
+}
+
+func TestRiskMessageRate(t *testing.T) {
+	rm := newTestRiskManager(t, "risk_max_messages_per_second=2")
+	a := rm.account("A")
+	now := time.Now()
+	if a.countMessage(now) != "" || a.countMessage(now) != "" {
+		t.Error("messages should be accepted")
+	}
+	if a.countMessage(now) == "" {
+		t.Error("message rate should be rejected")
+	}
+	if a.countMessage(now.Add(time.Second)) != "" {
+		t.Error("message should be accepted in the next second")
+	}
+}
+
+func TestRiskAccounts(t *testing.T) {
+	discardMarketData()
+
+	var a = namedExchangeClient("A")
+	var i = NewInstrument(1033, "RISKACCOUNTS")
+	IMap.Put(i)
+
+	e := &exchange{risk: newTestRiskManager(t, "risk_max_open_orders=1\nrisk_accounts.A=ACCOUNT1")}
+
+	newOrder := func(id OrderID, account string) *Order {
+		order := LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("1"))
+		order.Id = id
+		order.Account = account
+		return order
+	}
+	if _, err := e.CreateOrder(a, newOrder(1, "")); err != nil {
+		t.Fatal("order should be accepted", err)
+	}
+	if _, err := e.CreateOrder(a, newOrder(2, "")); err == nil {
+		t.Error("open orders should be rejected")
+	}
+	order := newOrder(3, "OTHER")
+	if _, err := e.CreateOrder(a, order); err == nil || !strings.Contains(order.RejectReason, "not allowed") {
+		t.Error("order for another account should be rejected", order.RejectReason)
+	}
+	if _, err := e.CreateOrder(a, newOrder(4, "ACCOUNT1")); err != nil {
+		t.Error("order for a configured account should be accepted", err)
+	}
+	if _, err := e.CreateOrder(a, newOrder(5, "ACCOUNT1")); err == nil {
+		t.Error("open orders of the configured account should be rejected")
+	}
+
+	rm := newTestRiskManager(t, "risk_accounts=*")
+	if !rm.allowedAccount("A", "ANY") {
+		t.Error("any account should be allowed")
+	}
+}
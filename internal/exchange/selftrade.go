@@ -0,0 +1,120 @@
+package exchange
+
+import (
+	"errors"
+
+	. "github.com/robaho/fixed"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+// self trade prevention stops orders from the same session, or the same account, from trading with each other
+
+type stpMode string
+
+const (
+	stpNone         stpMode = "none"
+	stpCancelNewest stpMode = "cancel_newest"
+	stpCancelOldest stpMode = "cancel_oldest"
+	stpCancelBoth   stpMode = "cancel_both"
+	// decrement reduces both orders by the smaller remaining quantity, cancelling the smaller order
+	stpDecrement stpMode = "decrement"
+)
+
+// the reasons reported to the affected orders
+const (
+	selfTradeCancelled   = "cancelled by self trade prevention"
+	selfTradeDecremented = "decremented by self trade prevention"
+)
+
+type selfTradePrevention struct {
+	mode stpMode
+	// if true, orders are matched by Order.Account rather than session
+	byAccount bool
+}
+
+func newSelfTradePrevention(props Properties) (selfTradePrevention, error) {
+	stp := selfTradePrevention{}
+
+	stp.mode = stpMode(props.GetString("self_trade_prevention", string(stpNone)))
+	switch stp.mode {
+	case stpNone, stpCancelNewest, stpCancelOldest, stpCancelBoth, stpDecrement:
+	default:
+		return stp, errors.New("invalid self_trade_prevention " + string(stp.mode))
+	}
+
+	switch props.GetString("self_trade_key", "session") {
+	case "session":
+	case "account":
+		stp.byAccount = true
+	default:
+		return stp, errors.New("invalid self_trade_key " + props.GetString("self_trade_key", ""))
+	}
+	return stp, nil
+}
+
+func (stp selfTradePrevention) isSelfTrade(bid sessionOrder, ask sessionOrder) bool {
+	switch {
+	case stp.mode == "" || stp.mode == stpNone:
+		return false
+	case stp.byAccount:
+		return bid.order.Account != "" && bid.order.Account == ask.order.Account
+	default:
+		return bid.client == ask.client
+	}
+}
+
+// preventSelfTrade applies the configured action to the crossing bid and ask, so that at least one of them is
+// removed from the top of the book
+func (ob *orderBook) preventSelfTrade(bidNode *listNode, askNode *listNode) {
+	bid := bidNode.order
+	ask := askNode.order
+
+	newest, oldest := bid, ask
+	if bid.time.Before(ask.time) {
+		newest, oldest = ask, bid
+	}
+
+	switch ob.stp.mode {
+	case stpCancelNewest:
+		ob.cancelSelfTrade(newest)
+	case stpCancelOldest:
+		ob.cancelSelfTrade(oldest)
+	case stpCancelBoth:
+		ob.cancelSelfTrade(bid)
+		ob.cancelSelfTrade(ask)
+	case stpDecrement:
+		qty := MinDecimal(bid.order.Remaining, ask.order.Remaining)
+		ob.decrementSelfTrade(bidNode, qty)
+		ob.decrementSelfTrade(askNode, qty)
+	}
+}
+
+func (ob *orderBook) cancelSelfTrade(so sessionOrder) {
+	ob.remove(so)
+	so.order.RejectReason = selfTradeCancelled
+	ob.changed = append(ob.changed, so)
+}
+
+// decrementSelfTrade reduces the quantity of an order at the top of the book
+func (ob *orderBook) decrementSelfTrade(node *listNode, qty Fixed) {
+	so := node.order
+	so.order.Quantity = so.order.Quantity.Sub(qty)
+	so.order.Remaining = so.order.Remaining.Sub(qty)
+	node.visible = node.visible.Sub(qty)
+
+	if so.order.Remaining.IsZero() {
+		ob.cancelSelfTrade(so)
+		return
+	}
+	so.order.RejectReason = selfTradeDecremented
+	ob.changed = append(ob.changed, so)
+
+	if node.isExhausted() {
+		if so.order.Side == Buy {
+			ob.bids[0].orderList.refresh(so)
+		} else {
+			ob.asks[0].orderList.refresh(so)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/robaho/fixed"
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+type namedExchangeClient string
+
+func (c namedExchangeClient) SendOrderStatus(so sessionOrder) {}
+func (c namedExchangeClient) SendFill(so sessionOrder, price Fixed, quantity Fixed, remaining Fixed) {}
+func (c namedExchangeClient) SessionID() string {
+	return string(c)
+}
+
+func TestSelfTradeCancelNewest(t *testing.T) {
+	var ob = orderBook{stp: selfTradePrevention{mode: stpCancelNewest}}
+	var a = namedExchangeClient("A")
+	var b = namedExchangeClient("B")
+
+	var i = Equity{}
+
+	var o1 = LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("10"))
+	ob.add(sessionOrder{a, o1, time.Now()})
+	var o2 = LimitOrder(i, Sell, NewDecimal("101"), NewDecimal("10"))
+	ob.add(sessionOrder{b, o2, time.Now()})
+
+	var o3 = LimitOrder(i, Buy, NewDecimal("101"), NewDecimal("10"))
+	trades, _ := ob.add(sessionOrder{a, o3, time.Now()})
+
+	if len(trades) != 0 {
+		t.Error("should not trade", trades)
+	}
+	if o3.OrderState != Cancelled || o3.RejectReason != selfTradeCancelled {
+		t.Error("newest should be cancelled", o3)
+	}
+	if o1.OrderState != Booked {
+		t.Error("oldest should remain", o1)
+	}
+	changed, _ := ob.takeChanged()
+	if len(changed) != 1 || changed[0].order != o3 {
+		t.Error("wrong changed", changed)
+	}
+}
+
+func TestSelfTradeCancelOldest(t *testing.T) {
+	var ob = orderBook{stp: selfTradePrevention{mode: stpCancelOldest}}
+	var a = namedExchangeClient("A")
+	var b = namedExchangeClient("B")
+
+	var i = Equity{}
+
+	var o1 = LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("10"))
+	ob.add(sessionOrder{a, o1, time.Now()})
+	var o2 = LimitOrder(i, Sell, NewDecimal("101"), NewDecimal("10"))
+	ob.add(sessionOrder{b, o2, time.Now()})
+
+	var o3 = LimitOrder(i, Buy, NewDecimal("101"), NewDecimal("10"))
+	trades, _ := ob.add(sessionOrder{a, o3, time.Now()})
+
+	if len(trades) != 1 || trades[0].seller.order != o2 {
+		t.Error("should trade with the other session", trades)
+	}
+	if o1.OrderState != Cancelled || o1.RejectReason != selfTradeCancelled {
+		t.Error("oldest should be cancelled", o1)
+	}
+}
+
+func TestFillOrKillSelfTrade(t *testing.T) {
+	var a = namedExchangeClient("A")
+	var b = namedExchangeClient("B")
+
+	var i = Equity{}
+
+	for _, mode := range []stpMode{stpCancelNewest, stpCancelOldest, stpCancelBoth, stpDecrement} {
+		var ob = orderBook{stp: selfTradePrevention{mode: mode}}
+
+		ob.add(sessionOrder{b, LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("5")), time.Now()})
+		ob.add(sessionOrder{a, LimitOrder(i, Sell, NewDecimal("101"), NewDecimal("5")), time.Now()})
+		ob.add(sessionOrder{b, LimitOrder(i, Sell, NewDecimal("101"), NewDecimal("5")), time.Now()})
+
+		// only 10 can trade with the other session, and the own order would stop the match for cancel newest and both
+		fok := LimitOrder(i, Buy, NewDecimal("101"), NewDecimal("15"))
+		fok.TimeInForce = FillOrKill
+		trades, _ := ob.add(sessionOrder{a, fok, time.Now()})
+		if mode == stpDecrement {
+			// the own order reduces the fok to 10
+			if len(trades) != 2 || fok.OrderState != Filled || !fok.Quantity.Equal(NewDecimal("10")) {
+				t.Error("decremented fok should be filled", trades, fok)
+			}
+			continue
+		}
+		if len(trades) != 0 || fok.OrderState != Cancelled || !fok.Remaining.Equal(NewDecimal("15")) {
+			t.Error("fok should be killed without any fills", mode, trades, fok)
+		}
+
+		fok = LimitOrder(i, Buy, NewDecimal("101"), NewDecimal("10"))
+		fok.TimeInForce = FillOrKill
+		trades, _ = ob.add(sessionOrder{a, fok, time.Now()})
+		if mode == stpCancelOldest {
+			if len(trades) != 2 || fok.OrderState != Filled {
+				t.Error("fok should be filled", mode, trades, fok)
+			}
+		} else if len(trades) != 0 || fok.OrderState != Cancelled || !fok.Remaining.Equal(NewDecimal("10")) {
+			t.Error("fok should be killed before reaching its own order", mode, trades, fok)
+		}
+	}
+}
+
+func TestSelfTradeDecrementByAccount(t *testing.T) {
+	var ob = orderBook{stp: selfTradePrevention{mode: stpDecrement, byAccount: true}}
+	var a = namedExchangeClient("A")
+	var b = namedExchangeClient("B")
+
+	var i = Equity{}
+
+	var o1 = LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("10"))
+	o1.Account = "ACCT1"
+	ob.add(sessionOrder{a, o1, time.Now()})
+
+	// different session, but same account
+	var o2 = LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("4"))
+	o2.Account = "ACCT1"
+	trades, _ := ob.add(sessionOrder{b, o2, time.Now()})
+
+	if len(trades) != 0 {
+		t.Error("should not trade", trades)
+	}
+	if o2.OrderState != Cancelled || !o2.Quantity.IsZero() {
+		t.Error("smaller order should be cancelled", o2)
+	}
+	if o1.OrderState != Booked || !o1.Remaining.Equal(NewDecimal("6")) || o1.RejectReason != selfTradeDecremented {
+		t.Error("larger order should be decremented", o1)
+	}
+
+	b2 := ob.buildBook()
+	if len(b2.Bids) != 0 || len(b2.Asks) != 1 || !b2.Asks[0].Quantity.Equal(NewDecimal("6")) {
+		t.Error("wrong book", b2)
+	}
+}
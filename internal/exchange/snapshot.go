@@ -0,0 +1,411 @@
+package exchange
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	. "github.com/robaho/fixed"
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+// A snapshot is a point in time copy of the books, sessions and statistics, taken while no commands are being
+// processed. Recovery loads the latest snapshot and replays only the journal entries after it. Snapshots are JSON
+// with a version number, a snapshot written by an older version is upgraded when it is loaded.
+
+const snapshotVersion = 1
+
+type snapshot struct {
+	Version int
+	// the sequence number of the last journal entry included in the snapshot
+	Seq         uint64
+	Time        time.Time
+	NextOrder   int32
+	NextTradeID int64
+	Sessions    []sessionSnapshot
+	Books       []bookSnapshot
+	Statistics  []Statistics
+	Positions   []positionSnapshot
+	Blocked     []killSwitchSnapshot
+}
+
+type orderSnapshot struct {
+	Symbol string
+	JournaledOrder
+}
+
+type sessionSnapshot struct {
+	Id     string
+	Orders []orderSnapshot
+	Quotes []orderSnapshot
+}
+
+// restingOrder is an order in a book, in priority order. The order itself is in the session.
+type restingOrder struct {
+	Session    string
+	ExchangeId string
+	// the visible slice of an iceberg order
+	Visible Fixed
+}
+
+type bookSnapshot struct {
+	Symbol         string
+	State          TradingState
+	LastPrice      Fixed
+	HasLastPrice   bool
+	ReferencePrice Fixed
+	HasReference   bool
+	Bids           []restingOrder
+	Asks           []restingOrder
+	Stops          []restingOrder
+
+	// the state is a volatility auction, see priceband.go
+	VolatilityAuction bool
+}
+
+type positionSnapshot struct {
+	Account  string
+	Symbol   string
+	Position Fixed
+}
+
+type killSwitchSnapshot struct {
+	Session string
+	Account string
+	Symbol  string
+	Side    Side
+}
+
+type snapshotConfig struct {
+	dir      string
+	interval time.Duration
+	keep     int
+}
+
+func newSnapshotConfig(props Properties) (snapshotConfig, error) {
+	var c snapshotConfig
+	var err error
+	c.dir = props.GetString("snapshot_dir", "")
+	c.interval, err = time.ParseDuration(props.GetString("snapshot_interval", "0"))
+	if err != nil {
+		return c, errors.New("invalid snapshot_interval " + err.Error())
+	}
+	c.keep, err = strconv.Atoi(props.GetString("snapshot_keep", "3"))
+	if err != nil {
+		return c, errors.New("invalid snapshot_keep " + err.Error())
+	}
+	return c, nil
+}
+
+// TakeSnapshot writes a snapshot of the exchange, and returns the file name
+func (e *exchange) TakeSnapshot() (string, error) {
+	if e.snapshots.dir == "" {
+		return "", errors.New("snapshot_dir is not configured")
+	}
+	return writeSnapshot(e.snapshots.dir, e.snapshot(), e.snapshots.keep)
+}
+
+// snapshot copies the state of the exchange, commands are blocked while it is taken
+func (e *exchange) snapshot() *snapshot {
+	e.quiesce.Lock()
+	defer e.quiesce.Unlock()
+
+	snap := &snapshot{Version: snapshotVersion, Time: time.Now()}
+	snap.Seq = e.journal.lastSeq()
+	snap.NextOrder = atomic.LoadInt32(&e.nextOrder)
+	snap.NextTradeID = atomic.LoadInt64(&nextTradeID)
+
+	e.sessions.Range(func(key, value interface{}) bool {
+		s := value.(*session)
+		s.Lock()
+		defer s.Unlock()
+
+		ss := sessionSnapshot{Id: s.id}
+		for _, order := range s.orders {
+			ss.Orders = append(ss.Orders, orderSnapshot{order.Symbol(), *journalOrder(order)})
+		}
+		for _, qp := range s.quotes {
+			for _, so := range []sessionOrder{qp.bid, qp.ask} {
+				if so.order != nil {
+					ss.Quotes = append(ss.Quotes, orderSnapshot{so.order.Symbol(), *journalOrder(so.order)})
+				}
+			}
+		}
+		snap.Sessions = append(snap.Sessions, ss)
+		return true
+	})
+
+	e.orderBooks.Range(func(key, value interface{}) bool {
+		ob := value.(*orderBook)
+		ob.Lock()
+		defer ob.Unlock()
+
+		bs := bookSnapshot{Symbol: ob.Symbol(), State: ob.state, LastPrice: ob.lastPrice, HasLastPrice: ob.hasLastPrice,
+			ReferencePrice: ob.referencePrice, HasReference: ob.hasReference, VolatilityAuction: ob.volatilityAuction}
+		resting := func(levels []priceLevel) []restingOrder {
+			var orders []restingOrder
+			for _, level := range levels {
+				for node := level.head; node != nil; node = node.next {
+					orders = append(orders, restingOrder{node.order.client.SessionID(), node.order.order.ExchangeId, node.visible})
+				}
+			}
+			return orders
+		}
+		bs.Bids = resting(ob.bids)
+		bs.Asks = resting(ob.asks)
+		for _, so := range ob.stops {
+			bs.Stops = append(bs.Stops, restingOrder{Session: so.client.SessionID(), ExchangeId: so.order.ExchangeId})
+		}
+		snap.Books = append(snap.Books, bs)
+		if ob.stats.Symbol != "" {
+			snap.Statistics = append(snap.Statistics, ob.stats)
+		}
+		return true
+	})
+
+	if e.risk != nil {
+		e.risk.accounts.Range(func(key, value interface{}) bool {
+			a := value.(*riskAccount)
+			a.Lock()
+			defer a.Unlock()
+			for instrument, position := range a.position {
+				if !position.IsZero() {
+					snap.Positions = append(snap.Positions, positionSnapshot{a.name, instrument.Symbol(), position})
+				}
+			}
+			return true
+		})
+	}
+
+	for _, k := range e.Blocked() {
+		ks := killSwitchSnapshot{Session: k.SessionID, Account: k.Account, Side: k.Side}
+		if k.Instrument != nil {
+			ks.Symbol = k.Instrument.Symbol()
+		}
+		snap.Blocked = append(snap.Blocked, ks)
+	}
+
+	return snap
+}
+
+func snapshotFile(dir string, seq uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("snapshot-%020d.json", seq))
+}
+
+// writeSnapshot writes the snapshot atomically, and removes all but the latest keep snapshots
+func writeSnapshot(dir string, snap *snapshot, keep int) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := snapshotFile(dir, snap.Seq)
+	tmp := path + ".tmp"
+
+	file, err := os.Create(tmp)
+	if err != nil {
+		return "", err
+	}
+	err = json.NewEncoder(file).Encode(snap)
+	if err == nil {
+		err = file.Sync()
+	}
+	if err0 := file.Close(); err == nil {
+		err = err0
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	files, _ := snapshotFiles(dir)
+	for keep > 0 && len(files) > keep {
+		os.Remove(files[len(files)-1])
+		files = files[:len(files)-1]
+	}
+	return path, nil
+}
+
+// snapshotFiles returns the snapshots in the directory, latest first
+func snapshotFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "snapshot-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	// the sequence number is zero padded, so the names sort in sequence order
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+	return files, nil
+}
+
+func readSnapshot(path string) (*snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	snap := &snapshot{}
+	if err := json.Unmarshal(data, snap); err != nil {
+		return nil, err
+	}
+	return snap, upgradeSnapshot(snap)
+}
+
+// upgradeSnapshot converts a snapshot written by an earlier version to the current version
+func upgradeSnapshot(snap *snapshot) error {
+	if snap.Version < 1 || snap.Version > snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	// version 1 is the current version
+	return nil
+}
+
+// latestSnapshot returns the latest readable snapshot, or nil if there are none
+func latestSnapshot(dir string) *snapshot {
+	files, err := snapshotFiles(dir)
+	if err != nil {
+		return nil
+	}
+	for _, file := range files {
+		snap, err := readSnapshot(file)
+		if err == nil {
+			fmt.Println("loaded snapshot", file)
+			return snap
+		}
+		fmt.Println("unable to load snapshot", file, err)
+	}
+	return nil
+}
+
+// restoreSnapshot rebuilds the books and sessions from the snapshot, the sessions are restored as they are when
+// replaying the journal
+func (e *exchange) restoreSnapshot(snap *snapshot) {
+	atomic.StoreInt32(&e.nextOrder, snap.NextOrder)
+	atomic.StoreInt64(&nextTradeID, snap.NextTradeID)
+
+	// the orders by session and exchange id, to find the resting orders
+	orders := make(map[string]sessionOrder)
+	key := func(session string, exchangeId string) string {
+		return session + "/" + exchangeId
+	}
+
+	restore := func(o orderSnapshot) *Order {
+		instrument := IMap.GetBySymbol(o.Symbol)
+		if instrument == nil {
+			fmt.Println("skipping snapshot order", o.ExchangeId, "unknown symbol", o.Symbol)
+			return nil
+		}
+		order := replayedOrder(instrument, &o.JournaledOrder)
+		order.Remaining = o.Remaining
+		order.OrderState = o.OrderState
+		order.RejectReason = o.RejectReason
+		order.Triggered = o.Triggered
+		return order
+	}
+
+	for _, ss := range snap.Sessions {
+		client := e.recoveredSession(ss.Id)
+		s := e.lockSession(client)
+		for _, o := range ss.Orders {
+			if order := restore(o); order != nil {
+				s.orders[order.Id] = order
+				orders[key(ss.Id, order.ExchangeId)] = sessionOrder{client: client, order: order}
+			}
+		}
+		for _, o := range ss.Quotes {
+			order := restore(o)
+			if order == nil {
+				continue
+			}
+			so := sessionOrder{client: client, order: order}
+			qp := s.quotes[order.Instrument]
+			if order.Side == Buy {
+				qp.bid = so
+			} else {
+				qp.ask = so
+			}
+			s.quotes[order.Instrument] = qp
+			orders[key(ss.Id, order.ExchangeId)] = so
+		}
+		s.Unlock()
+	}
+
+	stats := make(map[string]Statistics)
+	for _, s := range snap.Statistics {
+		stats[s.Symbol] = s
+	}
+
+	for _, bs := range snap.Books {
+		instrument := IMap.GetBySymbol(bs.Symbol)
+		if instrument == nil {
+			fmt.Println("skipping snapshot book, unknown symbol", bs.Symbol)
+			continue
+		}
+		ob := e.lockOrderBook(instrument)
+		ob.state = bs.State
+		ob.lastPrice, ob.hasLastPrice = bs.LastPrice, bs.HasLastPrice
+		ob.referencePrice, ob.hasReference = bs.ReferencePrice, bs.HasReference
+		ob.volatilityAuction = bs.VolatilityAuction
+
+		book := func(levels []priceLevel, resting []restingOrder, direction int) []priceLevel {
+			for _, r := range resting {
+				so, ok := orders[key(r.Session, r.ExchangeId)]
+				if !ok {
+					fmt.Println("skipping snapshot resting order", r.Session, r.ExchangeId)
+					continue
+				}
+				levels = insertSort(levels, so, direction)
+				if !so.order.DisplayQuantity.IsZero() {
+					for i := range levels {
+						if node, ok := levels[i].allOrders[so.order]; ok {
+							node.visible = r.Visible
+						}
+					}
+				}
+				e.risk.track(so)
+			}
+			return levels
+		}
+		ob.bids = book(ob.bids, bs.Bids, 1)
+		ob.asks = book(ob.asks, bs.Asks, -1)
+		for _, r := range bs.Stops {
+			if so, ok := orders[key(r.Session, r.ExchangeId)]; ok {
+				ob.stops = append(ob.stops, so)
+				e.risk.track(so)
+			}
+		}
+		if s, ok := stats[bs.Symbol]; ok {
+			ob.stats = s
+		}
+		sendMarketData(ob.marketEvent(nil))
+		ob.Unlock()
+	}
+
+	if e.risk != nil {
+		for _, p := range snap.Positions {
+			if instrument := IMap.GetBySymbol(p.Symbol); instrument != nil {
+				a := e.risk.account(p.Account)
+				a.Lock()
+				a.position[instrument] = p.Position
+				a.Unlock()
+			}
+		}
+	}
+
+	for _, ks := range snap.Blocked {
+		k := KillSwitch{SessionID: ks.Session, Account: ks.Account, Side: ks.Side}
+		if ks.Symbol != "" {
+			if k.Instrument = IMap.GetBySymbol(ks.Symbol); k.Instrument == nil {
+				continue
+			}
+		}
+		e.blockLock.Lock()
+		e.blocked = append(e.blocked, k)
+		e.blockLock.Unlock()
+	}
+}
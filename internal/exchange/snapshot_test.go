@@ -0,0 +1,119 @@
+package exchange
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+func TestSnapshotRecovery(t *testing.T) {
+	discardMarketData()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.journal")
+	j, err := openJournal(path, fsyncNever)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var a = namedExchangeClient("A")
+	var b = namedExchangeClient("B")
+	var i = NewInstrument(1001, "SNAPSHOT")
+	IMap.Put(i)
+
+	e1 := &exchange{journal: j, snapshots: snapshotConfig{dir: dir, keep: 2}}
+
+	iceberg := LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("10"))
+	iceberg.DisplayQuantity = NewDecimal("4")
+	e1.CreateOrder(a, iceberg)
+	o2 := LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("5"))
+	o2.Id = 2
+	e1.CreateOrder(a, o2)
+	o3 := StopOrder(i, Buy, NewDecimal("101"), NewDecimal("1"))
+	o3.Id = 3
+	e1.CreateOrder(b, o3)
+	o4 := LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("3"))
+	o4.Id = 4
+	e1.CreateOrder(b, o4)
+	e1.Quote(a, i, NewDecimal("98"), NewDecimal("1"), NewDecimal("103"), NewDecimal("1"))
+
+	if _, err := e1.TakeSnapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	o5 := LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("2"))
+	o5.Id = 5
+	e1.CreateOrder(b, o5)
+	e1.CancelOrder(a, 2)
+	j.close()
+
+	snap := latestSnapshot(dir)
+	if snap == nil {
+		t.Fatal("snapshot not found")
+	}
+	if snap.Version != snapshotVersion || snap.Seq == 0 {
+		t.Error("wrong snapshot version or sequence", snap.Version, snap.Seq)
+	}
+	if len(snap.Statistics) != 1 || !snap.Statistics[0].Volume.Equal(NewDecimal("3")) {
+		t.Error("snapshot should have the statistics of the trades before it", snap.Statistics)
+	}
+
+	e2 := &exchange{}
+	e2.restoreSnapshot(snap)
+	if _, err := e2.replayJournal(path, snap.Seq); err != nil {
+		t.Fatal(err)
+	}
+
+	books := func(e *exchange) string {
+		ob := e.lockOrderBook(i)
+		defer ob.Unlock()
+		return ob.String()
+	}
+	if books(e1) != books(e2) {
+		t.Error("recovered book does not match", books(e1), books(e2))
+	}
+	stats := func(e *exchange) Statistics {
+		ob := e.lockOrderBook(i)
+		defer ob.Unlock()
+		return ob.stats
+	}
+	if stats(e1) != stats(e2) || !stats(e2).Volume.Equal(NewDecimal("5")) {
+		t.Error("recovered statistics do not match", stats(e1), stats(e2))
+	}
+	if e2.nextOrder != e1.nextOrder {
+		t.Error("wrong next order", e2.nextOrder, e1.nextOrder)
+	}
+
+	s := e2.newSession(a)
+	if order := s.orders[iceberg.Id]; order == nil || !order.Remaining.Equal(iceberg.Remaining) {
+		t.Error("iceberg order not recovered", order)
+	}
+	if order := s.orders[2]; order == nil || order.OrderState != Cancelled {
+		t.Error("cancel after the snapshot not recovered", order)
+	}
+	if qp, ok := s.quotes[i]; !ok || qp.bid.order == nil || qp.ask.order == nil {
+		t.Error("quotes not recovered")
+	}
+}
+
+func TestSnapshotVersion(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := writeSnapshot(dir, &snapshot{Version: snapshotVersion, Seq: 1}, 2); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(snapshotFile(dir, 2), []byte(`{"Version":99,"Seq":2}`), 0644)
+
+	snap := latestSnapshot(dir)
+	if snap == nil || snap.Seq != 1 {
+		t.Error("unsupported snapshot version should be skipped", snap)
+	}
+
+	writeSnapshot(dir, &snapshot{Version: snapshotVersion, Seq: 3}, 2)
+	files, _ := snapshotFiles(dir)
+	if len(files) != 2 || files[0] != snapshotFile(dir, 3) {
+		t.Error("old snapshots should be removed", files)
+	}
+}
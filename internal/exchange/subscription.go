@@ -0,0 +1,191 @@
+package exchange
+
+import (
+	"errors"
+	"sync"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+// subscriber receives the market data published for a set of symbols, e.g. for the gRPC market data stream. The
+// publishers never block on a subscriber, the updates are queued until they are taken, and a subscriber that falls
+// too far behind is closed.
+type subscriber struct {
+	sync.Mutex
+	// nil for all instruments
+	symbols map[string]bool
+	// if true a queued book is replaced by a newer book for the instrument
+	conflate bool
+	updates  []subscriberUpdate
+	// the index of the queued book and statistics for an instrument
+	books map[Instrument]int
+	stats map[Instrument]int
+	// the updates emptied when a newer book or statistics was queued
+	empty int
+	// the sequence of the last book queued for an instrument, older books are dropped
+	sequences map[Instrument]uint64
+	// signalled when updates are queued
+	ready  chan struct{}
+	closed error
+}
+
+// subscriberUpdate is a book, the trades, or the statistics of an instrument
+type subscriberUpdate struct {
+	book   *Book
+	trades []Trade
+	stats  *Statistics
+}
+
+// maxSubscriberUpdates limits the updates queued for a subscriber
+const maxSubscriberUpdates = 100000
+
+var errSlowConsumer = errors.New("market data subscriber is too slow")
+
+func newSubscriber(symbols []string, conflate bool) *subscriber {
+	sub := &subscriber{conflate: conflate, ready: make(chan struct{}, 1)}
+	if len(symbols) > 0 {
+		sub.symbols = make(map[string]bool)
+		for _, symbol := range symbols {
+			sub.symbols[symbol] = true
+		}
+	}
+	sub.books = make(map[Instrument]int)
+	sub.stats = make(map[Instrument]int)
+	sub.sequences = make(map[Instrument]uint64)
+	return sub
+}
+
+// subscribe adds the subscriber, and queues the latest book and statistics of the subscribed instruments. A book
+// published after the subscriber was added is newer than the cached book, which is then dropped.
+func subscribe(sub *subscriber) {
+	subMutex.Lock()
+	subs, _ := subscriptions.Load().([]*subscriber)
+	subscriptions.Store(append(subs[:len(subs):len(subs)], sub))
+	subMutex.Unlock()
+
+	bookCache.Range(func(key, value interface{}) bool {
+		book := value.(*Book)
+		sub.publish(book, nil, getStatistics(book.Instrument))
+		return true
+	})
+}
+
+func unsubscribe(sub *subscriber) {
+	subMutex.Lock()
+	defer subMutex.Unlock()
+
+	subs, _ := subscriptions.Load().([]*subscriber)
+	var copy []*subscriber
+	for _, v := range subs {
+		if v != sub {
+			copy = append(copy, v)
+		}
+	}
+	subscriptions.Store(copy)
+}
+
+// publishToSubscribers is called by the market data publishers
+func publishToSubscribers(book *Book, trades []Trade, stats *Statistics) {
+	subs, _ := subscriptions.Load().([]*subscriber)
+	for _, sub := range subs {
+		sub.publish(book, trades, stats)
+	}
+}
+
+func (sub *subscriber) publish(book *Book, trades []Trade, stats *Statistics) {
+	if sub.symbols != nil && !sub.symbols[book.Instrument.Symbol()] {
+		return
+	}
+
+	sub.Lock()
+	defer sub.Unlock()
+
+	if sub.closed != nil {
+		return
+	}
+	if len(sub.updates)-sub.empty >= maxSubscriberUpdates {
+		sub.closed = errSlowConsumer
+		sub.signal()
+		return
+	}
+
+	instrument := book.Instrument
+	if book.Sequence <= sub.sequences[instrument] {
+		return
+	}
+	sub.sequences[instrument] = book.Sequence
+
+	// a conflated book or the statistics are moved to the end of the queue, so they are not sent before the trades
+	// queued after them
+	if i, ok := sub.books[instrument]; ok && sub.conflate {
+		sub.updates[i].book = nil
+		sub.empty++
+	}
+	sub.books[instrument] = len(sub.updates)
+	sub.updates = append(sub.updates, subscriberUpdate{book: book})
+	if len(trades) > 0 {
+		sub.updates = append(sub.updates, subscriberUpdate{trades: trades})
+	}
+	if stats != nil {
+		// the publisher modifies the statistics
+		copy := *stats
+		if i, ok := sub.stats[instrument]; ok {
+			sub.updates[i].stats = nil
+			sub.empty++
+		}
+		sub.stats[instrument] = len(sub.updates)
+		sub.updates = append(sub.updates, subscriberUpdate{stats: &copy})
+	}
+	if sub.empty > len(sub.updates)/2 {
+		sub.compact()
+	}
+	sub.signal()
+}
+
+// compact removes the emptied updates from the queue
+func (sub *subscriber) compact() {
+	// the new index of each update
+	moved := make([]int, len(sub.updates))
+	updates := sub.updates[:0]
+	for i, update := range sub.updates {
+		if update.book == nil && update.stats == nil && len(update.trades) == 0 {
+			continue
+		}
+		moved[i] = len(updates)
+		updates = append(updates, update)
+	}
+	for instrument, i := range sub.books {
+		sub.books[instrument] = moved[i]
+	}
+	for instrument, i := range sub.stats {
+		sub.stats[instrument] = moved[i]
+	}
+	clear(sub.updates[len(updates):])
+	sub.updates = updates
+	sub.empty = 0
+}
+
+func (sub *subscriber) signal() {
+	select {
+	case sub.ready <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the queued updates, or an error if the subscriber was closed
+func (sub *subscriber) take() ([]subscriberUpdate, error) {
+	sub.Lock()
+	defer sub.Unlock()
+
+	if sub.closed != nil {
+		return nil, sub.closed
+	}
+	if sub.empty > 0 {
+		sub.compact()
+	}
+	updates := sub.updates
+	sub.updates = nil
+	clear(sub.books)
+	clear(sub.stats)
+	return updates, nil
+}
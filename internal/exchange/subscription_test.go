@@ -0,0 +1,79 @@
+package exchange
+
+import (
+	"testing"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+func TestSubscriber(t *testing.T) {
+	i1 := NewInstrument(3001, "SUB1")
+	i2 := NewInstrument(3002, "SUB2")
+	trades := []Trade{{Instrument: i1, Price: NewDecimal("100"), Quantity: NewDecimal("1")}}
+	stats := &Statistics{Symbol: "SUB1", Volume: NewDecimal("1")}
+
+	sub := newSubscriber([]string{"SUB1"}, true)
+	sub.publish(&Book{Instrument: i1, Sequence: 1}, nil, nil)
+	sub.publish(&Book{Instrument: i2, Sequence: 2}, nil, nil)
+	sub.publish(&Book{Instrument: i1, Sequence: 3}, trades, stats)
+	stats.Volume = NewDecimal("2")
+
+	updates, err := sub.take()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 3 || updates[0].book == nil || updates[0].book.Sequence != 3 {
+		t.Fatal("book should be conflated", updates)
+	}
+	if len(updates[1].trades) != 1 {
+		t.Error("trades should not be conflated", updates[1])
+	}
+	if updates[2].stats == nil || !updates[2].stats.Volume.Equal(NewDecimal("1")) {
+		t.Error("statistics should be copied", updates[2])
+	}
+
+	sub = newSubscriber(nil, false)
+	sub.publish(&Book{Instrument: i1, Sequence: 1}, nil, nil)
+	sub.publish(&Book{Instrument: i1, Sequence: 2}, nil, nil)
+	if updates, _ := sub.take(); len(updates) != 2 {
+		t.Error("every book should be sent without conflation", updates)
+	}
+
+	for j := 0; j <= maxSubscriberUpdates; j++ {
+		sub.publish(&Book{Instrument: i1, Sequence: uint64(3 + j)}, nil, nil)
+	}
+	if _, err := sub.take(); err != errSlowConsumer {
+		t.Error("slow subscriber should be closed", err)
+	}
+}
+
+func TestSubscriberOrdering(t *testing.T) {
+	i1 := NewInstrument(3003, "SUB3")
+	trades := []Trade{{Instrument: i1, Price: NewDecimal("100"), Quantity: NewDecimal("1")}}
+
+	sub := newSubscriber(nil, true)
+	sub.publish(&Book{Instrument: i1, Sequence: 1}, nil, nil)
+	sub.publish(&Book{Instrument: i1, Sequence: 2}, trades, nil)
+	sub.publish(&Book{Instrument: i1, Sequence: 3}, nil, nil)
+
+	updates, _ := sub.take()
+	if len(updates) != 2 || len(updates[0].trades) != 1 || updates[1].book == nil || updates[1].book.Sequence != 3 {
+		t.Fatal("conflated book should be queued after the trades", updates)
+	}
+
+	// the cached book queued on subscribe is older than a book already published
+	sub.publish(&Book{Instrument: i1, Sequence: 5}, nil, nil)
+	sub.publish(&Book{Instrument: i1, Sequence: 4}, nil, &Statistics{Symbol: "SUB3"})
+	updates, _ = sub.take()
+	if len(updates) != 1 || updates[0].book.Sequence != 5 {
+		t.Error("older book should be dropped", updates)
+	}
+
+	for j := 0; j < 10; j++ {
+		sub.publish(&Book{Instrument: i1, Sequence: uint64(6 + j)}, trades, &Statistics{Symbol: "SUB3"})
+	}
+	updates, _ = sub.take()
+	if len(updates) != 12 || updates[9].book.Sequence != 15 || updates[11].stats == nil {
+		t.Error("emptied updates should be removed", len(updates))
+	}
+}
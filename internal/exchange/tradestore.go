@@ -0,0 +1,92 @@
+package exchange
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	. "github.com/robaho/fixed"
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+// Every match is kept in the trade store, with both sides, so the trades can be reconciled after the execution
+// reports have been sent. The store is in memory, and is rebuilt from the journal trades on startup, including those
+// before the latest snapshot, so it keeps the original trade ids and times.
+
+type storedTrade struct {
+	// ReportID is unique, TradeID is shared by the trades of a match as in the market data
+	ReportID  int64
+	TradeID   int64
+	Symbol    string
+	Price     Fixed
+	Quantity  Fixed
+	Buyer     string
+	BuyOrder  string
+	Seller    string
+	SellOrder string
+	// the side of the incoming order, empty for auction trades
+	Aggressor Side `json:",omitempty"`
+	Time      time.Time
+}
+
+type tradeStore struct {
+	sync.RWMutex
+	// the trades of each symbol in the order they were matched
+	trades       map[string][]*storedTrade
+	lastReportID int64
+}
+
+func (ts *tradeStore) add(t storedTrade) {
+	ts.Lock()
+	defer ts.Unlock()
+
+	if ts.trades == nil {
+		ts.trades = make(map[string][]*storedTrade)
+	}
+	ts.lastReportID++
+	t.ReportID = ts.lastReportID
+	ts.trades[t.Symbol] = append(ts.trades[t.Symbol], &t)
+}
+
+func (ts *tradeStore) addTrades(trades []trade) {
+	for _, t := range trades {
+		ts.add(storedTrade{TradeID: t.tradeid, Symbol: t.buyer.order.Symbol(), Price: t.price, Quantity: t.quantity,
+			Buyer: t.buyer.client.SessionID(), BuyOrder: t.buyer.order.ExchangeId,
+			Seller: t.seller.client.SessionID(), SellOrder: t.seller.order.ExchangeId,
+			Aggressor: t.aggressor, Time: t.when})
+	}
+}
+
+func (ts *tradeStore) addJournaled(entry *JournalEntry) {
+	t := entry.Trade
+	ts.add(storedTrade{TradeID: t.TradeID, Symbol: entry.Symbol, Price: t.Price, Quantity: t.Quantity,
+		Buyer: t.Buyer, BuyOrder: t.BuyOrder, Seller: t.Seller, SellOrder: t.SellOrder, Aggressor: t.Aggressor, Time: entry.Time})
+}
+
+// query returns the trades of the symbol, or all symbols if empty, from and to the times inclusive, a zero time is
+// unbounded. The trades are in the order they were matched.
+func (ts *tradeStore) query(symbol string, from time.Time, to time.Time) []storedTrade {
+	ts.RLock()
+	defer ts.RUnlock()
+
+	var result []storedTrade
+	add := func(trades []*storedTrade) {
+		i := sort.Search(len(trades), func(i int) bool { return !trades[i].Time.Before(from) })
+		for ; i < len(trades); i++ {
+			t := trades[i]
+			if !to.IsZero() && t.Time.After(to) {
+				break
+			}
+			result = append(result, *t)
+		}
+	}
+	if symbol != "" {
+		add(ts.trades[symbol])
+		return result
+	}
+	for _, trades := range ts.trades {
+		add(trades)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ReportID < result[j].ReportID })
+	return result
+}
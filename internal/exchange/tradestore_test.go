@@ -0,0 +1,53 @@
+package exchange
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+func TestTradeStore(t *testing.T) {
+	discardMarketData()
+
+	path := filepath.Join(t.TempDir(), "test.journal")
+	j, err := openJournal(path, fsyncNever)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var a = namedExchangeClient("A")
+	var b = namedExchangeClient("B")
+	var i = NewInstrument(1001, "TSTORE")
+	IMap.Put(i)
+
+	e1 := &exchange{journal: j}
+	e1.CreateOrder(a, LimitOrder(i, Sell, NewDecimal("100"), NewDecimal("10")))
+	e1.CreateOrder(b, LimitOrder(i, Buy, NewDecimal("101"), NewDecimal("4")))
+	j.close()
+
+	trades := e1.trades.query("TSTORE", time.Time{}, time.Time{})
+	if len(trades) != 1 {
+		t.Fatal("wrong number of trades", trades)
+	}
+	trade := trades[0]
+	if trade.Buyer != "B" || trade.Seller != "A" || trade.Aggressor != Buy || !trade.Price.Equal(NewDecimal("100")) || !trade.Quantity.Equal(NewDecimal("4")) {
+		t.Error("wrong trade", trade)
+	}
+	if len(e1.trades.query("", trade.Time.Add(time.Nanosecond), time.Time{})) != 0 {
+		t.Error("trade before the range should not be returned")
+	}
+	if len(e1.trades.query("", time.Time{}, trade.Time)) != 1 {
+		t.Error("range should include the end time")
+	}
+
+	e2 := &exchange{}
+	if _, err := e2.replayJournal(path, 0); err != nil {
+		t.Fatal(err)
+	}
+	replayed := e2.trades.query("TSTORE", time.Time{}, time.Time{})
+	if len(replayed) != 1 || replayed[0].TradeID != trade.TradeID || replayed[0].Aggressor != Buy || !replayed[0].Time.Equal(trade.Time) {
+		t.Error("trades should be loaded from the journal", replayed, trade)
+	}
+}
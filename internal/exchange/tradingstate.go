@@ -0,0 +1,92 @@
+package exchange
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+// orders are matched when the instrument is open, in the other states they are booked without matching
+// (pre-open and auction), or rejected (halted and closed). Cancels are always accepted.
+
+// isMatching returns true if orders are matched as they are received
+func (ob *orderBook) isMatching() bool {
+	return ob.state != PreOpen && ob.state != Auction && ob.state != Halted && ob.state != Closed
+}
+
+// checkState returns the reason an order or quote should be rejected in the current state, or "" if it is
+// accepted
+func (ob *orderBook) checkState() string {
+	switch ob.state {
+	case Halted:
+		return "trading in " + ob.Symbol() + " is halted"
+	case Closed:
+		return ob.Symbol() + " is closed"
+	}
+	return ""
+}
+
+// setState changes the trading state. Moving to open uncrosses any orders accumulated while not matching,
+// and the resulting trades are returned.
+func (ob *orderBook) setState(state TradingState, now time.Time) []trade {
+	ob.state = state
+	ob.volatilityAuction = false
+	if state == Open {
+		return ob.uncross(now)
+	}
+	return nil
+}
+
+// GetTradingState returns the current trading state of the instrument
+func (e *exchange) GetTradingState(instrument Instrument) TradingState {
+	ob := e.lockOrderBook(instrument)
+	defer ob.Unlock()
+
+	return ob.state
+}
+
+// SetTradingState changes the trading state of the instrument, and publishes the change on the market data
+// feed and to all FIX sessions
+func (e *exchange) SetTradingState(instrument Instrument, state TradingState) error {
+	return e.setTradingState(instrument, state, time.Now())
+}
+
+// setTradingState changes the trading state at 'now', which is the time of any trades
+func (e *exchange) setTradingState(instrument Instrument, state TradingState, now time.Time) error {
+	if _, err := ParseTradingState(string(state)); err != nil {
+		return err
+	}
+
+	e.quiesce.RLock()
+	defer e.quiesce.RUnlock()
+	defer e.journal.commit()
+
+	ob := e.lockOrderBook(instrument)
+	defer ob.Unlock()
+
+	if ob.state == state {
+		return errors.New(instrument.Symbol() + " is already " + string(state))
+	}
+
+	e.journal.recordState(instrument, state, now)
+	trades := ob.setState(state, now)
+
+	e.sendTrades(trades)
+	sendMarketData(ob.marketEvent(trades))
+	e.sendStatusChanges(ob, nil)
+	App.sendSecurityStatus(instrument, state)
+	e.handleInterruption(ob)
+
+	return nil
+}
+
+// Halt stops trading in the instrument, until Resume is called
+func (e *exchange) Halt(instrument Instrument) error {
+	return e.SetTradingState(instrument, Halted)
+}
+
+// Resume resumes continuous trading in the instrument
+func (e *exchange) Resume(instrument Instrument) error {
+	return e.SetTradingState(instrument, Open)
+}
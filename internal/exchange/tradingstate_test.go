@@ -0,0 +1,51 @@
+package exchange
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+func TestTradingState(t *testing.T) {
+	var i = NewInstrument(1, "TEST")
+	var ob = orderBook{Instrument: i, state: PreOpen}
+	var ex = testExchangeClient{}
+
+	if reason := ob.checkState(); reason != "" {
+		t.Error("orders should be accepted pre-open", reason)
+	}
+
+	buy := LimitOrder(i, Buy, NewDecimal("100"), NewDecimal("10"))
+	sell := LimitOrder(i, Sell, NewDecimal("99"), NewDecimal("10"))
+	ob.add(sessionOrder{ex, buy, time.Now()})
+	trades, _ := ob.add(sessionOrder{ex, sell, time.Now()})
+	if len(trades) != 0 {
+		t.Error("orders should not match pre-open", trades)
+	}
+
+	trades = ob.setState(Open, time.Now())
+	if len(trades) != 1 || buy.OrderState != Filled || sell.OrderState != Filled {
+		t.Error("book should be uncrossed on open", trades)
+	}
+
+	ob.setState(Halted, time.Now())
+	if ob.checkState() != "trading in TEST is halted" {
+		t.Error("wrong reason", ob.checkState())
+	}
+	if book := ob.buildBook(); book.State != Halted {
+		t.Error("wrong book state", book.State)
+	}
+
+	ob.setState(Closed, time.Now())
+	if ob.checkState() != "TEST is closed" {
+		t.Error("wrong reason", ob.checkState())
+	}
+
+	if _, err := ParseTradingState("HALTED"); err != nil {
+		t.Error("should parse", err)
+	}
+	if _, err := ParseTradingState("trading"); err == nil {
+		t.Error("should not parse")
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/gernest/hot"
+	. "github.com/robaho/fixed"
 	. "github.com/robaho/go-trader/pkg/common"
 	"golang.org/x/net/websocket"
 )
@@ -45,9 +46,16 @@ func StartWebServer(addr string) {
 		http.HandleFunc("/book", bookHandler)
 		http.HandleFunc("/instruments", instrumentsHandler)
 		http.HandleFunc("/sessions", sessionsHandler)
+		http.HandleFunc("/risk", riskHandler)
 		http.HandleFunc("/api/instruments/", authenticate(apiInstrumentsHandler))
 		http.HandleFunc("/api/book/", authenticate(apiBookHandler))
 		http.HandleFunc("/api/stats/", authenticate(apiStatsHandler))
+		http.HandleFunc("/api/trades/", authenticate(apiTradesHandler))
+		http.HandleFunc("/api/orders", authenticate(apiOrdersHandler))
+		http.HandleFunc("/api/admin/state/", authenticate(apiStateHandler))
+		http.HandleFunc("/api/admin/killswitch", authenticate(apiKillSwitchHandler))
+		http.HandleFunc("/api/admin/snapshot", authenticate(apiSnapshotHandler))
+		http.HandleFunc("/api/admin/instruments", authenticate(apiAdminInstrumentsHandler))
 		http.HandleFunc("/", welcomeHandler)
 
 		http.Handle("/lit/", http.StripPrefix("/lit/", http.FileServer(http.Dir("web_lit/dist"))))
@@ -198,6 +206,12 @@ func bookToJSON(symbol string, book *Book) []byte {
 	m["Bids"] = book.Bids
 	m["Asks"] = book.Asks
 	m["Sequence"] = book.Sequence
+	m["State"] = book.State
+	m["InAuction"] = book.InAuction
+	if book.InAuction {
+		m["IndicativePrice"] = book.IndicativePrice
+		m["IndicativeVolume"] = book.IndicativeVolume
+	}
 	msg, _, _ := websocket.JSON.Marshal(m)
 	return msg
 }
@@ -218,6 +232,13 @@ func sessionsHandler(w http.ResponseWriter, r *http.Request) {
 	t.Execute(w, "sessions.html", data)
 }
 
+func riskHandler(w http.ResponseWriter, r *http.Request) {
+	data := make(map[string]interface{})
+	data["Accounts"] = TheExchange.RiskUtilisation()
+
+	t.Execute(w, "risk.html", data)
+}
+
 func instrumentsHandler(w http.ResponseWriter, r *http.Request) {
 	data := make(map[string]interface{})
 
@@ -295,3 +316,217 @@ func apiStatsHandler(w http.ResponseWriter, r *http.Request) {
 		w.Write(s)
 	}
 }
+
+// apiTradesHandler returns the trades of the symbol from the trade store, limited by the optional 'from' and 'to'
+// parameters, RFC 3339 times inclusive, e.g. from=2024-01-02T09:00:00Z
+func apiTradesHandler(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.TrimPrefix(r.URL.Path, "/api/trades/")
+
+	instrument := IMap.GetBySymbol(symbol)
+	if instrument == nil {
+		http.Error(w, "the symbol "+symbol+" is unknown", http.StatusNotFound)
+		return
+	}
+
+	var times [2]time.Time
+	for i, param := range []string{"from", "to"} {
+		if value := r.FormValue(param); value != "" {
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				http.Error(w, "invalid "+param+" "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			times[i] = t
+		}
+	}
+
+	trades := TheExchange.trades.query(symbol, times[0], times[1])
+	if trades == nil {
+		trades = []storedTrade{}
+	}
+	msg, _, _ := websocket.JSON.Marshal(trades)
+	w.Write(msg)
+}
+
+// apiOrdersHandler returns the open orders by session id, optionally limited by the 'session' and 'symbol' parameters
+func apiOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	var instrument Instrument
+	if symbol := r.FormValue("symbol"); symbol != "" {
+		instrument = IMap.GetBySymbol(symbol)
+		if instrument == nil {
+			http.Error(w, "the symbol "+symbol+" is unknown", http.StatusNotFound)
+			return
+		}
+	}
+
+	type jsonOrder struct {
+		ExchangeId      string
+		ClOrdId         OrderID
+		Symbol          string
+		Side            Side
+		OrderType       OrderType
+		OrderState      OrderState
+		Price           Fixed
+		Quantity        Fixed
+		Remaining       Fixed
+		StopPrice       Fixed
+		DisplayQuantity Fixed
+		TimeInForce     TimeInForce
+		ExpireTime      time.Time
+		Account         string `json:",omitempty"`
+	}
+
+	result := make(map[string][]jsonOrder)
+	for session, orders := range TheExchange.allOpenOrders(r.FormValue("session"), instrument) {
+		for _, o := range orders {
+			result[session] = append(result[session], jsonOrder{ExchangeId: o.ExchangeId, ClOrdId: o.Id, Symbol: o.Symbol(),
+				Side: o.Side, OrderType: o.OrderType, OrderState: o.OrderState, Price: o.Price, Quantity: o.Quantity,
+				Remaining: o.Remaining, StopPrice: o.StopPrice, DisplayQuantity: o.DisplayQuantity, TimeInForce: o.TimeInForce,
+				ExpireTime: o.ExpireTime, Account: o.Account})
+		}
+	}
+	msg, _, _ := websocket.JSON.Marshal(result)
+	w.Write(msg)
+}
+
+// apiStateHandler returns the trading state of the symbol, or changes it on a POST with a 'state' parameter,
+// e.g. state=halted to halt trading, and state=open to resume
+func apiStateHandler(w http.ResponseWriter, r *http.Request) {
+	symbol := strings.TrimPrefix(r.URL.Path, "/api/admin/state/")
+
+	instrument := IMap.GetBySymbol(symbol)
+	if instrument == nil {
+		http.Error(w, "the symbol "+symbol+" is unknown", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		state, err := ParseTradingState(r.FormValue("state"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = TheExchange.SetTradingState(instrument, state)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+	} else if r.Method != http.MethodGet {
+		http.Error(w, "unsupported method "+r.Method, http.StatusMethodNotAllowed)
+		return
+	}
+
+	m := make(map[string]interface{})
+	m["Symbol"] = symbol
+	m["State"] = TheExchange.GetTradingState(instrument)
+	msg, _, _ := websocket.JSON.Marshal(m)
+	w.Write(msg)
+}
+
+// apiKillSwitchHandler lists the active blocks, or on a POST cancels the orders and quotes selected by the 'scope'
+// (all, session, account or symbol) and 'value' parameters. The 'action' parameter is 'kill' to cancel, with
+// block=true to also reject new orders until they are re-enabled with action 'enable'.
+func apiKillSwitchHandler(w http.ResponseWriter, r *http.Request) {
+	m := make(map[string]interface{})
+
+	if r.Method == http.MethodPost {
+		k, err := ParseKillSwitch(r.FormValue("scope"), r.FormValue("value"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		switch r.FormValue("action") {
+		case "kill":
+			m["Cancelled"] = TheExchange.MassCancel(k, r.FormValue("block") == "true")
+		case "enable":
+			if !TheExchange.EnableTrading(k) {
+				http.Error(w, "no block for "+k.String(), http.StatusNotFound)
+				return
+			}
+		default:
+			http.Error(w, "unknown action "+r.FormValue("action"), http.StatusBadRequest)
+			return
+		}
+	} else if r.Method != http.MethodGet {
+		http.Error(w, "unsupported method "+r.Method, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var blocked []string
+	for _, k := range TheExchange.Blocked() {
+		blocked = append(blocked, k.String())
+	}
+	m["Blocked"] = blocked
+	msg, _, _ := websocket.JSON.Marshal(m)
+	w.Write(msg)
+}
+
+// apiSnapshotHandler takes a snapshot of the books and sessions on a POST, and returns the file name
+func apiSnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "unsupported method "+r.Method, http.StatusMethodNotAllowed)
+		return
+	}
+	file, err := TheExchange.TakeSnapshot()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	m := make(map[string]interface{})
+	m["File"] = file
+	msg, _, _ := websocket.JSON.Marshal(m)
+	w.Write(msg)
+}
+
+// apiAdminInstrumentsHandler lists the instruments created at runtime, or on a POST changes the instrument selected
+// by the 'symbol' parameter. The 'action' parameter is create, disable, enable or delete, and a create may have
+// a 'spec' parameter with the trading parameters, e.g. spec=ticks=0:0.01 lot=100
+func apiAdminInstrumentsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		symbol := r.FormValue("symbol")
+		var err error
+		switch r.FormValue("action") {
+		case "create":
+			var spec InstrumentSpec
+			spec, err = ParseInstrumentSpec(strings.Fields(r.FormValue("spec")))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			_, err = TheExchange.CreateInstrument(symbol, spec)
+		case "disable":
+			err = TheExchange.DisableInstrument(symbol)
+		case "enable":
+			err = TheExchange.EnableInstrument(symbol)
+		case "delete":
+			err = TheExchange.DeleteInstrument(symbol)
+		default:
+			http.Error(w, "unknown action "+r.FormValue("action"), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+	} else if r.Method != http.MethodGet {
+		http.Error(w, "unsupported method "+r.Method, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var instruments []map[string]interface{}
+	for symbol, disabled := range TheExchange.CreatedInstruments() {
+		instrument := IMap.GetBySymbol(symbol)
+		if instrument == nil {
+			continue
+		}
+		m := make(map[string]interface{})
+		m["Symbol"] = symbol
+		m["ID"] = instrument.ID()
+		m["Disabled"] = disabled
+		m["State"] = TheExchange.GetTradingState(instrument)
+		instruments = append(instruments, m)
+	}
+	sort.Slice(instruments, func(i, j int) bool { return instruments[i]["ID"].(int64) < instruments[j]["ID"].(int64) })
+	msg, _, _ := websocket.JSON.Marshal(instruments)
+	w.Write(msg)
+}
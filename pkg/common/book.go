@@ -15,6 +15,12 @@ type Book struct {
 	Bids       []BookLevel
 	Asks       []BookLevel
 	Sequence   uint64
+	// InAuction is true while the instrument is in an auction, and the book may be crossed. The indicative
+	// price and volume are what would trade if the auction was uncrossed now.
+	InAuction        bool
+	IndicativePrice  Fixed
+	IndicativeVolume Fixed
+	State            TradingState
 }
 
 func (book *Book) String() string {
@@ -26,6 +32,12 @@ func (book *Book) String() string {
 		s += "<nil>"
 	}
 	s = s + " bids: " + toString(book.Bids) + " asks: " + toString(book.Asks)
+	if book.State != "" && book.State != Open {
+		s = s + " state: " + string(book.State)
+	}
+	if book.InAuction {
+		s = s + " auction: " + book.IndicativeVolume.String() + " @ " + book.IndicativePrice.String()
+	}
 	return s
 }
 func (book *Book) Equals(other Book) bool {
@@ -20,11 +20,15 @@ type ExchangeConnector interface {
 	GetExchangeCode() string
 
 	// ask exchange to create the instrument if it does not already exist, and assign a numeric instrument id
-	// the instruments are not persisted across exchange restarts
+	// the instruments are persisted across exchange restarts, with the same ids, if the exchange has an instrument_store
 	CreateInstrument(symbol string)
 	// ask exchange for configured instruments, will be emitted via onInstrument() on the callback. this call
-	// blocks until all instruments are received
+	// blocks until all instruments are received. the instruments include the trading parameters, see Instrument.Spec()
 	DownloadInstruments() error
+	// ask exchange for the session's open orders, e.g. after a reconnect. this call blocks until all open orders are
+	// received, the local orders are updated, or created if unknown, and emitted via OnOrderStatus(). the final
+	// status of any locally active order that is no longer open is requested, and emitted when received
+	GetOpenOrders() ([]*Order, error)
 }
 
 // a fill on an order or quote
@@ -66,4 +70,6 @@ var OrderNotFound = errors.New("order not found")
 var InvalidConnector = errors.New("invalid connector")
 var UnknownInstrument = errors.New("unknown instrument")
 var UnsupportedOrderType = errors.New("unsupported order type")
+var OrderRejected = errors.New("order rejected")
 var DownloadFailed = errors.New("download failed")
+var OpenOrdersFailed = errors.New("open orders request failed")
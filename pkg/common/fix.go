@@ -2,6 +2,7 @@ package common
 
 import (
 	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/quickfix"
 	. "github.com/robaho/fixed"
 	"github.com/shopspring/decimal"
 )
@@ -34,6 +35,52 @@ func MapFromFixSide(side enum.Side) Side {
 	panic("unsupported side " + side)
 }
 
+func MapToFixOrdType(orderType OrderType) enum.OrdType {
+	switch orderType {
+	case Market:
+		return enum.OrdType_MARKET
+	case Limit:
+		return enum.OrdType_LIMIT
+	case Stop:
+		return enum.OrdType_STOP
+	case StopLimit:
+		return enum.OrdType_STOP_LIMIT
+	}
+	panic("unsupported order type " + orderType)
+}
+
+func MapToFixTimeInForce(tif TimeInForce) enum.TimeInForce {
+	switch tif {
+	case GoodTillCancel, "":
+		return enum.TimeInForce_GOOD_TILL_CANCEL
+	case Day:
+		return enum.TimeInForce_DAY
+	case ImmediateOrCancel:
+		return enum.TimeInForce_IMMEDIATE_OR_CANCEL
+	case FillOrKill:
+		return enum.TimeInForce_FILL_OR_KILL
+	case GoodTillDate:
+		return enum.TimeInForce_GOOD_TILL_DATE
+	}
+	panic("unsupported time in force " + tif)
+}
+
+func MapFromFixTimeInForce(tif enum.TimeInForce) TimeInForce {
+	switch tif {
+	case enum.TimeInForce_GOOD_TILL_CANCEL:
+		return GoodTillCancel
+	case enum.TimeInForce_DAY:
+		return Day
+	case enum.TimeInForce_IMMEDIATE_OR_CANCEL:
+		return ImmediateOrCancel
+	case enum.TimeInForce_FILL_OR_KILL:
+		return FillOrKill
+	case enum.TimeInForce_GOOD_TILL_DATE:
+		return GoodTillDate
+	}
+	panic("unsupported time in force " + tif)
+}
+
 func MapToFixOrdStatus(state OrderState) enum.OrdStatus {
 	switch state {
 	case Booked:
@@ -54,7 +101,7 @@ func MapFromFixOrdStatus(ordStatus enum.OrdStatus) OrderState {
 	switch ordStatus {
 	case enum.OrdStatus_NEW:
 		return Booked
-	case enum.OrdStatus_CANCELED:
+	case enum.OrdStatus_CANCELED, enum.OrdStatus_EXPIRED:
 		return Cancelled
 	case enum.OrdStatus_PARTIALLY_FILLED:
 		return PartialFill
@@ -65,3 +112,81 @@ func MapFromFixOrdStatus(ordStatus enum.OrdStatus) OrderState {
 	}
 	panic("unsupported order status " + ordStatus)
 }
+
+func MapToFixSecurityTradingStatus(state TradingState) enum.SecurityTradingStatus {
+	switch state {
+	case PreOpen:
+		return enum.SecurityTradingStatus_PRE_OPEN
+	case Open:
+		return enum.SecurityTradingStatus_READY_TO_TRADE
+	case Halted:
+		return enum.SecurityTradingStatus_TRADING_HALT
+	case Closed:
+		return enum.SecurityTradingStatus_NOT_AVAILABLE_FOR_TRADING
+	case Auction:
+		return enum.SecurityTradingStatus_PRE_CROSS
+	}
+	panic("unknown TradingState " + state)
+}
+
+func MapFromFixSecurityTradingStatus(status enum.SecurityTradingStatus) TradingState {
+	switch status {
+	case enum.SecurityTradingStatus_PRE_OPEN:
+		return PreOpen
+	case enum.SecurityTradingStatus_READY_TO_TRADE, enum.SecurityTradingStatus_RESUME:
+		return Open
+	case enum.SecurityTradingStatus_TRADING_HALT:
+		return Halted
+	case enum.SecurityTradingStatus_NOT_AVAILABLE_FOR_TRADING:
+		return Closed
+	case enum.SecurityTradingStatus_PRE_CROSS:
+		return Auction
+	}
+	panic("unsupported security trading status " + status)
+}
+
+// user defined tags used to deliver the instrument trading parameters on the SecurityDefinition
+const (
+	TagStaticPriceBand  quickfix.Tag = 20001
+	TagDynamicPriceBand quickfix.Tag = 20002
+	TagTickSizes        quickfix.Tag = 20003
+	TagLotSize          quickfix.Tag = 20004
+	TagMinQuantity      quickfix.Tag = 20005
+	TagMaxQuantity      quickfix.Tag = 20006
+)
+
+func SetInstrumentSpec(body *quickfix.Body, spec InstrumentSpec) {
+	body.SetString(TagStaticPriceBand, spec.PriceBands.Static.String())
+	body.SetString(TagDynamicPriceBand, spec.PriceBands.Dynamic.String())
+	if len(spec.TickSizes) > 0 {
+		body.SetString(TagTickSizes, spec.TickSizes.String())
+	}
+	body.SetString(TagLotSize, spec.LotSize.String())
+	body.SetString(TagMinQuantity, spec.MinQuantity.String())
+	body.SetString(TagMaxQuantity, spec.MaxQuantity.String())
+}
+
+// GetInstrumentSpec reads the trading parameters, any missing or invalid parameter is zero
+func GetInstrumentSpec(body *quickfix.Body) InstrumentSpec {
+	var spec InstrumentSpec
+	getFixed := func(tag quickfix.Tag) Fixed {
+		s, err := body.GetString(tag)
+		if err != nil {
+			return ZERO
+		}
+		f, err2 := NewSErr(s)
+		if err2 != nil {
+			return ZERO
+		}
+		return f
+	}
+	spec.PriceBands.Static = getFixed(TagStaticPriceBand)
+	spec.PriceBands.Dynamic = getFixed(TagDynamicPriceBand)
+	if s, err := body.GetString(TagTickSizes); err == nil {
+		spec.TickSizes, _ = ParseTickTable(s)
+	}
+	spec.LotSize = getFixed(TagLotSize)
+	spec.MinQuantity = getFixed(TagMinQuantity)
+	spec.MaxQuantity = getFixed(TagMaxQuantity)
+	return spec
+}
@@ -2,6 +2,11 @@ package common
 
 import "time"
 import (
+	"errors"
+	"math"
+	"strings"
+
+	. "github.com/robaho/fixed"
 	"github.com/shopspring/decimal"
 )
 
@@ -12,12 +17,128 @@ type Instrument interface {
 	ID() int64
 	Symbol() string
 	Group() string
+	// Spec returns the trading parameters of the instrument
+	Spec() InstrumentSpec
 }
 
 type base struct {
 	id     int64
 	symbol string
 	group  string
+	// a pointer so the instrument remains comparable, and can be used as a map key
+	spec *InstrumentSpec
+}
+
+// InstrumentSpec holds the trading parameters of an instrument, that are set by the exchange and delivered
+// with the instrument download
+type InstrumentSpec struct {
+	PriceBands PriceBands
+	// TickSizes is the minimum price increment, which may vary by price. Empty allows any price.
+	TickSizes TickTable
+	// LotSize is the quantity increment, zero allows any quantity
+	LotSize Fixed
+	// MinQuantity and MaxQuantity limit the order quantity, zero if there is no limit
+	MinQuantity Fixed
+	MaxQuantity Fixed
+}
+
+// TickLevel is the tick size for prices at or above Price, until the next level
+type TickLevel struct {
+	Price    Fixed
+	TickSize Fixed
+}
+
+// TickTable is a list of tick levels in ascending price order
+type TickTable []TickLevel
+
+// ParseTickTable parses a table in the form PRICE:TICKSIZE,PRICE:TICKSIZE e.g. 0:0.0001,1:0.01
+func ParseTickTable(s string) (TickTable, error) {
+	var table TickTable
+	for _, level := range strings.Split(s, ",") {
+		parts := strings.Split(level, ":")
+		if len(parts) != 2 {
+			return nil, errors.New("invalid tick level " + level)
+		}
+		price, err := NewSErr(parts[0])
+		if err != nil {
+			return nil, err
+		}
+		tickSize, err := NewSErr(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		if !tickSize.GreaterThan(ZERO) {
+			return nil, errors.New("invalid tick size " + level)
+		}
+		if len(table) > 0 && !price.GreaterThan(table[len(table)-1].Price) {
+			return nil, errors.New("tick levels must be in ascending price order")
+		}
+		table = append(table, TickLevel{Price: price, TickSize: tickSize})
+	}
+	return table, nil
+}
+
+func (table TickTable) String() string {
+	var levels []string
+	for _, level := range table {
+		levels = append(levels, level.Price.String()+":"+level.TickSize.String())
+	}
+	return strings.Join(levels, ",")
+}
+
+// TickSize returns the tick size for the price, or zero if any price is allowed
+func (table TickTable) TickSize(price Fixed) Fixed {
+	tickSize := ZERO
+	for _, level := range table {
+		if price.LessThan(level.Price) {
+			break
+		}
+		tickSize = level.TickSize
+	}
+	return tickSize
+}
+
+// RoundToTick returns the price rounded to the nearest valid tick
+func (table TickTable) RoundToTick(price Fixed) Fixed {
+	tickSize := table.TickSize(price)
+	if tickSize.IsZero() {
+		return price
+	}
+	return tickSize.Mul(NewI(int64(math.Round(price.Div(tickSize).Float())), 0))
+}
+
+// IsMultiple returns true if value is an exact multiple of increment, or increment is zero
+func IsMultiple(value Fixed, increment Fixed) bool {
+	if increment.IsZero() {
+		return true
+	}
+	// the division uses floating point, so check the neighbouring multiples
+	n := value.Div(increment).Int()
+	for i := n - 1; i <= n+1; i++ {
+		if increment.Mul(NewI(i, 0)).Equal(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// PriceBands limit the prices an instrument can trade at, as a percentage either side of a reference price. The
+// static band is around the opening auction price or first trade, and the dynamic band around the last trade.
+// A zero percentage disables the band.
+type PriceBands struct {
+	Static  Fixed
+	Dynamic Fixed
+}
+
+var hundred = NewDecimal("100")
+
+// WithinBand returns true if price is within percent of the reference price, or percent is zero
+func WithinBand(reference Fixed, percent Fixed, price Fixed) bool {
+	if percent.IsZero() {
+		return true
+	}
+	width := reference.Mul(percent).Div(hundred)
+	return price.GreaterThanOrEqual(reference.Sub(width)) && price.LessThanOrEqual(reference.Add(width))
 }
 
 type instrumentImpl struct {
@@ -29,7 +150,13 @@ func (e *instrumentImpl) String() string {
 }
 
 func NewInstrument(id int64, symbol string) Instrument {
-	e := instrumentImpl{base{id, symbol, symbol}}
+	e := instrumentImpl{base{id, symbol, symbol, nil}}
+	return e
+}
+
+// NewInstrumentWithSpec creates an instrument with trading parameters
+func NewInstrumentWithSpec(id int64, symbol string, spec InstrumentSpec) Instrument {
+	e := instrumentImpl{base{id, symbol, symbol, &spec}}
 	return e
 }
 
@@ -42,6 +169,12 @@ func (b base) Symbol() string {
 func (b base) Group() string {
 	return b.group
 }
+func (b base) Spec() InstrumentSpec {
+	if b.spec == nil {
+		return InstrumentSpec{}
+	}
+	return *b.spec
+}
 func (b base) String() string {
 	return b.symbol
 }
@@ -85,3 +218,26 @@ type OptionStrategy struct {
 	Maturity Maturity
 	Legs     []OptionLeg
 }
+
+// TradingState is the trading phase of an instrument, and determines which orders the exchange accepts
+type TradingState string
+
+const (
+	// PreOpen accepts orders, but they are not matched until the instrument opens
+	PreOpen TradingState = "preopen"
+	Open    TradingState = "open"
+	// Halted rejects new orders and modifications, cancels are accepted
+	Halted TradingState = "halted"
+	// Closed rejects new orders and modifications, cancels are accepted
+	Closed TradingState = "closed"
+	// Auction accepts orders, which are matched when the auction is uncrossed
+	Auction TradingState = "auction"
+)
+
+func ParseTradingState(s string) (TradingState, error) {
+	switch state := TradingState(strings.ToLower(s)); state {
+	case PreOpen, Open, Halted, Closed, Auction:
+		return state, nil
+	}
+	return "", errors.New("unknown trading state " + s)
+}
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestTickTable(t *testing.T) {
+	table, err := ParseTickTable("0:0.0001,1:0.01,100:0.05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.String() != "0:0.0001,1:0.01,100:0.05" {
+		t.Error("wrong table", table)
+	}
+	if !table.TickSize(NewDecimal("0.5")).Equal(NewDecimal("0.0001")) || !table.TickSize(NewDecimal("100")).Equal(NewDecimal("0.05")) {
+		t.Error("wrong tick size")
+	}
+	if !IsMultiple(NewDecimal("12.34"), table.TickSize(NewDecimal("12.34"))) {
+		t.Error("12.34 is on a tick")
+	}
+	if IsMultiple(NewDecimal("100.03"), table.TickSize(NewDecimal("100.03"))) {
+		t.Error("100.03 is not on a tick")
+	}
+	if p := table.RoundToTick(NewDecimal("100.03")); !p.Equal(NewDecimal("100.05")) {
+		t.Error("wrong rounding", p)
+	}
+	if _, err := ParseTickTable("1:0.01,0:0.0001"); err == nil {
+		t.Error("levels must be ascending")
+	}
+}
+
+func TestParseInstrumentSpec(t *testing.T) {
+	spec, err := ParseInstrumentSpec([]string{"ticks=0:0.01", "lot=100", "min=100", "max=10000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.TickSizes) != 1 || !spec.LotSize.Equal(NewDecimal("100")) || !spec.MinQuantity.Equal(NewDecimal("100")) || !spec.MaxQuantity.Equal(NewDecimal("10000")) {
+		t.Error("wrong spec", spec)
+	}
+	if _, err := ParseInstrumentSpec([]string{"size=1"}); err == nil {
+		t.Error("unknown attribute should fail")
+	}
+}
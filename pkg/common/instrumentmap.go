@@ -2,10 +2,13 @@ package common
 
 import (
 	"bufio"
+	"errors"
 	"os"
 	"strings"
 	"sync"
 	"sync/atomic"
+
+	. "github.com/robaho/fixed"
 )
 
 // global instrument map which is fully synchronized
@@ -54,6 +57,22 @@ func (im *instrumentMap) Put(instrument Instrument) {
 	im.byID.Store(instrument.ID(), instrument)
 }
 
+// Remove removes the instrument, its id is not reused
+func (im *instrumentMap) Remove(instrument Instrument) {
+	im.bySymbol.Delete(instrument.Symbol())
+	im.byID.Delete(instrument.ID())
+}
+
+// Reserve ensures NextID returns an id greater than id, e.g. the id of an instrument that was removed
+func (im *instrumentMap) Reserve(id int64) {
+	for {
+		current := atomic.LoadInt64(&im.id)
+		if current >= id || atomic.CompareAndSwapInt64(&im.id, current, id) {
+			return
+		}
+	}
+}
+
 // load the instrument map from a file, see configs/instruments.txt for the format
 func (im *instrumentMap) Load(filepath string) error {
 	inputFile, err := os.Open(filepath)
@@ -73,8 +92,12 @@ func (im *instrumentMap) Load(filepath string) error {
 		}
 		parts := strings.Fields(s)
 		id := ParseInt(parts[0])
-		if len(parts) == 2 {
-			i := NewInstrument(int64(id), parts[1])
+		if len(parts) >= 2 {
+			spec, err := ParseInstrumentSpec(parts[2:])
+			if err != nil {
+				return errors.New("invalid instrument " + parts[1] + ", " + err.Error())
+			}
+			i := NewInstrumentWithSpec(int64(id), parts[1], spec)
 			im.Put(i)
 			if id > int(im.id) {
 				// ensure next dynamic instrument does not collide with loaded ones
@@ -85,5 +108,33 @@ func (im *instrumentMap) Load(filepath string) error {
 	return nil
 }
 
+// ParseInstrumentSpec parses the optional NAME=VALUE instrument attributes, see configs/instruments.txt
+func ParseInstrumentSpec(attributes []string) (InstrumentSpec, error) {
+	var spec InstrumentSpec
+	for _, attribute := range attributes {
+		parts := strings.SplitN(attribute, "=", 2)
+		if len(parts) != 2 {
+			return spec, errors.New("invalid attribute " + attribute)
+		}
+		var err error
+		switch parts[0] {
+		case "ticks":
+			spec.TickSizes, err = ParseTickTable(parts[1])
+		case "lot":
+			spec.LotSize, err = NewSErr(parts[1])
+		case "min":
+			spec.MinQuantity, err = NewSErr(parts[1])
+		case "max":
+			spec.MaxQuantity, err = NewSErr(parts[1])
+		default:
+			err = errors.New("unknown attribute " + parts[0])
+		}
+		if err != nil {
+			return spec, err
+		}
+	}
+	return spec, nil
+}
+
 func init() {
 }
@@ -4,11 +4,13 @@ import (
 	. "github.com/robaho/fixed"
 	"strconv"
 	"sync"
+	"time"
 )
 
 type Side string
 type OrderState string
 type OrderType string
+type TimeInForce string
 
 type OrderID int32
 
@@ -25,8 +27,18 @@ const (
 )
 
 const (
-	Market OrderType = "market"
-	Limit  OrderType = "limit"
+	Market    OrderType = "market"
+	Limit     OrderType = "limit"
+	Stop      OrderType = "stop"
+	StopLimit OrderType = "stoplimit"
+)
+
+const (
+	GoodTillCancel    TimeInForce = "gtc"
+	Day               TimeInForce = "day"
+	ImmediateOrCancel TimeInForce = "ioc"
+	FillOrKill        TimeInForce = "fok"
+	GoodTillDate      TimeInForce = "gtd"
 )
 
 const (
@@ -50,6 +62,17 @@ type Order struct {
 	OrderType
 	OrderState
 	RejectReason string
+	// StopPrice is the trigger price for stop and stop limit orders
+	StopPrice Fixed
+	// Triggered is set by the exchange once a stop or stop limit order has been triggered
+	Triggered bool
+	TimeInForce
+	// ExpireTime is required for GoodTillDate orders, and is set by the exchange for Day orders
+	ExpireTime time.Time
+	// DisplayQuantity is the visible slice of an iceberg order, zero to display the entire quantity
+	DisplayQuantity Fixed
+	// Account is an optional account or trader id, used by the exchange for self trade prevention
+	Account string
 }
 
 func (order *Order) String() string {
@@ -58,8 +81,22 @@ func (order *Order) String() string {
 		" " + order.Instrument.Symbol() +
 		" " + order.Quantity.String() + "@" + order.Price.String() +
 		" remaining " + order.Remaining.String() +
+		stopString(order) +
 		" " + string(order.OrderState)
 }
+func stopString(order *Order) string {
+	if !order.IsStop() {
+		return ""
+	}
+	s := " stop " + order.StopPrice.String()
+	if order.Triggered {
+		s += " (triggered)"
+	}
+	return s
+}
+func (order *Order) IsStop() bool {
+	return order.OrderType == Stop || order.OrderType == StopLimit
+}
 func (order *Order) IsActive() bool {
 	return order.OrderState != Filled && order.OrderState != Cancelled && order.OrderState != Rejected
 }
@@ -77,6 +114,25 @@ func LimitOrder(instrument Instrument, side Side, price Fixed, quantity Fixed) *
 	order.OrderType = Limit
 	return order
 }
+
+// StopOrder creates an order that is held by the exchange until a trade prints at or through the stopPrice,
+// and is then executed as a market order
+func StopOrder(instrument Instrument, side Side, stopPrice Fixed, quantity Fixed) *Order {
+	order := newOrder(instrument, side, quantity)
+	order.Price = ZERO
+	order.StopPrice = stopPrice
+	order.OrderType = Stop
+	return order
+}
+
+// StopLimitOrder is similar to StopOrder, but is executed as a limit order at price once triggered
+func StopLimitOrder(instrument Instrument, side Side, price Fixed, stopPrice Fixed, quantity Fixed) *Order {
+	order := newOrder(instrument, side, quantity)
+	order.Price = price
+	order.StopPrice = stopPrice
+	order.OrderType = StopLimit
+	return order
+}
 func newOrder(instrument Instrument, side Side, qty Fixed) *Order {
 	order := new(Order)
 	order.Instrument = instrument
@@ -84,5 +140,6 @@ func newOrder(instrument Instrument, side Side, qty Fixed) *Order {
 	order.Quantity = qty
 	order.Remaining = qty
 	order.OrderState = New
+	order.TimeInForce = GoodTillCancel
 	return order
 }
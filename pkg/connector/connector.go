@@ -23,6 +23,13 @@ func NewConnector(callback common.ConnectorCallback, props common.Properties, lo
 		c = qfix.NewConnector(callback, props, logOutput)
 	}
 
-	marketdata.StartMarketDataReceiver(c, callback, props, logOutput)
+	switch props.GetString("marketdata_source", "multicast") {
+	case "grpc":
+		marketdata.StartGrpcMarketData(callback, props, logOutput)
+	case "fix":
+		// the FIX connector subscribes to the market data when it logs on
+	default:
+		marketdata.StartMarketDataReceiver(c, callback, props, logOutput)
+	}
 	return c
 }
@@ -6,6 +6,7 @@ import (
 	"log"
 	"strings"
 	"sync"
+	"time"
 
 	. "github.com/robaho/fixed"
 	. "github.com/robaho/go-trader/pkg/common"
@@ -23,10 +24,15 @@ type grpcConnector struct {
 	stream   protocol.Exchange_ConnectionClient
 	addr     string
 	loggedIn StatusBool
+	// the protocol version negotiated at login
+	version protocol.Version
 	// true after all instruments are downloaded from exchange
 	downloaded StatusBool
 	props      Properties
 	log        io.Writer
+	// receives the open orders reply, GetOpenOrders requests are serialized by openOrdersLock
+	openOrders     chan *protocol.OpenOrdersReply
+	openOrdersLock sync.Mutex
 }
 
 type cachedConnection struct {
@@ -83,7 +89,7 @@ func (c *grpcConnector) Connect() error {
 
 	log.Println("connection to exchange OK, sending login")
 
-	request := &protocol.InMessage_Login{Login: &protocol.LoginRequest{Username: "guest", Password: "guest"}}
+	request := &protocol.InMessage_Login{Login: &protocol.LoginRequest{Username: "guest", Password: "guest", ProtocolVersion: int32(protocol.LatestVersion), SessionName: c.props.GetString("grpc_session", "")}}
 
 	err = stream.Send(&protocol.InMessage{Request: request})
 	if err != nil {
@@ -108,6 +114,7 @@ func (c *grpcConnector) Connect() error {
 				if response.Error != "" {
 					log.Println("unable to login", response.Error)
 				} else {
+					c.version = protocol.NegotiateVersion(response.ProtocolVersion)
 					c.loggedIn.SetTrue()
 				}
 			case *protocol.OutMessage_Reject:
@@ -122,7 +129,17 @@ func (c *grpcConnector) Connect() error {
 					continue
 				}
 
-				instrument := NewInstrument(int64(sec.InstrumentID), sec.Symbol)
+				v := c.version
+				spec := InstrumentSpec{}
+				spec.PriceBands.Static = v.Decode(sec.StaticPriceBand, sec.ScaledStaticPriceBand)
+				spec.PriceBands.Dynamic = v.Decode(sec.DynamicPriceBand, sec.ScaledDynamicPriceBand)
+				for _, level := range sec.TickSizes {
+					spec.TickSizes = append(spec.TickSizes, TickLevel{Price: v.Decode(level.Price, level.ScaledPrice), TickSize: v.Decode(level.TickSize, level.ScaledTickSize)})
+				}
+				spec.LotSize = v.Decode(sec.LotSize, sec.ScaledLotSize)
+				spec.MinQuantity = v.Decode(sec.MinQuantity, sec.ScaledMinQuantity)
+				spec.MaxQuantity = v.Decode(sec.MaxQuantity, sec.ScaledMaxQuantity)
+				instrument := NewInstrumentWithSpec(int64(sec.InstrumentID), sec.Symbol, spec)
 
 				IMap.Put(instrument)
 
@@ -130,6 +147,12 @@ func (c *grpcConnector) Connect() error {
 			case *protocol.OutMessage_Execrpt:
 				rpt := msg.GetReply().(*protocol.OutMessage_Execrpt).Execrpt
 				c.handleExecutionReport(rpt)
+			case *protocol.OutMessage_Openorders:
+				select {
+				case c.openOrders <- msg.GetReply().(*protocol.OutMessage_Openorders).Openorders:
+				default:
+					log.Println("ignoring unrequested open orders")
+				}
 			}
 		}
 	}()
@@ -198,12 +221,109 @@ func (c *grpcConnector) DownloadInstruments() error {
 	return nil
 }
 
+func (c *grpcConnector) GetOpenOrders() ([]*Order, error) {
+	if !c.loggedIn.IsTrue() {
+		return nil, NotConnected
+	}
+
+	c.openOrdersLock.Lock()
+	defer c.openOrdersLock.Unlock()
+
+	// discard a reply that arrived after an earlier request timed out
+	select {
+	case <-c.openOrders:
+	default:
+	}
+
+	request := &protocol.InMessage_Openorders{Openorders: &protocol.OpenOrdersRequest{}}
+	err := c.stream.Send(&protocol.InMessage{Request: request})
+	if err != nil {
+		return nil, err
+	}
+
+	var reply *protocol.OpenOrdersReply
+	select {
+	case reply = <-c.openOrders:
+	case <-time.After(30 * time.Second):
+		return nil, OpenOrdersFailed
+	}
+	if reply.Error != "" {
+		log.Println("open orders request failed", reply.Error)
+		return nil, OpenOrdersFailed
+	}
+
+	var orders []*Order
+	open := make(map[OrderID]bool)
+	for _, rpt := range reply.Orders {
+		id := OrderID(rpt.ClOrdId)
+		if c.GetOrder(id) == nil {
+			instrument := IMap.GetBySymbol(rpt.Symbol)
+			if instrument == nil {
+				log.Println("unknown symbol in open orders ", rpt.Symbol)
+				continue
+			}
+			c.orders.Store(id, newOrder(instrument, rpt, c.version))
+		}
+		if int64(id) > c.nextOrder {
+			c.nextOrder = int64(id)
+		}
+		c.handleExecutionReport(rpt)
+		orders = append(orders, c.GetOrder(id))
+		open[id] = true
+	}
+
+	// the final state of the orders that are no longer open is requested, the replies are processed as execution reports
+	c.orders.Range(func(key, value interface{}) bool {
+		order := value.(*Order)
+		order.RLock()
+		active := order.IsActive()
+		order.RUnlock()
+		if active && !open[order.Id] {
+			request := &protocol.InMessage_Orderstatus{Orderstatus: &protocol.OrderStatusRequest{ClOrdId: int32(order.Id)}}
+			if err = c.stream.Send(&protocol.InMessage{Request: request}); err != nil {
+				return false
+			}
+		}
+		return true
+	})
+	return orders, err
+}
+
+// newOrder returns an order for an open order report of an order that is not known locally, e.g. created before a restart
+func newOrder(instrument Instrument, rpt *protocol.ExecutionReport, v protocol.Version) *Order {
+	var side Side
+	if rpt.Side == protocol.CreateOrderRequest_Buy {
+		side = Buy
+	} else {
+		side = Sell
+	}
+	price := v.Decode(rpt.Price, rpt.ScaledPrice)
+	quantity := v.Decode(rpt.Quantity, rpt.ScaledQuantity)
+	stopPrice := v.Decode(rpt.StopPrice, rpt.ScaledStopPrice)
+	var order *Order
+	if !stopPrice.IsZero() {
+		if !price.IsZero() {
+			order = StopLimitOrder(instrument, side, price, stopPrice, quantity)
+		} else {
+			order = StopOrder(instrument, side, stopPrice, quantity)
+		}
+	} else {
+		order = LimitOrder(instrument, side, price, quantity)
+	}
+	order.Id = OrderID(rpt.ClOrdId)
+	order.DisplayQuantity = v.Decode(rpt.DisplayQuantity, rpt.ScaledDisplayQuantity)
+	order.Account = rpt.Account
+	return order
+}
+
 func (c *grpcConnector) CreateOrder(order *Order) (OrderID, error) {
 	if !c.loggedIn.IsTrue() {
 		return -1, NotConnected
 	}
 
-	if order.OrderType != Limit && order.OrderType != Market {
+	switch order.OrderType {
+	case Limit, Market, Stop, StopLimit:
+	default:
 		return -1, UnsupportedOrderType
 	}
 
@@ -216,13 +336,32 @@ func (c *grpcConnector) CreateOrder(order *Order) (OrderID, error) {
 	co := protocol.CreateOrderRequest{}
 	co.ClOrdId = int32(orderID)
 	co.Symbol = order.Symbol()
-	co.Price = ToFloat(order.Price)
-	co.Quantity = ToFloat(order.Quantity)
+	v := c.version
+	v.Encode(order.Price, &co.Price, &co.ScaledPrice)
+	v.Encode(order.Quantity, &co.Quantity, &co.ScaledQuantity)
+	v.Encode(order.StopPrice, &co.StopPrice, &co.ScaledStopPrice)
+	v.Encode(order.DisplayQuantity, &co.DisplayQuantity, &co.ScaledDisplayQuantity)
+	co.Account = order.Account
 	switch order.OrderType {
 	case Market:
 		co.OrderType = protocol.CreateOrderRequest_Market
 	case Limit:
 		co.OrderType = protocol.CreateOrderRequest_Limit
+	case Stop:
+		co.OrderType = protocol.CreateOrderRequest_Stop
+	case StopLimit:
+		co.OrderType = protocol.CreateOrderRequest_StopLimit
+	}
+	switch order.TimeInForce {
+	case Day:
+		co.TimeInForce = protocol.CreateOrderRequest_Day
+	case ImmediateOrCancel:
+		co.TimeInForce = protocol.CreateOrderRequest_IOC
+	case FillOrKill:
+		co.TimeInForce = protocol.CreateOrderRequest_FOK
+	case GoodTillDate:
+		co.TimeInForce = protocol.CreateOrderRequest_GTD
+		co.ExpireTime = order.ExpireTime.UnixNano()
 	}
 	switch order.Side {
 	case Buy:
@@ -252,8 +391,8 @@ func (c *grpcConnector) ModifyOrder(id OrderID, price Fixed, quantity Fixed) err
 
 	co := protocol.ModifyOrderRequest{}
 	co.ClOrdId = int32(order.Id)
-	co.Price = ToFloat(order.Price)
-	co.Quantity = ToFloat(order.Quantity)
+	c.version.Encode(order.Price, &co.Price, &co.ScaledPrice)
+	c.version.Encode(order.Quantity, &co.Quantity, &co.ScaledQuantity)
 
 	request := &protocol.InMessage_Modify{Modify: &co}
 	err := c.stream.Send(&protocol.InMessage{Request: request})
@@ -287,10 +426,13 @@ func (c *grpcConnector) Quote(instrument Instrument, bidPrice Fixed, bidQuantity
 
 	c.nextQuote += 1
 
-	request := &protocol.InMessage_Massquote{Massquote: &protocol.MassQuoteRequest{
-		Symbol:   instrument.Symbol(),
-		BidPrice: ToFloat(bidPrice), BidQuantity: ToFloat(bidQuantity),
-		AskPrice: ToFloat(askPrice), AskQuantity: ToFloat(askQuantity)}}
+	q := &protocol.MassQuoteRequest{Symbol: instrument.Symbol()}
+	v := c.version
+	v.Encode(bidPrice, &q.BidPrice, &q.ScaledBidPrice)
+	v.Encode(bidQuantity, &q.BidQuantity, &q.ScaledBidQuantity)
+	v.Encode(askPrice, &q.AskPrice, &q.ScaledAskPrice)
+	v.Encode(askQuantity, &q.AskQuantity, &q.ScaledAskQuantity)
+	request := &protocol.InMessage_Massquote{Massquote: q}
 
 	err := c.stream.Send(&protocol.InMessage{Request: request})
 	if err != nil {
@@ -347,21 +489,39 @@ func (c *grpcConnector) handleExecutionReport(rpt *protocol.ExecutionReport) {
 		state = Rejected
 	}
 
+	if order != nil && rpt.ReportType == protocol.ExecutionReport_Status && rpt.ExOrdId == "" && state == Rejected {
+		// the status of an order the exchange does not have, it is no longer working
+		order.Lock()
+		defer order.Unlock()
+
+		if order.IsActive() {
+			order.OrderState = Cancelled
+			order.RejectReason = rpt.RejectReason
+			c.callback.OnOrderStatus(order)
+		}
+		return
+	}
+
 	if order != nil {
 		order.Lock()
 		defer order.Unlock()
 
 		order.ExchangeId = exchangeId
-		order.Remaining = NewDecimalF(rpt.Remaining)
-		order.Price = NewDecimalF(rpt.Price)
-		order.Quantity = NewDecimalF(rpt.Quantity)
+		order.Remaining = c.version.Decode(rpt.Remaining, rpt.ScaledRemaining)
+		order.Price = c.version.Decode(rpt.Price, rpt.ScaledPrice)
+		order.Quantity = c.version.Decode(rpt.Quantity, rpt.ScaledQuantity)
+		order.Triggered = rpt.Triggered
+		order.RejectReason = rpt.RejectReason
+		if rpt.ExpireTime != 0 {
+			order.ExpireTime = time.Unix(0, rpt.ExpireTime)
+		}
 
 		order.OrderState = state
 	}
 
 	if rpt.ReportType == protocol.ExecutionReport_Fill {
-		lastPx := NewF(rpt.LastPrice)
-		lastQty := NewF(rpt.LastQuantity)
+		lastPx := c.version.Decode(rpt.LastPrice, rpt.ScaledLastPrice)
+		lastQty := c.version.Decode(rpt.LastQuantity, rpt.ScaledLastQuantity)
 
 		var side Side
 		if rpt.Side == protocol.CreateOrderRequest_Buy {
@@ -381,6 +541,6 @@ func (c *grpcConnector) handleExecutionReport(rpt *protocol.ExecutionReport) {
 }
 
 func NewConnector(callback ConnectorCallback, props Properties, logOutput io.Writer) ExchangeConnector {
-	c := &grpcConnector{props: props, log: logOutput, callback: callback}
+	c := &grpcConnector{props: props, log: logOutput, callback: callback, openOrders: make(chan *protocol.OpenOrdersReply, 1)}
 	return c
 }
@@ -0,0 +1,115 @@
+package marketdata
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/robaho/go-trader/pkg/common"
+	"github.com/robaho/go-trader/pkg/protocol"
+	"google.golang.org/grpc"
+)
+
+// StartGrpcMarketData receives the market data from the exchange's gRPC market data stream, for hosts that cannot
+// join the multicast groups. The symbols are read from marketdata_subscribe, empty for all instruments, and the
+// conflation interval in milliseconds from marketdata_conflate. The stream is reopened if it fails.
+func StartGrpcMarketData(callback ConnectorCallback, props Properties, logOutput io.Writer) {
+	addr := props.GetString("grpc_host", "localhost") + ":" + props.GetString("grpc_port", "5000")
+
+	request := &protocol.MarketDataRequest{ProtocolVersion: int32(protocol.LatestVersion)}
+	for _, symbol := range strings.Split(props.GetString("marketdata_subscribe", ""), ",") {
+		if symbol = strings.TrimSpace(symbol); symbol != "" {
+			request.Symbols = append(request.Symbols, symbol)
+		}
+	}
+	interval, err := strconv.Atoi(props.GetString("marketdata_conflate", "0"))
+	if err != nil {
+		panic("invalid marketdata_conflate " + err.Error())
+	}
+	request.ConflateInterval = int32(interval)
+	depth, err := strconv.Atoi(props.GetString("marketdata_depth", "0"))
+	if err != nil {
+		panic("invalid marketdata_depth " + err.Error())
+	}
+	request.Depth = int32(depth)
+
+	go func() {
+		for {
+			err := receiveGrpcMarketData(addr, request, callback)
+			fmt.Fprintln(logOutput, "grpc market data stream failed", err)
+			time.Sleep(time.Second)
+		}
+	}()
+}
+
+func receiveGrpcMarketData(addr string, request *protocol.MarketDataRequest, callback ConnectorCallback) error {
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stream, err := protocol.NewExchangeClient(conn).MarketData(context.Background(), request)
+	if err != nil {
+		return err
+	}
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		// an exchange that does not support versions sends version 1
+		v := protocol.NegotiateVersion(msg.ProtocolVersion)
+		switch update := msg.GetUpdate().(type) {
+		case *protocol.MarketDataMessage_Book:
+			if book := toBook(update.Book, v); book != nil {
+				callback.OnBook(book)
+			}
+		case *protocol.MarketDataMessage_Trade:
+			if trade := toTrade(update.Trade, v); trade != nil {
+				callback.OnTrade(trade)
+			}
+		case *protocol.MarketDataMessage_Statistics:
+			stats := update.Statistics
+			instrument := IMap.GetBySymbol(stats.Symbol)
+			if sc, ok := callback.(StatisticsCallback); ok && instrument != nil {
+				sc.OnStatistics(instrument, &protocol.Statistics{Volume: v.Decode(stats.Volume, stats.ScaledVolume), High: v.Decode(stats.High, stats.ScaledHigh),
+					Low: v.Decode(stats.Low, stats.ScaledLow), HasHighLow: stats.HasHighLow})
+			}
+		}
+	}
+}
+
+// toBook returns the book, or nil if the instrument is unknown
+func toBook(mb *protocol.MarketDataBook, v protocol.Version) *Book {
+	instrument := IMap.GetBySymbol(mb.Symbol)
+	if instrument == nil {
+		return nil
+	}
+	levels := func(levels []*protocol.MarketDataLevel) []BookLevel {
+		var result []BookLevel
+		for _, level := range levels {
+			result = append(result, BookLevel{Price: v.Decode(level.Price, level.ScaledPrice), Quantity: v.Decode(level.Quantity, level.ScaledQuantity)})
+		}
+		return result
+	}
+	book := &Book{Instrument: instrument, Sequence: mb.Sequence, State: TradingState(mb.State)}
+	book.Bids = levels(mb.Bids)
+	book.Asks = levels(mb.Asks)
+	book.InAuction = mb.InAuction
+	book.IndicativePrice = v.Decode(mb.IndicativePrice, mb.ScaledIndicativePrice)
+	book.IndicativeVolume = v.Decode(mb.IndicativeVolume, mb.ScaledIndicativeVolume)
+	return book
+}
+
+// toTrade returns the trade, or nil if the instrument is unknown
+func toTrade(mt *protocol.MarketDataTrade, v protocol.Version) *Trade {
+	instrument := IMap.GetBySymbol(mt.Symbol)
+	if instrument == nil {
+		return nil
+	}
+	return &Trade{Instrument: instrument, Price: v.Decode(mt.Price, mt.ScaledPrice), Quantity: v.Decode(mt.Quantity, mt.ScaledQuantity), ExchangeID: mt.ExchangeID, TradeTime: time.Unix(0, mt.TradeTime)}
+}
@@ -7,6 +7,8 @@ import (
 	"io"
 	"log"
 	"net"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 
@@ -14,44 +16,65 @@ import (
 	"github.com/robaho/go-trader/pkg/protocol"
 )
 
-var replayRequests = make(chan protocol.ReplayRequest, 1000)
-
 type marketDataReceiver struct {
 	c        ExchangeConnector
 	callbacks atomic.Value
 	log      io.Writer
-	lastSequence map[Instrument]uint64
+	books map[Instrument]*instrumentBook
 	seqLock sync.Mutex
+	// the address of the snapshot service, or empty if the books are only recovered from the periodic snapshots
+	snapshotAddr     string
+	snapshotRequests chan protocol.SnapshotRequest
+	// the dropped packets are requested from the channel's replay service
+	replayRequests chan protocol.ReplayRequest
+}
+
+// instrumentBook is the book maintained from the incremental updates
+type instrumentBook struct {
+	sequence uint64
+	// nil until a snapshot is received. After a missed update it is the book at sequence, until the missing updates
+	// are replayed or a snapshot is received.
+	book *Book
+	// the book of individual orders, if the exchange publishes the market by order feed
+	orders *OrderBook
+	// the updates received while waiting for a snapshot
+	pending []*protocol.BookUpdate
+	// true if a snapshot has been requested from the snapshot service
+	requested bool
+	// the trades of the updates up to reported have been reported, along with the updates in reportedAhead, which
+	// were received before a missing update was replayed
+	reported      uint64
+	reportedAhead map[uint64]bool
+	// the fragments applied since the last complete update, which are reported with the last fragment
+	fragments []*protocol.BookUpdate
+}
+
+// StatisticsCallback is implemented by a ConnectorCallback that wants the statistics sent with the snapshots
+type StatisticsCallback interface {
+	OnStatistics(instrument Instrument, stats *protocol.Statistics)
+}
+
+// OrderBookCallback is implemented by a ConnectorCallback that wants the market by order feed. The book is only
+// valid during the call.
+type OrderBookCallback interface {
+	OnOrderBook(book *OrderBook, updates []protocol.OrderUpdate)
 }
 
 var receivers = make(map[string]*marketDataReceiver)
 var mdLock = sync.Mutex{}
 
-// StartMarketDataReceiver starts the multicast marketdata processor
+// StartMarketDataReceiver starts the multicast marketdata processor for each subscribed channel
 func StartMarketDataReceiver(c ExchangeConnector, callback ConnectorCallback, props Properties, logOutput io.Writer) {
 	mdLock.Lock()
 	defer mdLock.Unlock()
 
-	// read settings and create socket
+	// read settings and create sockets
 
-	saddr := props.GetString("multicast_addr", "")
-	if saddr == "" {
-		panic("unable to read multicast addr")
-	}
-
-	existing,ok := receivers[saddr]
-	if ok {
-		// fmt.Fprintln(logOutput,"adding connector to existing md connector",saddr)
-		// existing receiver for this address, so only add our callback
-		callbacks := existing.callbacks.Load().([]ConnectorCallback)
-		callbacks = append(callbacks, callback)
-		existing.callbacks.Store(callbacks)
-		return
+	channels, err := protocol.ParseChannels(props)
+	if err != nil {
+		panic(err)
 	}
 
-	md := marketDataReceiver{c: c, log: logOutput, lastSequence: make(map[Instrument]uint64)}
-	md.callbacks.Store([]ConnectorCallback{callback})
-
 	intf := props.GetString("multicast_intf", "lo0")
 	if intf == "" {
 		panic("unable to read multicast interface")
@@ -66,13 +89,81 @@ func StartMarketDataReceiver(c ExchangeConnector, callback ConnectorCallback, pr
 		panic("unable to read replay host")
 	}
 
-	rport := props.GetString("replay_port", "")
+	for _, channel := range subscribedChannels(channels, props.GetString("marketdata_subscribe", "")) {
+		startReceiver(c, callback, channel, _intf, rhost, props.GetBytes("marketdata_buffer", 1024*1024), logOutput)
+	}
+}
+
+// subscribedChannels returns the channels that publish the subscribed channel names or symbols, or all channels if
+// the subscription is empty
+func subscribedChannels(channels []protocol.ChannelConfig, subscribe string) []protocol.ChannelConfig {
+	if strings.TrimSpace(subscribe) == "" {
+		return channels
+	}
+	selected := make([]bool, len(channels))
+	for _, name := range strings.Split(subscribe, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		index := -1
+		for i, channel := range channels {
+			if channel.Name == name {
+				index = i
+			}
+		}
+		if index == -1 {
+			instrument := IMap.GetBySymbol(name)
+			if instrument == nil {
+				// the symbol is its group until the instrument is known
+				instrument = NewInstrument(0, name)
+			}
+			index = protocol.ChannelFor(channels, instrument)
+		}
+		selected[index] = true
+	}
+	var subscribed []protocol.ChannelConfig
+	for i, channel := range channels {
+		if selected[i] {
+			subscribed = append(subscribed, channel)
+		}
+	}
+	return subscribed
+}
+
+// startReceiver joins the channel's multicast group, and starts its replay processor
+func startReceiver(c ExchangeConnector, callback ConnectorCallback, channel protocol.ChannelConfig, intf *net.Interface, rhost string, bufferSize int, logOutput io.Writer) {
+	saddr := channel.MulticastAddr
+	if saddr == "" {
+		panic("unable to read multicast addr")
+	}
+
+	existing,ok := receivers[saddr]
+	if ok {
+		// fmt.Fprintln(logOutput,"adding connector to existing md connector",saddr)
+		// existing receiver for this address, so only add our callback
+		callbacks := existing.callbacks.Load().([]ConnectorCallback)
+		callbacks = append(callbacks, callback)
+		existing.callbacks.Store(callbacks)
+		return
+	}
+
+	md := marketDataReceiver{c: c, log: logOutput, books: make(map[Instrument]*instrumentBook)}
+	md.callbacks.Store([]ConnectorCallback{callback})
+	md.replayRequests = make(chan protocol.ReplayRequest, 1000)
+
+	rport := channel.ReplayPort
 	if rport == "" {
 		panic("unable to read replay port")
 	}
 
 	replayAddr := rhost + ":" + rport
 
+	if sport := channel.SnapshotPort; sport != "" {
+		md.snapshotAddr = rhost + ":" + sport
+		md.snapshotRequests = make(chan protocol.SnapshotRequest, 1000)
+	}
+
 	addr, err := net.ResolveUDPAddr("udp", saddr)
 	if err != nil {
 		panic(err)
@@ -80,15 +171,21 @@ func StartMarketDataReceiver(c ExchangeConnector, callback ConnectorCallback, pr
 
 	receivers[saddr]=&md
 
+	if md.snapshotAddr != "" {
+		go md.processSnapshotRequests()
+		// the books of a late joiner are loaded from the snapshot service, rather than waiting for each book to change
+		md.snapshotRequests <- protocol.SnapshotRequest{}
+	}
+
 	go func() {
 		var packetNumber uint64 = 0
-		l, err := net.ListenMulticastUDP("udp", _intf, addr)
+		l, err := net.ListenMulticastUDP("udp", intf, addr)
 		if err != nil {
 			fmt.Println("unable to open multicast socket")
 			panic(err)
 		}
 		log.Println("listening for market data on", l.LocalAddr())
-		l.SetReadBuffer(props.GetBytes("marketdata_buffer",1024*1024))
+		l.SetReadBuffer(bufferSize)
 		b := make([]byte, protocol.MaxMsgSize)
 		for {
 			n, _, err := l.ReadFromUDP(b)
@@ -108,7 +205,7 @@ func StartMarketDataReceiver(c ExchangeConnector, callback ConnectorCallback, pr
 		}()
 
 		for {
-			request := <-replayRequests
+			request := <-md.replayRequests
 			if replaycon == nil {
 				replaycon, err = net.Dial("tcp", replayAddr)
 				if err != nil {
@@ -155,16 +252,22 @@ func StartMarketDataReceiver(c ExchangeConnector, callback ConnectorCallback, pr
 func (c *marketDataReceiver) packetReceived(expected uint64, buf []byte) uint64 {
 	pn := binary.LittleEndian.Uint64(buf)
 	if pn < expected {
-		// server restart, reset the packet numbers
+		// server restart, reset the packet numbers. The books are reset under the lock, since the replay and
+		// snapshot go routines update them, and before the snapshot is requested
 		expected = 0
-		c.lastSequence = make(map[Instrument]uint64)
+		c.seqLock.Lock()
+		c.books = make(map[Instrument]*instrumentBook)
+		c.seqLock.Unlock()
+		if c.snapshotAddr != "" {
+			c.snapshotRequests <- protocol.SnapshotRequest{}
+		}
 	}
 
 	if expected != 0 && pn != expected {
 		// dropped some packets
 		request := protocol.ReplayRequest{Start: expected, End: pn}
 		fmt.Fprintln(c.log, "dropped packets from", expected, "to", pn)
-		replayRequests <- request
+		c.replayRequests <- request
 	}
 
 	c.processPacket(buf)
@@ -182,20 +285,182 @@ func (c *marketDataReceiver) processPacket(packet []byte) {
 	callbacks := c.callbacks.Load().([]ConnectorCallback)
 
 	for buf.Len() > 0 {
-		book, trades := protocol.DecodeMarketEvent(buf)
-		if book != nil {
-			last, ok := c.lastSequence[book.Instrument]
-			if (ok && book.Sequence > last) || !ok {
-				for _,callback := range callbacks {
-					callback.OnBook(book)
-				}
-				c.lastSequence[book.Instrument] = book.Sequence
+		update, err := protocol.DecodeBookUpdate(buf)
+		if err != nil {
+			// the rest of the packet can't be decoded
+			if err != UnknownInstrument {
+				fmt.Fprintln(c.log, "unable to decode market data packet", err)
 			}
+			return
+		}
+		c.applyUpdate(update, callbacks)
+	}
+}
+
+// applyUpdate applies the update to the instrument's book. If an update is missed the later updates are held until
+// the missing ones are replayed, or a snapshot is received from the feed or the snapshot service. Trades are always
+// reported.
+func (c *marketDataReceiver) applyUpdate(update *protocol.BookUpdate, callbacks []ConnectorCallback) {
+	ib := c.instrumentBook(update.Instrument)
+
+	switch {
+	case ib.book != nil && update.Sequence <= ib.sequence:
+		// already applied, e.g. a replayed packet
+	case update.Snapshot != nil:
+		c.apply(ib, update, callbacks)
+		c.applyPending(ib, callbacks)
+	case ib.book != nil && update.Sequence == ib.sequence+1:
+		c.apply(ib, update, callbacks)
+		if len(ib.pending) > 0 {
+			// the missing update was replayed
+			c.applyPending(ib, callbacks)
 		}
-		for _, trade := range trades {
+	case ib.book != nil && len(ib.pending) == 0:
+		fmt.Fprintln(c.log, "missed market data for", update.Instrument.Symbol(), "expected", ib.sequence+1, "received", update.Sequence, "waiting for replay or snapshot")
+		c.hold(ib, update)
+	default:
+		c.hold(ib, update)
+	}
+
+	if ib.unreported(update.Sequence) {
+		for _, trade := range update.Trades {
 			for _,callback := range callbacks {
 				callback.OnTrade(&trade)
 			}
 		}
 	}
 }
+
+// maxPending limits the updates held for an instrument while waiting for a snapshot
+const maxPending = 10000
+
+// hold keeps the update until the book is recovered, and requests a snapshot
+func (c *marketDataReceiver) hold(ib *instrumentBook, update *protocol.BookUpdate) {
+	if len(ib.pending) >= maxPending {
+		ib.pending = ib.pending[1:]
+	}
+	ib.pending = append(ib.pending, update)
+
+	if c.snapshotAddr != "" && !ib.requested {
+		select {
+		case c.snapshotRequests <- protocol.SnapshotRequest{InstrumentID: update.Instrument.ID()}:
+			ib.requested = true
+		default:
+		}
+	}
+}
+
+// applyPending applies the held updates that follow the book, the remaining updates are held again if any are missing
+func (c *marketDataReceiver) applyPending(ib *instrumentBook, callbacks []ConnectorCallback) {
+	pending := ib.pending
+	ib.pending = nil
+	requested := ib.requested
+	ib.requested = false
+
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].Sequence < pending[j].Sequence })
+	for i, update := range pending {
+		if update.Sequence <= ib.sequence {
+			continue
+		}
+		if update.Sequence != ib.sequence+1 && update.Snapshot == nil {
+			// a snapshot may already be on its way
+			ib.requested = requested
+			for _, update := range pending[i:] {
+				c.hold(ib, update)
+			}
+			return
+		}
+		c.apply(ib, update, callbacks)
+	}
+}
+
+// apply applies the update to the book, and reports the changes. The fragments of an update are reported together
+// with the last fragment, so the callbacks never see a partially updated book.
+func (c *marketDataReceiver) apply(ib *instrumentBook, update *protocol.BookUpdate, callbacks []ConnectorCallback) {
+	if update.Snapshot != nil {
+		ib.book = protocol.ApplyBookUpdate(nil, update)
+		ib.orders = NewOrderBook(update.Instrument)
+		// a snapshot replaces the fragments of an earlier update that was not completed, and the missing updates will
+		// not be replayed
+		ib.fragments = nil
+		ib.skipReported(update.Sequence - 1)
+	} else {
+		ib.book = protocol.ApplyBookUpdate(ib.book, update)
+	}
+	ib.sequence = update.Sequence
+	if len(update.Orders) > 0 {
+		if err := ib.orders.Apply(update.Orders); err != nil {
+			fmt.Fprintln(c.log, "invalid market by order update for", update.Instrument.Symbol(), err)
+		}
+	}
+
+	ib.fragments = append(ib.fragments, update)
+	if update.Continued {
+		return
+	}
+	fragments := ib.fragments
+	ib.fragments = nil
+
+	var changed bool
+	var orders []protocol.OrderUpdate
+	var stats *protocol.Statistics
+	for _, fragment := range fragments {
+		changed = changed || fragment.Snapshot != nil || len(fragment.Levels) > 0 || fragment.Status != nil
+		orders = append(orders, fragment.Orders...)
+		if fragment.Statistics != nil {
+			stats = fragment.Statistics
+		}
+	}
+
+	if changed {
+		for _,callback := range callbacks {
+			callback.OnBook(ib.book)
+		}
+	}
+	if len(orders) > 0 {
+		for _,callback := range callbacks {
+			if obc, ok := callback.(OrderBookCallback); ok {
+				obc.OnOrderBook(ib.orders, orders)
+			}
+		}
+	}
+	if stats != nil {
+		for _,callback := range callbacks {
+			if sc, ok := callback.(StatisticsCallback); ok {
+				sc.OnStatistics(update.Instrument, stats)
+			}
+		}
+	}
+}
+
+// unreported returns true the first time it is called for the sequence
+func (ib *instrumentBook) unreported(sequence uint64) bool {
+	if sequence <= ib.reported || ib.reportedAhead[sequence] {
+		return false
+	}
+	if sequence != ib.reported+1 {
+		if ib.reportedAhead == nil {
+			ib.reportedAhead = make(map[uint64]bool)
+		}
+		ib.reportedAhead[sequence] = true
+		return true
+	}
+	ib.skipReported(sequence)
+	return true
+}
+
+// skipReported treats the updates up to the sequence as reported
+func (ib *instrumentBook) skipReported(sequence uint64) {
+	if sequence > ib.reported {
+		ib.reported = sequence
+	}
+	for s := range ib.reportedAhead {
+		if s <= ib.reported {
+			delete(ib.reportedAhead, s)
+		}
+	}
+	for ib.reportedAhead[ib.reported+1] {
+		delete(ib.reportedAhead, ib.reported+1)
+		ib.reported++
+	}
+}
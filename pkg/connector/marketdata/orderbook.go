@@ -0,0 +1,187 @@
+package marketdata
+
+import (
+	"errors"
+
+	. "github.com/robaho/fixed"
+	. "github.com/robaho/go-trader/pkg/common"
+	"github.com/robaho/go-trader/pkg/protocol"
+)
+
+// BookOrder is an order in the market by order feed
+type BookOrder struct {
+	OrderID  uint64
+	Side     Side
+	Price    Fixed
+	Quantity Fixed
+}
+
+type orderLevel struct {
+	price  Fixed
+	orders []*BookOrder
+}
+
+// OrderBook is the book of individual orders built from the market by order feed, in price and time priority
+type OrderBook struct {
+	Instrument Instrument
+	bids       []*orderLevel
+	asks       []*orderLevel
+	orders     map[uint64]*BookOrder
+}
+
+func NewOrderBook(instrument Instrument) *OrderBook {
+	return &OrderBook{Instrument: instrument, orders: make(map[uint64]*BookOrder)}
+}
+
+var errUnknownOrder = errors.New("unknown order")
+
+// Apply applies the order updates, returning an error if an update is for an unknown order. The remaining updates
+// are still applied.
+func (book *OrderBook) Apply(updates []protocol.OrderUpdate) error {
+	var err error
+	for _, update := range updates {
+		order, ok := book.orders[update.OrderID]
+		switch update.Action {
+		case protocol.OrderAdd:
+			if ok {
+				book.remove(order)
+			}
+			book.add(&BookOrder{update.OrderID, update.Side, update.Price, update.Quantity})
+			continue
+		case protocol.OrderModify:
+			if ok {
+				book.remove(order)
+				order.Price, order.Quantity = update.Price, update.Quantity
+				book.add(order)
+				continue
+			}
+		case protocol.OrderDelete:
+			if ok {
+				book.remove(order)
+				continue
+			}
+		case protocol.OrderExecute:
+			if ok {
+				order.Quantity = order.Quantity.Sub(update.Quantity)
+				if !order.Quantity.GreaterThan(ZERO) {
+					book.remove(order)
+				}
+				continue
+			}
+		}
+		err = errUnknownOrder
+	}
+	return err
+}
+
+// Order returns the order with the id, or nil
+func (book *OrderBook) Order(id uint64) *BookOrder {
+	return book.orders[id]
+}
+
+// Orders returns the orders on the side in priority order
+func (book *OrderBook) Orders(side Side) []*BookOrder {
+	var orders []*BookOrder
+	for _, level := range book.levels(side) {
+		orders = append(orders, level.orders...)
+	}
+	return orders
+}
+
+// QuantityAhead returns the quantity at the same price with priority over the order
+func (book *OrderBook) QuantityAhead(id uint64) (Fixed, bool) {
+	order, ok := book.orders[id]
+	if !ok {
+		return ZERO, false
+	}
+	ahead := ZERO
+	for _, level := range book.levels(order.Side) {
+		if !level.price.Equal(order.Price) {
+			continue
+		}
+		for _, o := range level.orders {
+			if o == order {
+				break
+			}
+			ahead = ahead.Add(o.Quantity)
+		}
+	}
+	return ahead, true
+}
+
+// Book returns the aggregated book, the trading state is not included
+func (book *OrderBook) Book() *Book {
+	aggregate := func(levels []*orderLevel) []BookLevel {
+		var result []BookLevel
+		for _, level := range levels {
+			quantity := ZERO
+			for _, order := range level.orders {
+				quantity = quantity.Add(order.Quantity)
+			}
+			result = append(result, BookLevel{Price: level.price, Quantity: quantity})
+		}
+		return result
+	}
+	return &Book{Instrument: book.Instrument, Bids: aggregate(book.bids), Asks: aggregate(book.asks)}
+}
+
+func (book *OrderBook) levels(side Side) []*orderLevel {
+	if side == Buy {
+		return book.bids
+	}
+	return book.asks
+}
+
+func (book *OrderBook) setLevels(side Side, levels []*orderLevel) {
+	if side == Buy {
+		book.bids = levels
+	} else {
+		book.asks = levels
+	}
+}
+
+// add adds the order to the back of the queue at its price
+func (book *OrderBook) add(order *BookOrder) {
+	levels := book.levels(order.Side)
+	i := 0
+	for i < len(levels) && isBetter(order.Side, levels[i].price, order.Price) {
+		i++
+	}
+	if i < len(levels) && levels[i].price.Equal(order.Price) {
+		levels[i].orders = append(levels[i].orders, order)
+	} else {
+		levels = append(levels, nil)
+		copy(levels[i+1:], levels[i:])
+		levels[i] = &orderLevel{price: order.Price, orders: []*BookOrder{order}}
+	}
+	book.setLevels(order.Side, levels)
+	book.orders[order.OrderID] = order
+}
+
+func (book *OrderBook) remove(order *BookOrder) {
+	delete(book.orders, order.OrderID)
+	levels := book.levels(order.Side)
+	for i, level := range levels {
+		if !level.price.Equal(order.Price) {
+			continue
+		}
+		for j, o := range level.orders {
+			if o == order {
+				level.orders = append(level.orders[:j], level.orders[j+1:]...)
+				break
+			}
+		}
+		if len(level.orders) == 0 {
+			book.setLevels(order.Side, append(levels[:i], levels[i+1:]...))
+		}
+		return
+	}
+}
+
+// isBetter returns true if price p1 has priority over p2 for the side
+func isBetter(side Side, p1 Fixed, p2 Fixed) bool {
+	if side == Buy {
+		return p1.GreaterThan(p2)
+	}
+	return p1.LessThan(p2)
+}
@@ -0,0 +1,104 @@
+package marketdata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+
+	. "github.com/robaho/go-trader/pkg/common"
+	"github.com/robaho/go-trader/pkg/protocol"
+)
+
+// processSnapshotRequests requests the snapshots from the snapshot service, and lines them up with the live updates
+func (c *marketDataReceiver) processSnapshotRequests() {
+	for request := range c.snapshotRequests {
+		updates, err := readSnapshot(c.snapshotAddr, request)
+		if err != nil {
+			fmt.Fprintln(c.log, "unable to read snapshot", err)
+		}
+		c.applySnapshot(request, updates)
+	}
+}
+
+// readSnapshot returns the updates sent by the snapshot service for the request
+func readSnapshot(addr string, request protocol.SnapshotRequest) ([]*protocol.BookUpdate, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := binary.Write(conn, binary.LittleEndian, request); err != nil {
+		return nil, err
+	}
+
+	var updates []*protocol.BookUpdate
+	for {
+		var len uint16
+		if err := binary.Read(conn, binary.LittleEndian, &len); err != nil {
+			return updates, err
+		}
+		if len == 0 {
+			return updates, nil
+		}
+		packet := make([]byte, len)
+		if _, err := io.ReadFull(conn, packet); err != nil {
+			return updates, err
+		}
+		update, err := protocol.DecodeBookUpdate(bytes.NewBuffer(packet))
+		if err == UnknownInstrument {
+			continue
+		}
+		if err != nil {
+			return updates, err
+		}
+		updates = append(updates, update)
+	}
+}
+
+// applySnapshot replaces the books that are waiting for a snapshot, or are older than the snapshot, and applies the
+// updates held since
+func (c *marketDataReceiver) applySnapshot(request protocol.SnapshotRequest, updates []*protocol.BookUpdate) {
+	c.seqLock.Lock()
+	defer c.seqLock.Unlock()
+
+	callbacks := c.callbacks.Load().([]ConnectorCallback)
+
+	var ib *instrumentBook
+	var skip bool
+	for _, update := range updates {
+		if update.Snapshot != nil {
+			if ib != nil && !skip {
+				c.applyPending(ib, callbacks)
+			}
+			ib = c.instrumentBook(update.Instrument)
+			// the live feed may already be past the snapshot
+			skip = ib.book != nil && ib.sequence >= update.Sequence
+		}
+		if ib == nil || skip {
+			continue
+		}
+		c.apply(ib, update, callbacks)
+	}
+	if ib != nil && !skip {
+		c.applyPending(ib, callbacks)
+	}
+
+	// allow another request if the instrument was not in the snapshot
+	for instrument, ib := range c.books {
+		if request.InstrumentID == 0 || instrument.ID() == request.InstrumentID {
+			ib.requested = false
+		}
+	}
+}
+
+func (c *marketDataReceiver) instrumentBook(instrument Instrument) *instrumentBook {
+	ib, ok := c.books[instrument]
+	if !ok {
+		ib = &instrumentBook{}
+		c.books[instrument] = ib
+	}
+	return ib
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -39,6 +40,19 @@ type qfixConnector struct {
 	log        io.Writer
 	secReqId   int64
 	senderCompID	   string
+	// true if the market data is received over the FIX session
+	marketData   bool
+	mdSymbols    []string
+	mdDepth      int
+	mdSubscribed StatusBool
+	mdLock       sync.Mutex
+	books        map[Instrument]*Book
+	// the pending OrderMassStatusRequest, see orderstatus.go
+	massStatusRequest sync.Mutex
+	massStatusLock    sync.Mutex
+	massStatusReqID   string
+	massStatusOrders  []*Order
+	massStatusDone    chan struct{}
 }
 
 func (c *qfixConnector) IsConnected() bool {
@@ -150,6 +164,7 @@ func (c *qfixConnector) DownloadInstruments() error {
 	if !c.downloaded.WaitForTrue(30 * 1000) {
 		return DownloadFailed
 	}
+	c.subscribeMarketData()
 	return nil
 }
 
@@ -158,7 +173,9 @@ func (c *qfixConnector) CreateOrder(order *Order) (OrderID, error) {
 		return -1, NotConnected
 	}
 
-	if order.OrderType != Limit && order.OrderType != Market {
+	switch order.OrderType {
+	case Limit, Market, Stop, StopLimit:
+	default:
 		return -1, UnsupportedOrderType
 	}
 
@@ -170,15 +187,25 @@ func (c *qfixConnector) CreateOrder(order *Order) (OrderID, error) {
 
 	order.Id = orderID
 
-	var ordtype = field.NewOrdType(enum.OrdType_LIMIT)
-	if order.OrderType == Market {
-		ordtype = field.NewOrdType(enum.OrdType_MARKET)
-	}
+	var ordtype = field.NewOrdType(MapToFixOrdType(order.OrderType))
 
 	fixOrder := newordersingle.New(field.NewClOrdID(orderID.String()), field.NewSide(MapToFixSide(order.Side)), field.NewTransactTime(time.Now()), ordtype)
 	fixOrder.SetSymbol(order.Instrument.Symbol())
 	fixOrder.SetOrderQty(ToDecimal(order.Quantity), 4)
 	fixOrder.SetPrice(ToDecimal(order.Price), 4)
+	if order.IsStop() {
+		fixOrder.SetStopPx(ToDecimal(order.StopPrice), 4)
+	}
+	fixOrder.SetTimeInForce(MapToFixTimeInForce(order.TimeInForce))
+	if order.TimeInForce == GoodTillDate {
+		fixOrder.SetExpireTime(order.ExpireTime)
+	}
+	if !order.DisplayQuantity.IsZero() {
+		fixOrder.SetMaxFloor(ToDecimal(order.DisplayQuantity), 4)
+	}
+	if order.Account != "" {
+		fixOrder.SetAccount(order.Account)
+	}
 
 	return orderID, quickfix.SendToTarget(fixOrder, c.sessionID)
 }
@@ -269,6 +296,14 @@ func NewConnector(callback ConnectorCallback, props Properties, logOutput io.Wri
 	filename := props.GetString("fix", "")
 	senderCompID := props.GetString("senderCompID", "")
 	c := &qfixConnector{settings: filename, log: logOutput, senderCompID: senderCompID, callback: callback}
+	c.marketData = props.GetString("marketdata_source", "multicast") == "fix"
+	for _, symbol := range strings.Split(props.GetString("marketdata_subscribe", ""), ",") {
+		if symbol = strings.TrimSpace(symbol); symbol != "" {
+			c.mdSymbols = append(c.mdSymbols, symbol)
+		}
+	}
+	c.mdDepth, _ = strconv.Atoi(props.GetString("marketdata_depth", "0"))
+	c.books = make(map[Instrument]*Book)
 
 	return c
 }
@@ -0,0 +1,207 @@
+package qfix
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/field"
+	"github.com/quickfixgo/fix44/marketdataincrementalrefresh"
+	"github.com/quickfixgo/fix44/marketdatarequest"
+	"github.com/quickfixgo/fix44/marketdatarequestreject"
+	"github.com/quickfixgo/fix44/marketdatasnapshotfullrefresh"
+	"github.com/quickfixgo/quickfix"
+	. "github.com/robaho/go-trader/pkg/common"
+	"github.com/robaho/go-trader/pkg/protocol"
+)
+
+// In the FIX market data mode the connector subscribes to the books and trades with a MarketDataRequest after it
+// logs on, rather than receiving the multicast market data. The symbols are read from marketdata_subscribe, if empty
+// all instruments are subscribed once they are downloaded.
+
+// subscribeMarketData sends the MarketDataRequest, if the connector is in the FIX market data mode and has not
+// already subscribed in this session
+func (c *qfixConnector) subscribeMarketData() {
+	if !c.marketData || c.mdSubscribed.IsTrue() {
+		return
+	}
+	symbols := c.mdSymbols
+	if len(symbols) == 0 {
+		symbols = IMap.AllSymbols()
+	}
+	if len(symbols) == 0 {
+		// wait for the instruments to be downloaded
+		return
+	}
+
+	reqid := strconv.FormatInt(atomic.AddInt64(&c.secReqId, 1), 10)
+	requestType := field.NewSubscriptionRequestType(enum.SubscriptionRequestType_SNAPSHOT_PLUS_UPDATES)
+	msg := marketdatarequest.New(field.NewMDReqID(reqid), requestType, field.NewMarketDepth(c.mdDepth))
+	msg.SetMDUpdateType(enum.MDUpdateType_INCREMENTAL_REFRESH)
+	msg.SetAggregatedBook(true)
+
+	types := marketdatarequest.NewNoMDEntryTypesRepeatingGroup()
+	for _, entryType := range []enum.MDEntryType{enum.MDEntryType_BID, enum.MDEntryType_OFFER, enum.MDEntryType_TRADE} {
+		types.Add().SetMDEntryType(entryType)
+	}
+	msg.SetNoMDEntryTypes(types)
+
+	related := marketdatarequest.NewNoRelatedSymRepeatingGroup()
+	for _, symbol := range symbols {
+		related.Add().SetSymbol(symbol)
+	}
+	msg.SetNoRelatedSym(related)
+
+	if err := quickfix.SendToTarget(msg, c.sessionID); err != nil {
+		fmt.Fprintln(c.log, "unable to send MarketDataRequest", err)
+		return
+	}
+	c.mdSubscribed.SetTrue()
+}
+
+func (app *myApplication) onMarketDataSnapshot(msg marketdatasnapshotfullrefresh.MarketDataSnapshotFullRefresh, sessionID quickfix.SessionID) quickfix.MessageRejectError {
+	symbol, err := msg.GetSymbol()
+	if err != nil {
+		return err
+	}
+	instrument := IMap.GetBySymbol(symbol)
+	if instrument == nil {
+		return quickfix.NewBusinessMessageRejectError("unknown symbol "+symbol, 0, nil)
+	}
+	entries, err := msg.GetNoMDEntries()
+	if err != nil {
+		return err
+	}
+
+	book := &Book{Instrument: instrument}
+	for i := 0; i < entries.Len(); i++ {
+		entry := entries.Get(i)
+		entryType, err := entry.GetMDEntryType()
+		if err != nil {
+			return err
+		}
+		price, err := entry.GetMDEntryPx()
+		if err != nil {
+			return err
+		}
+		size, err := entry.GetMDEntrySize()
+		if err != nil {
+			return err
+		}
+		level := BookLevel{Price: ToFixed(price), Quantity: ToFixed(size)}
+		switch entryType {
+		case enum.MDEntryType_BID:
+			book.Bids = append(book.Bids, level)
+		case enum.MDEntryType_OFFER:
+			book.Asks = append(book.Asks, level)
+		}
+	}
+
+	c := app.c
+	c.mdLock.Lock()
+	if prev, ok := c.books[instrument]; ok {
+		book.Sequence = prev.Sequence + 1
+	}
+	c.books[instrument] = book
+	c.mdLock.Unlock()
+
+	c.callback.OnBook(book)
+	return nil
+}
+
+func (app *myApplication) onMarketDataIncremental(msg marketdataincrementalrefresh.MarketDataIncrementalRefresh, sessionID quickfix.SessionID) quickfix.MessageRejectError {
+	entries, err := msg.GetNoMDEntries()
+	if err != nil {
+		return err
+	}
+
+	c := app.c
+	var trades []*Trade
+	levels := make(map[Instrument][]protocol.LevelUpdate)
+	var changed []Instrument
+
+	for i := 0; i < entries.Len(); i++ {
+		entry := entries.Get(i)
+		symbol, err := entry.GetSymbol()
+		if err != nil {
+			return err
+		}
+		instrument := IMap.GetBySymbol(symbol)
+		if instrument == nil {
+			continue
+		}
+		action, err := entry.GetMDUpdateAction()
+		if err != nil {
+			return err
+		}
+		entryType, err := entry.GetMDEntryType()
+		if err != nil {
+			return err
+		}
+		price, err := entry.GetMDEntryPx()
+		if err != nil {
+			return err
+		}
+		size, err := entry.GetMDEntrySize()
+		if err != nil {
+			return err
+		}
+
+		if entryType == enum.MDEntryType_TRADE {
+			trade := &Trade{Instrument: instrument, Price: ToFixed(price), Quantity: ToFixed(size)}
+			trade.ExchangeID, _ = entry.GetMDEntryID()
+			date, _ := entry.GetMDEntryDate()
+			tod, _ := entry.GetMDEntryTime()
+			trade.TradeTime, _ = time.Parse("20060102 15:04:05.000", date+" "+tod)
+			trades = append(trades, trade)
+			continue
+		}
+
+		level := protocol.LevelUpdate{Side: Buy, Price: ToFixed(price), Quantity: ToFixed(size)}
+		if entryType == enum.MDEntryType_OFFER {
+			level.Side = Sell
+		}
+		switch action {
+		case enum.MDUpdateAction_NEW:
+			level.Action = protocol.LevelAdd
+		case enum.MDUpdateAction_CHANGE:
+			level.Action = protocol.LevelChange
+		case enum.MDUpdateAction_DELETE:
+			level.Action = protocol.LevelDelete
+		default:
+			return quickfix.NewBusinessMessageRejectError("unsupported update action "+string(action), 0, nil)
+		}
+		if _, ok := levels[instrument]; !ok {
+			changed = append(changed, instrument)
+		}
+		levels[instrument] = append(levels[instrument], level)
+	}
+
+	for _, instrument := range changed {
+		c.mdLock.Lock()
+		book, ok := c.books[instrument]
+		if ok {
+			book = protocol.ApplyBookUpdate(book, &protocol.BookUpdate{Instrument: instrument, Sequence: book.Sequence + 1, Levels: levels[instrument]})
+			c.books[instrument] = book
+		}
+		c.mdLock.Unlock()
+		if !ok {
+			fmt.Fprintln(c.log, "market data update before snapshot for", instrument.Symbol())
+			continue
+		}
+		c.callback.OnBook(book)
+	}
+	for _, trade := range trades {
+		c.callback.OnTrade(trade)
+	}
+	return nil
+}
+
+func (app *myApplication) onMarketDataRequestReject(msg marketdatarequestreject.MarketDataRequestReject, sessionID quickfix.SessionID) quickfix.MessageRejectError {
+	reqid, _ := msg.GetMDReqID()
+	text, _ := msg.GetText()
+	fmt.Fprintln(app.c.log, "market data request", reqid, "rejected", text)
+	return nil
+}
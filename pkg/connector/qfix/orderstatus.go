@@ -0,0 +1,176 @@
+package qfix
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/quickfixgo/enum"
+	"github.com/quickfixgo/field"
+	"github.com/quickfixgo/fix44/executionreport"
+	"github.com/quickfixgo/fix44/ordermassstatusrequest"
+	"github.com/quickfixgo/fix44/orderstatusrequest"
+	"github.com/quickfixgo/quickfix"
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+// GetOpenOrders sends an OrderMassStatusRequest for all orders, the status reports carry the MassStatusReqID and are
+// collected until the one with LastRptRequested. An OrderStatusRequest is then sent for each locally active order
+// that is not open at the exchange, the reports are processed as any other execution report.
+
+func (c *qfixConnector) GetOpenOrders() ([]*Order, error) {
+	if !c.loggedIn.IsTrue() {
+		return nil, NotConnected
+	}
+
+	c.massStatusRequest.Lock()
+	defer c.massStatusRequest.Unlock()
+
+	reqid := strconv.FormatInt(atomic.AddInt64(&c.secReqId, 1), 10)
+	done := make(chan struct{})
+
+	c.massStatusLock.Lock()
+	c.massStatusReqID = reqid
+	c.massStatusOrders = nil
+	c.massStatusDone = done
+	c.massStatusLock.Unlock()
+
+	msg := ordermassstatusrequest.New(field.NewMassStatusReqID(reqid), field.NewMassStatusReqType(enum.MassStatusReqType_STATUS_FOR_ALL_ORDERS))
+	err := quickfix.SendToTarget(msg, c.sessionID)
+
+	if err == nil {
+		select {
+		case <-done:
+		case <-time.After(30 * time.Second):
+			err = OpenOrdersFailed
+		}
+	}
+
+	c.massStatusLock.Lock()
+	orders := c.massStatusOrders
+	c.massStatusReqID = ""
+	c.massStatusOrders = nil
+	c.massStatusLock.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	open := make(map[OrderID]bool)
+	for _, order := range orders {
+		open[order.Id] = true
+		if int64(order.Id) > c.nextOrder {
+			c.nextOrder = int64(order.Id)
+		}
+	}
+
+	c.orders.Range(func(key, value interface{}) bool {
+		order := value.(*Order)
+		order.RLock()
+		active := order.IsActive()
+		msg := orderstatusrequest.New(field.NewClOrdID(order.Id.String()), field.NewSide(MapToFixSide(order.Side)))
+		msg.SetSymbol(order.Symbol())
+		order.RUnlock()
+		if active && !open[order.Id] {
+			if err = quickfix.SendToTarget(msg, c.sessionID); err != nil {
+				return false
+			}
+		}
+		return true
+	})
+	return orders, err
+}
+
+// massStatusReport records an order reported for the mass status request, the order is nil for the single report
+// sent if there are no open orders
+func (c *qfixConnector) massStatusReport(reqid string, order *Order, last bool) {
+	c.massStatusLock.Lock()
+	defer c.massStatusLock.Unlock()
+
+	if reqid != c.massStatusReqID {
+		fmt.Fprintln(c.log, "ignoring status report for mass status request", reqid)
+		return
+	}
+	if order != nil {
+		c.massStatusOrders = append(c.massStatusOrders, order)
+	}
+	if last {
+		close(c.massStatusDone)
+		c.massStatusReqID = ""
+	}
+}
+
+// newOrder returns an order for a status report of an order that is not known locally, e.g. created before a restart
+func newOrder(instrument Instrument, id OrderID, msg executionreport.ExecutionReport) (*Order, quickfix.MessageRejectError) {
+	side, err := msg.GetSide()
+	if err != nil {
+		return nil, err
+	}
+	ordType, err := msg.GetOrdType()
+	if err != nil {
+		return nil, err
+	}
+	price, err := msg.GetPrice()
+	if err != nil {
+		return nil, err
+	}
+	qty, err := msg.GetOrderQty()
+	if err != nil {
+		return nil, err
+	}
+
+	var order *Order
+	switch ordType {
+	case enum.OrdType_STOP, enum.OrdType_STOP_LIMIT:
+		stopPrice, err := msg.GetStopPx()
+		if err != nil {
+			return nil, err
+		}
+		if ordType == enum.OrdType_STOP {
+			order = StopOrder(instrument, MapFromFixSide(side), ToFixed(stopPrice), ToFixed(qty))
+		} else {
+			order = StopLimitOrder(instrument, MapFromFixSide(side), ToFixed(price), ToFixed(stopPrice), ToFixed(qty))
+		}
+	case enum.OrdType_MARKET:
+		order = MarketOrder(instrument, MapFromFixSide(side), ToFixed(qty))
+	default:
+		order = LimitOrder(instrument, MapFromFixSide(side), ToFixed(price), ToFixed(qty))
+	}
+	order.Id = id
+	if msg.HasTimeInForce() {
+		tif, _ := msg.GetTimeInForce()
+		order.TimeInForce = MapFromFixTimeInForce(tif)
+	}
+	if msg.HasMaxFloor() {
+		maxFloor, _ := msg.GetMaxFloor()
+		order.DisplayQuantity = ToFixed(maxFloor)
+	}
+	if msg.HasAccount() {
+		order.Account, _ = msg.GetAccount()
+	}
+	return order, nil
+}
+
+// onUnknownOrderReport handles the status report for an order the exchange does not have, the order is no longer
+// working, e.g. it was cancelled when the exchange restarted
+func (app *myApplication) onUnknownOrderReport(msg executionreport.ExecutionReport) quickfix.MessageRejectError {
+	clOrdID, err := msg.GetClOrdID()
+	if err != nil {
+		return err
+	}
+	order := app.c.GetOrder(OrderID(ParseInt(clOrdID)))
+	if order == nil {
+		return nil
+	}
+
+	order.Lock()
+	defer order.Unlock()
+
+	if order.IsActive() {
+		order.OrderState = Cancelled
+		order.RejectReason, _ = msg.GetText()
+		app.c.callback.OnOrderStatus(order)
+	}
+	return nil
+}
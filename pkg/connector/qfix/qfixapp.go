@@ -5,10 +5,14 @@ import (
 	"strings"
 
 	"github.com/quickfixgo/fix44/securitydefinition"
+	"github.com/quickfixgo/fix44/securitystatus"
 	"github.com/robaho/fixed"
 
 	"github.com/quickfixgo/enum"
 	"github.com/quickfixgo/fix44/executionreport"
+	"github.com/quickfixgo/fix44/marketdataincrementalrefresh"
+	"github.com/quickfixgo/fix44/marketdatarequestreject"
+	"github.com/quickfixgo/fix44/marketdatasnapshotfullrefresh"
 	"github.com/quickfixgo/quickfix"
 	. "github.com/robaho/go-trader/pkg/common"
 )
@@ -23,6 +27,10 @@ func newApplication(c *qfixConnector) *myApplication {
 	app.MessageRouter = quickfix.NewMessageRouter()
 	app.AddRoute(executionreport.Route(app.onExecutionReport))
 	app.AddRoute(securitydefinition.Route(app.onSecurityDefinition))
+	app.AddRoute(securitystatus.Route(app.onSecurityStatus))
+	app.AddRoute(marketdatasnapshotfullrefresh.Route(app.onMarketDataSnapshot))
+	app.AddRoute(marketdataincrementalrefresh.Route(app.onMarketDataIncremental))
+	app.AddRoute(marketdatarequestreject.Route(app.onMarketDataRequestReject))
 	app.c = c
 	return app
 }
@@ -34,6 +42,7 @@ func (app *myApplication) OnLogon(sessionID quickfix.SessionID) {
 	if sessionID == app.c.sessionID {
 		fmt.Fprintln(app.c.log, "we are logged in!")
 		app.c.loggedIn.SetTrue()
+		go app.c.subscribeMarketData()
 	}
 }
 
@@ -41,6 +50,8 @@ func (app *myApplication) OnLogout(sessionID quickfix.SessionID) {
 	if sessionID == app.c.sessionID {
 		fmt.Fprintln(app.c.log, "we are logged out!")
 		app.c.loggedIn.SetFalse()
+		// the exchange drops the subscriptions when the session ends
+		app.c.mdSubscribed.SetFalse()
 	}
 }
 
@@ -82,7 +93,7 @@ func (app *myApplication) onSecurityDefinition(msg securitydefinition.SecurityDe
 		return nil
 	}
 
-	instrument := NewInstrument(int64(instrumentID), symbol)
+	instrument := NewInstrumentWithSpec(int64(instrumentID), symbol, GetInstrumentSpec(msg.Body))
 
 	IMap.Put(instrument)
 
@@ -91,6 +102,20 @@ func (app *myApplication) onSecurityDefinition(msg securitydefinition.SecurityDe
 	return nil
 }
 
+// the trading state is also published with the book on the market data feed, so it is only logged
+func (app *myApplication) onSecurityStatus(msg securitystatus.SecurityStatus, sessionID quickfix.SessionID) quickfix.MessageRejectError {
+	symbol, err := msg.GetSymbol()
+	if err != nil {
+		return err
+	}
+	status, err := msg.GetSecurityTradingStatus()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(app.c.log, "security status", symbol, MapFromFixSecurityTradingStatus(status))
+	return nil
+}
+
 func (app *myApplication) onExecutionReport(msg executionreport.ExecutionReport, sessionID quickfix.SessionID) quickfix.MessageRejectError {
 
 	exchangeId, err := msg.GetOrderID()
@@ -98,6 +123,18 @@ func (app *myApplication) onExecutionReport(msg executionreport.ExecutionReport,
 		return err
 	}
 
+	var massStatusReqID string
+	if msg.HasMassStatusReqID() {
+		massStatusReqID, _ = msg.GetMassStatusReqID()
+		if total, _ := msg.GetTotNumReports(); total == 0 {
+			// there are no open orders
+			app.c.massStatusReport(massStatusReqID, nil, true)
+			return nil
+		}
+	} else if exchangeId == "NONE" {
+		return app.onUnknownOrderReport(msg)
+	}
+
 	clOrdID, err := msg.GetClOrdID()
 	if err != nil {
 		return err
@@ -120,6 +157,12 @@ func (app *myApplication) onExecutionReport(msg executionreport.ExecutionReport,
 	} else {
 		id = OrderID(ParseInt(clOrdID))
 		order = app.c.GetOrder(id)
+		if order == nil && massStatusReqID != "" && instrument != nil {
+			if order, err = newOrder(instrument, id, msg); err != nil {
+				return err
+			}
+			app.c.orders.Store(id, order)
+		}
 		if order == nil {
 			return quickfix.NewMessageRejectError("unknown order clOrdID "+clOrdID, 0, nil)
 		}
@@ -161,6 +204,15 @@ func (app *myApplication) onExecutionReport(msg executionreport.ExecutionReport,
 		order.Remaining = ToFixed(remaining)
 		order.Price = ToFixed(price)
 		order.Quantity = ToFixed(qty)
+		if execType == enum.ExecType_TRIGGERED_OR_ACTIVATED_BY_SYSTEM {
+			order.Triggered = true
+		}
+		if msg.HasExpireTime() {
+			order.ExpireTime, _ = msg.GetExpireTime()
+		}
+		if msg.HasText() {
+			order.RejectReason, _ = msg.GetText()
+		}
 
 		order.OrderState = MapFromFixOrdStatus(ordStatus)
 	}
@@ -178,7 +230,7 @@ func (app *myApplication) onExecutionReport(msg executionreport.ExecutionReport,
 		if err != nil {
 			return err
 		}
-		fill := &Fill{instrument, id == 0, order, exchangeId, fixed.NewF(lastQtyF), fixed.NewF(lastPxF), MapFromFixSide(side), false}
+		fill := &Fill{Instrument: instrument, IsQuote: id == 0, Order: order, ExchangeID: exchangeId, Quantity: fixed.NewF(lastQtyF), Price: fixed.NewF(lastPxF), Side: MapFromFixSide(side), IsLegTrade: false}
 		app.c.callback.OnFill(fill)
 	}
 
@@ -186,5 +238,10 @@ func (app *myApplication) onExecutionReport(msg executionreport.ExecutionReport,
 		app.c.callback.OnOrderStatus(order)
 	}
 
+	if massStatusReqID != "" {
+		last, _ := msg.GetLastRptRequested()
+		app.c.massStatusReport(massStatusReqID, order, last)
+	}
+
 	return nil
 }
@@ -0,0 +1,125 @@
+package protocol
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	. "github.com/robaho/go-trader/pkg/common"
+)
+
+// The market data can be split into channels, each with its own multicast address, packet numbers, replay and
+// snapshot service. A channel is configured by adding its name to marketdata_channels, and the keys with the name
+// as a suffix, e.g.
+//
+//	marketdata_channels=A
+//	multicast_addr.A=224.0.0.101:9999
+//	replay_port.A=10001
+//	snapshot_port.A=10002
+//	marketdata_groups.A=IBM,AAPL
+//	marketdata_ids.A=100-199,300-399
+//
+// An instrument is published on the first channel that includes its group or id, otherwise on the default channel,
+// which uses the keys without a suffix.
+
+// ChannelConfig is the configuration of a market data channel
+type ChannelConfig struct {
+	// Name is empty for the default channel
+	Name          string
+	MulticastAddr string
+	ReplayPort    string
+	SnapshotPort  string
+	Groups        []string
+	IDs           []IDRange
+}
+
+// IDRange is an inclusive range of instrument ids
+type IDRange struct {
+	From, To int64
+}
+
+// ParseChannels returns the configured channels, followed by the default channel
+func ParseChannels(props Properties) ([]ChannelConfig, error) {
+	var channels []ChannelConfig
+	for _, name := range splitList(props.GetString("marketdata_channels", "")) {
+		channel := ChannelConfig{Name: name}
+		channel.MulticastAddr = props.GetString("multicast_addr."+name, "")
+		if channel.MulticastAddr == "" {
+			return nil, errors.New("missing multicast_addr for market data channel " + name)
+		}
+		channel.ReplayPort = props.GetString("replay_port."+name, "")
+		if channel.ReplayPort == "" {
+			return nil, errors.New("missing replay_port for market data channel " + name)
+		}
+		channel.SnapshotPort = props.GetString("snapshot_port."+name, "")
+		channel.Groups = splitList(props.GetString("marketdata_groups."+name, ""))
+		for _, r := range splitList(props.GetString("marketdata_ids."+name, "")) {
+			idRange, err := parseIDRange(r)
+			if err != nil {
+				return nil, errors.New("invalid marketdata_ids for market data channel " + name + ", " + err.Error())
+			}
+			channel.IDs = append(channel.IDs, idRange)
+		}
+		channels = append(channels, channel)
+	}
+
+	channel := ChannelConfig{}
+	channel.MulticastAddr = props.GetString("multicast_addr", "")
+	channel.ReplayPort = props.GetString("replay_port", "")
+	channel.SnapshotPort = props.GetString("snapshot_port", "")
+	return append(channels, channel), nil
+}
+
+// Includes returns true if the channel is configured for the instrument, the default channel includes no instruments
+func (c *ChannelConfig) Includes(instrument Instrument) bool {
+	for _, group := range c.Groups {
+		if group == instrument.Group() {
+			return true
+		}
+	}
+	for _, r := range c.IDs {
+		if instrument.ID() >= r.From && instrument.ID() <= r.To {
+			return true
+		}
+	}
+	return false
+}
+
+// ChannelFor returns the index of the channel that publishes the instrument
+func ChannelFor(channels []ChannelConfig, instrument Instrument) int {
+	for i := range channels {
+		if channels[i].Includes(instrument) {
+			return i
+		}
+	}
+	return len(channels) - 1
+}
+
+// parseIDRange parses an id, or a range in the form FROM-TO
+func parseIDRange(s string) (IDRange, error) {
+	parts := strings.SplitN(s, "-", 2)
+	from, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return IDRange{}, err
+	}
+	to := from
+	if len(parts) == 2 {
+		if to, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+			return IDRange{}, err
+		}
+	}
+	if to < from {
+		return IDRange{}, errors.New("invalid range " + s)
+	}
+	return IDRange{from, to}, nil
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
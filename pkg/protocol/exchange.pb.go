@@ -26,24 +26,30 @@ const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 type CreateOrderRequest_OrderType int32
 
 const (
-	CreateOrderRequest_Market CreateOrderRequest_OrderType = 0
-	CreateOrderRequest_Limit  CreateOrderRequest_OrderType = 1
+	CreateOrderRequest_Market    CreateOrderRequest_OrderType = 0
+	CreateOrderRequest_Limit     CreateOrderRequest_OrderType = 1
+	CreateOrderRequest_Stop      CreateOrderRequest_OrderType = 2
+	CreateOrderRequest_StopLimit CreateOrderRequest_OrderType = 3
 )
 
 var CreateOrderRequest_OrderType_name = map[int32]string{
 	0: "Market",
 	1: "Limit",
+	2: "Stop",
+	3: "StopLimit",
 }
 var CreateOrderRequest_OrderType_value = map[string]int32{
-	"Market": 0,
-	"Limit":  1,
+	"Market":    0,
+	"Limit":     1,
+	"Stop":      2,
+	"StopLimit": 3,
 }
 
 func (x CreateOrderRequest_OrderType) String() string {
 	return proto.EnumName(CreateOrderRequest_OrderType_name, int32(x))
 }
 func (CreateOrderRequest_OrderType) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_exchange_58c6893b63e17bd9, []int{4, 0}
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{4, 0}
 }
 
 type CreateOrderRequest_OrderSide int32
@@ -66,7 +72,39 @@ func (x CreateOrderRequest_OrderSide) String() string {
 	return proto.EnumName(CreateOrderRequest_OrderSide_name, int32(x))
 }
 func (CreateOrderRequest_OrderSide) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_exchange_58c6893b63e17bd9, []int{4, 1}
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{4, 1}
+}
+
+type CreateOrderRequest_TimeInForce int32
+
+const (
+	CreateOrderRequest_GTC CreateOrderRequest_TimeInForce = 0
+	CreateOrderRequest_Day CreateOrderRequest_TimeInForce = 1
+	CreateOrderRequest_IOC CreateOrderRequest_TimeInForce = 2
+	CreateOrderRequest_FOK CreateOrderRequest_TimeInForce = 3
+	CreateOrderRequest_GTD CreateOrderRequest_TimeInForce = 4
+)
+
+var CreateOrderRequest_TimeInForce_name = map[int32]string{
+	0: "GTC",
+	1: "Day",
+	2: "IOC",
+	3: "FOK",
+	4: "GTD",
+}
+var CreateOrderRequest_TimeInForce_value = map[string]int32{
+	"GTC": 0,
+	"Day": 1,
+	"IOC": 2,
+	"FOK": 3,
+	"GTD": 4,
+}
+
+func (x CreateOrderRequest_TimeInForce) String() string {
+	return proto.EnumName(CreateOrderRequest_TimeInForce_name, int32(x))
+}
+func (CreateOrderRequest_TimeInForce) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{4, 2}
 }
 
 type ExecutionReport_OrderState int32
@@ -98,7 +136,7 @@ func (x ExecutionReport_OrderState) String() string {
 	return proto.EnumName(ExecutionReport_OrderState_name, int32(x))
 }
 func (ExecutionReport_OrderState) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_exchange_58c6893b63e17bd9, []int{11, 0}
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{12, 0}
 }
 
 type ExecutionReport_ReportType int32
@@ -121,7 +159,7 @@ func (x ExecutionReport_ReportType) String() string {
 	return proto.EnumName(ExecutionReport_ReportType_name, int32(x))
 }
 func (ExecutionReport_ReportType) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_exchange_58c6893b63e17bd9, []int{11, 1}
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{12, 1}
 }
 
 type InMessage struct {
@@ -133,6 +171,8 @@ type InMessage struct {
 	//	*InMessage_Massquote
 	//	*InMessage_Secdefreq
 	//	*InMessage_Download
+	//	*InMessage_Orderstatus
+	//	*InMessage_Openorders
 	Request              isInMessage_Request `protobuf_oneof:"request"`
 	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
 	XXX_unrecognized     []byte              `json:"-"`
@@ -143,7 +183,7 @@ func (m *InMessage) Reset()         { *m = InMessage{} }
 func (m *InMessage) String() string { return proto.CompactTextString(m) }
 func (*InMessage) ProtoMessage()    {}
 func (*InMessage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_exchange_58c6893b63e17bd9, []int{0}
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{0}
 }
 func (m *InMessage) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_InMessage.Unmarshal(m, b)
@@ -195,6 +235,14 @@ type InMessage_Download struct {
 	Download *DownloadRequest `protobuf:"bytes,7,opt,name=download,proto3,oneof"`
 }
 
+type InMessage_Orderstatus struct {
+	Orderstatus *OrderStatusRequest `protobuf:"bytes,8,opt,name=orderstatus,proto3,oneof"`
+}
+
+type InMessage_Openorders struct {
+	Openorders *OpenOrdersRequest `protobuf:"bytes,9,opt,name=openorders,proto3,oneof"`
+}
+
 func (*InMessage_Login) isInMessage_Request() {}
 
 func (*InMessage_Create) isInMessage_Request() {}
@@ -209,6 +257,10 @@ func (*InMessage_Secdefreq) isInMessage_Request() {}
 
 func (*InMessage_Download) isInMessage_Request() {}
 
+func (*InMessage_Orderstatus) isInMessage_Request() {}
+
+func (*InMessage_Openorders) isInMessage_Request() {}
+
 func (m *InMessage) GetRequest() isInMessage_Request {
 	if m != nil {
 		return m.Request
@@ -265,6 +317,20 @@ func (m *InMessage) GetDownload() *DownloadRequest {
 	return nil
 }
 
+func (m *InMessage) GetOrderstatus() *OrderStatusRequest {
+	if x, ok := m.GetRequest().(*InMessage_Orderstatus); ok {
+		return x.Orderstatus
+	}
+	return nil
+}
+
+func (m *InMessage) GetOpenorders() *OpenOrdersRequest {
+	if x, ok := m.GetRequest().(*InMessage_Openorders); ok {
+		return x.Openorders
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*InMessage) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _InMessage_OneofMarshaler, _InMessage_OneofUnmarshaler, _InMessage_OneofSizer, []interface{}{
@@ -275,6 +341,8 @@ func (*InMessage) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) err
 		(*InMessage_Massquote)(nil),
 		(*InMessage_Secdefreq)(nil),
 		(*InMessage_Download)(nil),
+		(*InMessage_Orderstatus)(nil),
+		(*InMessage_Openorders)(nil),
 	}
 }
 
@@ -317,6 +385,16 @@ func _InMessage_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.Download); err != nil {
 			return err
 		}
+	case *InMessage_Orderstatus:
+		b.EncodeVarint(8<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Orderstatus); err != nil {
+			return err
+		}
+	case *InMessage_Openorders:
+		b.EncodeVarint(9<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Openorders); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("InMessage.Request has unexpected type %T", x)
@@ -383,6 +461,22 @@ func _InMessage_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buff
 		err := b.DecodeMessage(msg)
 		m.Request = &InMessage_Download{msg}
 		return true, err
+	case 8: // request.orderstatus
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(OrderStatusRequest)
+		err := b.DecodeMessage(msg)
+		m.Request = &InMessage_Orderstatus{msg}
+		return true, err
+	case 9: // request.openorders
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(OpenOrdersRequest)
+		err := b.DecodeMessage(msg)
+		m.Request = &InMessage_Openorders{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -427,6 +521,16 @@ func _InMessage_OneofSizer(msg proto.Message) (n int) {
 		n += 1 // tag and wire
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *InMessage_Orderstatus:
+		s := proto.Size(x.Orderstatus)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *InMessage_Openorders:
+		s := proto.Size(x.Openorders)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -440,6 +544,7 @@ type OutMessage struct {
 	//	*OutMessage_Execrpt
 	//	*OutMessage_Secdef
 	//	*OutMessage_Reject
+	//	*OutMessage_Openorders
 	Reply                isOutMessage_Reply `protobuf_oneof:"reply"`
 	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
 	XXX_unrecognized     []byte             `json:"-"`
@@ -450,7 +555,7 @@ func (m *OutMessage) Reset()         { *m = OutMessage{} }
 func (m *OutMessage) String() string { return proto.CompactTextString(m) }
 func (*OutMessage) ProtoMessage()    {}
 func (*OutMessage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_exchange_58c6893b63e17bd9, []int{1}
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{1}
 }
 func (m *OutMessage) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_OutMessage.Unmarshal(m, b)
@@ -490,6 +595,10 @@ type OutMessage_Reject struct {
 	Reject *SessionReject `protobuf:"bytes,4,opt,name=reject,proto3,oneof"`
 }
 
+type OutMessage_Openorders struct {
+	Openorders *OpenOrdersReply `protobuf:"bytes,5,opt,name=openorders,proto3,oneof"`
+}
+
 func (*OutMessage_Login) isOutMessage_Reply() {}
 
 func (*OutMessage_Execrpt) isOutMessage_Reply() {}
@@ -498,6 +607,8 @@ func (*OutMessage_Secdef) isOutMessage_Reply() {}
 
 func (*OutMessage_Reject) isOutMessage_Reply() {}
 
+func (*OutMessage_Openorders) isOutMessage_Reply() {}
+
 func (m *OutMessage) GetReply() isOutMessage_Reply {
 	if m != nil {
 		return m.Reply
@@ -533,6 +644,13 @@ func (m *OutMessage) GetReject() *SessionReject {
 	return nil
 }
 
+func (m *OutMessage) GetOpenorders() *OpenOrdersReply {
+	if x, ok := m.GetReply().(*OutMessage_Openorders); ok {
+		return x.Openorders
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*OutMessage) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _OutMessage_OneofMarshaler, _OutMessage_OneofUnmarshaler, _OutMessage_OneofSizer, []interface{}{
@@ -540,6 +658,7 @@ func (*OutMessage) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) er
 		(*OutMessage_Execrpt)(nil),
 		(*OutMessage_Secdef)(nil),
 		(*OutMessage_Reject)(nil),
+		(*OutMessage_Openorders)(nil),
 	}
 }
 
@@ -567,6 +686,11 @@ func _OutMessage_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.Reject); err != nil {
 			return err
 		}
+	case *OutMessage_Openorders:
+		b.EncodeVarint(5<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Openorders); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("OutMessage.Reply has unexpected type %T", x)
@@ -609,6 +733,14 @@ func _OutMessage_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buf
 		err := b.DecodeMessage(msg)
 		m.Reply = &OutMessage_Reject{msg}
 		return true, err
+	case 5: // reply.openorders
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(OpenOrdersReply)
+		err := b.DecodeMessage(msg)
+		m.Reply = &OutMessage_Openorders{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -638,6 +770,11 @@ func _OutMessage_OneofSizer(msg proto.Message) (n int) {
 		n += 1 // tag and wire
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *OutMessage_Openorders:
+		s := proto.Size(x.Openorders)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -646,8 +783,12 @@ func _OutMessage_OneofSizer(msg proto.Message) (n int) {
 }
 
 type LoginRequest struct {
-	Username             string   `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
-	Password             string   `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	Username        string `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	Password        string `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	ProtocolVersion int32  `protobuf:"varint,3,opt,name=protocolVersion,proto3" json:"protocolVersion,omitempty"`
+	// identifies the session across connections, so its orders are recovered when the exchange restarts. Without a
+	// session name the session ends with the connection
+	SessionName          string   `protobuf:"bytes,4,opt,name=sessionName,proto3" json:"sessionName,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -657,7 +798,7 @@ func (m *LoginRequest) Reset()         { *m = LoginRequest{} }
 func (m *LoginRequest) String() string { return proto.CompactTextString(m) }
 func (*LoginRequest) ProtoMessage()    {}
 func (*LoginRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_exchange_58c6893b63e17bd9, []int{2}
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{2}
 }
 func (m *LoginRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_LoginRequest.Unmarshal(m, b)
@@ -691,8 +832,23 @@ func (m *LoginRequest) GetPassword() string {
 	return ""
 }
 
+func (m *LoginRequest) GetProtocolVersion() int32 {
+	if m != nil {
+		return m.ProtocolVersion
+	}
+	return 0
+}
+
+func (m *LoginRequest) GetSessionName() string {
+	if m != nil {
+		return m.SessionName
+	}
+	return ""
+}
+
 type LoginReply struct {
 	Error                string   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	ProtocolVersion      int32    `protobuf:"varint,3,opt,name=protocolVersion,proto3" json:"protocolVersion,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -702,7 +858,7 @@ func (m *LoginReply) Reset()         { *m = LoginReply{} }
 func (m *LoginReply) String() string { return proto.CompactTextString(m) }
 func (*LoginReply) ProtoMessage()    {}
 func (*LoginReply) Descriptor() ([]byte, []int) {
-	return fileDescriptor_exchange_58c6893b63e17bd9, []int{3}
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{3}
 }
 func (m *LoginReply) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_LoginReply.Unmarshal(m, b)
@@ -729,23 +885,42 @@ func (m *LoginReply) GetError() string {
 	return ""
 }
 
+func (m *LoginReply) GetProtocolVersion() int32 {
+	if m != nil {
+		return m.ProtocolVersion
+	}
+	return 0
+}
+
 type CreateOrderRequest struct {
-	ClOrdId              int32                        `protobuf:"varint,1,opt,name=clOrdId,proto3" json:"clOrdId,omitempty"`
-	Symbol               string                       `protobuf:"bytes,2,opt,name=symbol,proto3" json:"symbol,omitempty"`
-	Price                float64                      `protobuf:"fixed64,3,opt,name=price,proto3" json:"price,omitempty"`
-	Quantity             float64                      `protobuf:"fixed64,4,opt,name=quantity,proto3" json:"quantity,omitempty"`
-	OrderType            CreateOrderRequest_OrderType `protobuf:"varint,5,opt,name=orderType,proto3,enum=protocol.CreateOrderRequest_OrderType" json:"orderType,omitempty"`
-	OrderSide            CreateOrderRequest_OrderSide `protobuf:"varint,6,opt,name=orderSide,proto3,enum=protocol.CreateOrderRequest_OrderSide" json:"orderSide,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}                     `json:"-"`
-	XXX_unrecognized     []byte                       `json:"-"`
-	XXX_sizecache        int32                        `json:"-"`
+	ClOrdId     int32                          `protobuf:"varint,1,opt,name=clOrdId,proto3" json:"clOrdId,omitempty"`
+	Symbol      string                         `protobuf:"bytes,2,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Price       float64                        `protobuf:"fixed64,3,opt,name=price,proto3" json:"price,omitempty"`
+	Quantity    float64                        `protobuf:"fixed64,4,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	OrderType   CreateOrderRequest_OrderType   `protobuf:"varint,5,opt,name=orderType,proto3,enum=protocol.CreateOrderRequest_OrderType" json:"orderType,omitempty"`
+	OrderSide   CreateOrderRequest_OrderSide   `protobuf:"varint,6,opt,name=orderSide,proto3,enum=protocol.CreateOrderRequest_OrderSide" json:"orderSide,omitempty"`
+	StopPrice   float64                        `protobuf:"fixed64,7,opt,name=stopPrice,proto3" json:"stopPrice,omitempty"`
+	TimeInForce CreateOrderRequest_TimeInForce `protobuf:"varint,8,opt,name=timeInForce,proto3,enum=protocol.CreateOrderRequest_TimeInForce" json:"timeInForce,omitempty"`
+	// expireTime is required for GTD orders, in unix nanoseconds
+	ExpireTime int64 `protobuf:"varint,9,opt,name=expireTime,proto3" json:"expireTime,omitempty"`
+	// displayQuantity is the visible slice of an iceberg order, 0 to display the entire quantity
+	DisplayQuantity float64 `protobuf:"fixed64,10,opt,name=displayQuantity,proto3" json:"displayQuantity,omitempty"`
+	// account is an optional account or trader id, used for self trade prevention
+	Account               string   `protobuf:"bytes,11,opt,name=account,proto3" json:"account,omitempty"`
+	ScaledPrice           int64    `protobuf:"varint,12,opt,name=scaledPrice,proto3" json:"scaledPrice,omitempty"`
+	ScaledQuantity        int64    `protobuf:"varint,13,opt,name=scaledQuantity,proto3" json:"scaledQuantity,omitempty"`
+	ScaledStopPrice       int64    `protobuf:"varint,14,opt,name=scaledStopPrice,proto3" json:"scaledStopPrice,omitempty"`
+	ScaledDisplayQuantity int64    `protobuf:"varint,15,opt,name=scaledDisplayQuantity,proto3" json:"scaledDisplayQuantity,omitempty"`
+	XXX_NoUnkeyedLiteral  struct{} `json:"-"`
+	XXX_unrecognized      []byte   `json:"-"`
+	XXX_sizecache         int32    `json:"-"`
 }
 
 func (m *CreateOrderRequest) Reset()         { *m = CreateOrderRequest{} }
 func (m *CreateOrderRequest) String() string { return proto.CompactTextString(m) }
 func (*CreateOrderRequest) ProtoMessage()    {}
 func (*CreateOrderRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_exchange_58c6893b63e17bd9, []int{4}
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{4}
 }
 func (m *CreateOrderRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_CreateOrderRequest.Unmarshal(m, b)
@@ -807,10 +982,75 @@ func (m *CreateOrderRequest) GetOrderSide() CreateOrderRequest_OrderSide {
 	return CreateOrderRequest_Buy
 }
 
+func (m *CreateOrderRequest) GetStopPrice() float64 {
+	if m != nil {
+		return m.StopPrice
+	}
+	return 0
+}
+
+func (m *CreateOrderRequest) GetTimeInForce() CreateOrderRequest_TimeInForce {
+	if m != nil {
+		return m.TimeInForce
+	}
+	return CreateOrderRequest_GTC
+}
+
+func (m *CreateOrderRequest) GetExpireTime() int64 {
+	if m != nil {
+		return m.ExpireTime
+	}
+	return 0
+}
+
+func (m *CreateOrderRequest) GetDisplayQuantity() float64 {
+	if m != nil {
+		return m.DisplayQuantity
+	}
+	return 0
+}
+
+func (m *CreateOrderRequest) GetAccount() string {
+	if m != nil {
+		return m.Account
+	}
+	return ""
+}
+
+func (m *CreateOrderRequest) GetScaledPrice() int64 {
+	if m != nil {
+		return m.ScaledPrice
+	}
+	return 0
+}
+
+func (m *CreateOrderRequest) GetScaledQuantity() int64 {
+	if m != nil {
+		return m.ScaledQuantity
+	}
+	return 0
+}
+
+func (m *CreateOrderRequest) GetScaledStopPrice() int64 {
+	if m != nil {
+		return m.ScaledStopPrice
+	}
+	return 0
+}
+
+func (m *CreateOrderRequest) GetScaledDisplayQuantity() int64 {
+	if m != nil {
+		return m.ScaledDisplayQuantity
+	}
+	return 0
+}
+
 type ModifyOrderRequest struct {
 	ClOrdId              int32    `protobuf:"varint,1,opt,name=clOrdId,proto3" json:"clOrdId,omitempty"`
 	Price                float64  `protobuf:"fixed64,2,opt,name=price,proto3" json:"price,omitempty"`
 	Quantity             float64  `protobuf:"fixed64,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	ScaledPrice          int64    `protobuf:"varint,4,opt,name=scaledPrice,proto3" json:"scaledPrice,omitempty"`
+	ScaledQuantity       int64    `protobuf:"varint,5,opt,name=scaledQuantity,proto3" json:"scaledQuantity,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -820,7 +1060,7 @@ func (m *ModifyOrderRequest) Reset()         { *m = ModifyOrderRequest{} }
 func (m *ModifyOrderRequest) String() string { return proto.CompactTextString(m) }
 func (*ModifyOrderRequest) ProtoMessage()    {}
 func (*ModifyOrderRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_exchange_58c6893b63e17bd9, []int{5}
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{5}
 }
 func (m *ModifyOrderRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ModifyOrderRequest.Unmarshal(m, b)
@@ -861,6 +1101,20 @@ func (m *ModifyOrderRequest) GetQuantity() float64 {
 	return 0
 }
 
+func (m *ModifyOrderRequest) GetScaledPrice() int64 {
+	if m != nil {
+		return m.ScaledPrice
+	}
+	return 0
+}
+
+func (m *ModifyOrderRequest) GetScaledQuantity() int64 {
+	if m != nil {
+		return m.ScaledQuantity
+	}
+	return 0
+}
+
 type CancelOrderRequest struct {
 	ClOrdId              int32    `protobuf:"varint,1,opt,name=clOrdId,proto3" json:"clOrdId,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -872,7 +1126,7 @@ func (m *CancelOrderRequest) Reset()         { *m = CancelOrderRequest{} }
 func (m *CancelOrderRequest) String() string { return proto.CompactTextString(m) }
 func (*CancelOrderRequest) ProtoMessage()    {}
 func (*CancelOrderRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_exchange_58c6893b63e17bd9, []int{6}
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{6}
 }
 func (m *CancelOrderRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_CancelOrderRequest.Unmarshal(m, b)
@@ -905,6 +1159,10 @@ type MassQuoteRequest struct {
 	BidQuantity          float64  `protobuf:"fixed64,3,opt,name=bidQuantity,proto3" json:"bidQuantity,omitempty"`
 	AskPrice             float64  `protobuf:"fixed64,4,opt,name=askPrice,proto3" json:"askPrice,omitempty"`
 	AskQuantity          float64  `protobuf:"fixed64,5,opt,name=askQuantity,proto3" json:"askQuantity,omitempty"`
+	ScaledBidPrice       int64    `protobuf:"varint,6,opt,name=scaledBidPrice,proto3" json:"scaledBidPrice,omitempty"`
+	ScaledBidQuantity    int64    `protobuf:"varint,7,opt,name=scaledBidQuantity,proto3" json:"scaledBidQuantity,omitempty"`
+	ScaledAskPrice       int64    `protobuf:"varint,8,opt,name=scaledAskPrice,proto3" json:"scaledAskPrice,omitempty"`
+	ScaledAskQuantity    int64    `protobuf:"varint,9,opt,name=scaledAskQuantity,proto3" json:"scaledAskQuantity,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -914,7 +1172,7 @@ func (m *MassQuoteRequest) Reset()         { *m = MassQuoteRequest{} }
 func (m *MassQuoteRequest) String() string { return proto.CompactTextString(m) }
 func (*MassQuoteRequest) ProtoMessage()    {}
 func (*MassQuoteRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_exchange_58c6893b63e17bd9, []int{7}
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{7}
 }
 func (m *MassQuoteRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_MassQuoteRequest.Unmarshal(m, b)
@@ -969,6 +1227,34 @@ func (m *MassQuoteRequest) GetAskQuantity() float64 {
 	return 0
 }
 
+func (m *MassQuoteRequest) GetScaledBidPrice() int64 {
+	if m != nil {
+		return m.ScaledBidPrice
+	}
+	return 0
+}
+
+func (m *MassQuoteRequest) GetScaledBidQuantity() int64 {
+	if m != nil {
+		return m.ScaledBidQuantity
+	}
+	return 0
+}
+
+func (m *MassQuoteRequest) GetScaledAskPrice() int64 {
+	if m != nil {
+		return m.ScaledAskPrice
+	}
+	return 0
+}
+
+func (m *MassQuoteRequest) GetScaledAskQuantity() int64 {
+	if m != nil {
+		return m.ScaledAskQuantity
+	}
+	return 0
+}
+
 type SecurityDefinitionRequest struct {
 	Symbol               string   `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -980,7 +1266,7 @@ func (m *SecurityDefinitionRequest) Reset()         { *m = SecurityDefinitionReq
 func (m *SecurityDefinitionRequest) String() string { return proto.CompactTextString(m) }
 func (*SecurityDefinitionRequest) ProtoMessage()    {}
 func (*SecurityDefinitionRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_exchange_58c6893b63e17bd9, []int{8}
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{8}
 }
 func (m *SecurityDefinitionRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SecurityDefinitionRequest.Unmarshal(m, b)
@@ -1017,7 +1303,7 @@ func (m *DownloadRequest) Reset()         { *m = DownloadRequest{} }
 func (m *DownloadRequest) String() string { return proto.CompactTextString(m) }
 func (*DownloadRequest) ProtoMessage()    {}
 func (*DownloadRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_exchange_58c6893b63e17bd9, []int{9}
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{9}
 }
 func (m *DownloadRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DownloadRequest.Unmarshal(m, b)
@@ -1038,18 +1324,31 @@ func (m *DownloadRequest) XXX_DiscardUnknown() {
 var xxx_messageInfo_DownloadRequest proto.InternalMessageInfo
 
 type SecurityDefinition struct {
-	Symbol               string   `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
-	InstrumentID         int64    `protobuf:"varint,2,opt,name=instrumentID,proto3" json:"instrumentID,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Symbol       string `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	InstrumentID int64  `protobuf:"varint,2,opt,name=instrumentID,proto3" json:"instrumentID,omitempty"`
+	// price bands as a percentage either side of the reference price, zero if disabled
+	StaticPriceBand  float64          `protobuf:"fixed64,3,opt,name=staticPriceBand,proto3" json:"staticPriceBand,omitempty"`
+	DynamicPriceBand float64          `protobuf:"fixed64,4,opt,name=dynamicPriceBand,proto3" json:"dynamicPriceBand,omitempty"`
+	TickSizes        []*TickSizeLevel `protobuf:"bytes,5,rep,name=tickSizes,proto3" json:"tickSizes,omitempty"`
+	// zero if there is no lot size or quantity limit
+	LotSize                float64  `protobuf:"fixed64,6,opt,name=lotSize,proto3" json:"lotSize,omitempty"`
+	MinQuantity            float64  `protobuf:"fixed64,7,opt,name=minQuantity,proto3" json:"minQuantity,omitempty"`
+	MaxQuantity            float64  `protobuf:"fixed64,8,opt,name=maxQuantity,proto3" json:"maxQuantity,omitempty"`
+	ScaledStaticPriceBand  int64    `protobuf:"varint,9,opt,name=scaledStaticPriceBand,proto3" json:"scaledStaticPriceBand,omitempty"`
+	ScaledDynamicPriceBand int64    `protobuf:"varint,10,opt,name=scaledDynamicPriceBand,proto3" json:"scaledDynamicPriceBand,omitempty"`
+	ScaledLotSize          int64    `protobuf:"varint,11,opt,name=scaledLotSize,proto3" json:"scaledLotSize,omitempty"`
+	ScaledMinQuantity      int64    `protobuf:"varint,12,opt,name=scaledMinQuantity,proto3" json:"scaledMinQuantity,omitempty"`
+	ScaledMaxQuantity      int64    `protobuf:"varint,13,opt,name=scaledMaxQuantity,proto3" json:"scaledMaxQuantity,omitempty"`
+	XXX_NoUnkeyedLiteral   struct{} `json:"-"`
+	XXX_unrecognized       []byte   `json:"-"`
+	XXX_sizecache          int32    `json:"-"`
 }
 
 func (m *SecurityDefinition) Reset()         { *m = SecurityDefinition{} }
 func (m *SecurityDefinition) String() string { return proto.CompactTextString(m) }
 func (*SecurityDefinition) ProtoMessage()    {}
 func (*SecurityDefinition) Descriptor() ([]byte, []int) {
-	return fileDescriptor_exchange_58c6893b63e17bd9, []int{10}
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{10}
 }
 func (m *SecurityDefinition) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SecurityDefinition.Unmarshal(m, b)
@@ -1083,29 +1382,182 @@ func (m *SecurityDefinition) GetInstrumentID() int64 {
 	return 0
 }
 
+func (m *SecurityDefinition) GetStaticPriceBand() float64 {
+	if m != nil {
+		return m.StaticPriceBand
+	}
+	return 0
+}
+
+func (m *SecurityDefinition) GetDynamicPriceBand() float64 {
+	if m != nil {
+		return m.DynamicPriceBand
+	}
+	return 0
+}
+
+func (m *SecurityDefinition) GetTickSizes() []*TickSizeLevel {
+	if m != nil {
+		return m.TickSizes
+	}
+	return nil
+}
+
+func (m *SecurityDefinition) GetLotSize() float64 {
+	if m != nil {
+		return m.LotSize
+	}
+	return 0
+}
+
+func (m *SecurityDefinition) GetMinQuantity() float64 {
+	if m != nil {
+		return m.MinQuantity
+	}
+	return 0
+}
+
+func (m *SecurityDefinition) GetMaxQuantity() float64 {
+	if m != nil {
+		return m.MaxQuantity
+	}
+	return 0
+}
+
+func (m *SecurityDefinition) GetScaledStaticPriceBand() int64 {
+	if m != nil {
+		return m.ScaledStaticPriceBand
+	}
+	return 0
+}
+
+func (m *SecurityDefinition) GetScaledDynamicPriceBand() int64 {
+	if m != nil {
+		return m.ScaledDynamicPriceBand
+	}
+	return 0
+}
+
+func (m *SecurityDefinition) GetScaledLotSize() int64 {
+	if m != nil {
+		return m.ScaledLotSize
+	}
+	return 0
+}
+
+func (m *SecurityDefinition) GetScaledMinQuantity() int64 {
+	if m != nil {
+		return m.ScaledMinQuantity
+	}
+	return 0
+}
+
+func (m *SecurityDefinition) GetScaledMaxQuantity() int64 {
+	if m != nil {
+		return m.ScaledMaxQuantity
+	}
+	return 0
+}
+
+// the tick size for prices at or above price, until the next level
+type TickSizeLevel struct {
+	Price                float64  `protobuf:"fixed64,1,opt,name=price,proto3" json:"price,omitempty"`
+	TickSize             float64  `protobuf:"fixed64,2,opt,name=tickSize,proto3" json:"tickSize,omitempty"`
+	ScaledPrice          int64    `protobuf:"varint,3,opt,name=scaledPrice,proto3" json:"scaledPrice,omitempty"`
+	ScaledTickSize       int64    `protobuf:"varint,4,opt,name=scaledTickSize,proto3" json:"scaledTickSize,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TickSizeLevel) Reset()         { *m = TickSizeLevel{} }
+func (m *TickSizeLevel) String() string { return proto.CompactTextString(m) }
+func (*TickSizeLevel) ProtoMessage()    {}
+func (*TickSizeLevel) Descriptor() ([]byte, []int) {
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{11}
+}
+func (m *TickSizeLevel) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TickSizeLevel.Unmarshal(m, b)
+}
+func (m *TickSizeLevel) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TickSizeLevel.Marshal(b, m, deterministic)
+}
+func (dst *TickSizeLevel) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TickSizeLevel.Merge(dst, src)
+}
+func (m *TickSizeLevel) XXX_Size() int {
+	return xxx_messageInfo_TickSizeLevel.Size(m)
+}
+func (m *TickSizeLevel) XXX_DiscardUnknown() {
+	xxx_messageInfo_TickSizeLevel.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TickSizeLevel proto.InternalMessageInfo
+
+func (m *TickSizeLevel) GetPrice() float64 {
+	if m != nil {
+		return m.Price
+	}
+	return 0
+}
+
+func (m *TickSizeLevel) GetTickSize() float64 {
+	if m != nil {
+		return m.TickSize
+	}
+	return 0
+}
+
+func (m *TickSizeLevel) GetScaledPrice() int64 {
+	if m != nil {
+		return m.ScaledPrice
+	}
+	return 0
+}
+
+func (m *TickSizeLevel) GetScaledTickSize() int64 {
+	if m != nil {
+		return m.ScaledTickSize
+	}
+	return 0
+}
+
 type ExecutionReport struct {
-	Symbol               string                       `protobuf:"bytes,1,opt,name=Symbol,proto3" json:"Symbol,omitempty"`
-	ClOrdId              int32                        `protobuf:"varint,2,opt,name=clOrdId,proto3" json:"clOrdId,omitempty"`
-	ExOrdId              string                       `protobuf:"bytes,3,opt,name=exOrdId,proto3" json:"exOrdId,omitempty"`
-	OrderState           ExecutionReport_OrderState   `protobuf:"varint,4,opt,name=orderState,proto3,enum=protocol.ExecutionReport_OrderState" json:"orderState,omitempty"`
-	ReportType           ExecutionReport_ReportType   `protobuf:"varint,5,opt,name=reportType,proto3,enum=protocol.ExecutionReport_ReportType" json:"reportType,omitempty"`
-	Price                float64                      `protobuf:"fixed64,6,opt,name=price,proto3" json:"price,omitempty"`
-	Quantity             float64                      `protobuf:"fixed64,7,opt,name=quantity,proto3" json:"quantity,omitempty"`
-	Remaining            float64                      `protobuf:"fixed64,8,opt,name=remaining,proto3" json:"remaining,omitempty"`
-	LastPrice            float64                      `protobuf:"fixed64,9,opt,name=lastPrice,proto3" json:"lastPrice,omitempty"`
-	LastQuantity         float64                      `protobuf:"fixed64,10,opt,name=lastQuantity,proto3" json:"lastQuantity,omitempty"`
-	Side                 CreateOrderRequest_OrderSide `protobuf:"varint,11,opt,name=side,proto3,enum=protocol.CreateOrderRequest_OrderSide" json:"side,omitempty"`
-	RejectReason         string                       `protobuf:"bytes,12,opt,name=rejectReason,proto3" json:"rejectReason,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}                     `json:"-"`
-	XXX_unrecognized     []byte                       `json:"-"`
-	XXX_sizecache        int32                        `json:"-"`
+	Symbol       string                       `protobuf:"bytes,1,opt,name=Symbol,proto3" json:"Symbol,omitempty"`
+	ClOrdId      int32                        `protobuf:"varint,2,opt,name=clOrdId,proto3" json:"clOrdId,omitempty"`
+	ExOrdId      string                       `protobuf:"bytes,3,opt,name=exOrdId,proto3" json:"exOrdId,omitempty"`
+	OrderState   ExecutionReport_OrderState   `protobuf:"varint,4,opt,name=orderState,proto3,enum=protocol.ExecutionReport_OrderState" json:"orderState,omitempty"`
+	ReportType   ExecutionReport_ReportType   `protobuf:"varint,5,opt,name=reportType,proto3,enum=protocol.ExecutionReport_ReportType" json:"reportType,omitempty"`
+	Price        float64                      `protobuf:"fixed64,6,opt,name=price,proto3" json:"price,omitempty"`
+	Quantity     float64                      `protobuf:"fixed64,7,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	Remaining    float64                      `protobuf:"fixed64,8,opt,name=remaining,proto3" json:"remaining,omitempty"`
+	LastPrice    float64                      `protobuf:"fixed64,9,opt,name=lastPrice,proto3" json:"lastPrice,omitempty"`
+	LastQuantity float64                      `protobuf:"fixed64,10,opt,name=lastQuantity,proto3" json:"lastQuantity,omitempty"`
+	Side         CreateOrderRequest_OrderSide `protobuf:"varint,11,opt,name=side,proto3,enum=protocol.CreateOrderRequest_OrderSide" json:"side,omitempty"`
+	RejectReason string                       `protobuf:"bytes,12,opt,name=rejectReason,proto3" json:"rejectReason,omitempty"`
+	StopPrice    float64                      `protobuf:"fixed64,13,opt,name=stopPrice,proto3" json:"stopPrice,omitempty"`
+	Triggered    bool                         `protobuf:"varint,14,opt,name=triggered,proto3" json:"triggered,omitempty"`
+	// the expire time of day and GTD orders, in unix nanoseconds
+	ExpireTime            int64    `protobuf:"varint,15,opt,name=expireTime,proto3" json:"expireTime,omitempty"`
+	DisplayQuantity       float64  `protobuf:"fixed64,16,opt,name=displayQuantity,proto3" json:"displayQuantity,omitempty"`
+	Account               string   `protobuf:"bytes,17,opt,name=account,proto3" json:"account,omitempty"`
+	ScaledPrice           int64    `protobuf:"varint,18,opt,name=scaledPrice,proto3" json:"scaledPrice,omitempty"`
+	ScaledQuantity        int64    `protobuf:"varint,19,opt,name=scaledQuantity,proto3" json:"scaledQuantity,omitempty"`
+	ScaledRemaining       int64    `protobuf:"varint,20,opt,name=scaledRemaining,proto3" json:"scaledRemaining,omitempty"`
+	ScaledLastPrice       int64    `protobuf:"varint,21,opt,name=scaledLastPrice,proto3" json:"scaledLastPrice,omitempty"`
+	ScaledLastQuantity    int64    `protobuf:"varint,22,opt,name=scaledLastQuantity,proto3" json:"scaledLastQuantity,omitempty"`
+	ScaledStopPrice       int64    `protobuf:"varint,23,opt,name=scaledStopPrice,proto3" json:"scaledStopPrice,omitempty"`
+	ScaledDisplayQuantity int64    `protobuf:"varint,24,opt,name=scaledDisplayQuantity,proto3" json:"scaledDisplayQuantity,omitempty"`
+	XXX_NoUnkeyedLiteral  struct{} `json:"-"`
+	XXX_unrecognized      []byte   `json:"-"`
+	XXX_sizecache         int32    `json:"-"`
 }
 
 func (m *ExecutionReport) Reset()         { *m = ExecutionReport{} }
 func (m *ExecutionReport) String() string { return proto.CompactTextString(m) }
 func (*ExecutionReport) ProtoMessage()    {}
 func (*ExecutionReport) Descriptor() ([]byte, []int) {
-	return fileDescriptor_exchange_58c6893b63e17bd9, []int{11}
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{12}
 }
 func (m *ExecutionReport) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ExecutionReport.Unmarshal(m, b)
@@ -1209,44 +1661,1106 @@ func (m *ExecutionReport) GetRejectReason() string {
 	return ""
 }
 
-type SessionReject struct {
-	Error                string   `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+func (m *ExecutionReport) GetStopPrice() float64 {
+	if m != nil {
+		return m.StopPrice
+	}
+	return 0
 }
 
-func (m *SessionReject) Reset()         { *m = SessionReject{} }
-func (m *SessionReject) String() string { return proto.CompactTextString(m) }
-func (*SessionReject) ProtoMessage()    {}
-func (*SessionReject) Descriptor() ([]byte, []int) {
-	return fileDescriptor_exchange_58c6893b63e17bd9, []int{12}
+func (m *ExecutionReport) GetTriggered() bool {
+	if m != nil {
+		return m.Triggered
+	}
+	return false
+}
+
+func (m *ExecutionReport) GetExpireTime() int64 {
+	if m != nil {
+		return m.ExpireTime
+	}
+	return 0
+}
+
+func (m *ExecutionReport) GetDisplayQuantity() float64 {
+	if m != nil {
+		return m.DisplayQuantity
+	}
+	return 0
+}
+
+func (m *ExecutionReport) GetAccount() string {
+	if m != nil {
+		return m.Account
+	}
+	return ""
+}
+
+func (m *ExecutionReport) GetScaledPrice() int64 {
+	if m != nil {
+		return m.ScaledPrice
+	}
+	return 0
+}
+
+func (m *ExecutionReport) GetScaledQuantity() int64 {
+	if m != nil {
+		return m.ScaledQuantity
+	}
+	return 0
+}
+
+func (m *ExecutionReport) GetScaledRemaining() int64 {
+	if m != nil {
+		return m.ScaledRemaining
+	}
+	return 0
+}
+
+func (m *ExecutionReport) GetScaledLastPrice() int64 {
+	if m != nil {
+		return m.ScaledLastPrice
+	}
+	return 0
+}
+
+func (m *ExecutionReport) GetScaledLastQuantity() int64 {
+	if m != nil {
+		return m.ScaledLastQuantity
+	}
+	return 0
+}
+
+func (m *ExecutionReport) GetScaledStopPrice() int64 {
+	if m != nil {
+		return m.ScaledStopPrice
+	}
+	return 0
+}
+
+func (m *ExecutionReport) GetScaledDisplayQuantity() int64 {
+	if m != nil {
+		return m.ScaledDisplayQuantity
+	}
+	return 0
+}
+
+// OrderStatusRequest is answered with a status ExecutionReport of the session's order, or one with the orderState
+// Rejected and rejectReason "unknown order" if the session has no such order
+type OrderStatusRequest struct {
+	ClOrdId              int32    `protobuf:"varint,1,opt,name=clOrdId,proto3" json:"clOrdId,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *OrderStatusRequest) Reset()         { *m = OrderStatusRequest{} }
+func (m *OrderStatusRequest) String() string { return proto.CompactTextString(m) }
+func (*OrderStatusRequest) ProtoMessage()    {}
+func (*OrderStatusRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{13}
+}
+func (m *OrderStatusRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OrderStatusRequest.Unmarshal(m, b)
+}
+func (m *OrderStatusRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OrderStatusRequest.Marshal(b, m, deterministic)
+}
+func (dst *OrderStatusRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OrderStatusRequest.Merge(dst, src)
+}
+func (m *OrderStatusRequest) XXX_Size() int {
+	return xxx_messageInfo_OrderStatusRequest.Size(m)
+}
+func (m *OrderStatusRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_OrderStatusRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OrderStatusRequest proto.InternalMessageInfo
+
+func (m *OrderStatusRequest) GetClOrdId() int32 {
+	if m != nil {
+		return m.ClOrdId
+	}
+	return 0
+}
+
+// OpenOrdersRequest is answered with an OpenOrdersReply with the session's open orders
+type OpenOrdersRequest struct {
+	// the symbol, empty for all instruments
+	Symbol               string   `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *OpenOrdersRequest) Reset()         { *m = OpenOrdersRequest{} }
+func (m *OpenOrdersRequest) String() string { return proto.CompactTextString(m) }
+func (*OpenOrdersRequest) ProtoMessage()    {}
+func (*OpenOrdersRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{14}
+}
+func (m *OpenOrdersRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OpenOrdersRequest.Unmarshal(m, b)
+}
+func (m *OpenOrdersRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OpenOrdersRequest.Marshal(b, m, deterministic)
+}
+func (dst *OpenOrdersRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OpenOrdersRequest.Merge(dst, src)
+}
+func (m *OpenOrdersRequest) XXX_Size() int {
+	return xxx_messageInfo_OpenOrdersRequest.Size(m)
+}
+func (m *OpenOrdersRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_OpenOrdersRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OpenOrdersRequest proto.InternalMessageInfo
+
+func (m *OpenOrdersRequest) GetSymbol() string {
+	if m != nil {
+		return m.Symbol
+	}
+	return ""
+}
+
+type OpenOrdersReply struct {
+	Orders               []*ExecutionReport `protobuf:"bytes,1,rep,name=orders,proto3" json:"orders,omitempty"`
+	Error                string             `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *OpenOrdersReply) Reset()         { *m = OpenOrdersReply{} }
+func (m *OpenOrdersReply) String() string { return proto.CompactTextString(m) }
+func (*OpenOrdersReply) ProtoMessage()    {}
+func (*OpenOrdersReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{15}
+}
+func (m *OpenOrdersReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OpenOrdersReply.Unmarshal(m, b)
+}
+func (m *OpenOrdersReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OpenOrdersReply.Marshal(b, m, deterministic)
+}
+func (dst *OpenOrdersReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OpenOrdersReply.Merge(dst, src)
+}
+func (m *OpenOrdersReply) XXX_Size() int {
+	return xxx_messageInfo_OpenOrdersReply.Size(m)
+}
+func (m *OpenOrdersReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_OpenOrdersReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OpenOrdersReply proto.InternalMessageInfo
+
+func (m *OpenOrdersReply) GetOrders() []*ExecutionReport {
+	if m != nil {
+		return m.Orders
+	}
+	return nil
+}
+
+func (m *OpenOrdersReply) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type SessionReject struct {
+	Error                string   `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SessionReject) Reset()         { *m = SessionReject{} }
+func (m *SessionReject) String() string { return proto.CompactTextString(m) }
+func (*SessionReject) ProtoMessage()    {}
+func (*SessionReject) Descriptor() ([]byte, []int) {
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{16}
+}
+func (m *SessionReject) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SessionReject.Unmarshal(m, b)
+}
+func (m *SessionReject) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SessionReject.Marshal(b, m, deterministic)
+}
+func (dst *SessionReject) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SessionReject.Merge(dst, src)
+}
+func (m *SessionReject) XXX_Size() int {
+	return xxx_messageInfo_SessionReject.Size(m)
+}
+func (m *SessionReject) XXX_DiscardUnknown() {
+	xxx_messageInfo_SessionReject.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SessionReject proto.InternalMessageInfo
+
+func (m *SessionReject) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type MarketDataRequest struct {
+	// the symbols to receive, empty for all instruments
+	Symbols []string `protobuf:"bytes,1,rep,name=symbols,proto3" json:"symbols,omitempty"`
+	// if non-zero at most one book per symbol is sent in each interval, in milliseconds, with the latest levels. If zero
+	// every book is sent. Trades are never conflated.
+	ConflateInterval int32 `protobuf:"varint,2,opt,name=conflateInterval,proto3" json:"conflateInterval,omitempty"`
+	// the number of price levels sent on each side, zero for the complete book
+	Depth                int32    `protobuf:"varint,3,opt,name=depth,proto3" json:"depth,omitempty"`
+	ProtocolVersion      int32    `protobuf:"varint,4,opt,name=protocolVersion,proto3" json:"protocolVersion,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MarketDataRequest) Reset()         { *m = MarketDataRequest{} }
+func (m *MarketDataRequest) String() string { return proto.CompactTextString(m) }
+func (*MarketDataRequest) ProtoMessage()    {}
+func (*MarketDataRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{17}
+}
+func (m *MarketDataRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MarketDataRequest.Unmarshal(m, b)
+}
+func (m *MarketDataRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MarketDataRequest.Marshal(b, m, deterministic)
+}
+func (dst *MarketDataRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MarketDataRequest.Merge(dst, src)
+}
+func (m *MarketDataRequest) XXX_Size() int {
+	return xxx_messageInfo_MarketDataRequest.Size(m)
+}
+func (m *MarketDataRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_MarketDataRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MarketDataRequest proto.InternalMessageInfo
+
+func (m *MarketDataRequest) GetSymbols() []string {
+	if m != nil {
+		return m.Symbols
+	}
+	return nil
+}
+
+func (m *MarketDataRequest) GetConflateInterval() int32 {
+	if m != nil {
+		return m.ConflateInterval
+	}
+	return 0
+}
+
+func (m *MarketDataRequest) GetDepth() int32 {
+	if m != nil {
+		return m.Depth
+	}
+	return 0
+}
+
+func (m *MarketDataRequest) GetProtocolVersion() int32 {
+	if m != nil {
+		return m.ProtocolVersion
+	}
+	return 0
+}
+
+type MarketDataMessage struct {
+	// Types that are valid to be assigned to Update:
+	//	*MarketDataMessage_Book
+	//	*MarketDataMessage_Trade
+	//	*MarketDataMessage_Statistics
+	Update               isMarketDataMessage_Update `protobuf_oneof:"update"`
+	ProtocolVersion      int32                      `protobuf:"varint,4,opt,name=protocolVersion,proto3" json:"protocolVersion,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                   `json:"-"`
+	XXX_unrecognized     []byte                     `json:"-"`
+	XXX_sizecache        int32                      `json:"-"`
+}
+
+func (m *MarketDataMessage) Reset()         { *m = MarketDataMessage{} }
+func (m *MarketDataMessage) String() string { return proto.CompactTextString(m) }
+func (*MarketDataMessage) ProtoMessage()    {}
+func (*MarketDataMessage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{18}
+}
+func (m *MarketDataMessage) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MarketDataMessage.Unmarshal(m, b)
+}
+func (m *MarketDataMessage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MarketDataMessage.Marshal(b, m, deterministic)
+}
+func (dst *MarketDataMessage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MarketDataMessage.Merge(dst, src)
+}
+func (m *MarketDataMessage) XXX_Size() int {
+	return xxx_messageInfo_MarketDataMessage.Size(m)
+}
+func (m *MarketDataMessage) XXX_DiscardUnknown() {
+	xxx_messageInfo_MarketDataMessage.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MarketDataMessage proto.InternalMessageInfo
+
+type isMarketDataMessage_Update interface {
+	isMarketDataMessage_Update()
+}
+
+type MarketDataMessage_Book struct {
+	Book *MarketDataBook `protobuf:"bytes,1,opt,name=book,proto3,oneof"`
+}
+
+type MarketDataMessage_Trade struct {
+	Trade *MarketDataTrade `protobuf:"bytes,2,opt,name=trade,proto3,oneof"`
+}
+
+type MarketDataMessage_Statistics struct {
+	Statistics *MarketDataStatistics `protobuf:"bytes,3,opt,name=statistics,proto3,oneof"`
+}
+
+func (*MarketDataMessage_Book) isMarketDataMessage_Update() {}
+
+func (*MarketDataMessage_Trade) isMarketDataMessage_Update() {}
+
+func (*MarketDataMessage_Statistics) isMarketDataMessage_Update() {}
+
+func (m *MarketDataMessage) GetUpdate() isMarketDataMessage_Update {
+	if m != nil {
+		return m.Update
+	}
+	return nil
+}
+
+func (m *MarketDataMessage) GetBook() *MarketDataBook {
+	if x, ok := m.GetUpdate().(*MarketDataMessage_Book); ok {
+		return x.Book
+	}
+	return nil
+}
+
+func (m *MarketDataMessage) GetTrade() *MarketDataTrade {
+	if x, ok := m.GetUpdate().(*MarketDataMessage_Trade); ok {
+		return x.Trade
+	}
+	return nil
+}
+
+func (m *MarketDataMessage) GetStatistics() *MarketDataStatistics {
+	if x, ok := m.GetUpdate().(*MarketDataMessage_Statistics); ok {
+		return x.Statistics
+	}
+	return nil
+}
+
+func (m *MarketDataMessage) GetProtocolVersion() int32 {
+	if m != nil {
+		return m.ProtocolVersion
+	}
+	return 0
+}
+
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*MarketDataMessage) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _MarketDataMessage_OneofMarshaler, _MarketDataMessage_OneofUnmarshaler, _MarketDataMessage_OneofSizer, []interface{}{
+		(*MarketDataMessage_Book)(nil),
+		(*MarketDataMessage_Trade)(nil),
+		(*MarketDataMessage_Statistics)(nil),
+	}
+}
+
+func _MarketDataMessage_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*MarketDataMessage)
+	// update
+	switch x := m.Update.(type) {
+	case *MarketDataMessage_Book:
+		b.EncodeVarint(1<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Book); err != nil {
+			return err
+		}
+	case *MarketDataMessage_Trade:
+		b.EncodeVarint(2<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Trade); err != nil {
+			return err
+		}
+	case *MarketDataMessage_Statistics:
+		b.EncodeVarint(3<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Statistics); err != nil {
+			return err
+		}
+	case nil:
+	default:
+		return fmt.Errorf("MarketDataMessage.Update has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _MarketDataMessage_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*MarketDataMessage)
+	switch tag {
+	case 1: // update.book
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(MarketDataBook)
+		err := b.DecodeMessage(msg)
+		m.Update = &MarketDataMessage_Book{msg}
+		return true, err
+	case 2: // update.trade
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(MarketDataTrade)
+		err := b.DecodeMessage(msg)
+		m.Update = &MarketDataMessage_Trade{msg}
+		return true, err
+	case 3: // update.statistics
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(MarketDataStatistics)
+		err := b.DecodeMessage(msg)
+		m.Update = &MarketDataMessage_Statistics{msg}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func _MarketDataMessage_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*MarketDataMessage)
+	// update
+	switch x := m.Update.(type) {
+	case *MarketDataMessage_Book:
+		s := proto.Size(x.Book)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *MarketDataMessage_Trade:
+		s := proto.Size(x.Trade)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *MarketDataMessage_Statistics:
+		s := proto.Size(x.Statistics)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
+	}
+	return n
+}
+
+type MarketDataLevel struct {
+	Price                float64  `protobuf:"fixed64,1,opt,name=price,proto3" json:"price,omitempty"`
+	Quantity             float64  `protobuf:"fixed64,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	ScaledPrice          int64    `protobuf:"varint,3,opt,name=scaledPrice,proto3" json:"scaledPrice,omitempty"`
+	ScaledQuantity       int64    `protobuf:"varint,4,opt,name=scaledQuantity,proto3" json:"scaledQuantity,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MarketDataLevel) Reset()         { *m = MarketDataLevel{} }
+func (m *MarketDataLevel) String() string { return proto.CompactTextString(m) }
+func (*MarketDataLevel) ProtoMessage()    {}
+func (*MarketDataLevel) Descriptor() ([]byte, []int) {
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{19}
+}
+func (m *MarketDataLevel) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MarketDataLevel.Unmarshal(m, b)
+}
+func (m *MarketDataLevel) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MarketDataLevel.Marshal(b, m, deterministic)
+}
+func (dst *MarketDataLevel) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MarketDataLevel.Merge(dst, src)
+}
+func (m *MarketDataLevel) XXX_Size() int {
+	return xxx_messageInfo_MarketDataLevel.Size(m)
+}
+func (m *MarketDataLevel) XXX_DiscardUnknown() {
+	xxx_messageInfo_MarketDataLevel.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MarketDataLevel proto.InternalMessageInfo
+
+func (m *MarketDataLevel) GetPrice() float64 {
+	if m != nil {
+		return m.Price
+	}
+	return 0
+}
+
+func (m *MarketDataLevel) GetQuantity() float64 {
+	if m != nil {
+		return m.Quantity
+	}
+	return 0
+}
+
+func (m *MarketDataLevel) GetScaledPrice() int64 {
+	if m != nil {
+		return m.ScaledPrice
+	}
+	return 0
+}
+
+func (m *MarketDataLevel) GetScaledQuantity() int64 {
+	if m != nil {
+		return m.ScaledQuantity
+	}
+	return 0
+}
+
+type MarketDataBook struct {
+	Symbol                 string             `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Sequence               uint64             `protobuf:"varint,2,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Bids                   []*MarketDataLevel `protobuf:"bytes,3,rep,name=bids,proto3" json:"bids,omitempty"`
+	Asks                   []*MarketDataLevel `protobuf:"bytes,4,rep,name=asks,proto3" json:"asks,omitempty"`
+	State                  string             `protobuf:"bytes,5,opt,name=state,proto3" json:"state,omitempty"`
+	InAuction              bool               `protobuf:"varint,6,opt,name=inAuction,proto3" json:"inAuction,omitempty"`
+	IndicativePrice        float64            `protobuf:"fixed64,7,opt,name=indicativePrice,proto3" json:"indicativePrice,omitempty"`
+	IndicativeVolume       float64            `protobuf:"fixed64,8,opt,name=indicativeVolume,proto3" json:"indicativeVolume,omitempty"`
+	ScaledIndicativePrice  int64              `protobuf:"varint,9,opt,name=scaledIndicativePrice,proto3" json:"scaledIndicativePrice,omitempty"`
+	ScaledIndicativeVolume int64              `protobuf:"varint,10,opt,name=scaledIndicativeVolume,proto3" json:"scaledIndicativeVolume,omitempty"`
+	XXX_NoUnkeyedLiteral   struct{}           `json:"-"`
+	XXX_unrecognized       []byte             `json:"-"`
+	XXX_sizecache          int32              `json:"-"`
+}
+
+func (m *MarketDataBook) Reset()         { *m = MarketDataBook{} }
+func (m *MarketDataBook) String() string { return proto.CompactTextString(m) }
+func (*MarketDataBook) ProtoMessage()    {}
+func (*MarketDataBook) Descriptor() ([]byte, []int) {
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{20}
+}
+func (m *MarketDataBook) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MarketDataBook.Unmarshal(m, b)
+}
+func (m *MarketDataBook) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MarketDataBook.Marshal(b, m, deterministic)
+}
+func (dst *MarketDataBook) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MarketDataBook.Merge(dst, src)
+}
+func (m *MarketDataBook) XXX_Size() int {
+	return xxx_messageInfo_MarketDataBook.Size(m)
+}
+func (m *MarketDataBook) XXX_DiscardUnknown() {
+	xxx_messageInfo_MarketDataBook.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MarketDataBook proto.InternalMessageInfo
+
+func (m *MarketDataBook) GetSymbol() string {
+	if m != nil {
+		return m.Symbol
+	}
+	return ""
+}
+
+func (m *MarketDataBook) GetSequence() uint64 {
+	if m != nil {
+		return m.Sequence
+	}
+	return 0
+}
+
+func (m *MarketDataBook) GetBids() []*MarketDataLevel {
+	if m != nil {
+		return m.Bids
+	}
+	return nil
+}
+
+func (m *MarketDataBook) GetAsks() []*MarketDataLevel {
+	if m != nil {
+		return m.Asks
+	}
+	return nil
+}
+
+func (m *MarketDataBook) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *MarketDataBook) GetInAuction() bool {
+	if m != nil {
+		return m.InAuction
+	}
+	return false
+}
+
+func (m *MarketDataBook) GetIndicativePrice() float64 {
+	if m != nil {
+		return m.IndicativePrice
+	}
+	return 0
+}
+
+func (m *MarketDataBook) GetIndicativeVolume() float64 {
+	if m != nil {
+		return m.IndicativeVolume
+	}
+	return 0
+}
+
+func (m *MarketDataBook) GetScaledIndicativePrice() int64 {
+	if m != nil {
+		return m.ScaledIndicativePrice
+	}
+	return 0
+}
+
+func (m *MarketDataBook) GetScaledIndicativeVolume() int64 {
+	if m != nil {
+		return m.ScaledIndicativeVolume
+	}
+	return 0
+}
+
+type MarketDataTrade struct {
+	Symbol     string  `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Price      float64 `protobuf:"fixed64,2,opt,name=price,proto3" json:"price,omitempty"`
+	Quantity   float64 `protobuf:"fixed64,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	ExchangeID string  `protobuf:"bytes,4,opt,name=exchangeID,proto3" json:"exchangeID,omitempty"`
+	// in unix nanoseconds
+	TradeTime            int64    `protobuf:"varint,5,opt,name=tradeTime,proto3" json:"tradeTime,omitempty"`
+	ScaledPrice          int64    `protobuf:"varint,6,opt,name=scaledPrice,proto3" json:"scaledPrice,omitempty"`
+	ScaledQuantity       int64    `protobuf:"varint,7,opt,name=scaledQuantity,proto3" json:"scaledQuantity,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *MarketDataTrade) Reset()         { *m = MarketDataTrade{} }
+func (m *MarketDataTrade) String() string { return proto.CompactTextString(m) }
+func (*MarketDataTrade) ProtoMessage()    {}
+func (*MarketDataTrade) Descriptor() ([]byte, []int) {
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{21}
+}
+func (m *MarketDataTrade) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MarketDataTrade.Unmarshal(m, b)
+}
+func (m *MarketDataTrade) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MarketDataTrade.Marshal(b, m, deterministic)
+}
+func (dst *MarketDataTrade) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MarketDataTrade.Merge(dst, src)
+}
+func (m *MarketDataTrade) XXX_Size() int {
+	return xxx_messageInfo_MarketDataTrade.Size(m)
+}
+func (m *MarketDataTrade) XXX_DiscardUnknown() {
+	xxx_messageInfo_MarketDataTrade.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MarketDataTrade proto.InternalMessageInfo
+
+func (m *MarketDataTrade) GetSymbol() string {
+	if m != nil {
+		return m.Symbol
+	}
+	return ""
+}
+
+func (m *MarketDataTrade) GetPrice() float64 {
+	if m != nil {
+		return m.Price
+	}
+	return 0
+}
+
+func (m *MarketDataTrade) GetQuantity() float64 {
+	if m != nil {
+		return m.Quantity
+	}
+	return 0
+}
+
+func (m *MarketDataTrade) GetExchangeID() string {
+	if m != nil {
+		return m.ExchangeID
+	}
+	return ""
+}
+
+func (m *MarketDataTrade) GetTradeTime() int64 {
+	if m != nil {
+		return m.TradeTime
+	}
+	return 0
 }
-func (m *SessionReject) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_SessionReject.Unmarshal(m, b)
+
+func (m *MarketDataTrade) GetScaledPrice() int64 {
+	if m != nil {
+		return m.ScaledPrice
+	}
+	return 0
 }
-func (m *SessionReject) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_SessionReject.Marshal(b, m, deterministic)
+
+func (m *MarketDataTrade) GetScaledQuantity() int64 {
+	if m != nil {
+		return m.ScaledQuantity
+	}
+	return 0
 }
-func (dst *SessionReject) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_SessionReject.Merge(dst, src)
+
+type MarketDataStatistics struct {
+	Symbol               string   `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Volume               float64  `protobuf:"fixed64,2,opt,name=volume,proto3" json:"volume,omitempty"`
+	High                 float64  `protobuf:"fixed64,3,opt,name=high,proto3" json:"high,omitempty"`
+	Low                  float64  `protobuf:"fixed64,4,opt,name=low,proto3" json:"low,omitempty"`
+	HasHighLow           bool     `protobuf:"varint,5,opt,name=hasHighLow,proto3" json:"hasHighLow,omitempty"`
+	ScaledVolume         int64    `protobuf:"varint,6,opt,name=scaledVolume,proto3" json:"scaledVolume,omitempty"`
+	ScaledHigh           int64    `protobuf:"varint,7,opt,name=scaledHigh,proto3" json:"scaledHigh,omitempty"`
+	ScaledLow            int64    `protobuf:"varint,8,opt,name=scaledLow,proto3" json:"scaledLow,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
-func (m *SessionReject) XXX_Size() int {
-	return xxx_messageInfo_SessionReject.Size(m)
+
+func (m *MarketDataStatistics) Reset()         { *m = MarketDataStatistics{} }
+func (m *MarketDataStatistics) String() string { return proto.CompactTextString(m) }
+func (*MarketDataStatistics) ProtoMessage()    {}
+func (*MarketDataStatistics) Descriptor() ([]byte, []int) {
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{22}
 }
-func (m *SessionReject) XXX_DiscardUnknown() {
-	xxx_messageInfo_SessionReject.DiscardUnknown(m)
+func (m *MarketDataStatistics) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MarketDataStatistics.Unmarshal(m, b)
+}
+func (m *MarketDataStatistics) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MarketDataStatistics.Marshal(b, m, deterministic)
+}
+func (dst *MarketDataStatistics) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MarketDataStatistics.Merge(dst, src)
+}
+func (m *MarketDataStatistics) XXX_Size() int {
+	return xxx_messageInfo_MarketDataStatistics.Size(m)
+}
+func (m *MarketDataStatistics) XXX_DiscardUnknown() {
+	xxx_messageInfo_MarketDataStatistics.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_SessionReject proto.InternalMessageInfo
+var xxx_messageInfo_MarketDataStatistics proto.InternalMessageInfo
 
-func (m *SessionReject) GetError() string {
+func (m *MarketDataStatistics) GetSymbol() string {
 	if m != nil {
-		return m.Error
+		return m.Symbol
+	}
+	return ""
+}
+
+func (m *MarketDataStatistics) GetVolume() float64 {
+	if m != nil {
+		return m.Volume
+	}
+	return 0
+}
+
+func (m *MarketDataStatistics) GetHigh() float64 {
+	if m != nil {
+		return m.High
+	}
+	return 0
+}
+
+func (m *MarketDataStatistics) GetLow() float64 {
+	if m != nil {
+		return m.Low
+	}
+	return 0
+}
+
+func (m *MarketDataStatistics) GetHasHighLow() bool {
+	if m != nil {
+		return m.HasHighLow
+	}
+	return false
+}
+
+func (m *MarketDataStatistics) GetScaledVolume() int64 {
+	if m != nil {
+		return m.ScaledVolume
+	}
+	return 0
+}
+
+func (m *MarketDataStatistics) GetScaledHigh() int64 {
+	if m != nil {
+		return m.ScaledHigh
+	}
+	return 0
+}
+
+func (m *MarketDataStatistics) GetScaledLow() int64 {
+	if m != nil {
+		return m.ScaledLow
+	}
+	return 0
+}
+
+type TradesRequest struct {
+	// the symbol, empty for all instruments
+	Symbol string `protobuf:"bytes,1,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	// the time range inclusive, in unix nanoseconds, zero is unbounded
+	FromTime             int64    `protobuf:"varint,2,opt,name=fromTime,proto3" json:"fromTime,omitempty"`
+	ToTime               int64    `protobuf:"varint,3,opt,name=toTime,proto3" json:"toTime,omitempty"`
+	ProtocolVersion      int32    `protobuf:"varint,4,opt,name=protocolVersion,proto3" json:"protocolVersion,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TradesRequest) Reset()         { *m = TradesRequest{} }
+func (m *TradesRequest) String() string { return proto.CompactTextString(m) }
+func (*TradesRequest) ProtoMessage()    {}
+func (*TradesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{23}
+}
+func (m *TradesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TradesRequest.Unmarshal(m, b)
+}
+func (m *TradesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TradesRequest.Marshal(b, m, deterministic)
+}
+func (dst *TradesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TradesRequest.Merge(dst, src)
+}
+func (m *TradesRequest) XXX_Size() int {
+	return xxx_messageInfo_TradesRequest.Size(m)
+}
+func (m *TradesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TradesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TradesRequest proto.InternalMessageInfo
+
+func (m *TradesRequest) GetSymbol() string {
+	if m != nil {
+		return m.Symbol
+	}
+	return ""
+}
+
+func (m *TradesRequest) GetFromTime() int64 {
+	if m != nil {
+		return m.FromTime
+	}
+	return 0
+}
+
+func (m *TradesRequest) GetToTime() int64 {
+	if m != nil {
+		return m.ToTime
+	}
+	return 0
+}
+
+func (m *TradesRequest) GetProtocolVersion() int32 {
+	if m != nil {
+		return m.ProtocolVersion
+	}
+	return 0
+}
+
+type TradesReply struct {
+	Trades               []*TradeCapture `protobuf:"bytes,1,rep,name=trades,proto3" json:"trades,omitempty"`
+	ProtocolVersion      int32           `protobuf:"varint,2,opt,name=protocolVersion,proto3" json:"protocolVersion,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *TradesReply) Reset()         { *m = TradesReply{} }
+func (m *TradesReply) String() string { return proto.CompactTextString(m) }
+func (*TradesReply) ProtoMessage()    {}
+func (*TradesReply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{24}
+}
+func (m *TradesReply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TradesReply.Unmarshal(m, b)
+}
+func (m *TradesReply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TradesReply.Marshal(b, m, deterministic)
+}
+func (dst *TradesReply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TradesReply.Merge(dst, src)
+}
+func (m *TradesReply) XXX_Size() int {
+	return xxx_messageInfo_TradesReply.Size(m)
+}
+func (m *TradesReply) XXX_DiscardUnknown() {
+	xxx_messageInfo_TradesReply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TradesReply proto.InternalMessageInfo
+
+func (m *TradesReply) GetTrades() []*TradeCapture {
+	if m != nil {
+		return m.Trades
+	}
+	return nil
+}
+
+func (m *TradesReply) GetProtocolVersion() int32 {
+	if m != nil {
+		return m.ProtocolVersion
+	}
+	return 0
+}
+
+type TradeCapture struct {
+	// reportID is unique, tradeID is shared by the trades of a match and is the exchangeID of the market data trade
+	ReportID  int64   `protobuf:"varint,1,opt,name=reportID,proto3" json:"reportID,omitempty"`
+	TradeID   int64   `protobuf:"varint,2,opt,name=tradeID,proto3" json:"tradeID,omitempty"`
+	Symbol    string  `protobuf:"bytes,3,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Price     float64 `protobuf:"fixed64,4,opt,name=price,proto3" json:"price,omitempty"`
+	Quantity  float64 `protobuf:"fixed64,5,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	Buyer     string  `protobuf:"bytes,6,opt,name=buyer,proto3" json:"buyer,omitempty"`
+	BuyOrder  string  `protobuf:"bytes,7,opt,name=buyOrder,proto3" json:"buyOrder,omitempty"`
+	Seller    string  `protobuf:"bytes,8,opt,name=seller,proto3" json:"seller,omitempty"`
+	SellOrder string  `protobuf:"bytes,9,opt,name=sellOrder,proto3" json:"sellOrder,omitempty"`
+	// the side of the incoming order, buy or sell, empty for auction trades
+	Aggressor string `protobuf:"bytes,10,opt,name=aggressor,proto3" json:"aggressor,omitempty"`
+	// in unix nanoseconds
+	TradeTime            int64    `protobuf:"varint,11,opt,name=tradeTime,proto3" json:"tradeTime,omitempty"`
+	ScaledPrice          int64    `protobuf:"varint,12,opt,name=scaledPrice,proto3" json:"scaledPrice,omitempty"`
+	ScaledQuantity       int64    `protobuf:"varint,13,opt,name=scaledQuantity,proto3" json:"scaledQuantity,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TradeCapture) Reset()         { *m = TradeCapture{} }
+func (m *TradeCapture) String() string { return proto.CompactTextString(m) }
+func (*TradeCapture) ProtoMessage()    {}
+func (*TradeCapture) Descriptor() ([]byte, []int) {
+	return fileDescriptor_exchange_763b01faa6a6a151, []int{25}
+}
+func (m *TradeCapture) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TradeCapture.Unmarshal(m, b)
+}
+func (m *TradeCapture) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TradeCapture.Marshal(b, m, deterministic)
+}
+func (dst *TradeCapture) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TradeCapture.Merge(dst, src)
+}
+func (m *TradeCapture) XXX_Size() int {
+	return xxx_messageInfo_TradeCapture.Size(m)
+}
+func (m *TradeCapture) XXX_DiscardUnknown() {
+	xxx_messageInfo_TradeCapture.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TradeCapture proto.InternalMessageInfo
+
+func (m *TradeCapture) GetReportID() int64 {
+	if m != nil {
+		return m.ReportID
+	}
+	return 0
+}
+
+func (m *TradeCapture) GetTradeID() int64 {
+	if m != nil {
+		return m.TradeID
+	}
+	return 0
+}
+
+func (m *TradeCapture) GetSymbol() string {
+	if m != nil {
+		return m.Symbol
+	}
+	return ""
+}
+
+func (m *TradeCapture) GetPrice() float64 {
+	if m != nil {
+		return m.Price
+	}
+	return 0
+}
+
+func (m *TradeCapture) GetQuantity() float64 {
+	if m != nil {
+		return m.Quantity
+	}
+	return 0
+}
+
+func (m *TradeCapture) GetBuyer() string {
+	if m != nil {
+		return m.Buyer
+	}
+	return ""
+}
+
+func (m *TradeCapture) GetBuyOrder() string {
+	if m != nil {
+		return m.BuyOrder
+	}
+	return ""
+}
+
+func (m *TradeCapture) GetSeller() string {
+	if m != nil {
+		return m.Seller
+	}
+	return ""
+}
+
+func (m *TradeCapture) GetSellOrder() string {
+	if m != nil {
+		return m.SellOrder
 	}
 	return ""
 }
 
+func (m *TradeCapture) GetAggressor() string {
+	if m != nil {
+		return m.Aggressor
+	}
+	return ""
+}
+
+func (m *TradeCapture) GetTradeTime() int64 {
+	if m != nil {
+		return m.TradeTime
+	}
+	return 0
+}
+
+func (m *TradeCapture) GetScaledPrice() int64 {
+	if m != nil {
+		return m.ScaledPrice
+	}
+	return 0
+}
+
+func (m *TradeCapture) GetScaledQuantity() int64 {
+	if m != nil {
+		return m.ScaledQuantity
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*InMessage)(nil), "protocol.InMessage")
 	proto.RegisterType((*OutMessage)(nil), "protocol.OutMessage")
@@ -1259,10 +2773,24 @@ func init() {
 	proto.RegisterType((*SecurityDefinitionRequest)(nil), "protocol.SecurityDefinitionRequest")
 	proto.RegisterType((*DownloadRequest)(nil), "protocol.DownloadRequest")
 	proto.RegisterType((*SecurityDefinition)(nil), "protocol.SecurityDefinition")
+	proto.RegisterType((*TickSizeLevel)(nil), "protocol.TickSizeLevel")
 	proto.RegisterType((*ExecutionReport)(nil), "protocol.ExecutionReport")
+	proto.RegisterType((*OrderStatusRequest)(nil), "protocol.OrderStatusRequest")
+	proto.RegisterType((*OpenOrdersRequest)(nil), "protocol.OpenOrdersRequest")
+	proto.RegisterType((*OpenOrdersReply)(nil), "protocol.OpenOrdersReply")
 	proto.RegisterType((*SessionReject)(nil), "protocol.SessionReject")
+	proto.RegisterType((*MarketDataRequest)(nil), "protocol.MarketDataRequest")
+	proto.RegisterType((*MarketDataMessage)(nil), "protocol.MarketDataMessage")
+	proto.RegisterType((*MarketDataLevel)(nil), "protocol.MarketDataLevel")
+	proto.RegisterType((*MarketDataBook)(nil), "protocol.MarketDataBook")
+	proto.RegisterType((*MarketDataTrade)(nil), "protocol.MarketDataTrade")
+	proto.RegisterType((*MarketDataStatistics)(nil), "protocol.MarketDataStatistics")
+	proto.RegisterType((*TradesRequest)(nil), "protocol.TradesRequest")
+	proto.RegisterType((*TradesReply)(nil), "protocol.TradesReply")
+	proto.RegisterType((*TradeCapture)(nil), "protocol.TradeCapture")
 	proto.RegisterEnum("protocol.CreateOrderRequest_OrderType", CreateOrderRequest_OrderType_name, CreateOrderRequest_OrderType_value)
 	proto.RegisterEnum("protocol.CreateOrderRequest_OrderSide", CreateOrderRequest_OrderSide_name, CreateOrderRequest_OrderSide_value)
+	proto.RegisterEnum("protocol.CreateOrderRequest_TimeInForce", CreateOrderRequest_TimeInForce_name, CreateOrderRequest_TimeInForce_value)
 	proto.RegisterEnum("protocol.ExecutionReport_OrderState", ExecutionReport_OrderState_name, ExecutionReport_OrderState_value)
 	proto.RegisterEnum("protocol.ExecutionReport_ReportType", ExecutionReport_ReportType_name, ExecutionReport_ReportType_value)
 }
@@ -1280,6 +2808,11 @@ const _ = grpc.SupportPackageIsVersion4
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type ExchangeClient interface {
 	Connection(ctx context.Context, opts ...grpc.CallOption) (Exchange_ConnectionClient, error)
+	// MarketData streams the books, trades and statistics of the requested symbols, for clients that cannot receive
+	// the multicast market data
+	MarketData(ctx context.Context, in *MarketDataRequest, opts ...grpc.CallOption) (Exchange_MarketDataClient, error)
+	// Trades returns the trades from the exchange's trade store, with the buyer and seller of each
+	Trades(ctx context.Context, in *TradesRequest, opts ...grpc.CallOption) (*TradesReply, error)
 }
 
 type exchangeClient struct {
@@ -1321,9 +2854,55 @@ func (x *exchangeConnectionClient) Recv() (*OutMessage, error) {
 	return m, nil
 }
 
+func (c *exchangeClient) MarketData(ctx context.Context, in *MarketDataRequest, opts ...grpc.CallOption) (Exchange_MarketDataClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Exchange_serviceDesc.Streams[1], "/protocol.Exchange/MarketData", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &exchangeMarketDataClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Exchange_MarketDataClient interface {
+	Recv() (*MarketDataMessage, error)
+	grpc.ClientStream
+}
+
+type exchangeMarketDataClient struct {
+	grpc.ClientStream
+}
+
+func (x *exchangeMarketDataClient) Recv() (*MarketDataMessage, error) {
+	m := new(MarketDataMessage)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *exchangeClient) Trades(ctx context.Context, in *TradesRequest, opts ...grpc.CallOption) (*TradesReply, error) {
+	out := new(TradesReply)
+	err := c.cc.Invoke(ctx, "/protocol.Exchange/Trades", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ExchangeServer is the server API for Exchange service.
 type ExchangeServer interface {
 	Connection(Exchange_ConnectionServer) error
+	// MarketData streams the books, trades and statistics of the requested symbols, for clients that cannot receive
+	// the multicast market data
+	MarketData(*MarketDataRequest, Exchange_MarketDataServer) error
+	// Trades returns the trades from the exchange's trade store, with the buyer and seller of each
+	Trades(context.Context, *TradesRequest) (*TradesReply, error)
 }
 
 func RegisterExchangeServer(s *grpc.Server, srv ExchangeServer) {
@@ -1356,10 +2935,54 @@ func (x *exchangeConnectionServer) Recv() (*InMessage, error) {
 	return m, nil
 }
 
+func _Exchange_MarketData_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(MarketDataRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ExchangeServer).MarketData(m, &exchangeMarketDataServer{stream})
+}
+
+type Exchange_MarketDataServer interface {
+	Send(*MarketDataMessage) error
+	grpc.ServerStream
+}
+
+type exchangeMarketDataServer struct {
+	grpc.ServerStream
+}
+
+func (x *exchangeMarketDataServer) Send(m *MarketDataMessage) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Exchange_Trades_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TradesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServer).Trades(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/protocol.Exchange/Trades",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServer).Trades(ctx, req.(*TradesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Exchange_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "protocol.Exchange",
 	HandlerType: (*ExchangeServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Trades",
+			Handler:    _Exchange_Trades_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "Connection",
@@ -1367,68 +2990,149 @@ var _Exchange_serviceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "MarketData",
+			Handler:       _Exchange_MarketData_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "exchange.proto",
 }
 
-func init() { proto.RegisterFile("exchange.proto", fileDescriptor_exchange_58c6893b63e17bd9) }
-
-var fileDescriptor_exchange_58c6893b63e17bd9 = []byte{
-	// 891 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x56, 0x5d, 0x6e, 0xdc, 0x36,
-	0x10, 0x5e, 0xed, 0xbf, 0xc6, 0x4e, 0xa2, 0xb2, 0x41, 0xaa, 0x18, 0x41, 0x11, 0xb0, 0x3f, 0xc8,
-	0x43, 0xb1, 0x68, 0x1d, 0xb4, 0x05, 0x02, 0xf4, 0xc5, 0xde, 0x04, 0x35, 0x10, 0xd7, 0x8e, 0xdc,
-	0x03, 0x54, 0x96, 0xc6, 0x2e, 0x6b, 0xad, 0xb8, 0x26, 0x29, 0xd8, 0x7b, 0xa1, 0xa2, 0x47, 0xe9,
-	0x5b, 0x4f, 0xd1, 0x7b, 0x14, 0x43, 0x6a, 0x45, 0x69, 0x17, 0x6b, 0x34, 0x4f, 0xc2, 0xcc, 0x7c,
-	0xdf, 0x0c, 0x39, 0x7f, 0x14, 0x3c, 0xc6, 0xfb, 0xec, 0xf7, 0xb4, 0xbc, 0xc6, 0xd9, 0x52, 0x49,
-	0x23, 0xd9, 0xd4, 0x7e, 0x32, 0x59, 0xf0, 0xbf, 0x06, 0x10, 0x9e, 0x94, 0xa7, 0xa8, 0x75, 0x7a,
-	0x8d, 0x6c, 0x06, 0xa3, 0x42, 0x5e, 0x8b, 0x32, 0x0e, 0x5e, 0x06, 0xaf, 0xf6, 0x0e, 0x9f, 0xcd,
-	0xd6, 0xb8, 0xd9, 0x7b, 0x52, 0x27, 0x78, 0x5b, 0xa1, 0x36, 0x3f, 0xf7, 0x12, 0x07, 0x63, 0x3f,
-	0xc0, 0x38, 0x53, 0x98, 0x1a, 0x8c, 0xfb, 0x96, 0xf0, 0xc2, 0x13, 0x8e, 0xad, 0xfe, 0x4c, 0xe5,
-	0xa8, 0x3c, 0xad, 0x46, 0x13, 0x6f, 0x21, 0x73, 0x71, 0xb5, 0x8a, 0x07, 0x9b, 0xbc, 0x53, 0xab,
-	0xdf, 0xe4, 0x39, 0xb4, 0x8d, 0x97, 0x96, 0x19, 0x16, 0xf1, 0x70, 0x2b, 0x9e, 0xd5, 0x6f, 0xc5,
-	0xb3, 0x5a, 0xf6, 0x06, 0xc2, 0x45, 0xaa, 0xf5, 0x6d, 0x25, 0x0d, 0xc6, 0x23, 0x4b, 0x3d, 0x68,
-	0x85, 0x4c, 0xb5, 0xfe, 0x40, 0x26, 0x4f, 0xf4, 0x70, 0x76, 0x0c, 0xa1, 0xc6, 0x2c, 0xc7, 0x2b,
-	0x85, 0xb7, 0xf1, 0xd8, 0x72, 0xbf, 0xf0, 0xdc, 0x0b, 0xcc, 0x2a, 0x25, 0xcc, 0x6a, 0x8e, 0x57,
-	0xa2, 0x14, 0x46, 0xc8, 0x56, 0x92, 0x3c, 0x8f, 0xfd, 0x08, 0xd3, 0x5c, 0xde, 0x95, 0x85, 0x4c,
-	0xf3, 0x78, 0x62, 0x7d, 0x3c, 0xf7, 0x3e, 0xe6, 0xb5, 0xc5, 0x33, 0x1b, 0xf0, 0x51, 0x08, 0x13,
-	0xe5, 0xd4, 0xfc, 0xdf, 0x00, 0xe0, 0xac, 0x32, 0xeb, 0x5a, 0x7d, 0xd3, 0xad, 0xd5, 0xd3, 0xad,
-	0x5a, 0x2d, 0x8b, 0x95, 0xaf, 0xd4, 0xf7, 0x30, 0xc1, 0x7b, 0xcc, 0xd4, 0xd2, 0xd4, 0xa5, 0x6a,
-	0xc5, 0x7f, 0x7b, 0x8f, 0x59, 0xe5, 0x8e, 0xbe, 0x94, 0x8a, 0xe2, 0xaf, 0xb1, 0x94, 0x70, 0x77,
-	0x89, 0xed, 0x42, 0x6d, 0xdf, 0x9c, 0x12, 0xee, 0xd0, 0xec, 0x3b, 0x18, 0x2b, 0xfc, 0x03, 0x33,
-	0x53, 0x17, 0xea, 0xb3, 0x36, 0x4f, 0x6b, 0x1b, 0x8b, 0xcc, 0x44, 0x71, 0xc0, 0xa3, 0x09, 0x8c,
-	0x14, 0x9d, 0x99, 0xbf, 0x83, 0xfd, 0x76, 0xb7, 0xb1, 0x03, 0x98, 0x56, 0x1a, 0x55, 0x99, 0x2e,
-	0xd0, 0xde, 0x35, 0x4c, 0x1a, 0x99, 0x6c, 0xcb, 0x54, 0xeb, 0x3b, 0xa9, 0x72, 0x7b, 0xaf, 0x30,
-	0x69, 0x64, 0xce, 0x01, 0x7c, 0x26, 0xd8, 0x53, 0x18, 0xa1, 0x52, 0x52, 0xd5, 0x30, 0x27, 0xf0,
-	0xbf, 0xfb, 0xc0, 0xb6, 0x3b, 0x95, 0xc5, 0x30, 0xc9, 0xa8, 0x97, 0x4e, 0x72, 0x1b, 0x71, 0x94,
-	0xac, 0x45, 0xf6, 0x0c, 0xc6, 0x7a, 0xb5, 0xb8, 0x94, 0x45, 0xed, 0xa7, 0x96, 0xc8, 0xfd, 0x52,
-	0x89, 0x0c, 0x6d, 0x9e, 0x82, 0xc4, 0x09, 0x74, 0xbc, 0xdb, 0x2a, 0x2d, 0x8d, 0x30, 0x2b, 0x9b,
-	0x88, 0x20, 0x69, 0x64, 0x36, 0x87, 0x50, 0x52, 0xcc, 0x5f, 0x57, 0x4b, 0xd7, 0x93, 0x8f, 0x0f,
-	0xbf, 0x7e, 0x68, 0x7c, 0x66, 0x67, 0x6b, 0x74, 0xe2, 0x89, 0x8d, 0x97, 0x0b, 0x91, 0xa3, 0xed,
-	0xce, 0xff, 0xe5, 0x85, 0xd0, 0x89, 0x27, 0x72, 0x0e, 0x61, 0xe3, 0x9d, 0x01, 0x8c, 0x4f, 0x53,
-	0x75, 0x83, 0x26, 0xea, 0xb1, 0x10, 0x46, 0xef, 0xc5, 0x42, 0x98, 0x28, 0xe0, 0x9f, 0xd7, 0x18,
-	0x22, 0xb0, 0x09, 0x0c, 0x8e, 0xaa, 0x55, 0xd4, 0x63, 0x53, 0x18, 0x5e, 0x60, 0x51, 0x44, 0x01,
-	0xff, 0x0d, 0xd8, 0xf6, 0xec, 0x3e, 0x90, 0xc9, 0x26, 0x63, 0xfd, 0x5d, 0x19, 0x1b, 0x74, 0x33,
-	0xc6, 0x67, 0xc0, 0xb6, 0xa7, 0x7c, 0x77, 0x04, 0xfe, 0x67, 0x00, 0xd1, 0xe6, 0x6c, 0xb7, 0x0a,
-	0x18, 0x74, 0x0a, 0x78, 0x00, 0xd3, 0x4b, 0x91, 0x9f, 0xb7, 0x4e, 0xd4, 0xc8, 0xec, 0x25, 0xec,
-	0x5d, 0x8a, 0xfc, 0x43, 0xf7, 0x5c, 0x6d, 0x15, 0xb1, 0x53, 0x7d, 0xe3, 0xd8, 0x75, 0xa1, 0xd7,
-	0x32, 0xb1, 0x53, 0x7d, 0xd3, 0xb0, 0x47, 0x8e, 0xdd, 0x52, 0xf1, 0xd7, 0xf0, 0x7c, 0xe7, 0x1e,
-	0xd9, 0x75, 0x60, 0xfe, 0x09, 0x3c, 0xd9, 0x58, 0x1c, 0xfc, 0x1c, 0xd8, 0xb6, 0x9f, 0x9d, 0x37,
-	0xe6, 0xb0, 0x2f, 0x4a, 0x6d, 0x54, 0xb5, 0xc0, 0xd2, 0x9c, 0xcc, 0xed, 0xad, 0x07, 0x49, 0x47,
-	0xc7, 0xff, 0x19, 0xc2, 0x93, 0x8d, 0xf5, 0x40, 0xfe, 0x2e, 0x3a, 0xfe, 0x9c, 0xd4, 0x2e, 0x44,
-	0xbf, 0x5b, 0xea, 0x98, 0x96, 0x8f, 0xb3, 0x0c, 0x2c, 0x65, 0x2d, 0xb2, 0x39, 0x80, 0xeb, 0x42,
-	0x43, 0x8f, 0xc8, 0xd0, 0xf6, 0xef, 0x97, 0x3b, 0x37, 0x53, 0xdd, 0xbc, 0x84, 0x4d, 0x5a, 0x3c,
-	0xf2, 0xa2, 0x2c, 0xa0, 0x35, 0x4b, 0x0f, 0x78, 0x49, 0x1a, 0x6c, 0xd2, 0xe2, 0xf9, 0x86, 0x1c,
-	0xef, 0x6a, 0xc8, 0xc9, 0xc6, 0x08, 0xbf, 0x80, 0x50, 0xe1, 0x22, 0x15, 0xa5, 0x28, 0xaf, 0xe3,
-	0xa9, 0x35, 0x7a, 0x05, 0x59, 0x8b, 0x54, 0x1b, 0xd7, 0x14, 0xa1, 0xb3, 0x36, 0x0a, 0xca, 0x3e,
-	0x09, 0x4d, 0x5b, 0x80, 0x05, 0x74, 0x74, 0xec, 0x0d, 0x0c, 0x35, 0xcd, 0xf5, 0xde, 0x47, 0xcd,
-	0xb5, 0xe5, 0x90, 0x7f, 0xb7, 0x58, 0x13, 0x4c, 0xb5, 0x2c, 0xe3, 0x7d, 0x9b, 0xf8, 0x8e, 0x8e,
-	0xff, 0x02, 0xe0, 0x33, 0x4a, 0x73, 0x7f, 0x24, 0xe5, 0x0d, 0xe6, 0x51, 0x8f, 0xed, 0xc3, 0xd4,
-	0x2d, 0x68, 0xcc, 0xa3, 0x80, 0xed, 0xc1, 0xe4, 0x3c, 0x55, 0x46, 0xa4, 0x45, 0xd4, 0x27, 0xd8,
-	0x3b, 0x51, 0x14, 0x98, 0x47, 0x03, 0xf6, 0x08, 0x42, 0x37, 0x91, 0x24, 0x0e, 0x69, 0xe3, 0xfa,
-	0xdc, 0x12, 0x90, 0x1c, 0x57, 0xda, 0xad, 0x09, 0x22, 0x45, 0x01, 0xff, 0x0a, 0x1e, 0x75, 0x5e,
-	0x00, 0xbf, 0x98, 0x83, 0xd6, 0x62, 0x3e, 0x3c, 0x81, 0xe9, 0xdb, 0xfa, 0x9f, 0x85, 0xfd, 0x04,
-	0x70, 0x2c, 0xcb, 0x12, 0x33, 0xdb, 0xce, 0x9f, 0xfa, 0x34, 0x34, 0x3f, 0x2e, 0x07, 0xad, 0xd7,
-	0xcf, 0x3f, 0x91, 0xbc, 0xf7, 0x2a, 0xf8, 0x36, 0xb8, 0x1c, 0x5b, 0xd3, 0xeb, 0xff, 0x02, 0x00,
-	0x00, 0xff, 0xff, 0x17, 0x62, 0x46, 0x64, 0x05, 0x09, 0x00, 0x00,
+func init() { proto.RegisterFile("exchange.proto", fileDescriptor_exchange_763b01faa6a6a151) }
+
+var fileDescriptor_exchange_763b01faa6a6a151 = []byte{
+	// 2105 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x59, 0xcd, 0x6f, 0xdc, 0xc6,
+	0x15, 0x17, 0xf7, 0x9b, 0x6f, 0xf5, 0xb1, 0x9a, 0xc8, 0xf2, 0x46, 0x31, 0x0c, 0x83, 0x4d, 0x03,
+	0xa3, 0x4d, 0x85, 0x44, 0x69, 0x52, 0xc0, 0x41, 0x81, 0x58, 0xda, 0xb8, 0xde, 0x54, 0x8e, 0xec,
+	0x91, 0x90, 0x43, 0x6f, 0x23, 0x72, 0xb4, 0x62, 0xc5, 0x25, 0xd7, 0x24, 0xd7, 0xd2, 0xf6, 0xde,
+	0x53, 0xd1, 0x4b, 0x81, 0x1e, 0x5a, 0xa0, 0x97, 0xde, 0xda, 0xfe, 0x35, 0xed, 0xbf, 0xd0, 0x6b,
+	0xd1, 0xbf, 0xa0, 0x87, 0xe2, 0xbd, 0x21, 0x39, 0x43, 0xee, 0x87, 0xd7, 0x39, 0x69, 0xdf, 0xef,
+	0xfd, 0xde, 0xcc, 0xbc, 0x8f, 0x99, 0x79, 0x43, 0xc1, 0xb6, 0xbc, 0x73, 0xaf, 0x45, 0x38, 0x92,
+	0x87, 0x93, 0x38, 0x4a, 0x23, 0xd6, 0xa1, 0x3f, 0x6e, 0x14, 0x38, 0x7f, 0x6b, 0x80, 0x3d, 0x0c,
+	0x5f, 0xc8, 0x24, 0x11, 0x23, 0xc9, 0x0e, 0xa1, 0x19, 0x44, 0x23, 0x3f, 0xec, 0x5b, 0x8f, 0xac,
+	0xc7, 0xdd, 0xa3, 0xfd, 0xc3, 0x9c, 0x77, 0x78, 0x8a, 0x30, 0x97, 0xaf, 0xa7, 0x32, 0x49, 0x9f,
+	0x6f, 0x70, 0x45, 0x63, 0x5f, 0x40, 0xcb, 0x8d, 0xa5, 0x48, 0x65, 0xbf, 0x46, 0x06, 0x0f, 0xb4,
+	0xc1, 0x09, 0xe1, 0x67, 0xb1, 0x27, 0x63, 0x6d, 0x96, 0xb1, 0xd1, 0x6e, 0x1c, 0x79, 0xfe, 0xd5,
+	0xac, 0x5f, 0xaf, 0xda, 0xbd, 0x20, 0xbc, 0x6a, 0xa7, 0xd8, 0x34, 0x9f, 0x08, 0x5d, 0x19, 0xf4,
+	0x1b, 0x73, 0xf3, 0x11, 0x3e, 0x37, 0x1f, 0xa1, 0xec, 0x09, 0xd8, 0x63, 0x91, 0x24, 0xaf, 0xa7,
+	0x51, 0x2a, 0xfb, 0x4d, 0x32, 0x3d, 0x30, 0xa6, 0x14, 0x49, 0xf2, 0x0a, 0x55, 0xda, 0x50, 0xd3,
+	0xd9, 0x09, 0xd8, 0x89, 0x74, 0x3d, 0x79, 0x15, 0xcb, 0xd7, 0xfd, 0x16, 0xd9, 0xfe, 0x40, 0xdb,
+	0x9e, 0x4b, 0x77, 0x1a, 0xfb, 0xe9, 0x6c, 0x20, 0xaf, 0xfc, 0xd0, 0x4f, 0xfd, 0xc8, 0x08, 0x92,
+	0xb6, 0x63, 0x3f, 0x83, 0x8e, 0x17, 0xdd, 0x86, 0x41, 0x24, 0xbc, 0x7e, 0x9b, 0xc6, 0x78, 0x5f,
+	0x8f, 0x31, 0xc8, 0x34, 0xda, 0xb2, 0x20, 0xb3, 0xaf, 0xa0, 0x1b, 0xa1, 0x4f, 0x49, 0x2a, 0xd2,
+	0x69, 0xd2, 0xef, 0x54, 0xdd, 0x26, 0x87, 0xcf, 0x49, 0xa9, 0xcd, 0x4d, 0x13, 0xf6, 0x73, 0x80,
+	0x68, 0x22, 0x43, 0x05, 0xf5, 0x6d, 0x1a, 0xe0, 0x03, 0x63, 0x80, 0x89, 0x0c, 0x69, 0x10, 0xc3,
+	0xde, 0x30, 0x38, 0xb6, 0xa1, 0x1d, 0x2b, 0x85, 0xf3, 0xd7, 0x1a, 0xc0, 0xd9, 0x34, 0xcd, 0x8b,
+	0xe5, 0xe3, 0x72, 0xb1, 0xec, 0xcd, 0x15, 0xcb, 0x24, 0x98, 0xe9, 0x52, 0xf9, 0x1c, 0xda, 0xf2,
+	0x4e, 0xba, 0xf1, 0x24, 0xed, 0xd7, 0xaa, 0x01, 0xf8, 0xfa, 0x4e, 0xba, 0x53, 0x15, 0xbb, 0x49,
+	0x14, 0xe3, 0x0a, 0x72, 0x2e, 0x66, 0x5c, 0x45, 0x71, 0xbe, 0x52, 0xe6, 0x43, 0x8f, 0x19, 0x57,
+	0x6c, 0xf6, 0x29, 0xb4, 0x62, 0xf9, 0x6b, 0xe9, 0xa6, 0x59, 0xa5, 0xdc, 0x37, 0xed, 0x92, 0x84,
+	0xe6, 0x42, 0x35, 0x9a, 0x28, 0x22, 0xfb, 0xb2, 0x14, 0xa8, 0x66, 0x75, 0x91, 0x66, 0xa0, 0x94,
+	0x67, 0x66, 0x98, 0xda, 0xd0, 0x8c, 0x11, 0x76, 0xfe, 0x60, 0xc1, 0xa6, 0xb9, 0x59, 0xd8, 0x01,
+	0x74, 0xa6, 0x89, 0x8c, 0x43, 0x31, 0x96, 0x14, 0x29, 0x9b, 0x17, 0x32, 0xea, 0x26, 0x22, 0x49,
+	0x6e, 0xa3, 0xd8, 0xa3, 0xa8, 0xd8, 0xbc, 0x90, 0xd9, 0x63, 0xd8, 0xc9, 0xe7, 0xfe, 0x4e, 0xc6,
+	0xb8, 0x62, 0x0a, 0x41, 0x93, 0x57, 0x61, 0xf6, 0x08, 0xba, 0x89, 0xf2, 0xe9, 0x5b, 0x9c, 0xa4,
+	0x41, 0x03, 0x99, 0x90, 0x73, 0x0a, 0xa0, 0x73, 0xc2, 0xf6, 0xa0, 0x29, 0xe3, 0x38, 0x8a, 0xb3,
+	0x29, 0x95, 0xb0, 0xfe, 0x7c, 0xce, 0x1f, 0x5b, 0xc0, 0xe6, 0xb7, 0x37, 0xeb, 0x43, 0xdb, 0xc5,
+	0x0d, 0x38, 0xf4, 0xc8, 0xcf, 0x26, 0xcf, 0x45, 0xb6, 0x0f, 0xad, 0x64, 0x36, 0xbe, 0x8c, 0x82,
+	0x6c, 0xc6, 0x4c, 0xc2, 0x85, 0x4c, 0x62, 0xdf, 0x95, 0x34, 0x91, 0xc5, 0x95, 0x80, 0x41, 0x79,
+	0x3d, 0x15, 0x61, 0xea, 0xa7, 0x33, 0xf2, 0xc5, 0xe2, 0x85, 0xcc, 0x06, 0x60, 0x53, 0xc0, 0x2f,
+	0x66, 0x13, 0xb5, 0x91, 0xb7, 0x8f, 0x3e, 0x5a, 0x75, 0xe6, 0x1c, 0x9e, 0xe5, 0x6c, 0xae, 0x0d,
+	0x8b, 0x51, 0xce, 0x7d, 0x4f, 0xf6, 0x5b, 0xeb, 0x8e, 0x82, 0x6c, 0xae, 0x0d, 0xd9, 0x03, 0xb0,
+	0x93, 0x34, 0x9a, 0xbc, 0x24, 0x0f, 0xda, 0xb4, 0x50, 0x0d, 0xb0, 0x6f, 0xa0, 0x9b, 0xfa, 0x63,
+	0x39, 0x0c, 0x9f, 0x45, 0xb1, 0x2b, 0x69, 0xe3, 0x6e, 0x1f, 0x3d, 0x5e, 0x39, 0xcb, 0x85, 0xe6,
+	0x73, 0xd3, 0x98, 0x3d, 0x04, 0x90, 0x77, 0x13, 0x3f, 0x96, 0xc8, 0xa0, 0x2d, 0x5c, 0xe7, 0x06,
+	0x82, 0xa9, 0xf3, 0xfc, 0x64, 0x12, 0x88, 0xd9, 0xab, 0x3c, 0x70, 0x40, 0xeb, 0xa9, 0xc2, 0x98,
+	0x23, 0xe1, 0xba, 0xd1, 0x34, 0x4c, 0xfb, 0x5d, 0x4a, 0x45, 0x2e, 0x52, 0x11, 0xb9, 0x22, 0x90,
+	0x9e, 0xf2, 0x67, 0x93, 0x26, 0x31, 0x21, 0xf6, 0x11, 0x6c, 0x2b, 0xb1, 0x98, 0x64, 0x8b, 0x48,
+	0x15, 0x14, 0x57, 0xa3, 0x90, 0xf3, 0x22, 0x3a, 0xdb, 0x44, 0xac, 0xc2, 0xec, 0xa7, 0x70, 0x4f,
+	0x41, 0x83, 0xca, 0xea, 0x77, 0x88, 0xbf, 0x58, 0xe9, 0x7c, 0x09, 0x76, 0x91, 0x55, 0x06, 0xd0,
+	0x7a, 0x21, 0xe2, 0x1b, 0x99, 0xf6, 0x36, 0x98, 0x0d, 0xcd, 0x53, 0x7f, 0xec, 0xa7, 0x3d, 0x8b,
+	0x75, 0xa0, 0x81, 0xd3, 0xf4, 0x6a, 0x6c, 0x0b, 0x6c, 0xfc, 0xa5, 0x14, 0x75, 0xe7, 0x61, 0x66,
+	0x4c, 0x19, 0x6c, 0x43, 0xfd, 0x78, 0x3a, 0xeb, 0x6d, 0x10, 0x5d, 0x06, 0x41, 0xcf, 0x72, 0x9e,
+	0x40, 0xd7, 0x48, 0x03, 0x32, 0x7e, 0x71, 0x71, 0xd2, 0xdb, 0xc0, 0x1f, 0x03, 0x31, 0xeb, 0x59,
+	0xf8, 0x63, 0x78, 0x76, 0xd2, 0xab, 0xe1, 0x8f, 0x67, 0x67, 0xbf, 0xec, 0xd5, 0x15, 0x67, 0xd0,
+	0x6b, 0x38, 0x7f, 0xb7, 0x80, 0xcd, 0x5f, 0x5f, 0x2b, 0xf6, 0x45, 0x51, 0xff, 0xb5, 0x65, 0xf5,
+	0x5f, 0xaf, 0xd4, 0x7f, 0x25, 0x4b, 0x8d, 0x75, 0xb2, 0xd4, 0x5c, 0x94, 0x25, 0xe7, 0x10, 0xd8,
+	0xfc, 0x95, 0xb9, 0x7c, 0xad, 0xce, 0xbf, 0x6a, 0xd0, 0xab, 0x5e, 0x94, 0xc6, 0xc6, 0xb6, 0x4a,
+	0x1b, 0xfb, 0x00, 0x3a, 0x97, 0xbe, 0xf7, 0xd2, 0xf0, 0xad, 0x90, 0xd1, 0x85, 0x4b, 0xdf, 0x7b,
+	0x55, 0xf6, 0xd0, 0x84, 0xd0, 0x5a, 0x24, 0x37, 0xda, 0x43, 0x8b, 0x17, 0x32, 0x5a, 0x8b, 0xe4,
+	0xa6, 0xe4, 0x9b, 0xc5, 0x4d, 0x48, 0x07, 0xe0, 0x38, 0x5f, 0x41, 0xcb, 0x0c, 0x40, 0x8e, 0xb2,
+	0x8f, 0x61, 0xb7, 0x40, 0x8a, 0xf1, 0xda, 0x44, 0x9d, 0x57, 0xe8, 0x51, 0x9f, 0xe6, 0x2b, 0xeb,
+	0x98, 0xa3, 0xe6, 0xa8, 0x1e, 0xf5, 0xa9, 0xb1, 0x4a, 0xdb, 0x1c, 0xd5, 0x50, 0x38, 0x9f, 0xc1,
+	0xfb, 0x4b, 0x1b, 0x88, 0x65, 0xc1, 0x75, 0x76, 0x61, 0xa7, 0xd2, 0x31, 0x38, 0xff, 0x68, 0x00,
+	0x9b, 0x1f, 0x68, 0x69, 0x7a, 0x1c, 0xd8, 0xf4, 0xc3, 0x24, 0x8d, 0xa7, 0x63, 0x19, 0xa6, 0xc3,
+	0x01, 0xa5, 0xa8, 0xce, 0x4b, 0x18, 0xed, 0xe2, 0x54, 0xa4, 0xbe, 0x4b, 0x7e, 0x1d, 0x8b, 0xd0,
+	0xcb, 0x52, 0x55, 0x85, 0xd9, 0x8f, 0xa0, 0xe7, 0xcd, 0x42, 0x31, 0x36, 0xa9, 0x2a, 0x6d, 0x73,
+	0x38, 0xfb, 0x1c, 0xec, 0xd4, 0x77, 0x6f, 0xce, 0xfd, 0xdf, 0x48, 0xbc, 0x62, 0xeb, 0xe5, 0x9b,
+	0xf9, 0x22, 0x53, 0x9d, 0xca, 0x37, 0x32, 0xe0, 0x9a, 0x89, 0x65, 0x19, 0x44, 0x29, 0xfe, 0xa6,
+	0x64, 0x5a, 0x3c, 0x17, 0xb1, 0x1e, 0xc6, 0x7e, 0x58, 0xca, 0x9f, 0xc5, 0x4d, 0x88, 0x18, 0xe2,
+	0xae, 0x60, 0x74, 0x32, 0x86, 0x86, 0xf4, 0x31, 0x74, 0x5e, 0x71, 0xd8, 0x36, 0x8f, 0xa1, 0x8a,
+	0x92, 0x7d, 0x01, 0xfb, 0xd9, 0xf9, 0x54, 0x75, 0x1e, 0xc8, 0x6c, 0x89, 0x96, 0x7d, 0x08, 0x5b,
+	0x4a, 0x73, 0x9a, 0x79, 0xd4, 0x25, 0x7a, 0x19, 0xd4, 0x75, 0xf4, 0xc2, 0xf0, 0x6e, 0xd3, 0xac,
+	0x23, 0x43, 0x61, 0xb0, 0xc5, 0x5d, 0xe5, 0x74, 0x9e, 0x57, 0x38, 0xbf, 0xb3, 0x60, 0xab, 0x14,
+	0x6a, 0x7d, 0x10, 0x59, 0x95, 0x83, 0x28, 0x4f, 0x41, 0xbe, 0x8b, 0x73, 0xb9, 0x7a, 0x10, 0xd5,
+	0x57, 0x1c, 0x44, 0xf9, 0x54, 0xd9, 0x69, 0x55, 0x41, 0x9d, 0xbf, 0x74, 0x60, 0xa7, 0xd2, 0x00,
+	0x62, 0xe1, 0x9e, 0x97, 0x0a, 0x57, 0x49, 0xe6, 0xf1, 0x54, 0x2b, 0x1f, 0xa5, 0x7d, 0x6c, 0x2f,
+	0x95, 0xa6, 0xae, 0x2e, 0xb6, 0x4c, 0x64, 0x03, 0x80, 0x28, 0x6f, 0x92, 0xd5, 0x1a, 0xb6, 0x8f,
+	0x3e, 0x5c, 0xda, 0x7b, 0xea, 0x86, 0x5a, 0x72, 0xc3, 0x0e, 0x47, 0x89, 0x89, 0x60, 0x74, 0x1e,
+	0x2b, 0x46, 0xe1, 0x05, 0x97, 0x1b, 0x76, 0x3a, 0xce, 0xad, 0x65, 0x07, 0x7e, 0xbb, 0x72, 0xe0,
+	0x3f, 0x00, 0x3b, 0x96, 0x63, 0xe1, 0x87, 0x7e, 0x38, 0xca, 0x6a, 0x57, 0x03, 0xa8, 0x0d, 0x44,
+	0x92, 0xaa, 0x1c, 0xd8, 0x4a, 0x5b, 0x00, 0xb8, 0xcd, 0x51, 0xa8, 0xf4, 0x04, 0x25, 0x8c, 0x3d,
+	0x81, 0x46, 0xe2, 0x7b, 0xaa, 0x08, 0xd7, 0xef, 0x82, 0xc8, 0x06, 0xc7, 0x57, 0xad, 0x33, 0x97,
+	0x22, 0x89, 0x42, 0x2a, 0x4f, 0x9b, 0x97, 0xb0, 0x72, 0x93, 0xb4, 0x55, 0x6d, 0x92, 0x1e, 0x80,
+	0x9d, 0xc6, 0xfe, 0x68, 0x24, 0x63, 0xe9, 0x51, 0x93, 0xd0, 0xe1, 0x1a, 0xa8, 0xb4, 0x3d, 0x3b,
+	0xeb, 0xb4, 0x3d, 0xbd, 0xb7, 0xb6, 0x3d, 0xbb, 0x2b, 0xdb, 0x1e, 0xb6, 0xce, 0x85, 0xfa, 0xde,
+	0xea, 0xb6, 0x87, 0x17, 0xf9, 0xda, 0x33, 0xdb, 0x9e, 0x02, 0xd6, 0xcc, 0xd3, 0x22, 0x77, 0xf7,
+	0x4c, 0x66, 0x01, 0xb3, 0x43, 0x60, 0x1a, 0x2a, 0xe6, 0xdf, 0x27, 0xf2, 0x02, 0xcd, 0xa2, 0xd6,
+	0xeb, 0xfe, 0x3b, 0xb6, 0x5e, 0xfd, 0x55, 0xad, 0xd7, 0xb7, 0x00, 0x7a, 0x7f, 0x60, 0xef, 0x75,
+	0x1c, 0x45, 0x37, 0xd2, 0xeb, 0x6d, 0xb0, 0x4d, 0xe8, 0xa8, 0x07, 0x95, 0xf4, 0x7a, 0x16, 0xeb,
+	0x42, 0xfb, 0xa5, 0x88, 0x53, 0x5f, 0x04, 0xbd, 0x1a, 0xd2, 0x9e, 0xf9, 0x41, 0x20, 0xbd, 0x5e,
+	0x1d, 0xbb, 0x31, 0xd5, 0x75, 0xa0, 0xd8, 0x70, 0x1c, 0x00, 0xbd, 0x53, 0x90, 0xa8, 0x5e, 0xb2,
+	0xaa, 0x23, 0x43, 0xa3, 0x9e, 0x85, 0x8d, 0xca, 0xfc, 0x23, 0x77, 0x45, 0xa3, 0xf2, 0x63, 0xd8,
+	0x9d, 0x7b, 0xd3, 0x2e, 0xbd, 0x4b, 0x7f, 0x05, 0x3b, 0x95, 0x77, 0x1d, 0xbe, 0x1c, 0xb3, 0x27,
+	0xa0, 0xf5, 0xa8, 0x5e, 0x7e, 0x02, 0x56, 0x76, 0x39, 0xcf, 0x88, 0x8b, 0x1f, 0x54, 0xce, 0x0f,
+	0x61, 0xab, 0xf4, 0xd4, 0xd4, 0x34, 0xcb, 0xa4, 0xfd, 0xc9, 0x82, 0x5d, 0xd5, 0xc2, 0x0e, 0x44,
+	0x2a, 0x0c, 0xff, 0xd4, 0x12, 0xd5, 0x32, 0x6c, 0x9e, 0x8b, 0x78, 0xdd, 0xba, 0x51, 0x78, 0x15,
+	0x88, 0x54, 0x0e, 0xc3, 0x54, 0xc6, 0x6f, 0x44, 0x90, 0x1d, 0x86, 0x73, 0x38, 0xce, 0xe8, 0xc9,
+	0x49, 0x7a, 0x9d, 0xbd, 0xe4, 0x94, 0xb0, 0xe8, 0xa5, 0xd7, 0x58, 0xfc, 0xd2, 0xfb, 0x4f, 0x69,
+	0x6d, 0xfa, 0x2b, 0x51, 0xe3, 0x32, 0x8a, 0x6e, 0xb2, 0x77, 0x7f, 0xdf, 0xfc, 0x90, 0x92, 0x53,
+	0xb1, 0x2e, 0x9e, 0x6f, 0x70, 0xe2, 0xb1, 0x4f, 0xa1, 0x99, 0xc6, 0xc2, 0x93, 0xf3, 0x0f, 0x7f,
+	0x6d, 0x70, 0x81, 0x04, 0xfc, 0x5a, 0x40, 0x4c, 0xf6, 0x15, 0x00, 0xb5, 0x19, 0x49, 0xea, 0xbb,
+	0x49, 0xf6, 0xf4, 0x7f, 0xb8, 0xc8, 0xee, 0xbc, 0x60, 0xe1, 0x83, 0x5c, 0xdb, 0xac, 0xef, 0xe4,
+	0x71, 0x07, 0x5a, 0xd3, 0x89, 0x27, 0x52, 0xe9, 0xfc, 0xde, 0x82, 0x1d, 0x3d, 0xf4, 0x5b, 0xae,
+	0xc6, 0xe2, 0xc8, 0xae, 0xad, 0xee, 0xd1, 0xeb, 0xeb, 0x1c, 0x29, 0x8d, 0x85, 0x3d, 0xfa, 0x9f,
+	0xeb, 0xb0, 0x5d, 0x8e, 0xe9, 0xaa, 0x8e, 0x3b, 0xc1, 0xd2, 0x09, 0xb3, 0x8e, 0xbb, 0xc1, 0x0b,
+	0x99, 0xfd, 0x04, 0x1a, 0x97, 0xbe, 0x87, 0x61, 0xac, 0x2f, 0x0b, 0xbf, 0xea, 0xb8, 0x88, 0x86,
+	0x74, 0x91, 0xdc, 0x24, 0xfd, 0xc6, 0x5b, 0xe9, 0x48, 0xc3, 0x00, 0x25, 0x74, 0xb5, 0x36, 0x55,
+	0x55, 0x93, 0x80, 0x27, 0xbb, 0x1f, 0x3e, 0x9d, 0xba, 0xb8, 0x5b, 0xe8, 0xb6, 0xeb, 0x70, 0x0d,
+	0x60, 0x72, 0xfc, 0xd0, 0xf3, 0x5d, 0x91, 0xfa, 0x6f, 0xa4, 0xf9, 0x80, 0xae, 0xc2, 0x58, 0xed,
+	0x1a, 0xfa, 0x2e, 0x0a, 0xa6, 0x63, 0x99, 0x5d, 0x83, 0x73, 0xb8, 0x3e, 0xd3, 0x86, 0x95, 0xb1,
+	0x4b, 0x7d, 0x5c, 0x45, 0xa9, 0xfb, 0xb8, 0x61, 0x75, 0x9e, 0x52, 0x1f, 0x57, 0xd5, 0x3a, 0xff,
+	0x2e, 0x15, 0x0b, 0xd5, 0xef, 0xd2, 0xec, 0xbc, 0xfb, 0x43, 0x8f, 0xee, 0x3e, 0xf5, 0xcd, 0x76,
+	0x38, 0xc8, 0x3e, 0xe9, 0x18, 0x88, 0xba, 0x39, 0x85, 0xa7, 0xae, 0x46, 0xf5, 0xc2, 0xd3, 0x40,
+	0xb5, 0x04, 0x5b, 0xeb, 0x94, 0x60, 0x7b, 0x61, 0x09, 0xfe, 0xd7, 0x82, 0xbd, 0x45, 0xbb, 0x6d,
+	0xa9, 0xab, 0xfb, 0xd0, 0x7a, 0xa3, 0xc2, 0xa7, 0x7c, 0xcd, 0x24, 0xc6, 0xa0, 0x71, 0xed, 0x8f,
+	0xae, 0x33, 0x47, 0xe9, 0x37, 0xeb, 0x41, 0x3d, 0x88, 0x6e, 0xb3, 0xc7, 0x02, 0xfe, 0x44, 0xb7,
+	0xaf, 0x45, 0xf2, 0xdc, 0x1f, 0x5d, 0x9f, 0x46, 0xb7, 0xe4, 0x57, 0x87, 0x1b, 0x08, 0xb6, 0x1c,
+	0x6a, 0x81, 0x59, 0x8a, 0x94, 0x67, 0x25, 0x0c, 0xc7, 0x50, 0x32, 0x1a, 0x65, 0x6e, 0x19, 0x08,
+	0xb5, 0x24, 0x59, 0xaf, 0x7d, 0x9b, 0xbd, 0xe2, 0x34, 0xe0, 0xfc, 0x16, 0x9b, 0x63, 0x0c, 0x64,
+	0xb2, 0xc6, 0x23, 0xf7, 0x2a, 0x8e, 0xc6, 0x17, 0x7e, 0xe6, 0x6b, 0x9d, 0x17, 0x32, 0xda, 0xa4,
+	0x11, 0x69, 0xd4, 0xf6, 0xcf, 0xa4, 0x77, 0x38, 0x7a, 0x47, 0xd0, 0xcd, 0x97, 0x81, 0xb7, 0xd2,
+	0x21, 0xb4, 0x28, 0xbd, 0xf9, 0xad, 0x64, 0x7c, 0x9a, 0x27, 0xda, 0x89, 0x98, 0xa4, 0xd3, 0x58,
+	0xf2, 0x8c, 0xb5, 0x68, 0xa2, 0xda, 0xe2, 0x89, 0xfe, 0x57, 0x83, 0x4d, 0x73, 0x08, 0xf4, 0x4b,
+	0xb5, 0xac, 0xc3, 0x01, 0x79, 0x5c, 0xe7, 0x85, 0x8c, 0xd7, 0x12, 0x4d, 0x50, 0x3c, 0x1a, 0x73,
+	0xd1, 0x88, 0x52, 0x7d, 0x71, 0xe9, 0x37, 0x96, 0x95, 0x7e, 0xb3, 0x52, 0xfa, 0x7b, 0xd0, 0xbc,
+	0x9c, 0xce, 0x64, 0x4c, 0xc9, 0xb5, 0xb9, 0x12, 0xe8, 0x93, 0xc2, 0x54, 0x7d, 0x58, 0xa1, 0x9c,
+	0xda, 0xbc, 0x90, 0x69, 0x6e, 0x6c, 0x29, 0xe2, 0x7e, 0x27, 0x9b, 0x9b, 0x24, 0xca, 0xb4, 0x0c,
+	0xd4, 0x17, 0x0e, 0x3a, 0x04, 0x6c, 0xae, 0x01, 0xd4, 0x8a, 0xd1, 0x28, 0x96, 0x49, 0x12, 0xc5,
+	0xb4, 0xd7, 0x6d, 0xae, 0x81, 0xf2, 0x06, 0xeb, 0xbe, 0x65, 0x83, 0x7d, 0xff, 0xaf, 0x65, 0x47,
+	0xff, 0xb4, 0xa0, 0xf3, 0x75, 0xb6, 0xaf, 0xf1, 0x5b, 0xfd, 0x49, 0x14, 0x86, 0x52, 0x9d, 0x92,
+	0xef, 0xe9, 0x1c, 0x17, 0xff, 0xa2, 0x39, 0x30, 0x3e, 0xb3, 0xeb, 0x6f, 0xf1, 0xce, 0xc6, 0x63,
+	0xeb, 0x13, 0x8b, 0x7d, 0x03, 0xa0, 0xf7, 0x2a, 0xfb, 0x60, 0xd1, 0xc9, 0x9d, 0x15, 0xf5, 0xc1,
+	0x42, 0x65, 0x31, 0xda, 0x27, 0x16, 0x7b, 0x02, 0x2d, 0x55, 0x7f, 0xec, 0x7e, 0xa5, 0xd4, 0xf2,
+	0x8d, 0x71, 0x70, 0x6f, 0x5e, 0x81, 0x5f, 0xc0, 0x37, 0x2e, 0x5b, 0x84, 0x7f, 0xf6, 0xff, 0x01,
+	0x00, 0x7d, 0x3c, 0x36, 0x17, 0x77, 0x1a, 0x00, 0x00,
 }
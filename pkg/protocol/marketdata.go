@@ -2,41 +2,452 @@ package protocol
 
 import (
 	"bytes"
+	"errors"
+	"time"
+
+	. "github.com/robaho/fixed"
 	. "github.com/robaho/go-trader/pkg/common"
 )
 
-// very simplified structure, only one book and associated trades per UDP packet, and it contains the complete book
-// currently it all needs to fit in a single packet or it won't work, although straightforward to send additional trade packets
+// each UDP packet starts with the packet number, followed by one or more book updates. A book update is either a
+// snapshot of the complete book, or the levels that were added, changed or deleted since the previous update for the
+// instrument, along with any trades. The updates for an instrument are numbered sequentially, so a receiver that
+// misses an update holds the instrument's updates until the missing ones are replayed, or the next snapshot. An update
+// that does not fit in a single packet is split into several fragments, see Split. Each fragment is numbered like any
+// other update, and all but the last have the continuation flag set, so the receiver reports the book once the last
+// fragment is applied.
 
 // MaxMsgSize is the maximum length of a multicast message
 const MaxMsgSize = 1024
 
-func EncodeMarketEvent(w *bytes.Buffer, book *Book, trades []Trade) {
-	PutVarint(w, book.Instrument.ID())
-	if book != nil {
-		w.WriteByte(1) // has book
-		encodeBook(w, book)
+// LevelAction is the change to a price level in an incremental book update
+type LevelAction byte
+
+const (
+	LevelAdd LevelAction = iota
+	LevelChange
+	LevelDelete
+)
+
+// LevelUpdate is the new quantity of the price level, the quantity is zero for a LevelDelete
+type LevelUpdate struct {
+	Action   LevelAction
+	Side     Side
+	Price    Fixed
+	Quantity Fixed
+}
+
+// OrderAction is the change to an order in the market by order feed
+type OrderAction byte
+
+const (
+	// OrderAdd adds the order to the back of the queue at the price
+	OrderAdd OrderAction = iota
+	// OrderModify changes the price and quantity of the order, which moves it to the back of the queue at the price
+	OrderModify
+	// OrderDelete removes the order
+	OrderDelete
+	// OrderExecute reduces the quantity of the order by the quantity executed, keeping its priority. The order is
+	// removed when no quantity remains.
+	OrderExecute
+)
+
+// OrderUpdate is a change to an order in the book. The OrderID is assigned by the market data publisher, and does not
+// identify the session that entered the order. The quantity is the visible quantity, or the quantity executed.
+type OrderUpdate struct {
+	Action   OrderAction
+	OrderID  uint64
+	Side     Side
+	Price    Fixed
+	Quantity Fixed
+}
+
+// BookStatus is the trading state and auction information of a book
+type BookStatus struct {
+	InAuction        bool
+	IndicativePrice  Fixed
+	IndicativeVolume Fixed
+	State            TradingState
+}
+
+// Statistics are the instrument's trading statistics for the day, sent with snapshots
+type Statistics struct {
+	Volume     Fixed
+	High       Fixed
+	Low        Fixed
+	HasHighLow bool
+}
+
+// BookUpdate is a change to the book of an instrument
+type BookUpdate struct {
+	Instrument Instrument
+	// Sequence is per instrument, and increases by one with each update
+	Sequence uint64
+	// Snapshot is the complete book, which replaces the receiver's book, or nil for an incremental update
+	Snapshot *Book
+	Levels   []LevelUpdate
+	// Status is nil if it has not changed
+	Status *BookStatus
+	// Orders are the changes to the individual orders, if the market by order feed is enabled. For a snapshot they
+	// add all of the orders in the book, in priority order.
+	Orders []OrderUpdate
+	// Statistics may be sent with a snapshot
+	Statistics *Statistics
+	Trades     []Trade
+	// Continued is true if the update is a fragment that is followed by the rest of the change
+	Continued bool
+}
+
+const (
+	flagSnapshot = 1 << iota
+	flagStatus
+	flagOrders
+	flagStatistics
+	flagContinued
+)
+
+func EncodeBookUpdate(w *bytes.Buffer, update *BookUpdate) {
+	PutVarint(w, update.Instrument.ID())
+	PutUvarint(w, update.Sequence)
+
+	var flags byte
+	if update.Snapshot != nil {
+		flags |= flagSnapshot
+	}
+	if update.Status != nil {
+		flags |= flagStatus
+	}
+	if len(update.Orders) > 0 {
+		flags |= flagOrders
+	}
+	if update.Statistics != nil {
+		flags |= flagStatistics
+	}
+	if update.Continued {
+		flags |= flagContinued
+	}
+	w.WriteByte(flags)
+
+	if update.Snapshot != nil {
+		encodeBook(w, update.Snapshot)
 	} else {
-		w.WriteByte(0) // no book
+		encodeLevelUpdates(w, update.Levels)
+	}
+	if update.Status != nil {
+		encodeStatus(w, update.Status)
+	}
+	if len(update.Orders) > 0 {
+		encodeOrderUpdates(w, update.Orders)
+	}
+	if update.Statistics != nil {
+		encodeStatistics(w, update.Statistics)
 	}
-	encodeTrades(w, trades)
+	encodeTrades(w, update.Trades)
 }
 
-func DecodeMarketEvent(r *bytes.Buffer) (*Book, []Trade) {
-	instrumentId, _ := ReadVarint(r)
+// DecodeBookUpdate returns the next update in the packet. If the instrument is unknown UnknownInstrument is returned,
+// and ErrMalformed if the update is truncated or corrupt, in either case the rest of the packet cannot be decoded.
+func DecodeBookUpdate(r *bytes.Buffer) (*BookUpdate, error) {
+	d := &decoder{r: r}
+	instrumentId := d.varint()
+	if d.err != nil {
+		return nil, d.err
+	}
 	instrument := IMap.GetByID(instrumentId)
 
 	if instrument == nil {
-		return nil, nil
+		return nil, UnknownInstrument
+	}
+
+	update := &BookUpdate{Instrument: instrument}
+	update.Sequence = d.uvarint()
+
+	flags := d.byte()
+	if flags&flagSnapshot != 0 {
+		update.Snapshot = decodeBook(d, instrument)
+	} else {
+		update.Levels = decodeLevelUpdates(d)
+	}
+	if flags&flagStatus != 0 {
+		update.Status = decodeStatus(d)
+	}
+	if flags&flagOrders != 0 {
+		update.Orders = decodeOrderUpdates(d)
+	}
+	if flags&flagStatistics != 0 {
+		update.Statistics = decodeStatistics(d)
+	}
+	update.Trades = decodeTrades(d, instrument)
+	update.Continued = flags&flagContinued != 0
+	if d.err != nil {
+		return nil, d.err
+	}
+	return update, nil
+}
+
+// Split splits the update into fragments that encode to at most maxSize bytes. The status is in the last fragment,
+// and the others are marked as Continued. A snapshot that is too large is split into a snapshot of the top of the
+// book, followed by incremental updates that add the remaining levels and orders. The fragments must be numbered
+// after splitting.
+func (update *BookUpdate) Split(maxSize int) []*BookUpdate {
+	parts := update.split(maxSize)
+	for i, part := range parts {
+		part.Continued = update.Continued || i < len(parts)-1
+	}
+	return parts
+}
+
+func (update *BookUpdate) split(maxSize int) []*BookUpdate {
+	buf := new(bytes.Buffer)
+	EncodeBookUpdate(buf, update)
+	if buf.Len() <= maxSize {
+		return []*BookUpdate{update}
+	}
+
+	if update.Snapshot != nil {
+		book := *update.Snapshot
+		if len(book.Bids)+len(book.Asks)+len(update.Orders)+len(update.Trades) == 0 {
+			return []*BookUpdate{update}
+		}
+		bids, asks, orders := len(book.Bids)/2, len(book.Asks)/2, len(update.Orders)/2
+		rest := &BookUpdate{Instrument: update.Instrument, Orders: update.Orders[orders:], Trades: update.Trades}
+		for _, level := range book.Bids[bids:] {
+			rest.Levels = append(rest.Levels, LevelUpdate{LevelAdd, Buy, level.Price, level.Quantity})
+		}
+		for _, level := range book.Asks[asks:] {
+			rest.Levels = append(rest.Levels, LevelUpdate{LevelAdd, Sell, level.Price, level.Quantity})
+		}
+		book.Bids, book.Asks = book.Bids[:bids], book.Asks[:asks]
+		first := &BookUpdate{Instrument: update.Instrument, Snapshot: &book, Orders: update.Orders[:orders], Statistics: update.Statistics}
+		return append(first.split(maxSize), rest.split(maxSize)...)
+	}
+
+	levels, orders, trades := len(update.Levels)/2, len(update.Orders)/2, len(update.Trades)/2
+	if levels == 0 && orders == 0 && trades == 0 {
+		// at most one of each
+		if len(update.Levels)+len(update.Orders)+len(update.Trades) <= 1 {
+			return []*BookUpdate{update}
+		}
+		if len(update.Levels) > 0 {
+			levels = 1
+		} else {
+			orders = 1
+		}
+	}
+	first := &BookUpdate{Instrument: update.Instrument, Levels: update.Levels[:levels], Orders: update.Orders[:orders], Trades: update.Trades[:trades]}
+	rest := &BookUpdate{Instrument: update.Instrument, Levels: update.Levels[levels:], Status: update.Status, Orders: update.Orders[orders:], Trades: update.Trades[trades:]}
+	return append(first.split(maxSize), rest.split(maxSize)...)
+}
+
+// DiffBook returns the level changes and the new status, or nil if the status is unchanged, from prev to book
+func DiffBook(prev *Book, book *Book) ([]LevelUpdate, *BookStatus) {
+	var updates []LevelUpdate
+	updates = diffLevels(updates, Buy, prev.Bids, book.Bids)
+	updates = diffLevels(updates, Sell, prev.Asks, book.Asks)
+
+	var status *BookStatus
+	if prev.InAuction != book.InAuction || prev.State != book.State ||
+		!prev.IndicativePrice.Equal(book.IndicativePrice) || !prev.IndicativeVolume.Equal(book.IndicativeVolume) {
+		status = &BookStatus{book.InAuction, book.IndicativePrice, book.IndicativeVolume, book.State}
+	}
+	return updates, status
+}
+
+// diffLevels merges the levels, which are in price priority order for the side
+func diffLevels(updates []LevelUpdate, side Side, prev []BookLevel, levels []BookLevel) []LevelUpdate {
+	i, j := 0, 0
+	for i < len(prev) || j < len(levels) {
+		if j == len(levels) || (i < len(prev) && isBetter(side, prev[i].Price, levels[j].Price)) {
+			updates = append(updates, LevelUpdate{LevelDelete, side, prev[i].Price, ZERO})
+			i++
+		} else if i == len(prev) || isBetter(side, levels[j].Price, prev[i].Price) {
+			updates = append(updates, LevelUpdate{LevelAdd, side, levels[j].Price, levels[j].Quantity})
+			j++
+		} else {
+			if !prev[i].Quantity.Equal(levels[j].Quantity) {
+				updates = append(updates, LevelUpdate{LevelChange, side, levels[j].Price, levels[j].Quantity})
+			}
+			i++
+			j++
+		}
+	}
+	return updates
+}
+
+// isBetter returns true if price p1 has priority over p2 for the side
+func isBetter(side Side, p1 Fixed, p2 Fixed) bool {
+	if side == Buy {
+		return p1.GreaterThan(p2)
+	}
+	return p1.LessThan(p2)
+}
+
+// ApplyBookUpdate returns a new book with the update applied to the book, which may be nil for a snapshot. The
+// book is not modified.
+func ApplyBookUpdate(book *Book, update *BookUpdate) *Book {
+	if update.Snapshot != nil {
+		snapshot := *update.Snapshot
+		book = &snapshot
+	} else {
+		copy := *book
+		book = &copy
+	}
+	book.Instrument = update.Instrument
+	book.Sequence = update.Sequence
+
+	if len(update.Levels) > 0 {
+		book.Bids = append([]BookLevel(nil), book.Bids...)
+		book.Asks = append([]BookLevel(nil), book.Asks...)
+		for _, level := range update.Levels {
+			if level.Side == Buy {
+				book.Bids = applyLevel(book.Bids, level)
+			} else {
+				book.Asks = applyLevel(book.Asks, level)
+			}
+		}
+	}
+	if status := update.Status; status != nil {
+		book.InAuction = status.InAuction
+		book.IndicativePrice = status.IndicativePrice
+		book.IndicativeVolume = status.IndicativeVolume
+		book.State = status.State
 	}
+	return book
+}
 
-	hasBook, _ := r.ReadByte()
-	var book *Book
-	if hasBook == 1 {
-		book = decodeBook(r, instrument)
+func applyLevel(levels []BookLevel, update LevelUpdate) []BookLevel {
+	i := 0
+	for i < len(levels) && isBetter(update.Side, levels[i].Price, update.Price) {
+		i++
+	}
+	exists := i < len(levels) && levels[i].Price.Equal(update.Price)
+	switch {
+	case update.Action == LevelDelete:
+		if exists {
+			levels = append(levels[:i], levels[i+1:]...)
+		}
+	case exists:
+		levels[i].Quantity = update.Quantity
+	default:
+		levels = append(levels, BookLevel{})
+		copy(levels[i+1:], levels[i:])
+		levels[i] = BookLevel{Price: update.Price, Quantity: update.Quantity}
 	}
-	trades := decodeTrades(r, instrument)
-	return book, trades
+	return levels
+}
+
+func encodeLevelUpdates(w *bytes.Buffer, updates []LevelUpdate) {
+	PutUvarint(w, uint64(len(updates)))
+	for _, update := range updates {
+		w.WriteByte(byte(update.Action))
+		if update.Side == Buy {
+			w.WriteByte(0)
+		} else {
+			w.WriteByte(1)
+		}
+		EncodeDecimal(w, update.Price)
+		if update.Action != LevelDelete {
+			EncodeDecimal(w, update.Quantity)
+		}
+	}
+}
+
+func decodeLevelUpdates(d *decoder) []LevelUpdate {
+	// the action, side and price
+	n := d.count(3)
+	updates := make([]LevelUpdate, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		var update LevelUpdate
+		update.Action = LevelAction(d.byte())
+		update.Side = Buy
+		if d.byte() == 1 {
+			update.Side = Sell
+		}
+		update.Price = d.decimal()
+		if update.Action != LevelDelete {
+			update.Quantity = d.decimal()
+		}
+		updates = append(updates, update)
+	}
+	return updates
+}
+
+func encodeOrderUpdates(w *bytes.Buffer, updates []OrderUpdate) {
+	PutUvarint(w, uint64(len(updates)))
+	for _, update := range updates {
+		w.WriteByte(byte(update.Action))
+		PutUvarint(w, update.OrderID)
+		if update.Side == Buy {
+			w.WriteByte(0)
+		} else {
+			w.WriteByte(1)
+		}
+		EncodeDecimal(w, update.Price)
+		EncodeDecimal(w, update.Quantity)
+	}
+}
+
+func decodeOrderUpdates(d *decoder) []OrderUpdate {
+	// the action, order id, side, price and quantity
+	n := d.count(5)
+	updates := make([]OrderUpdate, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		var update OrderUpdate
+		update.Action = OrderAction(d.byte())
+		update.OrderID = d.uvarint()
+		update.Side = Buy
+		if d.byte() == 1 {
+			update.Side = Sell
+		}
+		update.Price = d.decimal()
+		update.Quantity = d.decimal()
+		updates = append(updates, update)
+	}
+	return updates
+}
+
+func encodeStatistics(w *bytes.Buffer, stats *Statistics) {
+	EncodeDecimal(w, stats.Volume)
+	if stats.HasHighLow {
+		w.WriteByte(1)
+		EncodeDecimal(w, stats.High)
+		EncodeDecimal(w, stats.Low)
+	} else {
+		w.WriteByte(0)
+	}
+}
+
+func decodeStatistics(d *decoder) *Statistics {
+	stats := new(Statistics)
+	stats.Volume = d.decimal()
+	if d.byte() == 1 {
+		stats.HasHighLow = true
+		stats.High = d.decimal()
+		stats.Low = d.decimal()
+	}
+	return stats
+}
+
+func encodeStatus(buf *bytes.Buffer, status *BookStatus) {
+	if status.InAuction {
+		buf.WriteByte(1) // in auction
+		EncodeDecimal(buf, status.IndicativePrice)
+		EncodeDecimal(buf, status.IndicativeVolume)
+	} else {
+		buf.WriteByte(0)
+	}
+	EncodeString(buf, string(status.State))
+}
+
+func decodeStatus(d *decoder) *BookStatus {
+	status := new(BookStatus)
+	if d.byte() == 1 {
+		status.InAuction = true
+		status.IndicativePrice = d.decimal()
+		status.IndicativeVolume = d.decimal()
+	}
+	status.State = TradingState(d.string())
+	return status
 }
 
 func encodeBook(buf *bytes.Buffer, book *Book) {
@@ -44,44 +455,52 @@ func encodeBook(buf *bytes.Buffer, book *Book) {
 
 	encodeLevels(buf, book.Bids)
 	encodeLevels(buf, book.Asks)
+
+	encodeStatus(buf, &BookStatus{book.InAuction, book.IndicativePrice, book.IndicativeVolume, book.State})
 }
 
-func decodeBook(r *bytes.Buffer, instrument Instrument) *Book {
+func decodeBook(d *decoder, instrument Instrument) *Book {
 	book := new(Book)
 
-	sequence, _ := ReadUvarint(r)
+	sequence := d.uvarint()
 
 	book.Instrument = instrument
 	book.Sequence = sequence
 
-	book.Bids = decodeLevels(r)
-	book.Asks = decodeLevels(r)
+	book.Bids = decodeLevels(d)
+	book.Asks = decodeLevels(d)
+
+	status := decodeStatus(d)
+	book.InAuction = status.InAuction
+	book.IndicativePrice = status.IndicativePrice
+	book.IndicativeVolume = status.IndicativeVolume
+	book.State = status.State
 
 	return book
 }
 
 func encodeLevels(w *bytes.Buffer, levels []BookLevel) {
-	w.WriteByte(byte(len(levels)))
+	PutUvarint(w, uint64(len(levels)))
 	for _, level := range levels {
 		EncodeDecimal(w, level.Price)
 		EncodeDecimal(w, level.Quantity)
 	}
 }
 
-func decodeLevels(r ByteReader) []BookLevel {
-	n, _ := r.ReadByte()
+func decodeLevels(d *decoder) []BookLevel {
+	// the price and quantity
+	n := d.count(2)
 	levels := make([]BookLevel, n)
-	for i := 0; i < int(n); i++ {
-		price := DecodeDecimal(r)
-		qty := DecodeDecimal(r)
+	for i := 0; i < n && d.err == nil; i++ {
+		price := d.decimal()
+		qty := d.decimal()
 		levels[i] = BookLevel{Price: price, Quantity: qty}
 	}
 	return levels
 }
 
-// this will blow up if any given match generates a ton of trades...
 func encodeTrades(buf *bytes.Buffer, trades []Trade) {
-	buf.WriteByte(byte(len(trades)))
+	PutUvarint(buf, uint64(len(trades)))
 	for _, v := range trades {
 		EncodeDecimal(buf, v.Quantity)
 		EncodeDecimal(buf, v.Price)
@@ -90,20 +509,118 @@ func encodeTrades(buf *bytes.Buffer, trades []Trade) {
 	}
 }
 
-func decodeTrades(r *bytes.Buffer, instrument Instrument) []Trade {
-	n, _ := r.ReadByte() // read length
+func decodeTrades(d *decoder, instrument Instrument) []Trade {
+	// the quantity, price, exchange id length and time
+	n := d.count(4)
 	trades := make([]Trade, n)
-	for i := 0; i < int(n); i++ {
-		qty := DecodeDecimal(r)
-		price := DecodeDecimal(r)
-		exchangeID := DecodeString(r)
-		tradeTime := DecodeTime(r)
+	for i := 0; i < n && d.err == nil; i++ {
+		qty := d.decimal()
+		price := d.decimal()
+		exchangeID := d.string()
+		tradeTime := d.time()
 
 		trades[i] = Trade{Instrument: instrument, Price: price, Quantity: qty, ExchangeID: exchangeID, TradeTime: tradeTime}
 	}
 	return trades
 }
 
+// ErrMalformed is returned for a market data update that is truncated, or has a count larger than the rest of the
+// packet could hold
+var ErrMalformed = errors.New("malformed market data update")
+
+// decoder reads the fields of an update. After the first error the reads return zero values, and the error is kept
+// so the caller can reject the update.
+type decoder struct {
+	r   *bytes.Buffer
+	err error
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = ErrMalformed
+	}
+}
+
+func (d *decoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	b, err := d.r.ReadByte()
+	if err != nil {
+		d.fail()
+	}
+	return b
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := ReadUvarint(d.r)
+	if err != nil {
+		d.fail()
+	}
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := ReadVarint(d.r)
+	if err != nil {
+		d.fail()
+	}
+	return v
+}
+
+func (d *decoder) decimal() Fixed {
+	if d.err != nil {
+		return ZERO
+	}
+	f, err := ReadFrom(d.r)
+	if err != nil {
+		d.fail()
+		return ZERO
+	}
+	return f
+}
+
+func (d *decoder) string() string {
+	n := int(d.byte())
+	if d.err != nil || n > d.r.Len() {
+		d.fail()
+		return ""
+	}
+	return string(d.r.Next(n))
+}
+
+func (d *decoder) time() time.Time {
+	ns := d.varint()
+	return time.Unix(0, ns)
+}
+
+// count reads the number of entries that follow, which must fit in the rest of the packet given the minimum encoded
+// size of an entry
+func (d *decoder) count(minSize int) int {
+	n := d.uvarint()
+	if d.err == nil && n > uint64(d.r.Len()/minSize) {
+		d.fail()
+	}
+	if d.err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+// SnapshotRequest requests a snapshot of the instrument's book, or all books if the InstrumentID is 0. The response
+// is a series of packets, each a uint16 length and book updates, ending with a zero length. The updates for an
+// instrument all have the sequence number of the last update published for it, the first is a snapshot and any
+// others add the levels and orders that did not fit.
+type SnapshotRequest struct {
+	InstrumentID int64
+}
+
 type ReplayRequest struct {
 	// Start is inclusive, and End is exclusive
 	Start, End uint64
@@ -5,6 +5,9 @@ import (
 	"bytes"
 	. "github.com/robaho/go-trader/pkg/common"
 	"reflect"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func TestEncodeDecodeBook(t *testing.T) {
@@ -13,15 +16,291 @@ func TestEncodeDecodeBook(t *testing.T) {
 	IMap.Put(instrument)
 
 	book := Book{Instrument: instrument, Sequence: 123456789}
-	book.Bids = []BookLevel{BookLevel{NewDecimal("99.4567"), NewDecimal("100")}}
-	book.Asks = []BookLevel{BookLevel{NewDecimal("100.4567"), NewDecimal("120")}}
+	book.Bids = []BookLevel{{Price: NewDecimal("99.4567"), Quantity: NewDecimal("100")}}
+	book.Asks = []BookLevel{{Price: NewDecimal("100.4567"), Quantity: NewDecimal("120")}}
 
 	buf := new(bytes.Buffer)
 	encodeBook(buf, &book)
 
-	book2 := decodeBook(buf, instrument)
+	d := &decoder{r: buf}
+	book2 := decodeBook(d, instrument)
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
 
 	if !reflect.DeepEqual(book, *book2) {
 		t.Error("books do not match", &book, book2)
 	}
 }
+
+func TestEncodeDecodeAuctionBook(t *testing.T) {
+
+	instrument := NewInstrument(12346, "AUCT")
+	IMap.Put(instrument)
+
+	book := Book{Instrument: instrument, Sequence: 1}
+	book.Bids = []BookLevel{{Price: NewDecimal("101"), Quantity: NewDecimal("100")}}
+	book.Asks = []BookLevel{{Price: NewDecimal("100"), Quantity: NewDecimal("50")}}
+	book.InAuction = true
+	book.IndicativePrice = NewDecimal("100")
+	book.IndicativeVolume = NewDecimal("50")
+	book.State = Auction
+
+	buf := new(bytes.Buffer)
+	encodeBook(buf, &book)
+
+	d := &decoder{r: buf}
+	book2 := decodeBook(d, instrument)
+	if d.err != nil {
+		t.Fatal(d.err)
+	}
+
+	if !reflect.DeepEqual(book, *book2) {
+		t.Error("books do not match", &book, book2)
+	}
+}
+
+func applyEncoded(t *testing.T, book *Book, update *BookUpdate) *Book {
+	buf := new(bytes.Buffer)
+	EncodeBookUpdate(buf, update)
+	if buf.Len() > MaxMsgSize-8 {
+		t.Error("update too large", buf.Len())
+	}
+	decoded, err := DecodeBookUpdate(buf)
+	if err != nil || buf.Len() != 0 {
+		t.Fatal("unable to decode update", err)
+	}
+	return ApplyBookUpdate(book, decoded)
+}
+
+func TestIncrementalBookUpdates(t *testing.T) {
+
+	instrument := NewInstrument(12347, "INCR")
+	IMap.Put(instrument)
+
+	book := &Book{Instrument: instrument, State: Open}
+	book.Bids = []BookLevel{{Price: NewDecimal("100"), Quantity: NewDecimal("10")}, {Price: NewDecimal("99"), Quantity: NewDecimal("5")}}
+	book.Asks = []BookLevel{{Price: NewDecimal("101"), Quantity: NewDecimal("7")}}
+
+	received := applyEncoded(t, nil, &BookUpdate{Instrument: instrument, Sequence: 1, Snapshot: book})
+
+	next := &Book{Instrument: instrument, State: Halted}
+	next.Bids = []BookLevel{{Price: NewDecimal("100.5"), Quantity: NewDecimal("1")}, {Price: NewDecimal("100"), Quantity: NewDecimal("4")}}
+	next.Asks = []BookLevel{{Price: NewDecimal("101"), Quantity: NewDecimal("7")}, {Price: NewDecimal("102"), Quantity: NewDecimal("3")}}
+
+	levels, status := DiffBook(book, next)
+	if len(levels) != 4 {
+		t.Error("wrong number of level updates", levels)
+	}
+	if status == nil || status.State != Halted {
+		t.Error("status change not detected", status)
+	}
+	trades := []Trade{{Instrument: instrument, Price: NewDecimal("99"), Quantity: NewDecimal("5"), ExchangeID: "1", TradeTime: time.Unix(0, 1)}}
+	update := &BookUpdate{Instrument: instrument, Sequence: 2, Levels: levels, Status: status, Trades: trades}
+
+	buf := new(bytes.Buffer)
+	EncodeBookUpdate(buf, update)
+	decoded, err := DecodeBookUpdate(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(update, decoded) {
+		t.Error("updates do not match", update, decoded)
+	}
+
+	received = ApplyBookUpdate(received, decoded)
+	next.Sequence = 2
+	if !received.Equals(*next) {
+		t.Error("books do not match", next, received)
+	}
+	if len(book.Bids) != 2 || !book.Bids[1].Price.Equal(NewDecimal("99")) {
+		t.Error("original book should not be modified", book)
+	}
+}
+
+func TestSplitBookUpdate(t *testing.T) {
+
+	instrument := NewInstrument(12348, "DEEP")
+	IMap.Put(instrument)
+
+	book := &Book{Instrument: instrument, State: Open}
+	for i := 0; i < 300; i++ {
+		book.Bids = append(book.Bids, BookLevel{Price: NewDecimalF(1000 - float64(i)*0.01), Quantity: NewDecimal("100")})
+		book.Asks = append(book.Asks, BookLevel{Price: NewDecimalF(1001 + float64(i)*0.01), Quantity: NewDecimal("100")})
+	}
+
+	updates := (&BookUpdate{Instrument: instrument, Snapshot: book}).Split(MaxMsgSize - 8)
+	if len(updates) < 2 || updates[0].Snapshot == nil {
+		t.Fatal("deep book should be split", len(updates))
+	}
+	var received *Book
+	for i, update := range updates {
+		if update.Continued != (i < len(updates)-1) {
+			t.Error("only the last fragment should not be continued", i)
+		}
+		update.Sequence = uint64(i + 1)
+		received = applyEncoded(t, received, update)
+	}
+	if !reflect.DeepEqual(book.Bids, received.Bids) || !reflect.DeepEqual(book.Asks, received.Asks) {
+		t.Error("split book does not match")
+	}
+
+	empty := &Book{Instrument: instrument, State: Open, Sequence: uint64(len(updates))}
+	levels, _ := DiffBook(received, empty)
+	for _, update := range (&BookUpdate{Instrument: instrument, Levels: levels}).Split(MaxMsgSize - 8) {
+		received = applyEncoded(t, received, update)
+	}
+	if !received.IsEmpty() {
+		t.Error("all levels should be deleted", received)
+	}
+}
+
+func TestFragmentedTrades(t *testing.T) {
+
+	instrument := NewInstrument(12349, "SWEEP")
+	IMap.Put(instrument)
+
+	var trades []Trade
+	for i := 0; i < 500; i++ {
+		trades = append(trades, Trade{Instrument: instrument, Price: NewDecimalF(100 + float64(i)*0.01), Quantity: NewDecimal("1"), ExchangeID: strconv.Itoa(i), TradeTime: time.Unix(0, int64(i))})
+	}
+	levels := []LevelUpdate{{LevelDelete, Sell, NewDecimal("100"), NewDecimal("0")}}
+	status := &BookStatus{State: Open}
+
+	fragments := (&BookUpdate{Instrument: instrument, Levels: levels, Status: status, Trades: trades}).Split(MaxMsgSize - 8)
+	if len(fragments) < 2 {
+		t.Fatal("sweep should be fragmented", len(fragments))
+	}
+
+	var received []Trade
+	var reassembled []*BookUpdate
+	for i, fragment := range fragments {
+		fragment.Sequence = uint64(i + 1)
+		buf := new(bytes.Buffer)
+		EncodeBookUpdate(buf, fragment)
+		if buf.Len() > MaxMsgSize-8 {
+			t.Error("fragment too large", buf.Len())
+		}
+		decoded, err := DecodeBookUpdate(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reassembled = append(reassembled, decoded)
+		received = append(received, decoded.Trades...)
+		if decoded.Continued != (i < len(fragments)-1) {
+			t.Error("only the last fragment should not be continued", i)
+		}
+		if decoded.Status != nil && decoded.Continued {
+			t.Error("the status should be in the last fragment")
+		}
+	}
+	if !reflect.DeepEqual(trades, received) {
+		t.Error("reassembled trades do not match")
+	}
+	if last := reassembled[len(reassembled)-1]; !reflect.DeepEqual(last.Status, status) {
+		t.Error("status not in the last fragment", last.Status)
+	}
+}
+
+func TestParseChannels(t *testing.T) {
+	settings := `multicast_addr=224.0.0.100:9999
+replay_port=9999
+marketdata_channels=A,B
+multicast_addr.A=224.0.0.101:9999
+replay_port.A=10001
+snapshot_port.A=10002
+marketdata_groups.A=IBM
+multicast_addr.B=224.0.0.102:9999
+replay_port.B=10003
+marketdata_ids.B=100-199,300
+`
+	props, err := NewPropertiesFromReader(strings.NewReader(settings))
+	if err != nil {
+		t.Fatal(err)
+	}
+	channels, err := ParseChannels(props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channels) != 3 || channels[2].Name != "" || channels[2].MulticastAddr != "224.0.0.100:9999" {
+		t.Fatal("wrong channels", channels)
+	}
+	if channels[0].SnapshotPort != "10002" || channels[1].SnapshotPort != "" {
+		t.Error("wrong snapshot ports", channels)
+	}
+
+	tests := []struct {
+		instrument Instrument
+		channel    int
+	}{
+		{NewInstrument(1, "IBM"), 0},
+		{NewInstrument(150, "AAPL"), 1},
+		{NewInstrument(300, "MSFT"), 1},
+		{NewInstrument(200, "ORCL"), 2},
+	}
+	for _, test := range tests {
+		if channel := ChannelFor(channels, test.instrument); channel != test.channel {
+			t.Error("wrong channel for", test.instrument, channel)
+		}
+	}
+
+	props.SetString("marketdata_ids.B", "200-100")
+	if _, err := ParseChannels(props); err == nil {
+		t.Error("invalid id range should fail")
+	}
+}
+
+func encodedUpdate(instrument Instrument) []byte {
+	book := &Book{Instrument: instrument, Sequence: 7, State: Open}
+	book.Bids = []BookLevel{{Price: NewDecimal("99.5"), Quantity: NewDecimal("100")}}
+	book.Asks = []BookLevel{{Price: NewDecimal("100.5"), Quantity: NewDecimal("50")}}
+	update := &BookUpdate{Instrument: instrument, Sequence: 7, Snapshot: book, Status: &BookStatus{State: Open},
+		Orders:     []OrderUpdate{{Action: OrderAdd, OrderID: 1, Side: Buy, Price: NewDecimal("99.5"), Quantity: NewDecimal("100")}},
+		Statistics: &Statistics{Volume: NewDecimal("10")},
+		Trades:     []Trade{{Instrument: instrument, Price: NewDecimal("100"), Quantity: NewDecimal("10"), ExchangeID: "1", TradeTime: time.Unix(0, 1)}}}
+	buf := new(bytes.Buffer)
+	EncodeBookUpdate(buf, update)
+	return buf.Bytes()
+}
+
+func TestDecodeTruncatedUpdate(t *testing.T) {
+
+	instrument := NewInstrument(12350, "TRUNC")
+	IMap.Put(instrument)
+
+	packet := encodedUpdate(instrument)
+	if _, err := DecodeBookUpdate(bytes.NewBuffer(packet)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(packet); i++ {
+		if update, err := DecodeBookUpdate(bytes.NewBuffer(packet[:i])); err == nil {
+			t.Error("truncated update should not decode", i, update)
+		}
+	}
+
+	// an update with a huge level count must be rejected without allocating it
+	buf := new(bytes.Buffer)
+	PutVarint(buf, instrument.ID())
+	PutUvarint(buf, 1)
+	buf.WriteByte(0)
+	PutUvarint(buf, 1<<62)
+	buf.Write(make([]byte, 16))
+	if _, err := DecodeBookUpdate(buf); err != ErrMalformed {
+		t.Error("count larger than the packet should be rejected", err)
+	}
+}
+
+func FuzzDecodeBookUpdate(f *testing.F) {
+	instrument := NewInstrument(12351, "FUZZ")
+	IMap.Put(instrument)
+
+	f.Add(encodedUpdate(instrument))
+	f.Fuzz(func(t *testing.T, packet []byte) {
+		buf := bytes.NewBuffer(packet)
+		for buf.Len() > 0 {
+			if _, err := DecodeBookUpdate(buf); err != nil {
+				return
+			}
+		}
+	})
+}
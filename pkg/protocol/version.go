@@ -0,0 +1,75 @@
+package protocol
+
+import (
+	"math"
+
+	. "github.com/robaho/fixed"
+)
+
+// Version is the gRPC protocol version, it determines how the prices and quantities are encoded, see exchange.proto
+type Version int32
+
+const (
+	// VersionDouble sends the prices and quantities as double, it is used by clients that do not send a version
+	VersionDouble Version = 1
+	// VersionScaled sends the prices and quantities as int64 scaled by 10^7, the precision of Fixed
+	VersionScaled Version = 2
+	// LatestVersion is the highest version supported
+	LatestVersion = VersionScaled
+)
+
+// scaledPlaces is the number of decimal places of the scaled values
+const scaledPlaces = 7
+
+// ScaledNaN is the scaled value sent for NaN, e.g. a price that is not set
+const ScaledNaN = math.MinInt64
+
+// scaleFactor is 10^scaledPlaces
+var scaleFactor = NewI(10000000, 0)
+
+// NegotiateVersion returns the version to use with a peer that supports the version, 0 if it did not send one
+func NegotiateVersion(version int32) Version {
+	switch {
+	case Version(version) < VersionDouble:
+		return VersionDouble
+	case Version(version) > LatestVersion:
+		return LatestVersion
+	}
+	return Version(version)
+}
+
+// Encode sets the double or the scaled field to the value, depending on the version
+func (v Version) Encode(f Fixed, double *float64, scaled *int64) {
+	if v >= VersionScaled {
+		*scaled = ToScaled(f)
+	} else {
+		*double = f.Float()
+	}
+}
+
+// Decode returns the value of the double or the scaled field, depending on the version
+func (v Version) Decode(double float64, scaled int64) Fixed {
+	if v >= VersionScaled {
+		return FromScaled(scaled)
+	}
+	return NewF(double)
+}
+
+// ToScaled returns the value multiplied by 10^7, exactly, or ScaledNaN if the value is NaN
+func ToScaled(f Fixed) int64 {
+	if f.IsNaN() {
+		return ScaledNaN
+	}
+	// the fraction is scaled separately, so the product cannot overflow
+	i := f.Int()
+	frac := f.Sub(NewI(i, 0))
+	return i*10000000 + frac.Mul(scaleFactor).Int()
+}
+
+// FromScaled returns the value of a number multiplied by 10^7, or NaN for ScaledNaN
+func FromScaled(scaled int64) Fixed {
+	if scaled == ScaledNaN {
+		return NaN
+	}
+	return NewI(scaled, scaledPlaces)
+}
@@ -0,0 +1,64 @@
+package protocol
+
+import (
+	"testing"
+
+	. "github.com/robaho/fixed"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	if v := NegotiateVersion(0); v != VersionDouble {
+		t.Error("client without version should use doubles", v)
+	}
+	if v := NegotiateVersion(int32(VersionScaled)); v != VersionScaled {
+		t.Error("wrong version", v)
+	}
+	if v := NegotiateVersion(int32(LatestVersion) + 1); v != LatestVersion {
+		t.Error("newer client should use latest version", v)
+	}
+}
+
+func TestScaled(t *testing.T) {
+	for _, s := range []string{"0", "1", "0.0000001", "123.4567891", "-98765.4321", "99999999999.9999999", "-99999999999.9999999", "-0.0000001"} {
+		f := NewS(s)
+		if scaled := FromScaled(ToScaled(f)); !scaled.Equal(f) {
+			t.Error("wrong scaled value", s, scaled)
+		}
+	}
+	if ToScaled(NewS("1.5")) != 15000000 {
+		t.Error("wrong scale", ToScaled(NewS("1.5")))
+	}
+	if scaled := ToScaled(NewS("99999999999.9999999")); scaled != 999999999999999999 {
+		t.Error("wrong scaled max value", scaled)
+	}
+	if scaled := ToScaled(NewS("-12.5")); scaled != -125000000 {
+		t.Error("wrong scaled negative value", scaled)
+	}
+	if scaled := ToScaled(NaN); scaled != ScaledNaN {
+		t.Error("NaN should be sent as ScaledNaN", scaled)
+	}
+	if !FromScaled(ScaledNaN).IsNaN() {
+		t.Error("ScaledNaN should be NaN", FromScaled(ScaledNaN))
+	}
+
+	price := NewS("0.1234567")
+	var double float64
+	var scaled int64
+
+	VersionScaled.Encode(price, &double, &scaled)
+	if double != 0 || scaled != 1234567 {
+		t.Error("version 2 should only set the scaled field", double, scaled)
+	}
+	if !VersionScaled.Decode(double, scaled).Equal(price) {
+		t.Error("wrong decoded value", VersionScaled.Decode(double, scaled))
+	}
+
+	double, scaled = 0, 0
+	VersionDouble.Encode(price, &double, &scaled)
+	if double != 0.1234567 || scaled != 0 {
+		t.Error("version 1 should only set the double field", double, scaled)
+	}
+	if !VersionDouble.Decode(double, scaled).Equal(price) {
+		t.Error("wrong decoded value", VersionDouble.Decode(double, scaled))
+	}
+}